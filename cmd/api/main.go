@@ -2,16 +2,27 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
 
 	appcmd "demo/internal/application/command"
 	appquery "demo/internal/application/query"
+	"demo/internal/config"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/auditstore"
 	"demo/internal/infrastructure/auth"
 	"demo/internal/infrastructure/cache"
 	"demo/internal/infrastructure/deckstore"
 	"demo/internal/infrastructure/eventstore"
 	"demo/internal/infrastructure/messaging"
+	"demo/internal/infrastructure/ratelimit"
+	"demo/internal/infrastructure/shredding"
+	"demo/internal/infrastructure/userstore"
 	httpiface "demo/internal/interfaces/http"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
@@ -19,6 +30,8 @@ import (
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+const redisAddr = "localhost:6379"
+
 func initTracer() func(context.Context) error {
 	exp, err := stdouttrace.New()
 	if err != nil {
@@ -32,6 +45,146 @@ func initTracer() func(context.Context) error {
 	return tp.Shutdown
 }
 
+// formatsFile returns the path of the deck formats configuration.
+func formatsFile() string {
+	if path := os.Getenv("DECK_FORMATS_FILE"); path != "" {
+		return path
+	}
+	return "configs/formats.json"
+}
+
+// deckRepositories returns the deck repository selected by DECK_STORE, which
+// also keeps the history of the decks: "eventstore" (default) keeps deck
+// streams in the card event store, with the decks of every user indexed in
+// MySQL, "mysql" and "redis" keep the current deck state and its revisions
+// in those databases and "memory" keeps them in process.
+func deckRepositories(es *eventstore.MySQLStore, redisAddr string) (deck.Repository, deck.HistoryRepository, error) {
+	switch backend := os.Getenv("DECK_STORE"); backend {
+	case "", "eventstore":
+		index, err := deckstore.NewMySQLUserIndex(es.DB)
+		if err != nil {
+			return nil, nil, err
+		}
+		repo := deckstore.NewEventStore(es)
+		repo.Index = index
+		return repo, repo, nil
+	case "mysql":
+		repo, err := deckstore.NewMySQLStore(es.DB)
+		return repo, repo, err
+	case "redis":
+		repo := deckstore.NewRedisStore(redisAddr)
+		return repo, repo, nil
+	case "memory":
+		repo := deckstore.NewInMemoryStore()
+		return repo, repo, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown DECK_STORE %q", backend)
+	}
+}
+
+// deckGallery returns the read model of the public deck gallery, kept in
+// process for the "memory" DECK_STORE and in MySQL otherwise.
+func deckGallery(es *eventstore.MySQLStore) (deck.GalleryRepository, error) {
+	if os.Getenv("DECK_STORE") == "memory" {
+		return deckstore.NewInMemoryGallery(), nil
+	}
+	return deckstore.NewMySQLGallery(es.DB)
+}
+
+// cardNumbers returns the numbering of the cards deck codes refer to, kept
+// in process for the "memory" DECK_STORE and in MySQL otherwise.
+func cardNumbers(es *eventstore.MySQLStore) (deck.CardNumberRepository, error) {
+	if os.Getenv("DECK_STORE") == "memory" {
+		return deckstore.NewInMemoryCardNumbers(), nil
+	}
+	return deckstore.NewMySQLCardNumbers(es.DB)
+}
+
+// rateLimits returns the rate limits selected by RATE_LIMITER: "redis"
+// (default) shares the buckets between replicas, "memory" keeps them in
+// process and "off" turns rate limiting off.
+func rateLimits(redisAddr string) (httpiface.RateLimits, error) {
+	switch name := os.Getenv("RATE_LIMITER"); name {
+	case "", "redis":
+		return httpiface.DefaultRateLimits(ratelimit.NewRedisLimiter(redisAddr)), nil
+	case "memory":
+		return httpiface.DefaultRateLimits(ratelimit.NewInMemoryLimiter()), nil
+	case "off":
+		return httpiface.RateLimits{}, nil
+	default:
+		return httpiface.RateLimits{}, fmt.Errorf("unknown RATE_LIMITER %q", name)
+	}
+}
+
+// passwordHasher returns the hasher selected by PASSWORD_HASHER: "argon2id"
+// (default) or "bcrypt".
+func passwordHasher() (auth.PasswordHasher, error) {
+	switch name := os.Getenv("PASSWORD_HASHER"); name {
+	case "", "argon2id":
+		return auth.NewArgon2id(), nil
+	case "bcrypt":
+		return auth.Bcrypt{Cost: 12}, nil
+	default:
+		return nil, fmt.Errorf("unknown PASSWORD_HASHER %q", name)
+	}
+}
+
+// signingKeys returns the keys signing access tokens, given in JWT_KEYS as
+// comma separated kid=secret pairs with the signing key first. Without
+// JWT_KEYS a random key is used and sessions end on restart.
+func signingKeys() (*auth.KeyRing, error) {
+	if keys := os.Getenv("JWT_KEYS"); keys != "" {
+		return auth.ParseKeyRing(keys)
+	}
+	log.Println("JWT_KEYS not set, signing access tokens with a random key")
+	return auth.NewRandomKeyRing(), nil
+}
+
+// tenants returns the tenants served, given in TENANTS as a comma separated
+// list of ids. The default tenant is always served.
+func tenants() (*tenant.Registry, error) {
+	var ids []string
+	for _, id := range strings.Split(os.Getenv("TENANTS"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return tenant.NewRegistry(ids...)
+}
+
+// oidcProvider returns the OpenID provider configured by OIDC_ISSUER,
+// OIDC_CLIENT_ID, OIDC_CLIENT_SECRET and OIDC_REDIRECT_URL, or nil without
+// OIDC_ISSUER.
+func oidcProvider(ctx context.Context) (*auth.OIDCProvider, error) {
+	issuer := os.Getenv("OIDC_ISSUER")
+	if issuer == "" {
+		return nil, nil
+	}
+	return auth.NewOIDCProvider(ctx, auth.OIDCConfig{
+		IssuerURL:    issuer,
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+	})
+}
+
+// promoteAdmin gives the user named by ADMIN_USER the admin role, so that a
+// new deployment has someone to manage the other users.
+func promoteAdmin(ctx context.Context, authSvc *auth.Service) error {
+	name := os.Getenv("ADMIN_USER")
+	if name == "" {
+		return nil
+	}
+	u, err := authSvc.Users.ByUsername(ctx, name)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return fmt.Errorf("ADMIN_USER %q is not registered", name)
+	}
+	return authSvc.SetRole(ctx, u.ID, user.RoleAdmin)
+}
+
 func main() {
 	shutdown := initTracer()
 	defer func() { _ = shutdown(context.Background()) }()
@@ -40,21 +193,110 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	keys, err := shredding.NewGormKeyStore(es.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	es.Cipher = shredding.NewProtector(keys)
 	// wrap repository with redis cache
-	repo := cache.NewRedisRepository(es, "localhost:6379")
-	deckRepo := deckstore.NewInMemoryStore()
-	authSvc := auth.NewService()
+	repo := cache.NewRedisRepository(es, redisAddr)
+	deckRepo, history, err := deckRepositories(es, redisAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	gallery, err := deckGallery(es)
+	if err != nil {
+		log.Fatal(err)
+	}
+	numbers, err := cardNumbers(es)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// keep the gallery in step with every saved deck
+	deckRepo = deckstore.NewGalleryProjection(deckRepo, gallery, repo)
+	users, err := userstore.NewMySQLStore(es.DB)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authSvc := auth.NewService(users)
+	if authSvc.Hasher, err = passwordHasher(); err != nil {
+		log.Fatal(err)
+	}
+	if authSvc.Keys, err = signingKeys(); err != nil {
+		log.Fatal(err)
+	}
+	if authSvc.Tenants, err = tenants(); err != nil {
+		log.Fatal(err)
+	}
+	authSvc.Tokens = auth.NewRedisTokenStore(redisAddr)
+	authSvc.Lockouts = auth.NewRedisLockoutStore(redisAddr)
+	if authSvc.APIKeys, err = auth.NewMySQLAPIKeyStore(es.DB); err != nil {
+		log.Fatal(err)
+	}
+	if authSvc.Identities, err = auth.NewMySQLIdentityStore(es.DB); err != nil {
+		log.Fatal(err)
+	}
+	if authSvc.Audit, err = auditstore.NewMySQLStore(es.DB); err != nil {
+		log.Fatal(err)
+	}
+	if authSvc.OIDC, err = oidcProvider(context.Background()); err != nil {
+		log.Fatal(err)
+	}
+	// PASSWORD_LOGIN=off leaves logging in to the OpenID provider
+	authSvc.DisablePasswords = authSvc.OIDC != nil && os.Getenv("PASSWORD_LOGIN") == "off"
+	if err := promoteAdmin(context.Background(), authSvc); err != nil {
+		log.Println("no admin promoted", err)
+	}
 	publisher, err := messaging.NewPublisher([]string{"localhost:9092"})
 	if err != nil {
 		log.Println("failed to create publisher", err)
 	}
+	if publisher != nil {
+		// personal data leaves for Kafka encrypted like in the event store
+		publisher.Cipher = es.Cipher
+		authSvc.Publisher = publisher
+	}
+	// deleted accounts lose their decks and the key of their personal data
+	authSvc.Forget = &appcmd.ForgetUserHandler{Keys: keys, Decks: deckRepo, Publisher: publisher}
 
 	createHandler := &appcmd.CreateCardHandler{Repo: repo, Publisher: publisher}
 	updateHandler := &appcmd.UpdateCardHandler{Repo: repo, Publisher: publisher}
 	searchHandler := &appquery.SearchCardsHandler{Repo: repo}
-	deckHandler := &appcmd.CreateDeckHandler{Repo: deckRepo}
+	formats, err := config.LoadFormats(formatsFile())
+	if err != nil {
+		log.Println("no deck formats loaded", err)
+	}
+	rules, err := deck.NewRules(formats)
+	if err != nil {
+		log.Fatal(err)
+	}
+	validator := &appcmd.DeckValidator{Rules: rules, Cards: repo}
+
+	limits, err := rateLimits(redisAddr)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	r := httpiface.Router(authSvc, createHandler, updateHandler, searchHandler, deckHandler)
+	r := httpiface.Router(authSvc, httpiface.Handlers{
+		CreateCard:    createHandler,
+		UpdateCard:    updateHandler,
+		SearchCards:   searchHandler,
+		ImportCards:   &appcmd.ImportCardsHandler{Create: createHandler, Update: updateHandler},
+		CreateDeck:    &appcmd.CreateDeckHandler{Repo: deckRepo, Publisher: publisher, Validator: validator},
+		UpdateDeck:    &appcmd.UpdateDeckHandler{Repo: deckRepo, Publisher: publisher, Validator: validator, History: history},
+		DeleteDeck:    &appcmd.DeleteDeckHandler{Repo: deckRepo, Publisher: publisher},
+		CloneDeck:     &appcmd.CloneDeckHandler{Repo: deckRepo, Publisher: publisher, Validator: validator},
+		ListDecks:     &appquery.ListDecksHandler{Decks: deckRepo},
+		GetDeck:       &appquery.GetDeckHandler{Decks: deckRepo, Cards: repo, Rules: rules},
+		DeckCode:      &appquery.GetDeckCodeHandler{Decks: deckRepo, Numbers: numbers},
+		ImportDeck:    &appcmd.ImportDeckHandler{Repo: deckRepo, Numbers: numbers, Publisher: publisher, Validator: validator},
+		ImportList:    &appcmd.ImportDeckListHandler{Repo: deckRepo, Cards: repo, Publisher: publisher, Validator: validator},
+		DeckStats:     &appquery.DeckStatsHandler{Decks: deckRepo, Cards: repo},
+		DeckHistory:   &appquery.DeckHistoryHandler{Decks: deckRepo, History: history},
+		SetVisibility: &appcmd.SetDeckVisibilityHandler{Repo: deckRepo, Publisher: publisher},
+		LikeDeck:      &appcmd.LikeDeckHandler{Gallery: gallery},
+		Gallery:       &appquery.GalleryHandler{Gallery: gallery, Decks: deckRepo, Cards: repo},
+	}, limits)
 	log.Println("http server started on :8080")
 	if err := http.ListenAndServe(":8080", r); err != nil {
 		log.Fatal(err)
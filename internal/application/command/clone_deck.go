@@ -0,0 +1,39 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// CloneDeckCommand copies a deck. An empty Name names the copy after the
+// original.
+type CloneDeckCommand struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+	Name   string
+}
+
+// CloneDeckHandler handles deck cloning.
+type CloneDeckHandler struct {
+	Repo      deck.Repository
+	Publisher EventPublisher
+	// Validator, when set, rejects copies breaking the rules of the format,
+	// e.g. after the format was changed.
+	Validator *DeckValidator
+}
+
+// Handle creates a new deck for the user with the zones of the original.
+func (h *CloneDeckHandler) Handle(ctx context.Context, cmd CloneDeckCommand) (*deck.Deck, error) {
+	src, err := loadOwnedDeck(ctx, h.Repo, cmd.DeckID, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+	name := cmd.Name
+	if name == "" {
+		name = src.Name + " (copy)"
+	}
+	create := &CreateDeckHandler{Repo: h.Repo, Publisher: h.Publisher, Validator: h.Validator}
+	return create.Handle(ctx, CreateDeckCommand{UserID: cmd.UserID, Name: name, Format: src.Format, Zones: src.Zones})
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestCloneDeckHandler(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	owner := uuid.New()
+	a := uuid.New()
+	d, err := (&CreateDeckHandler{Repo: repo}).Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d", Format: "standard", CardIDs: []uuid.UUID{a, a}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &mockPublisher{}
+	h := &CloneDeckHandler{Repo: repo, Publisher: pub}
+	c, err := h.Handle(ctx, CloneDeckCommand{DeckID: d.ID, UserID: owner})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.ID == d.ID || c.Name != "d (copy)" || c.Format != "standard" || len(c.CardIDs()) != 2 || c.UserID != owner {
+		t.Fatalf("unexpected clone %+v", c)
+	}
+	if loaded, _ := repo.Load(ctx, c.ID); loaded == nil || len(loaded.CardIDs()) != 2 {
+		t.Fatalf("unexpected stored clone %+v", loaded)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("expected 1 published event got %d", len(pub.events))
+	}
+	named, err := h.Handle(ctx, CloneDeckCommand{DeckID: d.ID, UserID: owner, Name: "x"})
+	if err != nil || named.Name != "x" {
+		t.Fatalf("unexpected clone %+v %v", named, err)
+	}
+	if _, err := h.Handle(ctx, CloneDeckCommand{DeckID: d.ID, UserID: uuid.New()}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+	if _, err := h.Handle(ctx, CloneDeckCommand{DeckID: uuid.New(), UserID: owner}); !errors.Is(err, deck.ErrNotFound) {
+		t.Fatalf("expected deck.ErrNotFound got %v", err)
+	}
+}
@@ -7,21 +7,43 @@ import (
 	"github.com/google/uuid"
 )
 
-// CreateDeckCommand contains info needed to create a deck.
+// CreateDeckCommand contains info needed to create a deck. CardIDs, one
+// element per copy, are added to the main zone of Zones.
 type CreateDeckCommand struct {
 	UserID  uuid.UUID
 	Name    string
+	Format  string
 	CardIDs []uuid.UUID
+	Zones   []deck.Zone
 }
 
 // CreateDeckHandler handles deck creation.
-type CreateDeckHandler struct{ Repo deck.Repository }
+type CreateDeckHandler struct {
+	Repo      deck.Repository
+	Publisher EventPublisher
+	// Validator, when set, rejects decks breaking the rules of their format.
+	Validator *DeckValidator
+}
 
 // Handle creates the deck and persists it.
 func (h *CreateDeckHandler) Handle(ctx context.Context, cmd CreateDeckCommand) (*deck.Deck, error) {
-	d := deck.NewDeck(cmd.UserID, cmd.Name, cmd.CardIDs)
-	if err := h.Repo.Save(ctx, d); err != nil {
+	zones, err := deck.NormalizeZones(append([]deck.Zone{deck.ZoneOf(deck.ZoneMain, cmd.CardIDs)}, cmd.Zones...))
+	if err != nil {
+		return nil, err
+	}
+	d := deck.NewDeck(cmd.UserID, cmd.Name, nil)
+	d.Format = cmd.Format
+	d.Zones = zones
+	if err := h.Validator.Check(ctx, nil, d); err != nil {
 		return nil, err
 	}
-	return d, nil
+	// the new deck is its first revision
+	events := []interface{}{d.Created(), d.Revise()}
+	if err := h.Repo.Save(ctx, 0, events); err != nil {
+		return nil, err
+	}
+	if h.Publisher != nil {
+		_ = h.Publisher.Publish(ctx, "deck_events", events[0])
+	}
+	return deck.Replay(events), nil
 }
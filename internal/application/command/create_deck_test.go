@@ -0,0 +1,61 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+type mockPublisher struct {
+	topics []string
+	events []interface{}
+}
+
+func (m *mockPublisher) Publish(ctx context.Context, topic string, event interface{}) error {
+	m.topics = append(m.topics, topic)
+	m.events = append(m.events, event)
+	return nil
+}
+
+func TestCreateDeckHandler(t *testing.T) {
+	repo := deckstore.NewInMemoryStore()
+	pub := &mockPublisher{}
+	h := &CreateDeckHandler{Repo: repo, Publisher: pub}
+	d, err := h.Handle(context.Background(), CreateDeckCommand{UserID: uuid.New(), Name: "d", CardIDs: []uuid.UUID{uuid.New()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := repo.Load(context.Background(), d.ID); loaded == nil || loaded.Name != "d" || len(loaded.CardIDs()) != 1 {
+		t.Fatalf("unexpected deck %+v", loaded)
+	}
+	if len(pub.events) != 1 || pub.topics[0] != "deck_events" {
+		t.Fatalf("unexpected published events %v", pub.events)
+	}
+	if _, ok := pub.events[0].(deck.DeckCreated); !ok {
+		t.Fatalf("expected DeckCreated got %T", pub.events[0])
+	}
+}
+
+func TestCreateDeckHandlerZones(t *testing.T) {
+	repo := deckstore.NewInMemoryStore()
+	h := &CreateDeckHandler{Repo: repo}
+	a, b := uuid.New(), uuid.New()
+	d, err := h.Handle(context.Background(), CreateDeckCommand{Name: "d", CardIDs: []uuid.UUID{a}, Zones: []deck.Zone{
+		{Name: deck.ZoneSideboard, Cards: []deck.Entry{{CardID: b, Quantity: 2}}},
+		{Name: deck.ZoneMain, Cards: []deck.Entry{{CardID: a, Quantity: 2}}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := repo.Load(context.Background(), d.ID)
+	if loaded == nil || len(loaded.Zones) != 2 || loaded.Zone(deck.ZoneMain).Quantity(a) != 3 || loaded.Zone(deck.ZoneSideboard).Quantity(b) != 2 {
+		t.Fatalf("unexpected deck %+v", loaded)
+	}
+	if _, err := h.Handle(context.Background(), CreateDeckCommand{Name: "d", Zones: []deck.Zone{{Name: "side board"}}}); !errors.Is(err, deck.ErrInvalidZone) {
+		t.Fatalf("expected ErrInvalidZone got %v", err)
+	}
+}
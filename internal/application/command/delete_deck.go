@@ -0,0 +1,39 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// DeleteDeckCommand deletes a deck.
+type DeleteDeckCommand struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+}
+
+// DeleteDeckHandler handles deck deletion.
+type DeleteDeckHandler struct {
+	Repo      deck.Repository
+	Publisher EventPublisher
+}
+
+// Handle deletes the deck if it is owned by the user.
+func (h *DeleteDeckHandler) Handle(ctx context.Context, cmd DeleteDeckCommand) error {
+	d, err := loadOwnedDeck(ctx, h.Repo, cmd.DeckID, cmd.UserID)
+	if err != nil {
+		return err
+	}
+	evt, err := d.Delete()
+	if err != nil {
+		return err
+	}
+	if err := h.Repo.Save(ctx, d.Version, []interface{}{evt}); err != nil {
+		return err
+	}
+	if h.Publisher != nil {
+		_ = h.Publisher.Publish(ctx, "deck_events", evt)
+	}
+	return nil
+}
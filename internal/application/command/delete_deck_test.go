@@ -0,0 +1,34 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestDeleteDeckHandler(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	owner := uuid.New()
+	d, err := (&CreateDeckHandler{Repo: repo}).Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &DeleteDeckHandler{Repo: repo}
+	if err := h.Handle(ctx, DeleteDeckCommand{DeckID: d.ID, UserID: uuid.New()}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+	if err := h.Handle(ctx, DeleteDeckCommand{DeckID: d.ID, UserID: owner}); err != nil {
+		t.Fatal(err)
+	}
+	if loaded, _ := repo.Load(ctx, d.ID); loaded != nil {
+		t.Fatalf("expected deck deleted got %+v", loaded)
+	}
+	if err := h.Handle(ctx, DeleteDeckCommand{DeckID: d.ID, UserID: owner}); !errors.Is(err, deck.ErrNotFound) {
+		t.Fatalf("expected deck.ErrNotFound got %v", err)
+	}
+}
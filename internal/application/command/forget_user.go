@@ -0,0 +1,67 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// KeyDestroyer destroys the encryption key of a data subject.
+type KeyDestroyer interface {
+	Delete(ctx context.Context, subject string) error
+}
+
+// ForgetUserCommand requests erasure of a user's personal data.
+type ForgetUserCommand struct {
+	UserID uuid.UUID
+}
+
+// ForgetUserHandler crypto-shreds the personal data of a user by destroying
+// their encryption key. Events stay in the log but replay as redacted values.
+type ForgetUserHandler struct {
+	Keys KeyDestroyer
+	// Decks, when set, has the decks of the user in every tenant deleted
+	// first, so that stores and read models keeping their names in plain
+	// text drop them as well.
+	Decks     deck.Repository
+	Publisher EventPublisher
+}
+
+// Handle executes the command.
+func (h *ForgetUserHandler) Handle(ctx context.Context, cmd ForgetUserCommand) error {
+	if h.Decks != nil {
+		tenants, err := h.Decks.Tenants(ctx, cmd.UserID)
+		if err != nil {
+			return err
+		}
+		for _, t := range tenants {
+			if err := h.deleteDecks(tenant.NewContext(ctx, t), cmd.UserID); err != nil {
+				return err
+			}
+		}
+	}
+	return h.Keys.Delete(ctx, cmd.UserID.String())
+}
+
+// deleteDecks deletes the decks of a user in the tenant of ctx.
+func (h *ForgetUserHandler) deleteDecks(ctx context.Context, userID uuid.UUID) error {
+	decks, _, err := h.Decks.ListByUser(ctx, userID, 0, 0)
+	if err != nil {
+		return err
+	}
+	del := &DeleteDeckHandler{Repo: h.Decks, Publisher: h.Publisher}
+	for _, d := range decks {
+		if err := del.Handle(ctx, DeleteDeckCommand{DeckID: d.ID, UserID: userID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Forget forgets a user; it lets the handler erase the data of deleted
+// accounts (see auth.Service).
+func (h *ForgetUserHandler) Forget(ctx context.Context, userID uuid.UUID) error {
+	return h.Handle(ctx, ForgetUserCommand{UserID: userID})
+}
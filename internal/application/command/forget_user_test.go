@@ -0,0 +1,65 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+type mockKeys struct{ deleted []string }
+
+func (m *mockKeys) Delete(ctx context.Context, subject string) error {
+	m.deleted = append(m.deleted, subject)
+	return nil
+}
+
+func TestForgetUser(t *testing.T) {
+	keys := &mockKeys{}
+	h := &ForgetUserHandler{Keys: keys}
+	id := uuid.New()
+	if err := h.Handle(context.Background(), ForgetUserCommand{UserID: id}); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys.deleted) != 1 || keys.deleted[0] != id.String() {
+		t.Fatalf("unexpected deleted keys %v", keys.deleted)
+	}
+}
+
+func TestForgetUserDeletesDecks(t *testing.T) {
+	ctx := context.Background()
+	acme := tenant.NewContext(ctx, "acme")
+	repo := deckstore.NewInMemoryStore()
+	forgotten, other := uuid.New(), uuid.New()
+	create := &CreateDeckHandler{Repo: repo}
+	var decks []*deck.Deck
+	for _, owner := range []uuid.UUID{forgotten, forgotten, other} {
+		d, err := create.Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		decks = append(decks, d)
+	}
+	if _, err := create.Handle(acme, CreateDeckCommand{UserID: forgotten, Name: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	keys := &mockKeys{}
+	// forgetting from the default tenant reaches the decks in acme too
+	if err := (&ForgetUserHandler{Keys: keys, Decks: repo}).Forget(ctx, forgotten); err != nil {
+		t.Fatal(err)
+	}
+	for _, ctx := range []context.Context{ctx, acme} {
+		if left, total, err := repo.ListByUser(ctx, forgotten, 0, 0); err != nil || total != 0 || len(left) != 0 {
+			t.Fatalf("%s: expected the decks of the user deleted got %d %v", tenant.FromContext(ctx), total, err)
+		}
+	}
+	if d, _ := repo.Load(ctx, decks[2].ID); d == nil {
+		t.Fatal("expected the decks of other users kept")
+	}
+	if len(keys.deleted) != 1 || keys.deleted[0] != forgotten.String() {
+		t.Fatalf("unexpected deleted keys %v", keys.deleted)
+	}
+}
@@ -0,0 +1,132 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+)
+
+// errCardNotSaved is the row error of a card the repository failed to save.
+// The cause is logged rather than returned, as it may expose storage details.
+const errCardNotSaved = "card could not be saved"
+
+// RowError reports why a row of an imported file was rejected. Row is the
+// 1-based line number in the file.
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// CardRow is a card read from row Row of an imported file. ID is the card
+// the row updates, uuid.Nil for a new card.
+type CardRow struct {
+	Row  int
+	ID   uuid.UUID
+	Card CreateCardCommand
+}
+
+// ImportCardsCommand creates cards in bulk. With DryRun set the rows are
+// only checked.
+type ImportCardsCommand struct {
+	Rows   []CardRow
+	DryRun bool
+}
+
+// ImportCardsResult lists the cards created or updated, or that would have
+// been in a dry run, and the rows that were rejected.
+type ImportCardsResult struct {
+	Cards  []*card.Card
+	Errors []RowError
+}
+
+// ImportCardsHandler handles bulk card imports. Rows with an id update that
+// card through Update and the others are created through Create, one by
+// one; rejected rows do not stop the import.
+type ImportCardsHandler struct {
+	Create *CreateCardHandler
+	Update *UpdateCardHandler
+}
+
+// Handle checks and creates or updates the cards of every row. A new card
+// may not take the name of a card of the catalog, so that importing an
+// edited export updates the catalog instead of duplicating it.
+func (h *ImportCardsHandler) Handle(ctx context.Context, cmd ImportCardsCommand) (*ImportCardsResult, error) {
+	catalog, err := h.Create.Repo.Search(ctx, "", 0, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]bool, len(catalog))
+	byName := make(map[string]uuid.UUID, len(catalog))
+	for _, c := range catalog {
+		byID[c.ID] = true
+		byName[c.Name] = c.ID
+	}
+	res := &ImportCardsResult{}
+	seen := make(map[string]int)
+	for _, row := range cmd.Rows {
+		if msg := checkCardRow(row, seen, byID, byName); msg != "" {
+			res.Errors = append(res.Errors, RowError{Row: row.Row, Error: msg})
+			continue
+		}
+		seen[row.Card.Name] = row.Row
+		c, msg := h.importRow(ctx, row, cmd.DryRun)
+		if msg != "" {
+			res.Errors = append(res.Errors, RowError{Row: row.Row, Error: msg})
+			continue
+		}
+		res.Cards = append(res.Cards, c)
+	}
+	return res, nil
+}
+
+// importRow creates or updates the card of a checked row, or only returns it
+// in a dry run. It returns a message instead of a card when it was not saved.
+func (h *ImportCardsHandler) importRow(ctx context.Context, row CardRow, dryRun bool) (*card.Card, string) {
+	in := row.Card
+	if dryRun {
+		c := card.NewCard(in.Name, in.Cost, in.Faction, in.Category, in.SubCategory, in.Description)
+		if row.ID != uuid.Nil {
+			c.ID = row.ID
+		}
+		return c, ""
+	}
+	var c *card.Card
+	var err error
+	if row.ID == uuid.Nil {
+		c, err = h.Create.Handle(ctx, in)
+	} else {
+		c, err = h.Update.Handle(ctx, UpdateCardCommand{ID: row.ID, Name: in.Name, Cost: in.Cost, Faction: in.Faction,
+			Category: in.Category, SubCategory: in.SubCategory, Description: in.Description})
+	}
+	if err != nil {
+		log.Printf("import cards: row %d: %v", row.Row, err)
+		return nil, errCardNotSaved
+	}
+	if c == nil {
+		return nil, fmt.Sprintf("unknown card %s", row.ID)
+	}
+	return c, ""
+}
+
+// checkCardRow returns why a row cannot be imported, or "" when it can.
+// byID and byName index the cards of the catalog.
+func checkCardRow(row CardRow, seen map[string]int, byID map[uuid.UUID]bool, byName map[string]uuid.UUID) string {
+	switch {
+	case row.Card.Name == "":
+		return "name is required"
+	case row.Card.Cost < 0:
+		return "cost must not be negative"
+	case row.ID != uuid.Nil && !byID[row.ID]:
+		return fmt.Sprintf("unknown card %s", row.ID)
+	}
+	if prev, ok := seen[row.Card.Name]; ok {
+		return fmt.Sprintf("duplicate of row %d", prev)
+	}
+	if id, ok := byName[row.Card.Name]; ok && id != row.ID {
+		return fmt.Sprintf("card %q already exists, use its id", row.Card.Name)
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/infrastructure/eventstore"
+	"github.com/google/uuid"
+)
+
+func TestImportCardsHandler(t *testing.T) {
+	ctx := context.Background()
+	var saved int
+	repo := &mockRepo{SaveFn: func(ctx context.Context, evts []interface{}) error {
+		saved += len(evts)
+		return nil
+	}}
+	h := &ImportCardsHandler{Create: &CreateCardHandler{Repo: repo}}
+	rows := []CardRow{
+		{Row: 2, Card: CreateCardCommand{Name: "A", Cost: 1}},
+		{Row: 3, Card: CreateCardCommand{Cost: 1}},
+		{Row: 4, Card: CreateCardCommand{Name: "B", Cost: -1}},
+		{Row: 5, Card: CreateCardCommand{Name: "A", Cost: 2}},
+		{Row: 6, Card: CreateCardCommand{Name: "C"}},
+	}
+
+	res, err := h.Handle(ctx, ImportCardsCommand{Rows: rows, DryRun: true})
+	if err != nil || len(res.Cards) != 2 || len(res.Errors) != 3 || saved != 0 {
+		t.Fatalf("unexpected dry run %+v %v saved %d", res, err, saved)
+	}
+	if res.Errors[0].Row != 3 || res.Errors[1].Row != 4 || res.Errors[2].Row != 5 {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	res, err = h.Handle(ctx, ImportCardsCommand{Rows: rows})
+	if err != nil || len(res.Cards) != 2 || len(res.Errors) != 3 || saved != 2 {
+		t.Fatalf("unexpected import %+v %v saved %d", res, err, saved)
+	}
+
+	repo.SaveFn = func(ctx context.Context, evts []interface{}) error {
+		return errors.New("Error 1062: Duplicate entry for key 'cards.PRIMARY'")
+	}
+	res, err = h.Handle(ctx, ImportCardsCommand{Rows: rows[:1]})
+	if err != nil || len(res.Cards) != 0 || len(res.Errors) != 1 || res.Errors[0].Error != errCardNotSaved {
+		t.Fatalf("unexpected result %+v %v", res, err)
+	}
+}
+
+func TestImportCardsByID(t *testing.T) {
+	ctx := context.Background()
+	repo := eventstore.NewInMemoryStore()
+	fireball := card.NewCard("Fireball", 3, "F", "C", "S", "D")
+	if err := repo.Save(ctx, []interface{}{card.CardCreated(*fireball)}); err != nil {
+		t.Fatal(err)
+	}
+	h := &ImportCardsHandler{Create: &CreateCardHandler{Repo: repo}, Update: &UpdateCardHandler{Repo: repo}}
+	res, err := h.Handle(ctx, ImportCardsCommand{Rows: []CardRow{
+		{Row: 2, ID: fireball.ID, Card: CreateCardCommand{Name: "Fireball", Cost: 4}},
+		{Row: 3, Card: CreateCardCommand{Name: "Bolt", Cost: 1}},
+		{Row: 4, ID: uuid.New(), Card: CreateCardCommand{Name: "Shock"}},
+	}})
+	if err != nil || len(res.Cards) != 2 || len(res.Errors) != 1 || res.Errors[0].Row != 4 {
+		t.Fatalf("unexpected import %+v %v", res, err)
+	}
+	if c, _ := repo.Load(ctx, fireball.ID.String()); c == nil || c.Cost != 4 {
+		t.Fatalf("expected the card to be updated got %+v", c)
+	}
+
+	// reimporting a card by name would duplicate it
+	res, err = h.Handle(ctx, ImportCardsCommand{Rows: []CardRow{{Row: 2, Card: CreateCardCommand{Name: "Fireball"}}}})
+	if err != nil || len(res.Cards) != 0 || len(res.Errors) != 1 {
+		t.Fatalf("unexpected import %+v %v", res, err)
+	}
+	if cards, _ := repo.Search(ctx, "", 0, "", "", ""); len(cards) != 2 {
+		t.Fatalf("expected 2 cards got %d", len(cards))
+	}
+}
@@ -0,0 +1,45 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// DefaultImportName names imported decks when the command has no name.
+const DefaultImportName = "Imported deck"
+
+// ImportDeckCommand creates a deck from a deck code.
+type ImportDeckCommand struct {
+	UserID uuid.UUID
+	Name   string
+	Code   string
+}
+
+// ImportDeckHandler handles deck imports.
+type ImportDeckHandler struct {
+	Repo      deck.Repository
+	Publisher EventPublisher
+	// Numbers resolves the card numbers of the code.
+	Numbers deck.CardNumberRepository
+	// Validator, when set, rejects decoded decks breaking the rules of
+	// their format or naming unknown cards.
+	Validator *DeckValidator
+}
+
+// Handle decodes the code and creates the deck it describes.
+func (h *ImportDeckHandler) Handle(ctx context.Context, cmd ImportDeckCommand) (*deck.Deck, error) {
+	l, err := deck.DecodeCode(cmd.Code, func(numbers []uint64) (map[uint64]uuid.UUID, error) {
+		return h.Numbers.CardIDs(ctx, numbers)
+	})
+	if err != nil {
+		return nil, err
+	}
+	name := cmd.Name
+	if name == "" {
+		name = DefaultImportName
+	}
+	create := &CreateDeckHandler{Repo: h.Repo, Publisher: h.Publisher, Validator: h.Validator}
+	return create.Handle(ctx, CreateDeckCommand{UserID: cmd.UserID, Name: name, Format: l.Format, Zones: l.Zones})
+}
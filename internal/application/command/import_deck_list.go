@@ -0,0 +1,134 @@
+package command
+
+import (
+	"context"
+	"fmt"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// DeckListLine is a line of an imported deck list: Count copies of the card
+// named, or identified by, Card in Zone.
+type DeckListLine struct {
+	Row   int
+	Count int
+	Card  string
+	Zone  string
+}
+
+// ImportDeckListCommand creates a deck from a deck list. With DryRun set the
+// deck is resolved and validated but not saved.
+type ImportDeckListCommand struct {
+	UserID uuid.UUID
+	Name   string
+	Format string
+	Lines  []DeckListLine
+	DryRun bool
+}
+
+// ImportDeckListResult is the deck created, or that would have been created,
+// or the lines that could not be resolved. No deck is created when any line
+// is rejected.
+type ImportDeckListResult struct {
+	Deck   *deck.Deck
+	Errors []RowError
+}
+
+// ImportDeckListHandler handles deck list imports.
+type ImportDeckListHandler struct {
+	Repo      deck.Repository
+	Cards     card.Repository
+	Publisher EventPublisher
+	// Validator, when set, rejects decks breaking the rules of their format.
+	Validator *DeckValidator
+}
+
+// Handle resolves the cards of every line by id or exact name and creates
+// the deck.
+func (h *ImportDeckListHandler) Handle(ctx context.Context, cmd ImportDeckListCommand) (*ImportDeckListResult, error) {
+	idx, err := h.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &ImportDeckListResult{}
+	var zones []deck.Zone
+	for _, line := range cmd.Lines {
+		if line.Count < 1 {
+			res.Errors = append(res.Errors, RowError{Row: line.Row, Error: "count must be positive"})
+			continue
+		}
+		id, msg := idx.resolve(line.Card)
+		if msg != "" {
+			res.Errors = append(res.Errors, RowError{Row: line.Row, Error: msg})
+			continue
+		}
+		zones = append(zones, deck.Zone{Name: line.Zone, Cards: []deck.Entry{{CardID: id, Quantity: line.Count}}})
+	}
+	if len(res.Errors) > 0 {
+		return res, nil
+	}
+	name := cmd.Name
+	if name == "" {
+		name = DefaultImportName
+	}
+	if cmd.DryRun {
+		d := deck.NewDeck(cmd.UserID, name, nil)
+		d.Format = cmd.Format
+		if d.Zones, err = deck.NormalizeZones(zones); err != nil {
+			return nil, err
+		}
+		if err := h.Validator.Check(ctx, nil, d); err != nil {
+			return nil, err
+		}
+		res.Deck = d
+		return res, nil
+	}
+	create := &CreateDeckHandler{Repo: h.Repo, Publisher: h.Publisher, Validator: h.Validator}
+	d, err := create.Handle(ctx, CreateDeckCommand{UserID: cmd.UserID, Name: name, Format: cmd.Format, Zones: zones})
+	if err != nil {
+		return nil, err
+	}
+	res.Deck = d
+	return res, nil
+}
+
+// cardIndex finds the cards referenced by the lines of a deck list in the
+// catalog, loaded once for the whole list.
+type cardIndex struct {
+	ids    map[uuid.UUID]bool
+	byName map[string][]uuid.UUID
+}
+
+func (h *ImportDeckListHandler) index(ctx context.Context) (*cardIndex, error) {
+	cards, err := h.Cards.Search(ctx, "", 0, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	idx := &cardIndex{ids: make(map[uuid.UUID]bool, len(cards)), byName: make(map[string][]uuid.UUID)}
+	for _, c := range cards {
+		idx.ids[c.ID] = true
+		idx.byName[c.Name] = append(idx.byName[c.Name], c.ID)
+	}
+	return idx, nil
+}
+
+// resolve finds the card referenced by ref. It returns a message instead of
+// an id when there is no single such card.
+func (idx *cardIndex) resolve(ref string) (uuid.UUID, string) {
+	if id, err := uuid.Parse(ref); err == nil {
+		if !idx.ids[id] {
+			return uuid.Nil, fmt.Sprintf("unknown card %s", ref)
+		}
+		return id, ""
+	}
+	switch ids := idx.byName[ref]; len(ids) {
+	case 0:
+		return uuid.Nil, fmt.Sprintf("unknown card %q", ref)
+	case 1:
+		return ids[0], ""
+	default:
+		return uuid.Nil, fmt.Sprintf("ambiguous card name %q, use the card id", ref)
+	}
+}
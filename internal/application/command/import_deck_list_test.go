@@ -0,0 +1,69 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+	"github.com/google/uuid"
+)
+
+func TestImportDeckListHandler(t *testing.T) {
+	ctx := context.Background()
+	cards := eventstore.NewInMemoryStore()
+	fireball := card.NewCard("Fireball", 3, "F", "C", "S", "D")
+	twinA := card.NewCard("Twin", 1, "F", "C", "S", "D")
+	twinB := card.NewCard("Twin", 2, "F", "C", "S", "D")
+	for _, c := range []*card.Card{fireball, twinA, twinB} {
+		if err := cards.Save(ctx, []interface{}{card.CardCreated(*c)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules, _ := deck.NewRules([]deck.Format{{ID: "std", MaxCopies: 3}})
+	repo := deckstore.NewInMemoryStore()
+	h := &ImportDeckListHandler{Repo: repo, Cards: cards, Validator: &DeckValidator{Rules: rules, Cards: cards}}
+	user := uuid.New()
+
+	res, err := h.Handle(ctx, ImportDeckListCommand{UserID: user, Format: "std", Lines: []DeckListLine{
+		{Row: 1, Count: 3, Card: "Fireball", Zone: deck.ZoneMain},
+		{Row: 2, Count: 1, Card: twinB.ID.String(), Zone: deck.ZoneMain},
+	}, DryRun: true})
+	if err != nil || res.Deck == nil || len(res.Deck.CardIDs()) != 4 || len(res.Errors) != 0 {
+		t.Fatalf("unexpected dry run %+v %v", res, err)
+	}
+	if loaded, _ := repo.Load(ctx, res.Deck.ID); loaded != nil {
+		t.Fatal("dry run stored a deck")
+	}
+
+	res, err = h.Handle(ctx, ImportDeckListCommand{UserID: user, Name: "burn", Format: "std", Lines: []DeckListLine{
+		{Row: 1, Count: 2, Card: "Fireball", Zone: deck.ZoneMain},
+		{Row: 2, Count: 1, Card: "Fireball", Zone: deck.ZoneMain},
+		{Row: 3, Count: 2, Card: twinA.ID.String(), Zone: deck.ZoneSideboard},
+	}})
+	if err != nil || res.Deck == nil || res.Deck.Name != "burn" {
+		t.Fatalf("unexpected import %+v %v", res, err)
+	}
+	if loaded, _ := repo.Load(ctx, res.Deck.ID); loaded == nil || len(loaded.CardIDs()) != 3 || loaded.Zone(deck.ZoneSideboard).Quantity(twinA.ID) != 2 {
+		t.Fatalf("unexpected stored deck %+v", loaded)
+	}
+
+	res, err = h.Handle(ctx, ImportDeckListCommand{UserID: user, Lines: []DeckListLine{
+		{Row: 1, Count: 1, Card: "Twin", Zone: deck.ZoneMain},
+		{Row: 2, Count: 1, Card: "Nope", Zone: deck.ZoneMain},
+		{Row: 3, Count: 0, Card: "Fireball", Zone: deck.ZoneMain},
+		{Row: 4, Count: 1, Card: uuid.NewString(), Zone: deck.ZoneMain},
+	}})
+	if err != nil || res.Deck != nil || len(res.Errors) != 4 {
+		t.Fatalf("expected 4 row errors got %+v %v", res, err)
+	}
+
+	var verr *deck.ValidationError
+	_, err = h.Handle(ctx, ImportDeckListCommand{UserID: user, Format: "std", Lines: []DeckListLine{{Row: 1, Count: 4, Card: "Fireball", Zone: deck.ZoneMain}}, DryRun: true})
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected validation error got %v", err)
+	}
+}
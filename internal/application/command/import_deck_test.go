@@ -0,0 +1,65 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+	"github.com/google/uuid"
+)
+
+func TestImportDeckHandler(t *testing.T) {
+	ctx := context.Background()
+	cards := eventstore.NewInMemoryStore()
+	c := card.NewCard("N", 1, "F", "C", "S", "D")
+	if err := cards.Save(ctx, []interface{}{card.CardCreated(*c)}); err != nil {
+		t.Fatal(err)
+	}
+	rules, _ := deck.NewRules([]deck.Format{{ID: "std", MaxCopies: 2}})
+	repo := deckstore.NewInMemoryStore()
+	numbers := deckstore.NewInMemoryCardNumbers()
+	h := &ImportDeckHandler{Repo: repo, Numbers: numbers, Validator: &DeckValidator{Rules: rules, Cards: cards}}
+	user := uuid.New()
+	encode := func(ids ...uuid.UUID) string {
+		t.Helper()
+		nums, err := numbers.Numbers(ctx, ids)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := deck.EncodeCode(deck.DeckList{Format: "std", Zones: []deck.Zone{deck.ZoneOf(deck.ZoneMain, ids)}}, nums)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	code := encode(c.ID, c.ID)
+	d, err := h.Handle(ctx, ImportDeckCommand{UserID: user, Code: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.UserID != user || d.Name != DefaultImportName || d.Format != "std" || len(d.CardIDs()) != 2 {
+		t.Fatalf("unexpected deck %+v", d)
+	}
+	if loaded, _ := repo.Load(ctx, d.ID); loaded == nil {
+		t.Fatal("expected imported deck stored")
+	}
+
+	tooMany := encode(c.ID, c.ID, c.ID)
+	var verr *deck.ValidationError
+	if _, err := h.Handle(ctx, ImportDeckCommand{UserID: user, Code: tooMany}); !errors.As(err, &verr) {
+		t.Fatalf("expected validation error got %v", err)
+	}
+	// codes of another tenant refer to cards this one has not numbered
+	other := encode(uuid.New())
+	for _, code := range []string{"nope", other} {
+		if _, err := h.Handle(tenant.NewContext(ctx, "acme"), ImportDeckCommand{UserID: user, Code: code}); !errors.Is(err, deck.ErrInvalidCode) {
+			t.Fatalf("%q: expected ErrInvalidCode got %v", code, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// LikeDeckCommand likes or unlikes a deck of the gallery.
+type LikeDeckCommand struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+}
+
+// LikeDeckHandler handles the likes of gallery decks. Only public decks can
+// be liked, and every user likes a deck at most once.
+type LikeDeckHandler struct {
+	Gallery deck.GalleryRepository
+}
+
+// Like records that the user likes the deck and returns its updated gallery
+// entry.
+func (h *LikeDeckHandler) Like(ctx context.Context, cmd LikeDeckCommand) (*deck.GalleryEntry, error) {
+	return listed(h.Gallery.Like(ctx, cmd.DeckID, cmd.UserID))
+}
+
+// Unlike takes back the like of the user.
+func (h *LikeDeckHandler) Unlike(ctx context.Context, cmd LikeDeckCommand) (*deck.GalleryEntry, error) {
+	return listed(h.Gallery.Unlike(ctx, cmd.DeckID, cmd.UserID))
+}
+
+// listed turns the nil entry of a deck missing from the gallery into
+// deck.ErrNotFound.
+func listed(e *deck.GalleryEntry, err error) (*deck.GalleryEntry, error) {
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, deck.ErrNotFound
+	}
+	return e, nil
+}
@@ -0,0 +1,31 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestLikeDeckHandler(t *testing.T) {
+	ctx := context.Background()
+	gallery := deckstore.NewInMemoryGallery()
+	e := deck.GalleryEntry{DeckID: uuid.New(), UserID: uuid.New(), Name: "d"}
+	if err := gallery.Put(ctx, e); err != nil {
+		t.Fatal(err)
+	}
+	h := &LikeDeckHandler{Gallery: gallery}
+	user := uuid.New()
+	if got, err := h.Like(ctx, LikeDeckCommand{DeckID: e.DeckID, UserID: user}); err != nil || got.Likes != 1 {
+		t.Fatalf("expected 1 like got %+v %v", got, err)
+	}
+	if got, err := h.Unlike(ctx, LikeDeckCommand{DeckID: e.DeckID, UserID: user}); err != nil || got.Likes != 0 {
+		t.Fatalf("expected no likes got %+v %v", got, err)
+	}
+	if _, err := h.Like(ctx, LikeDeckCommand{DeckID: uuid.New(), UserID: user}); !errors.Is(err, deck.ErrNotFound) {
+		t.Fatalf("expected deck.ErrNotFound got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// SetDeckVisibilityCommand shares or hides a deck. Visibility is one of the
+// deck.Visibility constants.
+type SetDeckVisibilityCommand struct {
+	DeckID     uuid.UUID
+	UserID     uuid.UUID
+	Visibility string
+}
+
+// SetDeckVisibilityHandler handles changes to the visibility of decks. It
+// records no revision since revisions only hold the name and cards of a
+// deck.
+type SetDeckVisibilityHandler struct {
+	Repo      deck.Repository
+	Publisher EventPublisher
+}
+
+// Handle changes the visibility of the deck if it is owned by the user.
+func (h *SetDeckVisibilityHandler) Handle(ctx context.Context, cmd SetDeckVisibilityCommand) (*deck.Deck, error) {
+	d, err := loadOwnedDeck(ctx, h.Repo, cmd.DeckID, cmd.UserID)
+	if err != nil {
+		return nil, err
+	}
+	evt, err := d.SetVisibility(cmd.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	if d.Visibility == cmd.Visibility {
+		return d, nil
+	}
+	if err := h.Repo.Save(ctx, d.Version, []interface{}{evt}); err != nil {
+		return nil, err
+	}
+	d.Apply(evt)
+	if h.Publisher != nil {
+		_ = h.Publisher.Publish(ctx, "deck_events", evt)
+	}
+	return d, nil
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestSetDeckVisibilityHandler(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	owner := uuid.New()
+	d, err := (&CreateDeckHandler{Repo: repo}).Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &mockPublisher{}
+	h := &SetDeckVisibilityHandler{Repo: repo, Publisher: pub}
+	if _, err := h.Handle(ctx, SetDeckVisibilityCommand{DeckID: d.ID, UserID: uuid.New(), Visibility: deck.VisibilityPublic}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+	if _, err := h.Handle(ctx, SetDeckVisibilityCommand{DeckID: d.ID, UserID: owner, Visibility: "everyone"}); !errors.Is(err, deck.ErrInvalidVisibility) {
+		t.Fatalf("expected deck.ErrInvalidVisibility got %v", err)
+	}
+	got, err := h.Handle(ctx, SetDeckVisibilityCommand{DeckID: d.ID, UserID: owner, Visibility: deck.VisibilityPublic})
+	if err != nil || got.Visibility != deck.VisibilityPublic {
+		t.Fatalf("expected a public deck got %+v %v", got, err)
+	}
+	if loaded, _ := repo.Load(ctx, d.ID); loaded.Visibility != deck.VisibilityPublic {
+		t.Fatalf("expected the visibility to be saved got %q", loaded.Visibility)
+	}
+	// setting the current visibility again changes nothing
+	if _, err := h.Handle(ctx, SetDeckVisibilityCommand{DeckID: d.ID, UserID: owner, Visibility: deck.VisibilityPublic}); err != nil {
+		t.Fatal(err)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("expected 1 published event got %d", len(pub.events))
+	}
+}
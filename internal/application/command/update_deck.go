@@ -0,0 +1,185 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// UpdateDeckCommand changes the name and cards of a deck. A nil Name
+// leaves the name unchanged. CardIDs, when not nil, replaces the main zone
+// and Zones replaces the zones it lists; other zones are left unchanged and
+// an empty zone, or an empty non-nil CardIDs, removes all cards of the zone.
+type UpdateDeckCommand struct {
+	DeckID  uuid.UUID
+	UserID  uuid.UUID
+	Name    *string
+	CardIDs []uuid.UUID
+	Zones   []deck.Zone
+}
+
+// RenameDeckCommand renames a deck.
+type RenameDeckCommand struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+	Name   string
+}
+
+// AddCardToDeckCommand adds Quantity copies of a card to a zone of a deck.
+type AddCardToDeckCommand struct {
+	DeckID   uuid.UUID
+	UserID   uuid.UUID
+	CardID   uuid.UUID
+	Zone     string
+	Quantity int
+}
+
+// RemoveCardFromDeckCommand removes Quantity copies of a card from a zone of
+// a deck.
+type RemoveCardFromDeckCommand struct {
+	DeckID   uuid.UUID
+	UserID   uuid.UUID
+	CardID   uuid.UUID
+	Zone     string
+	Quantity int
+}
+
+// RestoreDeckCommand turns a deck back into one of its revisions.
+type RestoreDeckCommand struct {
+	DeckID  uuid.UUID
+	UserID  uuid.UUID
+	Version int
+}
+
+// UpdateDeckHandler handles changes to existing decks. Only the owner of a
+// deck may change it.
+type UpdateDeckHandler struct {
+	Repo      deck.Repository
+	Publisher EventPublisher
+	// Validator, when set, rejects changes breaking the rules of the deck
+	// format.
+	Validator *DeckValidator
+	// History, when set, allows restoring old revisions.
+	History deck.HistoryRepository
+}
+
+// Handle applies all requested changes as a single batch.
+func (h *UpdateDeckHandler) Handle(ctx context.Context, cmd UpdateDeckCommand) (*deck.Deck, error) {
+	return h.apply(ctx, cmd.DeckID, cmd.UserID, func(d *deck.Deck) ([]interface{}, error) {
+		var events []interface{}
+		if cmd.Name != nil && *cmd.Name != d.Name {
+			evt, err := d.Rename(*cmd.Name)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, evt)
+		}
+		zones := cmd.Zones
+		if cmd.CardIDs != nil {
+			zones = append([]deck.Zone{deck.ZoneOf(deck.ZoneMain, cmd.CardIDs)}, zones...)
+		}
+		if len(zones) > 0 {
+			evts, err := d.SetZones(zones)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, evts...)
+		}
+		return events, nil
+	})
+}
+
+// Rename changes the name of a deck.
+func (h *UpdateDeckHandler) Rename(ctx context.Context, cmd RenameDeckCommand) (*deck.Deck, error) {
+	return h.apply(ctx, cmd.DeckID, cmd.UserID, func(d *deck.Deck) ([]interface{}, error) {
+		evt, err := d.Rename(cmd.Name)
+		return []interface{}{evt}, err
+	})
+}
+
+// AddCard adds a card to a deck.
+func (h *UpdateDeckHandler) AddCard(ctx context.Context, cmd AddCardToDeckCommand) (*deck.Deck, error) {
+	return h.apply(ctx, cmd.DeckID, cmd.UserID, func(d *deck.Deck) ([]interface{}, error) {
+		evt, err := d.AddCards(cmd.Zone, cmd.CardID, cmd.Quantity)
+		return []interface{}{evt}, err
+	})
+}
+
+// RemoveCard removes a card from a deck.
+func (h *UpdateDeckHandler) RemoveCard(ctx context.Context, cmd RemoveCardFromDeckCommand) (*deck.Deck, error) {
+	return h.apply(ctx, cmd.DeckID, cmd.UserID, func(d *deck.Deck) ([]interface{}, error) {
+		evt, err := d.RemoveCards(cmd.Zone, cmd.CardID, cmd.Quantity)
+		return []interface{}{evt}, err
+	})
+}
+
+// Restore changes a deck back to the name and cards of an earlier revision,
+// recording the result as a new revision. It returns deck.ErrVersionNotFound
+// when the deck has no such revision or the handler keeps no history.
+func (h *UpdateDeckHandler) Restore(ctx context.Context, cmd RestoreDeckCommand) (*deck.Deck, error) {
+	return h.apply(ctx, cmd.DeckID, cmd.UserID, func(d *deck.Deck) ([]interface{}, error) {
+		if h.History == nil {
+			return nil, deck.ErrVersionNotFound
+		}
+		rev, err := h.History.Revision(ctx, d.ID, cmd.Version)
+		if err != nil {
+			return nil, err
+		}
+		if rev == nil {
+			return nil, deck.ErrVersionNotFound
+		}
+		return d.Restore(*rev)
+	})
+}
+
+// apply loads a deck owned by userID, records the events produced by change
+// as a new revision and returns the new deck state. The events are saved
+// against the version of the deck they were made on, so that a concurrent
+// change fails with deck.ErrVersionConflict instead of being overwritten.
+func (h *UpdateDeckHandler) apply(ctx context.Context, id, userID uuid.UUID, change func(*deck.Deck) ([]interface{}, error)) (*deck.Deck, error) {
+	d, err := loadOwnedDeck(ctx, h.Repo, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	events, err := change(d)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return d, nil
+	}
+	after := d.Clone()
+	for _, evt := range events {
+		after.Apply(evt)
+	}
+	if err := h.Validator.Check(ctx, d, after); err != nil {
+		return nil, err
+	}
+	revised := after.Revise()
+	if err := h.Repo.Save(ctx, d.Version, append(events, revised)); err != nil {
+		return nil, err
+	}
+	after.Apply(revised)
+	if h.Publisher != nil {
+		for _, evt := range events {
+			_ = h.Publisher.Publish(ctx, "deck_events", evt)
+		}
+	}
+	return after, nil
+}
+
+// loadOwnedDeck loads a deck and checks that userID owns it.
+func loadOwnedDeck(ctx context.Context, repo deck.Repository, id, userID uuid.UUID) (*deck.Deck, error) {
+	d, err := repo.Load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, deck.ErrNotFound
+	}
+	if err := d.CheckOwner(userID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
@@ -0,0 +1,160 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestUpdateDeckHandler(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	owner := uuid.New()
+	a, b := uuid.New(), uuid.New()
+	d, err := (&CreateDeckHandler{Repo: repo}).Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d", CardIDs: []uuid.UUID{a}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &mockPublisher{}
+	h := &UpdateDeckHandler{Repo: repo, Publisher: pub}
+	if _, err := h.AddCard(ctx, AddCardToDeckCommand{DeckID: d.ID, UserID: owner, CardID: b, Zone: deck.ZoneMain, Quantity: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.RemoveCard(ctx, RemoveCardFromDeckCommand{DeckID: d.ID, UserID: owner, CardID: a, Zone: deck.ZoneMain, Quantity: 1}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := h.Rename(ctx, RenameDeckCommand{DeckID: d.ID, UserID: owner, Name: "e"})
+	if err != nil || updated.Name != "e" || len(updated.CardIDs()) != 1 || updated.CardIDs()[0] != b {
+		t.Fatalf("unexpected deck %+v %v", updated, err)
+	}
+	if loaded, _ := repo.Load(ctx, d.ID); loaded.Name != "e" || len(loaded.CardIDs()) != 1 {
+		t.Fatalf("unexpected stored deck %+v", loaded)
+	}
+	if len(pub.events) != 3 {
+		t.Fatalf("expected 3 published events got %d", len(pub.events))
+	}
+	if _, err := h.RemoveCard(ctx, RemoveCardFromDeckCommand{DeckID: d.ID, UserID: owner, CardID: a, Zone: deck.ZoneMain, Quantity: 1}); !errors.Is(err, deck.ErrCardNotInDeck) {
+		t.Fatalf("expected ErrCardNotInDeck got %v", err)
+	}
+	if _, err := h.AddCard(ctx, AddCardToDeckCommand{DeckID: d.ID, UserID: owner, CardID: b, Zone: deck.ZoneMain, Quantity: -1}); !errors.Is(err, deck.ErrInvalidZone) {
+		t.Fatalf("expected negative quantities to be rejected got %v", err)
+	}
+	if _, err := h.AddCard(ctx, AddCardToDeckCommand{DeckID: d.ID, UserID: owner, CardID: b, Quantity: 1}); !errors.Is(err, deck.ErrInvalidZone) {
+		t.Fatalf("expected a missing zone to be rejected got %v", err)
+	}
+	if _, err := h.Rename(ctx, RenameDeckCommand{DeckID: uuid.New(), UserID: owner}); !errors.Is(err, deck.ErrNotFound) {
+		t.Fatalf("expected deck.ErrNotFound got %v", err)
+	}
+	if _, err := h.Rename(ctx, RenameDeckCommand{DeckID: d.ID, UserID: uuid.New(), Name: "x"}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+}
+
+func TestUpdateDeckHandlerHandle(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	owner := uuid.New()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	d, err := (&CreateDeckHandler{Repo: repo}).Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d", CardIDs: []uuid.UUID{a, a, b}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := &mockPublisher{}
+	h := &UpdateDeckHandler{Repo: repo, Publisher: pub}
+	name := "e"
+	updated, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner, Name: &name, CardIDs: []uuid.UUID{a, c, c}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rename, remove a, remove b, add two c
+	if len(pub.events) != 4 {
+		t.Fatalf("expected 4 published events got %d", len(pub.events))
+	}
+	loaded, _ := repo.Load(ctx, d.ID)
+	for _, got := range []*deck.Deck{updated, loaded} {
+		if got.Name != "e" || countOf(got.CardIDs(), a) != 1 || countOf(got.CardIDs(), b) != 0 || countOf(got.CardIDs(), c) != 2 {
+			t.Fatalf("unexpected deck %+v", got)
+		}
+	}
+
+	// nil fields leave the deck unchanged
+	if _, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner}); err != nil || len(pub.events) != 4 {
+		t.Fatalf("expected no events got %d %v", len(pub.events), err)
+	}
+	cleared, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner, CardIDs: []uuid.UUID{}})
+	if err != nil || len(cleared.CardIDs()) != 0 || cleared.Name != "e" {
+		t.Fatalf("unexpected deck %+v %v", cleared, err)
+	}
+
+	// zones not listed are left unchanged
+	side := []deck.Zone{{Name: "Sideboard", Cards: []deck.Entry{{CardID: b, Quantity: 2}}}}
+	if _, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner, CardIDs: []uuid.UUID{a}, Zones: side}); err != nil {
+		t.Fatal(err)
+	}
+	zoned, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner, Zones: []deck.Zone{{Name: deck.ZoneCommander, Cards: []deck.Entry{{CardID: c, Quantity: 1}}}}})
+	if err != nil || zoned.Zone(deck.ZoneMain).Quantity(a) != 1 || zoned.Zone(deck.ZoneSideboard).Quantity(b) != 2 || zoned.Zone(deck.ZoneCommander).Quantity(c) != 1 {
+		t.Fatalf("unexpected zones %+v %v", zoned, err)
+	}
+	if _, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner, Zones: []deck.Zone{{Name: "not a zone"}}}); !errors.Is(err, deck.ErrInvalidZone) {
+		t.Fatalf("expected ErrInvalidZone got %v", err)
+	}
+}
+
+func countOf(ids []uuid.UUID, id uuid.UUID) int {
+	n := 0
+	for _, x := range ids {
+		if x == id {
+			n++
+		}
+	}
+	return n
+}
+
+func TestUpdateDeckHandlerRestore(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	owner := uuid.New()
+	a, b := uuid.New(), uuid.New()
+	d, err := (&CreateDeckHandler{Repo: repo}).Handle(ctx, CreateDeckCommand{UserID: owner, Name: "d", CardIDs: []uuid.UUID{a, a}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &UpdateDeckHandler{Repo: repo, History: repo}
+	name := "e"
+	if _, err := h.Handle(ctx, UpdateDeckCommand{DeckID: d.ID, UserID: owner, Name: &name, CardIDs: []uuid.UUID{b},
+		Zones: []deck.Zone{deck.ZoneOf(deck.ZoneSideboard, []uuid.UUID{a})}}); err != nil {
+		t.Fatal(err)
+	}
+	if revs, _ := repo.Revisions(ctx, d.ID); len(revs) != 2 || revs[1].Name != "e" {
+		t.Fatalf("expected 2 revisions got %+v", revs)
+	}
+
+	restored, err := h.Restore(ctx, RestoreDeckCommand{DeckID: d.ID, UserID: owner, Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, _ := repo.Load(ctx, d.ID)
+	for _, got := range []*deck.Deck{restored, loaded} {
+		if got.Name != "d" || len(got.Zones) != 1 || got.Zone(deck.ZoneMain).Quantity(a) != 2 {
+			t.Fatalf("unexpected restored deck %+v", got)
+		}
+	}
+	if rev, _ := repo.Revision(ctx, d.ID, 3); rev == nil || rev.Name != "d" {
+		t.Fatalf("expected restore recorded as revision 3 got %+v", rev)
+	}
+
+	if _, err := h.Restore(ctx, RestoreDeckCommand{DeckID: d.ID, UserID: owner, Version: 9}); !errors.Is(err, deck.ErrVersionNotFound) {
+		t.Fatalf("expected deck.ErrVersionNotFound got %v", err)
+	}
+	if _, err := h.Restore(ctx, RestoreDeckCommand{DeckID: d.ID, UserID: uuid.New(), Version: 1}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+	noHistory := &UpdateDeckHandler{Repo: repo}
+	if _, err := noHistory.Restore(ctx, RestoreDeckCommand{DeckID: d.ID, UserID: owner, Version: 1}); !errors.Is(err, deck.ErrVersionNotFound) {
+		t.Fatalf("expected deck.ErrVersionNotFound got %v", err)
+	}
+}
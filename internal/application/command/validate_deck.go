@@ -0,0 +1,62 @@
+package command
+
+import (
+	"context"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// DeckValidator checks deck changes against the construction rules of the
+// deck's format.
+type DeckValidator struct {
+	Rules *deck.Rules
+	Cards card.Repository
+}
+
+// Check returns a *deck.ValidationError when after breaks a rule. before is
+// nil for new decks, which must follow every rule, as must decks changing
+// format; other changes may keep the violations of before as long as they do
+// not make them worse, see deck.NewViolations.
+func (v *DeckValidator) Check(ctx context.Context, before, after *deck.Deck) error {
+	if v == nil {
+		return nil
+	}
+	cards, err := v.resolve(ctx, after.AllCardIDs())
+	if err != nil {
+		return err
+	}
+	next, err := v.Rules.Validate(after, cards)
+	if err != nil {
+		return err
+	}
+	vs := next
+	if before != nil && before.Format == after.Format {
+		prev, err := v.Rules.Validate(before, cards)
+		if err != nil {
+			return err
+		}
+		vs = deck.NewViolations(prev, next)
+	}
+	if len(vs) > 0 {
+		return &deck.ValidationError{Format: after.Format, Violations: vs}
+	}
+	return nil
+}
+
+// resolve loads every distinct card of ids. Unknown cards are left out.
+func (v *DeckValidator) resolve(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*card.Card, error) {
+	cards := make(map[uuid.UUID]*card.Card)
+	for _, id := range ids {
+		if _, ok := cards[id]; ok {
+			continue
+		}
+		c, err := v.Cards.Load(ctx, id.String())
+		if err != nil {
+			return nil, err
+		}
+		cards[id] = c
+	}
+	return cards, nil
+}
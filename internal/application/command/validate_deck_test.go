@@ -0,0 +1,80 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func newValidator(t *testing.T, cards ...*card.Card) *DeckValidator {
+	t.Helper()
+	rules, err := deck.NewRules([]deck.Format{{ID: "std", MinSize: 3, MaxCopies: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &mockRepo{LoadFn: func(ctx context.Context, id string) (*card.Card, error) {
+		for _, c := range cards {
+			if c.ID.String() == id {
+				return c, nil
+			}
+		}
+		return nil, nil
+	}}
+	return &DeckValidator{Rules: rules, Cards: repo}
+}
+
+func TestCreateDeckValidation(t *testing.T) {
+	c := card.NewCard("N", 1, "F", "C", "S", "D")
+	o := card.NewCard("O", 1, "F", "C", "S", "D")
+	h := &CreateDeckHandler{Repo: deckstore.NewInMemoryStore(), Validator: newValidator(t, c, o)}
+	ctx := context.Background()
+
+	if _, err := h.Handle(ctx, CreateDeckCommand{Name: "d", Format: "std", CardIDs: []uuid.UUID{c.ID, c.ID, o.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	var verr *deck.ValidationError
+	_, err := h.Handle(ctx, CreateDeckCommand{Name: "d", Format: "std", CardIDs: []uuid.UUID{c.ID}})
+	if !errors.As(err, &verr) || len(verr.Violations) != 1 || verr.Violations[0].Rule != deck.RuleMinSize {
+		t.Fatalf("expected new decks to follow the minimum size got %v", err)
+	}
+	_, err = h.Handle(ctx, CreateDeckCommand{Name: "d", Format: "std", CardIDs: []uuid.UUID{c.ID, c.ID, c.ID, uuid.New()}})
+	if !errors.As(err, &verr) || len(verr.Violations) != 2 {
+		t.Fatalf("expected copies and unknown card violations got %v", err)
+	}
+	if _, err := h.Handle(ctx, CreateDeckCommand{Name: "d", Format: "nope"}); !errors.Is(err, deck.ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat got %v", err)
+	}
+}
+
+func TestUpdateDeckValidation(t *testing.T) {
+	c := card.NewCard("N", 1, "F", "C", "S", "D")
+	o := card.NewCard("O", 1, "F", "C", "S", "D")
+	repo := deckstore.NewInMemoryStore()
+	v := newValidator(t, c, o)
+	ctx := context.Background()
+	// a deck made before the format required 3 cards
+	d := deck.NewDeck(uuid.Nil, "d", []uuid.UUID{c.ID, o.ID})
+	d.Format = "std"
+	if err := repo.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	h := &UpdateDeckHandler{Repo: repo, Validator: v}
+	var verr *deck.ValidationError
+	if _, err := h.RemoveCard(ctx, RemoveCardFromDeckCommand{DeckID: d.ID, CardID: o.ID, Zone: deck.ZoneMain, Quantity: 1}); !errors.As(err, &verr) || verr.Violations[0].Rule != deck.RuleMinSize {
+		t.Fatalf("expected the deck to be kept from shrinking got %v", err)
+	}
+	if _, err := h.AddCard(ctx, AddCardToDeckCommand{DeckID: d.ID, CardID: c.ID, Zone: deck.ZoneMain, Quantity: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.AddCard(ctx, AddCardToDeckCommand{DeckID: d.ID, CardID: c.ID, Zone: deck.ZoneMain, Quantity: 1}); !errors.As(err, &verr) || verr.Violations[0].Rule != deck.RuleMaxCopies {
+		t.Fatalf("expected max copies violation got %v", err)
+	}
+	if loaded, _ := repo.Load(ctx, d.ID); len(loaded.CardIDs()) != 3 {
+		t.Fatalf("rejected change must not be stored, got %+v", loaded)
+	}
+}
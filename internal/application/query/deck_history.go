@@ -0,0 +1,98 @@
+package query
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// DeckVersionsQuery selects the revisions of a deck of a user.
+type DeckVersionsQuery struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+}
+
+// DeckVersionQuery selects a single revision of a deck of a user.
+type DeckVersionQuery struct {
+	DeckID  uuid.UUID
+	UserID  uuid.UUID
+	Version int
+}
+
+// DeckDiffQuery selects two revisions of a deck of a user to compare. A zero
+// To compares From with the latest revision.
+type DeckDiffQuery struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+	From   int
+	To     int
+}
+
+// DeckHistoryHandler handles reading the revisions of decks. Only the owner
+// of a deck may read them and deleted decks have no history.
+type DeckHistoryHandler struct {
+	Decks   deck.Repository
+	History deck.HistoryRepository
+}
+
+// Versions returns the revisions of the deck, oldest first.
+func (h *DeckHistoryHandler) Versions(ctx context.Context, q DeckVersionsQuery) ([]deck.Revision, error) {
+	if err := h.checkOwner(ctx, q.DeckID, q.UserID); err != nil {
+		return nil, err
+	}
+	return h.History.Revisions(ctx, q.DeckID)
+}
+
+// Version returns a revision of the deck or deck.ErrVersionNotFound.
+func (h *DeckHistoryHandler) Version(ctx context.Context, q DeckVersionQuery) (*deck.Revision, error) {
+	if err := h.checkOwner(ctx, q.DeckID, q.UserID); err != nil {
+		return nil, err
+	}
+	return h.revision(ctx, q.DeckID, q.Version)
+}
+
+// Diff compares two revisions of the deck.
+func (h *DeckHistoryHandler) Diff(ctx context.Context, q DeckDiffQuery) (*deck.Diff, error) {
+	if err := h.checkOwner(ctx, q.DeckID, q.UserID); err != nil {
+		return nil, err
+	}
+	from, err := h.revision(ctx, q.DeckID, q.From)
+	if err != nil {
+		return nil, err
+	}
+	var to *deck.Revision
+	if q.To == 0 {
+		revs, err := h.History.Revisions(ctx, q.DeckID)
+		if err != nil {
+			return nil, err
+		}
+		to = &revs[len(revs)-1]
+	} else if to, err = h.revision(ctx, q.DeckID, q.To); err != nil {
+		return nil, err
+	}
+	diff := deck.DiffRevisions(*from, *to)
+	return &diff, nil
+}
+
+func (h *DeckHistoryHandler) checkOwner(ctx context.Context, id, userID uuid.UUID) error {
+	d, err := h.Decks.Load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return deck.ErrNotFound
+	}
+	return d.CheckOwner(userID)
+}
+
+func (h *DeckHistoryHandler) revision(ctx context.Context, id uuid.UUID, version int) (*deck.Revision, error) {
+	rev, err := h.History.Revision(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, deck.ErrVersionNotFound
+	}
+	return rev, nil
+}
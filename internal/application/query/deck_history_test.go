@@ -0,0 +1,55 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestDeckHistoryHandler(t *testing.T) {
+	ctx := context.Background()
+	decks := deckstore.NewInMemoryStore()
+	user := uuid.New()
+	a, b := uuid.New(), uuid.New()
+	d := deck.NewDeck(user, "d", []uuid.UUID{a})
+	if err := decks.Save(ctx, 0, []interface{}{d.Created(), d.Revise()}); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ := d.SetCards([]uuid.UUID{b, b})
+	if err := decks.Save(ctx, 2, append(changes, d.Revise())); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &DeckHistoryHandler{Decks: decks, History: decks}
+	revs, err := h.Versions(ctx, DeckVersionsQuery{DeckID: d.ID, UserID: user})
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("expected 2 revisions got %+v %v", revs, err)
+	}
+	rev, err := h.Version(ctx, DeckVersionQuery{DeckID: d.ID, UserID: user, Version: 1})
+	if err != nil || rev.Version != 1 || rev.Zones[0].Quantity(a) != 1 {
+		t.Fatalf("unexpected revision %+v %v", rev, err)
+	}
+	if _, err := h.Version(ctx, DeckVersionQuery{DeckID: d.ID, UserID: user, Version: 3}); !errors.Is(err, deck.ErrVersionNotFound) {
+		t.Fatalf("expected deck.ErrVersionNotFound got %v", err)
+	}
+
+	// To defaults to the latest revision
+	diff, err := h.Diff(ctx, DeckDiffQuery{DeckID: d.ID, UserID: user, From: 1})
+	if err != nil || diff.To != 2 || len(diff.Added) != 1 || diff.Added[0].Quantity != 2 || len(diff.Removed) != 1 || diff.Removed[0].CardID != a {
+		t.Fatalf("unexpected diff %+v %v", diff, err)
+	}
+	if _, err := h.Diff(ctx, DeckDiffQuery{DeckID: d.ID, UserID: user, From: 1, To: 5}); !errors.Is(err, deck.ErrVersionNotFound) {
+		t.Fatalf("expected deck.ErrVersionNotFound got %v", err)
+	}
+
+	if _, err := h.Versions(ctx, DeckVersionsQuery{DeckID: d.ID, UserID: uuid.New()}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+	if _, err := h.Versions(ctx, DeckVersionsQuery{DeckID: uuid.New(), UserID: user}); !errors.Is(err, deck.ErrNotFound) {
+		t.Fatalf("expected deck.ErrNotFound got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package query
+
+import (
+	"context"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// DeckStatsQuery selects a saved deck to analyze. A HandSize <= 0 uses
+// deck.DefaultHandSize.
+type DeckStatsQuery struct {
+	DeckID   uuid.UUID
+	UserID   uuid.UUID
+	HandSize int
+}
+
+// AnalyzeCardsQuery analyzes an unsaved list of cards.
+type AnalyzeCardsQuery struct {
+	CardIDs  []uuid.UUID
+	HandSize int
+}
+
+// DeckStatsHandler computes deck statistics.
+type DeckStatsHandler struct {
+	Decks deck.Repository
+	Cards card.Repository
+}
+
+// Handle analyzes the main zone of the deck if it is owned by the user.
+func (h *DeckStatsHandler) Handle(ctx context.Context, q DeckStatsQuery) (*deck.Stats, error) {
+	d, err := h.Decks.Load(ctx, q.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, deck.ErrNotFound
+	}
+	if err := d.CheckOwner(q.UserID); err != nil {
+		return nil, err
+	}
+	return h.Analyze(ctx, AnalyzeCardsQuery{CardIDs: d.CardIDs(), HandSize: q.HandSize})
+}
+
+// Analyze resolves the cards through the card repository and analyzes them.
+func (h *DeckStatsHandler) Analyze(ctx context.Context, q AnalyzeCardsQuery) (*deck.Stats, error) {
+	cards := make(map[uuid.UUID]*card.Card)
+	for _, id := range q.CardIDs {
+		if _, ok := cards[id]; ok {
+			continue
+		}
+		c, err := h.Cards.Load(ctx, id.String())
+		if err != nil {
+			return nil, err
+		}
+		cards[id] = c
+	}
+	hand := q.HandSize
+	if hand <= 0 {
+		hand = deck.DefaultHandSize
+	}
+	s := deck.Analyze(q.CardIDs, cards, hand)
+	return &s, nil
+}
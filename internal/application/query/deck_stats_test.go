@@ -0,0 +1,46 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestDeckStats(t *testing.T) {
+	ctx := context.Background()
+	a := card.NewCard("A", 2, "Red", "Spell", "", "")
+	cards := &loadRepo{cards: map[string]*card.Card{a.ID.String(): a}}
+	decks := deckstore.NewInMemoryStore()
+	user := uuid.New()
+	ids := make([]uuid.UUID, 0, 20)
+	for i := 0; i < 20; i++ {
+		ids = append(ids, uuid.New())
+	}
+	ids[0], ids[1] = a.ID, a.ID
+	d := deck.NewDeck(user, "d", ids)
+	if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &DeckStatsHandler{Decks: decks, Cards: cards}
+	s, err := h.Handle(ctx, DeckStatsQuery{DeckID: d.ID, UserID: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size != 20 || s.UnknownCards != 18 || s.HandSize != deck.DefaultHandSize || s.AverageCost != 2 || s.Draws[0].CardID != a.ID {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if _, err := h.Handle(ctx, DeckStatsQuery{DeckID: d.ID, UserID: uuid.New()}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+
+	s, err = h.Analyze(ctx, AnalyzeCardsQuery{CardIDs: []uuid.UUID{a.ID}, HandSize: 3})
+	if err != nil || s.Size != 1 || s.HandSize != 1 || s.Draws[0].Probability != 1 {
+		t.Fatalf("unexpected stats %+v %v", s, err)
+	}
+}
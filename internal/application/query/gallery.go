@@ -0,0 +1,79 @@
+package query
+
+import (
+	"context"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// SearchGalleryQuery selects a page of the public decks. Empty filters match
+// every deck and CardIDs matches decks holding all of the cards. Liked
+// restricts the page to the decks UserID likes. Sort is deck.SortNewest,
+// the default, or deck.SortPopular.
+type SearchGalleryQuery struct {
+	UserID  uuid.UUID
+	Format  string
+	Faction string
+	CardIDs []uuid.UUID
+	Liked   bool
+	Sort    string
+	Offset  int
+	Limit   int
+}
+
+// GalleryPage is a page of gallery entries and the total number of matches.
+type GalleryPage struct {
+	Entries []deck.GalleryEntry
+	Total   int
+}
+
+// SharedDeckQuery selects a deck shared by another user. UserID is
+// uuid.Nil for anonymous users.
+type SharedDeckQuery struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+}
+
+// GalleryHandler handles browsing the public decks and reading shared
+// decks.
+type GalleryHandler struct {
+	Gallery deck.GalleryRepository
+	Decks   deck.Repository
+	Cards   card.Repository
+}
+
+// Search returns the matching public decks from the gallery read model.
+func (h *GalleryHandler) Search(ctx context.Context, q SearchGalleryQuery) (*GalleryPage, error) {
+	gq := deck.GalleryQuery{Format: q.Format, Faction: q.Faction, CardIDs: q.CardIDs, Sort: q.Sort, Offset: q.Offset, Limit: q.Limit}
+	if gq.Sort == "" {
+		gq.Sort = deck.SortNewest
+	}
+	if q.Liked {
+		if q.UserID == uuid.Nil {
+			return &GalleryPage{Entries: []deck.GalleryEntry{}}, nil
+		}
+		gq.LikedBy = q.UserID
+	}
+	entries, total, err := h.Gallery.Search(ctx, gq)
+	if err != nil {
+		return nil, err
+	}
+	return &GalleryPage{Entries: entries, Total: total}, nil
+}
+
+// Deck loads a public or unlisted deck, or a private deck of the user, and
+// resolves its cards. Decks the user may not see are reported as
+// deck.ErrNotFound so that their existence is not revealed.
+func (h *GalleryHandler) Deck(ctx context.Context, q SharedDeckQuery) (*DeckDetails, error) {
+	d, err := h.Decks.Load(ctx, q.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil || !d.CanView(q.UserID) {
+		return nil, deck.ErrNotFound
+	}
+	details, _, err := resolveCards(ctx, h.Cards, d)
+	return details, err
+}
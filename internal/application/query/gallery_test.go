@@ -0,0 +1,72 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestGalleryHandlerSearch(t *testing.T) {
+	ctx := context.Background()
+	gallery := deckstore.NewInMemoryGallery()
+	a := uuid.New()
+	red := deck.GalleryEntry{DeckID: uuid.New(), Name: "red", Format: "std", Factions: []string{"red"}, CardIDs: []uuid.UUID{a}}
+	blue := deck.GalleryEntry{DeckID: uuid.New(), Name: "blue", Format: "std", Factions: []string{"blue"}}
+	for _, e := range []deck.GalleryEntry{red, blue} {
+		if err := gallery.Put(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	user := uuid.New()
+	_, _ = gallery.Like(ctx, blue.DeckID, user)
+
+	h := &GalleryHandler{Gallery: gallery}
+	page, err := h.Search(ctx, SearchGalleryQuery{Format: "std", CardIDs: []uuid.UUID{a}})
+	if err != nil || page.Total != 1 || page.Entries[0].Name != "red" {
+		t.Fatalf("unexpected page %+v %v", page, err)
+	}
+	page, err = h.Search(ctx, SearchGalleryQuery{Sort: deck.SortPopular})
+	if err != nil || page.Total != 2 || page.Entries[0].Name != "blue" {
+		t.Fatalf("unexpected popular page %+v %v", page, err)
+	}
+	page, err = h.Search(ctx, SearchGalleryQuery{UserID: user, Liked: true})
+	if err != nil || page.Total != 1 || page.Entries[0].Name != "blue" {
+		t.Fatalf("unexpected liked page %+v %v", page, err)
+	}
+	if page, err := h.Search(ctx, SearchGalleryQuery{Liked: true}); err != nil || page.Total != 0 {
+		t.Fatalf("expected anonymous users to like nothing got %+v %v", page, err)
+	}
+}
+
+func TestGalleryHandlerDeck(t *testing.T) {
+	ctx := context.Background()
+	decks := deckstore.NewInMemoryStore()
+	a := uuid.New()
+	cards := &loadRepo{cards: map[string]*card.Card{a.String(): {ID: a, Name: "A"}}}
+	owner := uuid.New()
+	d := deck.NewDeck(owner, "d", []uuid.UUID{a})
+	if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &GalleryHandler{Decks: decks, Cards: cards}
+	if got, err := h.Deck(ctx, SharedDeckQuery{DeckID: d.ID, UserID: owner}); err != nil || got.Cards[0].Card.Name != "A" {
+		t.Fatalf("expected the owner to see the deck got %+v %v", got, err)
+	}
+	for _, user := range []uuid.UUID{uuid.New(), uuid.Nil} {
+		if _, err := h.Deck(ctx, SharedDeckQuery{DeckID: d.ID, UserID: user}); !errors.Is(err, deck.ErrNotFound) {
+			t.Fatalf("expected deck.ErrNotFound for a private deck got %v", err)
+		}
+	}
+	if err := decks.Save(ctx, 1, []interface{}{deck.DeckVisibilityChanged{ID: d.ID, Visibility: deck.VisibilityUnlisted}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := h.Deck(ctx, SharedDeckQuery{DeckID: d.ID}); err != nil || got.Deck.ID != d.ID {
+		t.Fatalf("expected anyone to see an unlisted deck got %+v %v", got, err)
+	}
+}
@@ -0,0 +1,87 @@
+package query
+
+import (
+	"context"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// GetDeckQuery selects a deck of a user.
+type GetDeckQuery struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+}
+
+// DeckCard is a distinct card of a deck zone with its number of copies.
+// Card is nil when the card is no longer in the catalog.
+type DeckCard struct {
+	ID    uuid.UUID
+	Card  *card.Card
+	Count int
+	Zone  string
+}
+
+// DeckDetails is a deck with its cards resolved. Violations lists the rules
+// of its format the deck breaks, which decks may keep doing after the rules
+// changed; the deck is legal when there are none.
+type DeckDetails struct {
+	Deck       *deck.Deck
+	Cards      []DeckCard
+	Violations []deck.Violation
+}
+
+// GetDeckHandler handles loading a single deck.
+type GetDeckHandler struct {
+	Decks deck.Repository
+	Cards card.Repository
+	// Rules, when set, checks the deck against the rules of its format.
+	Rules *deck.Rules
+}
+
+// Handle loads the deck if it is owned by the user and resolves its cards,
+// zone by zone.
+func (h *GetDeckHandler) Handle(ctx context.Context, q GetDeckQuery) (*DeckDetails, error) {
+	d, err := h.Decks.Load(ctx, q.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, deck.ErrNotFound
+	}
+	if err := d.CheckOwner(q.UserID); err != nil {
+		return nil, err
+	}
+	details, resolved, err := resolveCards(ctx, h.Cards, d)
+	if err != nil {
+		return nil, err
+	}
+	if h.Rules != nil {
+		if details.Violations, err = h.Rules.Validate(d, resolved); err != nil {
+			return nil, err
+		}
+	}
+	return details, nil
+}
+
+// resolveCards loads the cards of d from the catalog, zone by zone. It also
+// returns the distinct cards loaded, nil for those missing from the catalog.
+func resolveCards(ctx context.Context, cards card.Repository, d *deck.Deck) (*DeckDetails, map[uuid.UUID]*card.Card, error) {
+	details := &DeckDetails{Deck: d}
+	resolved := make(map[uuid.UUID]*card.Card)
+	for _, z := range d.Zones {
+		for _, e := range z.Cards {
+			c, ok := resolved[e.CardID]
+			if !ok {
+				var err error
+				if c, err = cards.Load(ctx, e.CardID.String()); err != nil {
+					return nil, nil, err
+				}
+				resolved[e.CardID] = c
+			}
+			details.Cards = append(details.Cards, DeckCard{ID: e.CardID, Card: c, Count: e.Quantity, Zone: z.Name})
+		}
+	}
+	return details, resolved, nil
+}
@@ -0,0 +1,41 @@
+package query
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// GetDeckCodeQuery selects the deck to share.
+type GetDeckCodeQuery struct {
+	DeckID uuid.UUID
+	UserID uuid.UUID
+}
+
+// GetDeckCodeHandler handles requests for deck codes.
+type GetDeckCodeHandler struct {
+	Decks deck.Repository
+	// Numbers numbers the cards the code refers to.
+	Numbers deck.CardNumberRepository
+}
+
+// Handle returns the code of the deck if it is owned by the user.
+func (h *GetDeckCodeHandler) Handle(ctx context.Context, q GetDeckCodeQuery) (string, error) {
+	d, err := h.Decks.Load(ctx, q.DeckID)
+	if err != nil {
+		return "", err
+	}
+	if d == nil {
+		return "", deck.ErrNotFound
+	}
+	if err := d.CheckOwner(q.UserID); err != nil {
+		return "", err
+	}
+	l := deck.DeckList{Format: d.Format, Zones: d.Zones}
+	numbers, err := h.Numbers.Numbers(ctx, l.CardIDs())
+	if err != nil {
+		return "", err
+	}
+	return deck.EncodeCode(l, numbers)
+}
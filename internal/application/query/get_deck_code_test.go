@@ -0,0 +1,35 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestGetDeckCode(t *testing.T) {
+	ctx := context.Background()
+	decks := deckstore.NewInMemoryStore()
+	user := uuid.New()
+	d := deck.NewDeck(user, "d", []uuid.UUID{uuid.New(), uuid.New()})
+	d.Format = "std"
+	if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	numbers := deckstore.NewInMemoryCardNumbers()
+	h := &GetDeckCodeHandler{Decks: decks, Numbers: numbers}
+	code, err := h.Handle(ctx, GetDeckCodeQuery{DeckID: d.ID, UserID: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cardIDs := func(n []uint64) (map[uint64]uuid.UUID, error) { return numbers.CardIDs(ctx, n) }
+	if l, err := deck.DecodeCode(code, cardIDs); err != nil || l.Format != "std" || len(l.Zones) != 1 || l.Zones[0].Size() != 2 {
+		t.Fatalf("unexpected list %+v %v", l, err)
+	}
+	if _, err := h.Handle(ctx, GetDeckCodeQuery{DeckID: d.ID, UserID: uuid.New()}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+type loadRepo struct {
+	mockRepo
+	cards map[string]*card.Card
+}
+
+func (r *loadRepo) Load(ctx context.Context, id string) (*card.Card, error) { return r.cards[id], nil }
+
+func TestGetDeck(t *testing.T) {
+	ctx := context.Background()
+	decks := deckstore.NewInMemoryStore()
+	a, b := uuid.New(), uuid.New()
+	cards := &loadRepo{cards: map[string]*card.Card{a.String(): {ID: a, Name: "A"}}}
+	user := uuid.New()
+	d := deck.NewDeck(user, "d", []uuid.UUID{a, b, a})
+	d.Zones = append(d.Zones, deck.ZoneOf(deck.ZoneSideboard, []uuid.UUID{a}))
+	if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &GetDeckHandler{Decks: decks, Cards: cards}
+	got, err := h.Handle(ctx, GetDeckQuery{DeckID: d.ID, UserID: user})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Deck.ID != d.ID || len(got.Cards) != 3 {
+		t.Fatalf("unexpected deck %+v", got)
+	}
+	if got.Cards[0].ID != a || got.Cards[0].Card.Name != "A" || got.Cards[0].Count != 2 || got.Cards[0].Zone != deck.ZoneMain {
+		t.Fatalf("unexpected card %+v", got.Cards[0])
+	}
+	if got.Cards[1].ID != b || got.Cards[1].Card != nil || got.Cards[1].Count != 1 {
+		t.Fatalf("unexpected missing card %+v", got.Cards[1])
+	}
+	if got.Cards[2].ID != a || got.Cards[2].Card.Name != "A" || got.Cards[2].Zone != deck.ZoneSideboard {
+		t.Fatalf("unexpected sideboard card %+v", got.Cards[2])
+	}
+
+	if _, err := h.Handle(ctx, GetDeckQuery{DeckID: d.ID, UserID: uuid.New()}); !errors.Is(err, deck.ErrNotOwner) {
+		t.Fatalf("expected deck.ErrNotOwner got %v", err)
+	}
+	if _, err := h.Handle(ctx, GetDeckQuery{DeckID: uuid.New(), UserID: user}); !errors.Is(err, deck.ErrNotFound) {
+		t.Fatalf("expected deck.ErrNotFound got %v", err)
+	}
+}
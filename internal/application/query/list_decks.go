@@ -0,0 +1,35 @@
+package query
+
+import (
+	"context"
+
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// ListDecksQuery selects a page of the decks of a user.
+type ListDecksQuery struct {
+	UserID uuid.UUID
+	Offset int
+	Limit  int
+}
+
+// DeckPage is a page of decks and the total number of decks.
+type DeckPage struct {
+	Decks []*deck.Deck
+	Total int
+}
+
+// ListDecksHandler handles listing the decks of a user.
+type ListDecksHandler struct {
+	Decks deck.Repository
+}
+
+// Handle returns the decks of the user, newest first.
+func (h *ListDecksHandler) Handle(ctx context.Context, q ListDecksQuery) (*DeckPage, error) {
+	decks, total, err := h.Decks.ListByUser(ctx, q.UserID, q.Offset, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &DeckPage{Decks: decks, Total: total}, nil
+}
@@ -0,0 +1,28 @@
+package query
+
+import (
+	"context"
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestListDecks(t *testing.T) {
+	ctx := context.Background()
+	repo := deckstore.NewInMemoryStore()
+	user := uuid.New()
+	for _, name := range []string{"a", "b", "c"} {
+		if err := repo.Save(ctx, 0, []interface{}{deck.NewDeck(user, name, nil).Created()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = repo.Save(ctx, 0, []interface{}{deck.NewDeck(uuid.New(), "other", nil).Created()})
+
+	h := &ListDecksHandler{Decks: repo}
+	page, err := h.Handle(ctx, ListDecksQuery{UserID: user, Offset: 1, Limit: 1})
+	if err != nil || page.Total != 3 || len(page.Decks) != 1 || page.Decks[0].UserID != user {
+		t.Fatalf("unexpected page %+v %v", page, err)
+	}
+}
@@ -0,0 +1,27 @@
+// Package config loads runtime configuration of the card service.
+package config
+
+import (
+	"encoding/json"
+	"os"
+
+	"demo/internal/domain/deck"
+)
+
+// formatsFile is the layout of the deck formats configuration file.
+type formatsFile struct {
+	Formats []deck.Format `json:"formats"`
+}
+
+// LoadFormats reads deck format definitions from a JSON file.
+func LoadFormats(path string) ([]deck.Format, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f formatsFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f.Formats, nil
+}
@@ -0,0 +1,22 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFormats(t *testing.T) {
+	formats, err := LoadFormats(filepath.Join("..", "..", "configs", "formats.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(formats) == 0 || formats[0].ID != "standard" || formats[0].MaxCopies != 3 {
+		t.Fatalf("unexpected formats %+v", formats)
+	}
+	path := filepath.Join(t.TempDir(), "bad.json")
+	_ = os.WriteFile(path, []byte("{"), 0o644)
+	if _, err := LoadFormats(path); err == nil {
+		t.Fatal("expected error for invalid file")
+	}
+}
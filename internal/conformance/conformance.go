@@ -0,0 +1,54 @@
+// Package conformance runs the conformance suites of the repository
+// interfaces. The suites themselves live next to the interfaces, in the
+// cardtest, decktest, usertest, audittest and authtest packages, and only
+// list their cases.
+package conformance
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// Base is a time for the records of a suite, rounded so that every store
+// keeps it exactly.
+var Base = time.Now().UTC().Truncate(time.Second)
+
+// Factory returns an empty repository for a single subtest.
+type Factory[R any] func(t *testing.T) R
+
+// Case is a test of a suite.
+type Case[R any] struct {
+	Name string
+	Fn   func(t *testing.T, repo R)
+}
+
+// Suite is the conformance suite of the implementations of R.
+type Suite[R any] []Case[R]
+
+// Run runs every case as a subtest. Every subtest gets a fresh repository
+// from newRepo.
+func (s Suite[R]) Run(t *testing.T, newRepo Factory[R]) {
+	t.Helper()
+	for _, c := range s {
+		t.Run(c.Name, func(t *testing.T) {
+			c.Fn(t, newRepo(t))
+		})
+	}
+}
+
+// Concurrently calls fn n times at once, with i from 0 to n-1, and returns
+// the errors of the calls in order of i.
+func Concurrently(n int, fn func(i int) error) []error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
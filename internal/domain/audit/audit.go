@@ -0,0 +1,92 @@
+// Package audit describes the security audit log: an append-only record of
+// logins, tokens, permission denials and privileged actions.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Action names what an entry records.
+type Action string
+
+// Recorded actions.
+const (
+	LoginSucceeded   Action = "login.succeeded"
+	LoginFailed      Action = "login.failed"
+	LoginLockedOut   Action = "login.locked_out"
+	TokenRefreshed   Action = "token.refreshed"
+	TokenRevoked     Action = "token.revoked"
+	PermissionDenied Action = "permission.denied"
+	UserDisabled     Action = "user.disabled"
+	UserEnabled      Action = "user.enabled"
+	UserRoleChanged  Action = "user.role_changed"
+	UserDeleted      Action = "user.deleted"
+	APIKeyCreated    Action = "api_key.created"
+	APIKeyRevoked    Action = "api_key.revoked"
+	CardCreated      Action = "card.created"
+	CardUpdated      Action = "card.updated"
+	CardsImported    Action = "cards.imported"
+)
+
+// Entry is a record of the audit log. ActorID is the user acting, uuid.Nil
+// for anonymous requests, and APIKeyID the API key they acted with, if any.
+// Target names what the action applies to, such as a user id, the login
+// tried or a route, and Details adds to it. TraceID is the trace of the
+// request.
+type Entry struct {
+	ID        uuid.UUID
+	Time      time.Time
+	Action    Action
+	ActorID   uuid.UUID
+	APIKeyID  uuid.UUID
+	Target    string
+	Details   string
+	IP        string
+	UserAgent string
+	TraceID   string
+}
+
+// Query selects a page of the log. Empty fields match every entry; Since is
+// inclusive and Until exclusive.
+type Query struct {
+	Action  Action
+	ActorID uuid.UUID
+	Target  string
+	Since   time.Time
+	Until   time.Time
+	Offset  int
+	Limit   int
+}
+
+// Matches reports whether e is selected by the filters of q.
+func (q Query) Matches(e Entry) bool {
+	if q.Action != "" && e.Action != q.Action {
+		return false
+	}
+	if q.ActorID != uuid.Nil && e.ActorID != q.ActorID {
+		return false
+	}
+	if q.Target != "" && e.Target != q.Target {
+		return false
+	}
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+// Repository stores the audit log. Entries are never changed or removed.
+type Repository interface {
+	// Append adds an entry to the log.
+	Append(ctx context.Context, e Entry) error
+	// Search returns a page of matching entries, newest first, and the
+	// total number of matches. A limit <= 0 returns all entries from offset
+	// on.
+	Search(ctx context.Context, q Query) ([]Entry, int, error)
+}
@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestQueryMatches(t *testing.T) {
+	now := time.Now()
+	actor := uuid.New()
+	e := Entry{Time: now, Action: LoginSucceeded, ActorID: actor, Target: actor.String()}
+	tests := []struct {
+		name string
+		q    Query
+		want bool
+	}{
+		{"empty", Query{}, true},
+		{"action", Query{Action: LoginSucceeded}, true},
+		{"other action", Query{Action: LoginFailed}, false},
+		{"actor", Query{ActorID: actor}, true},
+		{"other actor", Query{ActorID: uuid.New()}, false},
+		{"target", Query{Target: actor.String()}, true},
+		{"other target", Query{Target: "bob"}, false},
+		{"since inclusive", Query{Since: now}, true},
+		{"since later", Query{Since: now.Add(time.Second)}, false},
+		{"until exclusive", Query{Until: now}, false},
+		{"until later", Query{Until: now.Add(time.Second)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Matches(e); got != tt.want {
+			t.Fatalf("%s: expected %v got %v", tt.name, tt.want, got)
+		}
+	}
+}
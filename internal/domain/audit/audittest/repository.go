@@ -0,0 +1,139 @@
+// Package audittest provides a conformance suite for audit.Repository
+// implementations.
+package audittest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/audit"
+	"github.com/google/uuid"
+)
+
+// RepositorySuite is the conformance suite of audit.Repository
+// implementations.
+var RepositorySuite = conformance.Suite[audit.Repository]{
+	{Name: "AppendAndSearch", Fn: testAppendAndSearch},
+	{Name: "Filters", Fn: testFilters},
+	{Name: "Paging", Fn: testPaging},
+	{Name: "ConcurrentAppends", Fn: testConcurrentAppends},
+}
+
+func newEntry(action audit.Action, actor uuid.UUID, target string, age int) audit.Entry {
+	return audit.Entry{
+		ID:      uuid.New(),
+		Time:    conformance.Base.Add(-time.Duration(age) * time.Minute),
+		Action:  action,
+		ActorID: actor,
+		Target:  target,
+	}
+}
+
+func appendEntries(t *testing.T, repo audit.Repository, entries ...audit.Entry) {
+	t.Helper()
+	for _, e := range entries {
+		if err := repo.Append(context.Background(), e); err != nil {
+			t.Fatalf("append %s: %v", e.Action, err)
+		}
+	}
+}
+
+func testAppendAndSearch(t *testing.T, repo audit.Repository) {
+	e := audit.Entry{
+		ID:        uuid.New(),
+		Time:      conformance.Base,
+		Action:    audit.UserRoleChanged,
+		ActorID:   uuid.New(),
+		APIKeyID:  uuid.New(),
+		Target:    uuid.NewString(),
+		Details:   "designer",
+		IP:        "192.0.2.1",
+		UserAgent: "curl/8.0",
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+	}
+	appendEntries(t, repo, e)
+	got, total, err := repo.Search(context.Background(), audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || len(got) != 1 {
+		t.Fatalf("expected 1 entry got %d of %d", len(got), total)
+	}
+	if g := got[0]; g.ID != e.ID || !g.Time.Equal(e.Time) || g.Action != e.Action || g.ActorID != e.ActorID || g.APIKeyID != e.APIKeyID ||
+		g.Target != e.Target || g.Details != e.Details || g.IP != e.IP || g.UserAgent != e.UserAgent || g.TraceID != e.TraceID {
+		t.Fatalf("expected %+v got %+v", e, g)
+	}
+}
+
+func testFilters(t *testing.T, repo audit.Repository) {
+	alice, bob := uuid.New(), uuid.New()
+	appendEntries(t, repo,
+		newEntry(audit.LoginSucceeded, alice, alice.String(), 30),
+		newEntry(audit.LoginFailed, uuid.Nil, "bob", 20),
+		newEntry(audit.LoginSucceeded, bob, bob.String(), 10),
+		newEntry(audit.UserDisabled, alice, bob.String(), 0),
+	)
+	tests := []struct {
+		name string
+		q    audit.Query
+		want int
+	}{
+		{"all", audit.Query{}, 4},
+		{"action", audit.Query{Action: audit.LoginSucceeded}, 2},
+		{"actor", audit.Query{ActorID: alice}, 2},
+		{"target", audit.Query{Target: bob.String()}, 2},
+		{"since", audit.Query{Since: conformance.Base.Add(-10 * time.Minute)}, 2},
+		{"until", audit.Query{Until: conformance.Base.Add(-10 * time.Minute)}, 2},
+		{"combined", audit.Query{Action: audit.LoginSucceeded, ActorID: alice, Until: conformance.Base}, 1},
+		{"none", audit.Query{Action: audit.APIKeyCreated}, 0},
+	}
+	for _, tt := range tests {
+		got, total, err := repo.Search(context.Background(), tt.q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != tt.want || len(got) != tt.want {
+			t.Fatalf("%s: expected %d entries got %d of %d", tt.name, tt.want, len(got), total)
+		}
+		for _, e := range got {
+			if !tt.q.Matches(e) {
+				t.Fatalf("%s: unexpected entry %+v", tt.name, e)
+			}
+		}
+	}
+}
+
+func testPaging(t *testing.T, repo audit.Repository) {
+	var entries []audit.Entry
+	for i := 0; i < 5; i++ {
+		entries = append(entries, newEntry(audit.LoginFailed, uuid.Nil, fmt.Sprint(i), i))
+	}
+	appendEntries(t, repo, entries...)
+	got, total, err := repo.Search(context.Background(), audit.Query{Offset: 1, Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || len(got) != 2 || got[0].Target != "1" || got[1].Target != "2" {
+		t.Fatalf("expected entries 1 and 2, newest first, got %+v of %d", got, total)
+	}
+	if got, _, _ := repo.Search(context.Background(), audit.Query{Offset: 3}); len(got) != 2 || got[1].Target != "4" {
+		t.Fatalf("expected the oldest entries without a limit got %+v", got)
+	}
+}
+
+func testConcurrentAppends(t *testing.T, repo audit.Repository) {
+	errs := conformance.Concurrently(20, func(i int) error {
+		return repo.Append(context.Background(), newEntry(audit.LoginFailed, uuid.Nil, fmt.Sprint(i), 0))
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, total, _ := repo.Search(context.Background(), audit.Query{}); total != 20 {
+		t.Fatalf("expected 20 entries got %d", total)
+	}
+}
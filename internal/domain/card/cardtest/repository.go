@@ -0,0 +1,264 @@
+// Package cardtest provides a conformance suite for card.Repository
+// implementations.
+package cardtest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// RepositorySuite is the conformance suite of card.Repository implementations.
+var RepositorySuite = conformance.Suite[card.Repository]{
+	{Name: "SaveLoad", Fn: testSaveLoad},
+	{Name: "SaveEmpty", Fn: testSaveEmpty},
+	{Name: "LoadNotFound", Fn: testLoadNotFound},
+	{Name: "EventOrdering", Fn: testEventOrdering},
+	{Name: "Search", Fn: testSearch},
+	{Name: "UnknownEvent", Fn: testUnknownEvent},
+	{Name: "MultiStreamBatch", Fn: testMultiStreamBatch},
+	{Name: "AtomicBatch", Fn: testAtomicBatch},
+	{Name: "ConcurrentStreams", Fn: testConcurrentStreams},
+	{Name: "ConcurrentAppends", Fn: testConcurrentAppends},
+	{Name: "Tenants", Fn: testTenants},
+}
+
+func created(c *card.Card) card.CardCreated {
+	return card.CardCreated{ID: c.ID, Name: c.Name, Cost: c.Cost, Faction: c.Faction, Category: c.Category, SubCategory: c.SubCategory, Description: c.Description}
+}
+
+func save(t *testing.T, repo card.Repository, events ...interface{}) {
+	t.Helper()
+	if err := repo.Save(context.Background(), events); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func load(t *testing.T, repo card.Repository, id uuid.UUID) *card.Card {
+	t.Helper()
+	c, err := repo.Load(context.Background(), id.String())
+	if err != nil {
+		t.Fatalf("load %s: %v", id, err)
+	}
+	if c == nil {
+		t.Fatalf("card %s not found", id)
+	}
+	return c
+}
+
+func testSaveLoad(t *testing.T, repo card.Repository) {
+	c := card.NewCard("Name", 3, "Faction", "Category", "Sub", "Desc")
+	save(t, repo, created(c))
+	if got := load(t, repo, c.ID); *got != *c {
+		t.Fatalf("expected %+v got %+v", c, got)
+	}
+}
+
+func testSaveEmpty(t *testing.T, repo card.Repository) {
+	if err := repo.Save(context.Background(), nil); err != nil {
+		t.Fatalf("empty save: %v", err)
+	}
+}
+
+func testLoadNotFound(t *testing.T, repo card.Repository) {
+	c, err := repo.Load(context.Background(), uuid.NewString())
+	if err != nil || c != nil {
+		t.Fatalf("expected nil, nil got %+v %v", c, err)
+	}
+}
+
+func testEventOrdering(t *testing.T, repo card.Repository) {
+	c := card.NewCard("A", 1, "F", "C", "S", "D")
+	save(t, repo, created(c))
+	save(t, repo, card.CardUpdated{ID: c.ID, Name: "B", Cost: 2, Faction: "F"})
+	save(t, repo, card.CardUpdated{ID: c.ID, Name: "C", Cost: 3, Faction: "G", Category: "X"})
+	got := load(t, repo, c.ID)
+	want := card.Card{ID: c.ID, Name: "C", Cost: 3, Faction: "G", Category: "X"}
+	if *got != want {
+		t.Fatalf("expected %+v got %+v", want, got)
+	}
+}
+
+func testSearch(t *testing.T, repo card.Repository) {
+	a := card.NewCard("A", 1, "Human", "Soldier", "Infantry", "")
+	b := card.NewCard("B", 2, "Human", "Spell", "Fire", "")
+	c := card.NewCard("C", 2, "Elf", "Soldier", "Archer", "")
+	save(t, repo, created(a))
+	save(t, repo, created(b))
+	save(t, repo, created(c))
+	// an update must be reflected in search results
+	save(t, repo, card.CardUpdated{ID: a.ID, Name: "A2", Cost: 1, Faction: "Human", Category: "Soldier", SubCategory: "Infantry"})
+
+	tests := []struct {
+		name                   string
+		cardName               string
+		cost                   int
+		faction, category, sub string
+		want                   []uuid.UUID
+	}{
+		{name: "all", want: []uuid.UUID{a.ID, b.ID, c.ID}},
+		{name: "name", cardName: "A2", want: []uuid.UUID{a.ID}},
+		{name: "stale name", cardName: "A"},
+		{name: "cost", cost: 2, want: []uuid.UUID{b.ID, c.ID}},
+		{name: "faction", faction: "Human", want: []uuid.UUID{a.ID, b.ID}},
+		{name: "category", category: "Soldier", want: []uuid.UUID{a.ID, c.ID}},
+		{name: "sub", sub: "Fire", want: []uuid.UUID{b.ID}},
+		{name: "combined", cost: 2, category: "Soldier", want: []uuid.UUID{c.ID}},
+		{name: "no match", faction: "Orc"},
+	}
+	for _, tt := range tests {
+		cards, err := repo.Search(context.Background(), tt.cardName, tt.cost, tt.faction, tt.category, tt.sub)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got := make(map[uuid.UUID]bool)
+		for _, c := range cards {
+			got[c.ID] = true
+		}
+		if len(cards) != len(tt.want) || len(got) != len(tt.want) {
+			t.Fatalf("%s: expected %d cards got %d", tt.name, len(tt.want), len(cards))
+		}
+		for _, id := range tt.want {
+			if !got[id] {
+				t.Fatalf("%s: missing card %s", tt.name, id)
+			}
+		}
+	}
+}
+
+func testUnknownEvent(t *testing.T, repo card.Repository) {
+	if err := repo.Save(context.Background(), []interface{}{struct{}{}}); err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+	cards, err := repo.Search(context.Background(), "", 0, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 0 {
+		t.Fatalf("expected 0 cards got %d", len(cards))
+	}
+}
+
+func testMultiStreamBatch(t *testing.T, repo card.Repository) {
+	a := card.NewCard("A", 1, "F", "C", "S", "D")
+	b := card.NewCard("B", 2, "F", "C", "S", "D")
+	save(t, repo, created(a), created(b), card.CardUpdated{ID: a.ID, Name: "A2", Cost: 5})
+	if got := load(t, repo, a.ID); got.Name != "A2" || got.Cost != 5 {
+		t.Fatalf("unexpected card %+v", got)
+	}
+	if got := load(t, repo, b.ID); *got != *b {
+		t.Fatalf("expected %+v got %+v", b, got)
+	}
+}
+
+// testAtomicBatch checks that a batch failing part way leaves no trace.
+func testAtomicBatch(t *testing.T, repo card.Repository) {
+	a := card.NewCard("A", 1, "F", "C", "S", "D")
+	b := card.NewCard("B", 2, "F", "C", "S", "D")
+	if err := repo.Save(context.Background(), []interface{}{created(a), struct{}{}, created(b)}); err == nil {
+		t.Fatal("expected error for batch with unknown event")
+	}
+	for _, id := range []uuid.UUID{a.ID, b.ID} {
+		if c, err := repo.Load(context.Background(), id.String()); err != nil || c != nil {
+			t.Fatalf("expected nothing stored for %s got %+v %v", id, c, err)
+		}
+	}
+}
+
+func testConcurrentStreams(t *testing.T, repo card.Repository) {
+	const n = 20
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	errs := conformance.Concurrently(n, func(i int) error {
+		ctx := context.Background()
+		if err := repo.Save(ctx, []interface{}{card.CardCreated{ID: ids[i], Name: "N", Cost: i}}); err != nil {
+			return err
+		}
+		return repo.Save(ctx, []interface{}{card.CardUpdated{ID: ids[i], Name: fmt.Sprint(i), Cost: i}})
+	})
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent save: %v", err)
+		}
+	}
+	for i, id := range ids {
+		if c := load(t, repo, id); c.Name != fmt.Sprint(i) || c.Cost != i {
+			t.Fatalf("unexpected card %+v", c)
+		}
+	}
+}
+
+// testConcurrentAppends writes to one stream from several goroutines. Stores
+// may reject conflicting appends, but at least one must succeed and the final
+// state must come from exactly one of the successful events.
+func testConcurrentAppends(t *testing.T, repo card.Repository) {
+	c := card.NewCard("N", 0, "", "", "", "")
+	save(t, repo, created(c))
+	const n = 20
+	errs := conformance.Concurrently(n, func(i int) error {
+		evt := card.CardUpdated{ID: c.ID, Name: fmt.Sprint(i + 1), Cost: i + 1}
+		return repo.Save(context.Background(), []interface{}{evt})
+	})
+	got := load(t, repo, c.ID)
+	if got.Cost < 1 || got.Cost > n || errs[got.Cost-1] != nil || got.Name != fmt.Sprint(got.Cost) {
+		t.Fatalf("final state %+v does not match a successful append", got)
+	}
+}
+
+// testTenants checks that the cards of a tenant are invisible to the others
+// and that the default tenant is that of contexts naming none.
+func testTenants(t *testing.T, repo card.Repository) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+	a := card.NewCard("A", 1, "F", "C", "S", "D")
+	b := card.NewCard("B", 1, "F", "C", "S", "D")
+	d := card.NewCard("D", 1, "F", "C", "S", "D")
+	if err := repo.Save(acme, []interface{}{created(a), card.CardUpdated{ID: a.ID, Name: "A2", Cost: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(globex, []interface{}{created(b)}); err != nil {
+		t.Fatal(err)
+	}
+	save(t, repo, created(d))
+
+	for name, tt := range map[string]struct {
+		ctx     context.Context
+		visible uuid.UUID
+	}{
+		"acme":    {acme, a.ID},
+		"globex":  {globex, b.ID},
+		"default": {tenant.NewContext(context.Background(), tenant.Default), d.ID},
+	} {
+		for _, id := range []uuid.UUID{a.ID, b.ID, d.ID} {
+			c, err := repo.Load(tt.ctx, id.String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (c != nil) != (id == tt.visible) {
+				t.Fatalf("%s: unexpected card %s: %+v", name, id, c)
+			}
+		}
+		cards, err := repo.Search(tt.ctx, "", 0, "", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cards) != 1 || cards[0].ID != tt.visible {
+			t.Fatalf("%s: expected only %s got %+v", name, tt.visible, cards)
+		}
+	}
+	if c, _ := repo.Load(acme, a.ID.String()); c == nil || c.Name != "A2" {
+		t.Fatalf("expected the update to apply within the tenant got %+v", c)
+	}
+	// an update saved by another tenant does not touch the card
+	_ = repo.Save(globex, []interface{}{card.CardUpdated{ID: a.ID, Name: "stolen"}})
+	if c, _ := repo.Load(acme, a.ID.String()); c == nil || c.Name != "A2" {
+		t.Fatalf("expected other tenants not to change the card got %+v", c)
+	}
+}
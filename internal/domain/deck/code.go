@@ -0,0 +1,221 @@
+package deck
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// CodeVersion is the version of the deck codes written by EncodeCode.
+const CodeVersion = 1
+
+// maxCodeCards bounds the number of cards a code may expand to, so that a
+// short code cannot claim millions of copies.
+const maxCodeCards = 1000
+
+var (
+	// ErrInvalidCode is returned for malformed or corrupted deck codes.
+	ErrInvalidCode = errors.New("deck: invalid deck code")
+	// ErrCodeVersion is returned for deck codes of an unsupported version.
+	ErrCodeVersion = errors.New("deck: unsupported deck code version")
+)
+
+// DeckList is the content of a deck code: a format and the zones of a deck.
+type DeckList struct {
+	Format string
+	Zones  []Zone
+}
+
+// CardIDs returns the distinct cards of all zones.
+func (l DeckList) CardIDs() []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, z := range l.Zones {
+		for _, e := range z.Cards {
+			if !seen[e.CardID] {
+				seen[e.CardID] = true
+				ids = append(ids, e.CardID)
+			}
+		}
+	}
+	return ids
+}
+
+// EncodeCode returns the URL-safe code sharing the cards of a deck, which
+// refers to cards by the numbers given by a CardNumberRepository. Every card
+// of the list must be in numbers. The zones must be normalized. The order of
+// the cards within a zone is not kept: equal lists produce equal codes.
+//
+// A code is the unpadded base64url encoding of
+//
+//	version           byte
+//	len(format)       uvarint, followed by the format id
+//	zones             uvarint
+//	per zone          len(name) as uvarint, the name, the number of
+//	                  distinct cards as uvarint and per card the difference
+//	                  between its number and that of the previous card of
+//	                  the zone (0 before the first) as uvarint followed by
+//	                  its quantity as uvarint
+//	checksum          CRC-32 (IEEE) of all preceding bytes, big endian
+//
+// with cards in ascending number order.
+func EncodeCode(l DeckList, numbers map[uuid.UUID]uint64) (string, error) {
+	buf := []byte{CodeVersion}
+	buf = appendString(buf, l.Format)
+	buf = binary.AppendUvarint(buf, uint64(len(l.Zones)))
+	for _, z := range l.Zones {
+		buf = appendString(buf, z.Name)
+		var err error
+		if buf, err = appendEntries(buf, z.Cards, numbers); err != nil {
+			return "", err
+		}
+	}
+	buf = binary.BigEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf))
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// numberedEntry is an entry of a zone with the number of its card.
+type numberedEntry struct {
+	number   uint64
+	quantity int
+}
+
+func appendEntries(buf []byte, entries []Entry, numbers map[uuid.UUID]uint64) ([]byte, error) {
+	numbered := make([]numberedEntry, 0, len(entries))
+	for _, e := range entries {
+		n, ok := numbers[e.CardID]
+		if !ok || n == 0 {
+			return nil, fmt.Errorf("deck: card %s has no number", e.CardID)
+		}
+		numbered = append(numbered, numberedEntry{number: n, quantity: e.Quantity})
+	}
+	sort.Slice(numbered, func(i, j int) bool { return numbered[i].number < numbered[j].number })
+	buf = binary.AppendUvarint(buf, uint64(len(numbered)))
+	prev := uint64(0)
+	for _, e := range numbered {
+		buf = binary.AppendUvarint(buf, e.number-prev)
+		buf = binary.AppendUvarint(buf, uint64(e.quantity))
+		prev = e.number
+	}
+	return buf, nil
+}
+
+// DecodeCode parses a code written by EncodeCode. cardIDs returns the cards
+// with the numbers the code refers to, leaving out unknown numbers. The zones
+// are returned normalized.
+func DecodeCode(code string, cardIDs func(numbers []uint64) (map[uint64]uuid.UUID, error)) (DeckList, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(code)
+	if err != nil || len(buf) < 5 {
+		return DeckList{}, ErrInvalidCode
+	}
+	body, sum := buf[:len(buf)-4], binary.BigEndian.Uint32(buf[len(buf)-4:])
+	if crc32.ChecksumIEEE(body) != sum {
+		return DeckList{}, fmt.Errorf("%w: checksum mismatch", ErrInvalidCode)
+	}
+	if version := body[0]; version != CodeVersion {
+		return DeckList{}, fmt.Errorf("%w: %d", ErrCodeVersion, version)
+	}
+	r := codeReader{buf: body[1:]}
+	var l DeckList
+	l.Format = string(r.bytes(r.uvarint()))
+	zones := r.uvarint()
+	// the card numbers of every entry, in entry order
+	var numbers []uint64
+	for i := uint64(0); i < zones && r.err == nil; i++ {
+		z := Zone{Name: string(r.bytes(r.uvarint()))}
+		n := r.uvarint()
+		number := uint64(0)
+		for j := uint64(0); j < n && r.err == nil; j++ {
+			delta := r.uvarint()
+			if delta == 0 || number+delta < number {
+				return DeckList{}, fmt.Errorf("%w: bad card number", ErrInvalidCode)
+			}
+			number += delta
+			numbers = append(numbers, number)
+			q := r.uvarint()
+			if q == 0 || q > maxCodeCards {
+				return DeckList{}, fmt.Errorf("%w: bad card count", ErrInvalidCode)
+			}
+			z.Cards = append(z.Cards, Entry{Quantity: int(q)})
+			r.total += q
+			if r.total > maxCodeCards {
+				return DeckList{}, fmt.Errorf("%w: too many cards", ErrInvalidCode)
+			}
+		}
+		l.Zones = append(l.Zones, z)
+	}
+	if r.err != nil || len(r.buf) != 0 {
+		return DeckList{}, fmt.Errorf("%w: malformed body", ErrInvalidCode)
+	}
+	if len(numbers) > 0 {
+		if err := resolveNumbers(l.Zones, numbers, cardIDs); err != nil {
+			return DeckList{}, err
+		}
+	}
+	if l.Zones, err = NormalizeZones(l.Zones); err != nil {
+		return DeckList{}, fmt.Errorf("%w: %v", ErrInvalidCode, err)
+	}
+	return l, nil
+}
+
+// resolveNumbers sets the cards of the entries of zones to the cards with
+// numbers, given in entry order.
+func resolveNumbers(zones []Zone, numbers []uint64, cardIDs func([]uint64) (map[uint64]uuid.UUID, error)) error {
+	ids, err := cardIDs(numbers)
+	if err != nil {
+		return err
+	}
+	i := 0
+	for _, z := range zones {
+		for j := range z.Cards {
+			id, ok := ids[numbers[i]]
+			if !ok {
+				return fmt.Errorf("%w: unknown card %d", ErrInvalidCode, numbers[i])
+			}
+			z.Cards[j].CardID = id
+			i++
+		}
+	}
+	return nil
+}
+
+// codeReader reads the body of a deck code. After the first error every read
+// returns zero values and err stays set.
+type codeReader struct {
+	buf   []byte
+	err   error
+	total uint64
+}
+
+func (r *codeReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = ErrInvalidCode
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *codeReader) bytes(n uint64) []byte {
+	if r.err != nil || n > uint64(len(r.buf)) {
+		r.err = ErrInvalidCode
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
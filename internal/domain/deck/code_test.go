@@ -0,0 +1,143 @@
+package deck
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// catalog numbers cards like a CardNumberRepository.
+type catalog struct {
+	numbers map[uuid.UUID]uint64
+	ids     map[uint64]uuid.UUID
+}
+
+func newCatalog(ids ...uuid.UUID) *catalog {
+	c := &catalog{numbers: make(map[uuid.UUID]uint64), ids: make(map[uint64]uuid.UUID)}
+	for _, id := range ids {
+		c.add(id, uint64(len(c.numbers)+1))
+	}
+	return c
+}
+
+func (c *catalog) add(id uuid.UUID, n uint64) {
+	c.numbers[id] = n
+	c.ids[n] = id
+}
+
+func (c *catalog) cardIDs(numbers []uint64) (map[uint64]uuid.UUID, error) {
+	ids := make(map[uint64]uuid.UUID)
+	for _, n := range numbers {
+		if id, ok := c.ids[n]; ok {
+			ids[n] = id
+		}
+	}
+	return ids, nil
+}
+
+func (c *catalog) encode(t *testing.T, l DeckList) string {
+	t.Helper()
+	code, err := EncodeCode(l, c.numbers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestCodeRoundTrip(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	cat := newCatalog(a, b)
+	zones := []Zone{
+		{Name: ZoneMain, Cards: []Entry{{CardID: a, Quantity: 3}, {CardID: b, Quantity: 1}}},
+		{Name: ZoneSideboard, Cards: []Entry{{CardID: b, Quantity: 2}}},
+	}
+	code := cat.encode(t, DeckList{Format: "standard", Zones: zones})
+	reordered := []Zone{zones[0], zones[1]}
+	reordered[0].Cards = []Entry{zones[0].Cards[1], zones[0].Cards[0]}
+	if code != cat.encode(t, DeckList{Format: "standard", Zones: reordered}) {
+		t.Fatal("expected code independent of card order")
+	}
+	got, err := DecodeCode(code, cat.cardIDs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != "standard" || len(got.Zones) != 2 || got.Zones[0].Quantity(a) != 3 || got.Zones[0].Quantity(b) != 1 || got.Zones[1].Name != ZoneSideboard || got.Zones[1].Quantity(b) != 2 {
+		t.Fatalf("unexpected list %+v", got)
+	}
+
+	empty, err := DecodeCode(cat.encode(t, DeckList{}), cat.cardIDs)
+	if err != nil || empty.Format != "" || len(empty.Zones) != 0 {
+		t.Fatalf("unexpected empty list %+v %v", empty, err)
+	}
+
+	if _, err := EncodeCode(DeckList{Zones: []Zone{ZoneOf(ZoneMain, []uuid.UUID{uuid.New()})}}, cat.numbers); err == nil {
+		t.Fatal("expected error for a card without number")
+	}
+}
+
+func TestCodeLength(t *testing.T) {
+	// two copies each of 20 cards spread over a catalog of 10000 cards
+	cat := newCatalog()
+	var ids []uuid.UUID
+	for i := 0; i < 20; i++ {
+		id := uuid.New()
+		cat.add(id, uint64(10000-i*499))
+		ids = append(ids, id, id)
+	}
+	code := cat.encode(t, DeckList{Format: "standard", Zones: []Zone{ZoneOf(ZoneMain, ids)}})
+	if len(code) > 120 {
+		t.Fatalf("expected a code of at most 120 characters got %d: %s", len(code), code)
+	}
+	if got, err := DecodeCode(code, cat.cardIDs); err != nil || got.Zones[0].Size() != 40 {
+		t.Fatalf("unexpected list %+v %v", got, err)
+	}
+}
+
+func TestDecodeCodeErrors(t *testing.T) {
+	cat := newCatalog(uuid.New())
+	code := cat.encode(t, DeckList{Format: "f", Zones: []Zone{cat.zone(1)}})
+	raw, _ := base64.RawURLEncoding.DecodeString(code)
+
+	corrupt := append([]byte(nil), raw...)
+	corrupt[3] ^= 0xff
+	if _, err := DecodeCode(base64.RawURLEncoding.EncodeToString(corrupt), cat.cardIDs); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode for checksum mismatch got %v", err)
+	}
+	for _, bad := range []string{"", "!!", "AAAA", code[:len(code)-2]} {
+		if _, err := DecodeCode(bad, cat.cardIDs); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("%q: expected ErrInvalidCode got %v", bad, err)
+		}
+	}
+
+	for _, version := range []byte{0, CodeVersion + 1} {
+		if _, err := DecodeCode(withChecksum([]byte{version, 0, 0}), cat.cardIDs); !errors.Is(err, ErrCodeVersion) {
+			t.Fatalf("version %d: expected ErrCodeVersion got %v", version, err)
+		}
+	}
+	// a single card claiming far too many copies
+	huge := []byte{CodeVersion, 0, 1, 4, 'm', 'a', 'i', 'n', 1, 1, 0xff, 0xff, 0x03}
+	if _, err := DecodeCode(withChecksum(huge), cat.cardIDs); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("expected ErrInvalidCode for huge count got %v", err)
+	}
+	// numbers that are not ascending, and one no card has
+	for name, cards := range map[string][]byte{"repeated": {2, 1, 1, 0, 1}, "unknown": {1, 7, 1}} {
+		body := append([]byte{CodeVersion, 0, 1, 4, 'm', 'a', 'i', 'n'}, cards...)
+		if _, err := DecodeCode(withChecksum(body), cat.cardIDs); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("%s: expected ErrInvalidCode got %v", name, err)
+		}
+	}
+}
+
+// zone returns a main zone holding one copy of the card with number n.
+func (c *catalog) zone(n uint64) Zone {
+	return ZoneOf(ZoneMain, []uuid.UUID{c.ids[n]})
+}
+
+func withChecksum(body []byte) string {
+	body = binary.BigEndian.AppendUint32(body, crc32.ChecksumIEEE(body))
+	return base64.RawURLEncoding.EncodeToString(body)
+}
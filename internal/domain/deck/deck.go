@@ -1,21 +1,253 @@
 package deck
 
-import "github.com/google/uuid"
+import (
+	"errors"
+	"strings"
+	"time"
 
-// Deck represents a collection of cards owned by a user.
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned for unknown or deleted decks.
+	ErrNotFound = errors.New("deck: not found")
+	// ErrNotOwner is returned when a user acts on a deck of another user.
+	ErrNotOwner = errors.New("deck: not owner")
+	// ErrDeckDeleted is returned when changing a deleted deck.
+	ErrDeckDeleted = errors.New("deck: deck deleted")
+	// ErrCardNotInDeck is returned when removing a card the deck does not hold.
+	ErrCardNotInDeck = errors.New("deck: card not in deck")
+	// ErrVersionConflict is returned when saving the changes to a deck
+	// that was changed since it was loaded.
+	ErrVersionConflict = errors.New("deck: deck changed concurrently")
+)
+
+// Deck represents a collection of cards owned by a user. It is event
+// sourced: its state is the result of applying its events in order.
 type Deck struct {
-	ID      uuid.UUID
-	UserID  uuid.UUID
-	Name    string
-	CardIDs []uuid.UUID
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Name   string
+	Format string
+	// Zones holds the cards of the deck, main zone first. Zones never
+	// hold empty entries and empty zones are dropped.
+	Zones []Zone
+	// Visibility is one of the Visibility constants. Decks are private
+	// until their owner shares them.
+	Visibility string
+	CreatedAt  time.Time
+	// Deleted decks keep only their id, owner, creation time and version,
+	// so that the stores keeping them drop their name and cards.
+	Deleted bool
+	// Version is the number of events saved for the deck. Changes are
+	// saved against the version they were made on.
+	Version int
 }
 
-// NewDeck creates a new deck for a user.
+// NewDeck creates a new deck for a user with cardIDs in its main zone.
 func NewDeck(userID uuid.UUID, name string, cardIDs []uuid.UUID) *Deck {
 	return &Deck{
-		ID:      uuid.New(),
-		UserID:  userID,
-		Name:    name,
-		CardIDs: append([]uuid.UUID(nil), cardIDs...),
+		ID:         uuid.New(),
+		UserID:     userID,
+		Name:       name,
+		Zones:      sortZones([]Zone{ZoneOf(ZoneMain, cardIDs)}),
+		Visibility: VisibilityPrivate,
+		CreatedAt:  time.Now().UTC(),
+	}
+}
+
+// CheckOwner returns ErrNotOwner unless userID owns d.
+func (d *Deck) CheckOwner(userID uuid.UUID) error {
+	if d.UserID != userID {
+		return ErrNotOwner
+	}
+	return nil
+}
+
+// Zone returns the zone with the given name, which is empty when d has no
+// cards in it.
+func (d *Deck) Zone(name string) Zone {
+	return findZone(d.Zones, name)
+}
+
+// CardIDs returns the cards of the main zone with one element per copy.
+func (d *Deck) CardIDs() []uuid.UUID {
+	return d.Zone(ZoneMain).CardIDs()
+}
+
+// AllCardIDs returns the cards of every zone with one element per copy.
+func (d *Deck) AllCardIDs() []uuid.UUID {
+	var ids []uuid.UUID
+	for _, z := range d.Zones {
+		ids = append(ids, z.CardIDs()...)
+	}
+	return ids
+}
+
+// Clone returns a deep copy of d.
+func (d *Deck) Clone() *Deck {
+	c := *d
+	c.Zones = cloneZones(d.Zones)
+	return &c
+}
+
+// Created returns the event recording the creation of d.
+func (d *Deck) Created() DeckCreated {
+	return DeckCreated{ID: d.ID, UserID: d.UserID, Name: d.Name, Format: d.Format, Zones: cloneZones(d.Zones), CreatedAt: d.CreatedAt}
+}
+
+// Rename returns the event renaming d.
+func (d *Deck) Rename(name string) (DeckRenamed, error) {
+	if d.Deleted {
+		return DeckRenamed{}, ErrDeckDeleted
+	}
+	return DeckRenamed{ID: d.ID, UserID: d.UserID, Name: name}, nil
+}
+
+// AddCard returns the event adding a copy of a card to the main zone of d.
+func (d *Deck) AddCard(cardID uuid.UUID) (CardAddedToDeck, error) {
+	return d.AddCards(ZoneMain, cardID, 1)
+}
+
+// AddCards returns the event adding quantity copies of a card to a zone of
+// d.
+func (d *Deck) AddCards(zone string, cardID uuid.UUID, quantity int) (CardAddedToDeck, error) {
+	if d.Deleted {
+		return CardAddedToDeck{}, ErrDeckDeleted
+	}
+	if !zoneName.MatchString(zone) || quantity < 1 {
+		return CardAddedToDeck{}, ErrInvalidZone
+	}
+	return CardAddedToDeck{ID: d.ID, CardID: cardID, Zone: zone, Quantity: quantity}, nil
+}
+
+// RemoveCard returns the event removing a copy of a card from the main zone
+// of d.
+func (d *Deck) RemoveCard(cardID uuid.UUID) (CardRemovedFromDeck, error) {
+	return d.RemoveCards(ZoneMain, cardID, 1)
+}
+
+// RemoveCards returns the event removing quantity copies of a card from a
+// zone of d.
+func (d *Deck) RemoveCards(zone string, cardID uuid.UUID, quantity int) (CardRemovedFromDeck, error) {
+	if d.Deleted {
+		return CardRemovedFromDeck{}, ErrDeckDeleted
+	}
+	if !zoneName.MatchString(zone) || quantity < 1 {
+		return CardRemovedFromDeck{}, ErrInvalidZone
+	}
+	if d.Zone(zone).Quantity(cardID) < quantity {
+		return CardRemovedFromDeck{}, ErrCardNotInDeck
+	}
+	return CardRemovedFromDeck{ID: d.ID, CardID: cardID, Zone: zone, Quantity: quantity}, nil
+}
+
+// SetCards returns the events turning the main zone of d into cardIDs.
+func (d *Deck) SetCards(cardIDs []uuid.UUID) ([]interface{}, error) {
+	return d.SetZones([]Zone{ZoneOf(ZoneMain, cardIDs)})
+}
+
+// SetZones returns the events replacing the cards of the given zones. Zones
+// of d that are not given are left unchanged; a given zone without cards
+// removes all its cards.
+func (d *Deck) SetZones(zones []Zone) ([]interface{}, error) {
+	if d.Deleted {
+		return nil, ErrDeckDeleted
+	}
+	targets, err := NormalizeZones(zones)
+	if err != nil {
+		return nil, err
+	}
+	var events []interface{}
+	seen := make(map[string]bool)
+	for _, z := range zones {
+		name := strings.ToLower(strings.TrimSpace(z.Name))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		current, target := d.Zone(name), findZone(targets, name)
+		for _, e := range current.Cards {
+			if n := e.Quantity - target.Quantity(e.CardID); n > 0 {
+				events = append(events, CardRemovedFromDeck{ID: d.ID, CardID: e.CardID, Zone: name, Quantity: n})
+			}
+		}
+		for _, e := range target.Cards {
+			if n := e.Quantity - current.Quantity(e.CardID); n > 0 {
+				events = append(events, CardAddedToDeck{ID: d.ID, CardID: e.CardID, Zone: name, Quantity: n})
+			}
+		}
+	}
+	return events, nil
+}
+
+// Delete returns the event deleting d.
+func (d *Deck) Delete() (DeckDeleted, error) {
+	if d.Deleted {
+		return DeckDeleted{}, ErrDeckDeleted
+	}
+	return DeckDeleted{ID: d.ID}, nil
+}
+
+// Revise returns the event recording the state of d after the events
+// before it as its next revision.
+func (d *Deck) Revise() DeckRevised {
+	return DeckRevised{ID: d.ID, At: time.Now().UTC()}
+}
+
+// Apply folds an event into the deck state and counts it in its version.
+// Events of other types are ignored.
+func (d *Deck) Apply(evt interface{}) {
+	switch e := evt.(type) {
+	case DeckCreated:
+		d.ID = e.ID
+		d.UserID = e.UserID
+		d.Name = e.Name
+		d.Format = e.Format
+		d.Zones = cloneZones(e.Zones)
+		d.Visibility = VisibilityPrivate
+		d.CreatedAt = e.CreatedAt
+	case CardAddedToDeck:
+		d.updateZone(e.Zone, func(z *Zone) { z.add(e.CardID, e.Quantity) })
+	case CardRemovedFromDeck:
+		d.updateZone(e.Zone, func(z *Zone) { z.remove(e.CardID, e.Quantity) })
+	case DeckRenamed:
+		d.Name = e.Name
+	case DeckVisibilityChanged:
+		d.Visibility = e.Visibility
+	case DeckDeleted:
+		*d = Deck{ID: d.ID, UserID: d.UserID, CreatedAt: d.CreatedAt, Deleted: true, Version: d.Version}
+	case DeckRevised:
+		// the revision is kept by the history of the deck
+	default:
+		return
+	}
+	d.Version++
+}
+
+// updateZone changes the zone with the given name, creating it if needed.
+func (d *Deck) updateZone(name string, change func(z *Zone)) {
+	for i := range d.Zones {
+		if d.Zones[i].Name == name {
+			change(&d.Zones[i])
+			d.Zones = sortZones(d.Zones)
+			return
+		}
+	}
+	z := Zone{Name: name}
+	change(&z)
+	d.Zones = sortZones(append(d.Zones, z))
+}
+
+// Replay rebuilds a deck from its events. It returns nil when there are no
+// events.
+func Replay(events []interface{}) *Deck {
+	if len(events) == 0 {
+		return nil
+	}
+	d := &Deck{}
+	for _, e := range events {
+		d.Apply(e)
 	}
+	return d
 }
@@ -0,0 +1,158 @@
+package deck
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDeckEvents(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{a, a})
+	events := []interface{}{d.Created()}
+	replayed := Replay(events)
+
+	added, err := replayed.AddCard(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed.Apply(added)
+	removed, err := replayed.RemoveCard(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed.Apply(removed)
+	renamed, _ := replayed.Rename("e")
+	replayed.Apply(renamed)
+	events = append(events, added, removed, renamed)
+
+	got := Replay(events)
+	if got.Version != len(events) {
+		t.Fatalf("expected version %d got %d", len(events), got.Version)
+	}
+	if ids := got.CardIDs(); got.ID != d.ID || got.UserID != d.UserID || got.Name != "e" || len(ids) != 2 || ids[0] != a || ids[1] != b {
+		t.Fatalf("unexpected deck %+v", got)
+	}
+	if _, err := got.RemoveCard(uuid.New()); !errors.Is(err, ErrCardNotInDeck) {
+		t.Fatalf("expected ErrCardNotInDeck got %v", err)
+	}
+
+	deleted, _ := got.Delete()
+	got.Apply(deleted)
+	if !got.Deleted || got.Name != "" || got.Format != "" || len(got.Zones) != 0 || got.ID != d.ID || got.Version != len(events)+1 {
+		t.Fatalf("expected deck deleted and blanked got %+v", got)
+	}
+	if _, err := got.Rename("x"); !errors.Is(err, ErrDeckDeleted) {
+		t.Fatalf("expected ErrDeckDeleted got %v", err)
+	}
+	if Replay(nil) != nil {
+		t.Fatal("expected nil deck for empty stream")
+	}
+}
+
+func TestEventDeckID(t *testing.T) {
+	id := uuid.New()
+	if got, ok := EventDeckID(DeckRenamed{ID: id}); !ok || got != id {
+		t.Fatalf("unexpected %s %v", got, ok)
+	}
+	if _, ok := EventDeckID(struct{}{}); ok {
+		t.Fatal("expected unknown event")
+	}
+}
+
+func TestSetCards(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{a, a, b})
+	events, err := d.SetCards([]uuid.UUID{c, a, c})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events got %d", len(events))
+	}
+	for _, evt := range events {
+		d.Apply(evt)
+	}
+	counts := make(map[uuid.UUID]int)
+	for _, id := range d.CardIDs() {
+		counts[id]++
+	}
+	if counts[a] != 1 || counts[b] != 0 || counts[c] != 2 {
+		t.Fatalf("unexpected cards %v", d.CardIDs())
+	}
+	if events, _ := d.SetCards([]uuid.UUID{c, c, a}); len(events) != 0 {
+		t.Fatalf("expected no events for same cards got %v", events)
+	}
+}
+
+func TestDeckZones(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{a})
+	side, err := d.AddCards(ZoneSideboard, b, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Apply(side)
+	if d.Zone(ZoneSideboard).Quantity(b) != 3 || len(d.CardIDs()) != 1 || len(d.AllCardIDs()) != 4 {
+		t.Fatalf("unexpected zones %+v", d.Zones)
+	}
+	if _, err := d.RemoveCards(ZoneSideboard, b, 4); !errors.Is(err, ErrCardNotInDeck) {
+		t.Fatalf("expected ErrCardNotInDeck got %v", err)
+	}
+	if _, err := d.AddCards("Bad Zone", b, 1); !errors.Is(err, ErrInvalidZone) {
+		t.Fatalf("expected ErrInvalidZone got %v", err)
+	}
+
+	events, err := d.SetZones([]Zone{{Name: ZoneSideboard, Cards: []Entry{{CardID: b, Quantity: 1}, {CardID: a, Quantity: 2}}}})
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected 2 events got %v %v", events, err)
+	}
+	for _, evt := range events {
+		d.Apply(evt)
+	}
+	if z := d.Zone(ZoneSideboard); z.Quantity(a) != 2 || z.Quantity(b) != 1 || d.Zone(ZoneMain).Quantity(a) != 1 {
+		t.Fatalf("unexpected zones %+v", d.Zones)
+	}
+	if _, err := d.SetZones([]Zone{{Name: "Bad Zone"}}); !errors.Is(err, ErrInvalidZone) {
+		t.Fatalf("expected ErrInvalidZone got %v", err)
+	}
+	events, _ = d.SetZones([]Zone{{Name: "Sideboard"}})
+	for _, evt := range events {
+		d.Apply(evt)
+	}
+	if len(d.Zones) != 1 || d.Zones[0].Name != ZoneMain {
+		t.Fatalf("expected empty sideboard dropped got %+v", d.Zones)
+	}
+
+	// the main zone stays first when it is emptied and refilled
+	d.Apply(side)
+	removed, _ := d.RemoveCard(a)
+	d.Apply(removed)
+	added, _ := d.AddCard(a)
+	d.Apply(added)
+	if d.Zones[0].Name != ZoneMain {
+		t.Fatalf("expected main zone first got %+v", d.Zones)
+	}
+}
+
+func TestNormalizeZones(t *testing.T) {
+	a := uuid.New()
+	zones, err := NormalizeZones([]Zone{
+		{Name: "Sideboard", Cards: []Entry{{CardID: a, Quantity: 1}}},
+		{Name: "main", Cards: []Entry{{CardID: a, Quantity: 1}, {CardID: a, Quantity: 2}}},
+		{Name: "sideboard", Cards: []Entry{{CardID: a, Quantity: 1}}},
+		{Name: "empty", Cards: []Entry{{CardID: a, Quantity: 0}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 2 || zones[0].Name != ZoneMain || zones[0].Cards[0].Quantity != 3 || zones[1].Name != ZoneSideboard || zones[1].Cards[0].Quantity != 2 {
+		t.Fatalf("unexpected zones %+v", zones)
+	}
+	for _, bad := range []Zone{{Name: ""}, {Name: "a b"}, {Name: "x", Cards: []Entry{{CardID: a, Quantity: -1}}}} {
+		if _, err := NormalizeZones([]Zone{bad}); !errors.Is(err, ErrInvalidZone) {
+			t.Fatalf("%+v: expected ErrInvalidZone got %v", bad, err)
+		}
+	}
+}
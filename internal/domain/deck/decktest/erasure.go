@@ -0,0 +1,103 @@
+package decktest
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// Erasable is a deck store along with a dump of everything it keeps, to
+// check what survives the deletion of decks.
+type Erasable struct {
+	Store
+	// Dump returns the raw contents of the store, in any format keeping
+	// stored strings as they are.
+	Dump func(t *testing.T) string
+}
+
+// ErasureSuite is the conformance suite of the deck stores keeping decks
+// in plain text: deleted decks, and so forgotten users, must leave no name
+// behind.
+var ErasureSuite = conformance.Suite[Erasable]{
+	{Name: "DeleteErasesName", Fn: testDeleteErasesName},
+	{Name: "ForgetErasesNames", Fn: testForgetErasesNames},
+}
+
+// secretDeck saves a revised deck of a user in the tenant of ctx under a
+// name found nowhere else, renames it and returns it with both names.
+func secretDeck(t *testing.T, repo Erasable, ctx context.Context, userID uuid.UUID) (*deck.Deck, []string) {
+	t.Helper()
+	names := []string{"secret-" + uuid.NewString(), "renamed-" + uuid.NewString()}
+	d := deck.NewDeck(userID, names[0], []uuid.UUID{uuid.New()})
+	v := revise(t, repo, ctx, 0, d.Created())
+	revise(t, repo, ctx, v, deck.DeckRenamed{ID: d.ID, UserID: userID, Name: names[1]})
+	d, err := repo.Load(ctx, d.ID)
+	if err != nil || d == nil {
+		t.Fatalf("load: %+v %v", d, err)
+	}
+	return d, names
+}
+
+// expectDump checks which of names the store still keeps.
+func expectDump(t *testing.T, repo Erasable, kept bool, names ...string) {
+	t.Helper()
+	dump := repo.Dump(t)
+	for _, name := range names {
+		if strings.Contains(dump, name) != kept {
+			t.Fatalf("expected %q kept: %v", name, kept)
+		}
+	}
+}
+
+func testDeleteErasesName(t *testing.T, repo Erasable) {
+	ctx := tenant.NewContext(context.Background(), "acme")
+	d, names := secretDeck(t, repo, ctx, uuid.New())
+	expectDump(t, repo, true, names...)
+	if err := repo.Save(ctx, d.Version, []interface{}{deck.DeckDeleted{ID: d.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	expectDump(t, repo, false, names...)
+	if revs, err := repo.Revisions(ctx, d.ID); err != nil || len(revs) != 0 {
+		t.Fatalf("expected no revisions got %+v %v", revs, err)
+	}
+}
+
+// testForgetErasesNames deletes the decks of a user in every tenant listed
+// by Tenants, as forgetting the user does, and checks that none of their
+// names survive while the decks of other users stay.
+func testForgetErasesNames(t *testing.T, repo Erasable) {
+	userID := uuid.New()
+	var names []string
+	for _, id := range []string{"acme", "globex", tenant.Default} {
+		_, n := secretDeck(t, repo, tenant.NewContext(context.Background(), id), userID)
+		names = append(names, n...)
+	}
+	_, kept := secretDeck(t, repo, tenant.NewContext(context.Background(), "acme"), uuid.New())
+
+	tenants, err := repo.Tenants(context.Background(), userID)
+	if err != nil || len(tenants) != 3 {
+		t.Fatalf("expected 3 tenants got %v %v", tenants, err)
+	}
+	for _, id := range tenants {
+		ctx := tenant.NewContext(context.Background(), id)
+		decks, _, err := repo.ListByUser(ctx, userID, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range decks {
+			if err := repo.Save(ctx, d.Version, []interface{}{deck.DeckDeleted{ID: d.ID}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	expectDump(t, repo, false, names...)
+	expectDump(t, repo, true, kept...)
+	if tenants, err := repo.Tenants(context.Background(), userID); err != nil || len(tenants) != 0 {
+		t.Fatalf("expected no tenants left got %v %v", tenants, err)
+	}
+}
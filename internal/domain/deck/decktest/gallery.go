@@ -0,0 +1,241 @@
+package decktest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// GallerySuite is the conformance suite of deck.GalleryRepository
+// implementations.
+var GallerySuite = conformance.Suite[deck.GalleryRepository]{
+	{Name: "PutAndEntry", Fn: testPutAndEntry},
+	{Name: "SearchFilters", Fn: testSearchFilters},
+	{Name: "SearchPaging", Fn: testSearchPaging},
+	{Name: "Likes", Fn: testLikes},
+	{Name: "RemoveDropsLikes", Fn: testRemoveDropsLikes},
+	{Name: "Tenants", Fn: testGalleryTenants},
+}
+
+func galleryEntry(name, format string, age int, factions []string, cardIDs ...uuid.UUID) deck.GalleryEntry {
+	if factions == nil {
+		factions = []string{}
+	}
+	if cardIDs == nil {
+		cardIDs = []uuid.UUID{}
+	}
+	return deck.GalleryEntry{
+		DeckID:    uuid.New(),
+		UserID:    uuid.New(),
+		Name:      name,
+		Format:    format,
+		Factions:  factions,
+		CardIDs:   cardIDs,
+		CreatedAt: conformance.Base.Add(-time.Duration(age) * time.Minute),
+	}
+}
+
+func put(t *testing.T, repo deck.GalleryRepository, entries ...deck.GalleryEntry) {
+	t.Helper()
+	for _, e := range entries {
+		if err := repo.Put(context.Background(), e); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+}
+
+func search(t *testing.T, repo deck.GalleryRepository, q deck.GalleryQuery) ([]string, int) {
+	t.Helper()
+	entries, total, err := repo.Search(context.Background(), q)
+	if err != nil {
+		t.Fatalf("search %+v: %v", q, err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	return names, total
+}
+
+func sameNames(got []string, want ...string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func like(t *testing.T, repo deck.GalleryRepository, deckID, userID uuid.UUID) *deck.GalleryEntry {
+	t.Helper()
+	e, err := repo.Like(context.Background(), deckID, userID)
+	if err != nil {
+		t.Fatalf("like: %v", err)
+	}
+	return e
+}
+
+func testPutAndEntry(t *testing.T, repo deck.GalleryRepository) {
+	ctx := context.Background()
+	a, b := uuid.New(), uuid.New()
+	e := galleryEntry("d", "std", 0, []string{"blue", "red"}, a, b)
+	put(t, repo, e)
+	got, err := repo.Entry(ctx, e.DeckID)
+	if err != nil || got == nil {
+		t.Fatalf("expected entry got %+v %v", got, err)
+	}
+	if got.UserID != e.UserID || got.Name != "d" || got.Format != "std" || !got.CreatedAt.Equal(e.CreatedAt) ||
+		len(got.Factions) != 2 || got.Factions[0] != "blue" || len(got.CardIDs) != 2 || got.CardIDs[0] != a || got.Likes != 0 {
+		t.Fatalf("unexpected entry %+v", got)
+	}
+
+	like(t, repo, e.DeckID, uuid.New())
+	e.Name, e.Factions, e.CardIDs = "e", []string{"red"}, []uuid.UUID{b}
+	put(t, repo, e)
+	got, err = repo.Entry(ctx, e.DeckID)
+	if err != nil || got.Name != "e" || len(got.Factions) != 1 || len(got.CardIDs) != 1 || got.CardIDs[0] != b || got.Likes != 1 {
+		t.Fatalf("expected the entry to be replaced keeping its likes got %+v %v", got, err)
+	}
+	if got, err := repo.Entry(ctx, uuid.New()); err != nil || got != nil {
+		t.Fatalf("expected nil, nil got %+v %v", got, err)
+	}
+}
+
+func testSearchFilters(t *testing.T, repo deck.GalleryRepository) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	put(t, repo,
+		galleryEntry("red", "std", 1, []string{"red"}, a, b),
+		galleryEntry("blue", "std", 2, []string{"blue"}, b),
+		galleryEntry("mixed", "singleton", 3, []string{"blue", "red"}, a, c),
+	)
+	tests := []struct {
+		q    deck.GalleryQuery
+		want []string
+	}{
+		{deck.GalleryQuery{}, []string{"red", "blue", "mixed"}},
+		{deck.GalleryQuery{Format: "std"}, []string{"red", "blue"}},
+		{deck.GalleryQuery{Faction: "blue"}, []string{"blue", "mixed"}},
+		{deck.GalleryQuery{CardIDs: []uuid.UUID{a}}, []string{"red", "mixed"}},
+		{deck.GalleryQuery{CardIDs: []uuid.UUID{a, b, a}}, []string{"red"}},
+		{deck.GalleryQuery{Format: "std", Faction: "red", CardIDs: []uuid.UUID{c}}, nil},
+	}
+	for _, tt := range tests {
+		if got, total := search(t, repo, tt.q); !sameNames(got, tt.want...) || total != len(tt.want) {
+			t.Fatalf("%+v: expected %v got %v (%d)", tt.q, tt.want, got, total)
+		}
+	}
+}
+
+func testSearchPaging(t *testing.T, repo deck.GalleryRepository) {
+	old, mid, recent := galleryEntry("old", "std", 3, nil), galleryEntry("mid", "std", 2, nil), galleryEntry("recent", "std", 1, nil)
+	put(t, repo, old, mid, recent)
+	like(t, repo, old.DeckID, uuid.New())
+	like(t, repo, old.DeckID, uuid.New())
+	like(t, repo, mid.DeckID, uuid.New())
+
+	if got, total := search(t, repo, deck.GalleryQuery{Sort: deck.SortPopular}); !sameNames(got, "old", "mid", "recent") || total != 3 {
+		t.Fatalf("unexpected popular listing %v (%d)", got, total)
+	}
+	if got, total := search(t, repo, deck.GalleryQuery{Sort: deck.SortNewest, Offset: 1, Limit: 1}); !sameNames(got, "mid") || total != 3 {
+		t.Fatalf("unexpected page %v (%d)", got, total)
+	}
+	if got, total := search(t, repo, deck.GalleryQuery{Offset: 5}); len(got) != 0 || total != 3 {
+		t.Fatalf("expected an empty page got %v (%d)", got, total)
+	}
+}
+
+func testLikes(t *testing.T, repo deck.GalleryRepository) {
+	ctx := context.Background()
+	e, other := galleryEntry("d", "std", 1, nil), galleryEntry("other", "std", 2, nil)
+	put(t, repo, e, other)
+	alice, bob := uuid.New(), uuid.New()
+	like(t, repo, e.DeckID, alice)
+	if got := like(t, repo, e.DeckID, alice); got == nil || got.Likes != 1 {
+		t.Fatalf("expected liking twice to count once got %+v", got)
+	}
+	if got := like(t, repo, e.DeckID, bob); got.Likes != 2 {
+		t.Fatalf("expected 2 likes got %d", got.Likes)
+	}
+	like(t, repo, other.DeckID, bob)
+	if got, total := search(t, repo, deck.GalleryQuery{LikedBy: bob}); !sameNames(got, "d", "other") || total != 2 {
+		t.Fatalf("unexpected likes of bob %v (%d)", got, total)
+	}
+	got, err := repo.Unlike(ctx, e.DeckID, bob)
+	if err != nil || got.Likes != 1 {
+		t.Fatalf("expected 1 like got %+v %v", got, err)
+	}
+	if got, err := repo.Unlike(ctx, e.DeckID, bob); err != nil || got.Likes != 1 {
+		t.Fatalf("expected unliking twice to count once got %+v %v", got, err)
+	}
+	if got, _ := search(t, repo, deck.GalleryQuery{LikedBy: bob}); !sameNames(got, "other") {
+		t.Fatalf("unexpected likes of bob %v", got)
+	}
+	if got := like(t, repo, uuid.New(), alice); got != nil {
+		t.Fatalf("expected nil for a deck not listed got %+v", got)
+	}
+}
+
+func testRemoveDropsLikes(t *testing.T, repo deck.GalleryRepository) {
+	ctx := context.Background()
+	e := galleryEntry("d", "std", 1, []string{"red"}, uuid.New())
+	put(t, repo, e)
+	user := uuid.New()
+	like(t, repo, e.DeckID, user)
+	if err := repo.Remove(ctx, e.DeckID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.Entry(ctx, e.DeckID); err != nil || got != nil {
+		t.Fatalf("expected the entry to be removed got %+v %v", got, err)
+	}
+	if got, total := search(t, repo, deck.GalleryQuery{Faction: "red"}); len(got) != 0 || total != 0 {
+		t.Fatalf("expected no matches got %v (%d)", got, total)
+	}
+	put(t, repo, e)
+	if got, err := repo.Entry(ctx, e.DeckID); err != nil || got.Likes != 0 {
+		t.Fatalf("expected no likes got %+v %v", got, err)
+	}
+	if got, _ := search(t, repo, deck.GalleryQuery{LikedBy: user}); len(got) != 0 {
+		t.Fatalf("expected no likes of the user got %v", got)
+	}
+}
+
+// testGalleryTenants checks that every tenant has a gallery of its own.
+func testGalleryTenants(t *testing.T, repo deck.GalleryRepository) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	a := galleryEntry("a", "standard", 0, nil)
+	d := galleryEntry("d", "standard", 1, nil)
+	if err := repo.Put(acme, a); err != nil {
+		t.Fatal(err)
+	}
+	put(t, repo, d)
+
+	if names, total := search(t, repo, deck.GalleryQuery{}); total != 1 || !sameNames(names, "d") {
+		t.Fatalf("expected only the default gallery got %v %d", names, total)
+	}
+	entries, total, err := repo.Search(acme, deck.GalleryQuery{})
+	if err != nil || total != 1 || len(entries) != 1 || entries[0].DeckID != a.DeckID {
+		t.Fatalf("expected only the acme gallery got %+v %d %v", entries, total, err)
+	}
+	for _, ctx := range []context.Context{context.Background(), tenant.NewContext(context.Background(), "globex")} {
+		if e, err := repo.Entry(ctx, a.DeckID); err != nil || e != nil {
+			t.Fatalf("expected other tenants not to find the entry got %+v %v", e, err)
+		}
+		if e, err := repo.Like(ctx, a.DeckID, uuid.New()); err != nil || e != nil {
+			t.Fatalf("expected other tenants not to like the entry got %+v %v", e, err)
+		}
+		if err := repo.Remove(ctx, a.DeckID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if e, err := repo.Entry(acme, a.DeckID); err != nil || e == nil || e.Likes != 0 {
+		t.Fatalf("expected the entry to be left alone by other tenants got %+v %v", e, err)
+	}
+}
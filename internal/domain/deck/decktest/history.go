@@ -0,0 +1,176 @@
+package decktest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// Store is a deck repository keeping the history of its decks.
+type Store interface {
+	deck.Repository
+	deck.HistoryRepository
+}
+
+// HistorySuite is the conformance suite of the history kept by deck
+// repositories.
+var HistorySuite = conformance.Suite[Store]{
+	{Name: "SaveAndList", Fn: testSaveAndList},
+	{Name: "RevisionNotFound", Fn: testRevisionNotFound},
+	{Name: "SeparateDecks", Fn: testSeparateDecks},
+	{Name: "Unrevised", Fn: testUnrevised},
+	{Name: "ConflictRecordsNothing", Fn: testConflictRecordsNothing},
+	{Name: "ConcurrentRevisions", Fn: testConcurrentRevisions},
+	{Name: "Tenants", Fn: testHistoryTenants},
+}
+
+// revise saves events of a deck at version expected followed by a
+// DeckRevised event and returns the new version.
+func revise(t *testing.T, repo Store, ctx context.Context, expected int, events ...interface{}) int {
+	t.Helper()
+	id, _ := deck.EventDeckID(events[0])
+	events = append(events, deck.DeckRevised{ID: id, At: conformance.Base})
+	if err := repo.Save(ctx, expected, events); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	return expected + len(events)
+}
+
+func testSaveAndList(t *testing.T, repo Store) {
+	ctx := context.Background()
+	a, b := uuid.New(), uuid.New()
+	d := deck.NewDeck(uuid.New(), "d", []uuid.UUID{a, a})
+	d.Format = "std"
+	v := revise(t, repo, ctx, 0, d.Created())
+	revise(t, repo, ctx, v,
+		deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"},
+		deck.CardAddedToDeck{ID: d.ID, CardID: b, Zone: deck.ZoneSideboard, Quantity: 1},
+	)
+
+	revs, err := repo.Revisions(ctx, d.ID)
+	if err != nil || len(revs) != 2 {
+		t.Fatalf("expected 2 revisions got %+v %v", revs, err)
+	}
+	if revs[0].Version != 1 || revs[0].DeckID != d.ID || revs[0].Name != "d" || revs[0].Format != "std" || len(revs[0].Zones) != 1 || revs[0].Zones[0].Quantity(a) != 2 {
+		t.Fatalf("unexpected first revision %+v", revs[0])
+	}
+	if revs[1].Version != 2 || revs[1].Name != "e" || len(revs[1].Zones) != 2 || revs[1].Zones[1].Quantity(b) != 1 || !revs[1].CreatedAt.Equal(conformance.Base) {
+		t.Fatalf("unexpected second revision %+v", revs[1])
+	}
+	// changes to returned revisions must not leak into stored ones
+	revs[0].Zones[0].Cards[0].Quantity = 9
+	rev, err := repo.Revision(ctx, d.ID, 1)
+	if err != nil || rev == nil || rev.Version != 1 || rev.Zones[0].Quantity(a) != 2 {
+		t.Fatalf("unexpected revision %+v %v", rev, err)
+	}
+	rev, err = repo.Revision(ctx, d.ID, 2)
+	if err != nil || rev == nil || rev.Version != 2 || rev.Name != "e" || rev.Zones[0].Quantity(a) != 2 {
+		t.Fatalf("unexpected revision %+v %v", rev, err)
+	}
+}
+
+func testRevisionNotFound(t *testing.T, repo Store) {
+	ctx := context.Background()
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	if revs, err := repo.Revisions(ctx, d.ID); err != nil || len(revs) != 0 {
+		t.Fatalf("expected no revisions got %+v %v", revs, err)
+	}
+	revise(t, repo, ctx, 0, d.Created())
+	for _, v := range []int{0, 2, -1} {
+		if rev, err := repo.Revision(ctx, d.ID, v); err != nil || rev != nil {
+			t.Fatalf("version %d: expected nil, nil got %+v %v", v, rev, err)
+		}
+	}
+	if rev, err := repo.Revision(ctx, uuid.New(), 1); err != nil || rev != nil {
+		t.Fatalf("expected nil, nil got %+v %v", rev, err)
+	}
+}
+
+func testSeparateDecks(t *testing.T, repo Store) {
+	ctx := context.Background()
+	a := deck.NewDeck(uuid.New(), "a", nil)
+	b := deck.NewDeck(uuid.New(), "b", nil)
+	v := revise(t, repo, ctx, 0, a.Created())
+	revise(t, repo, ctx, v, deck.DeckRenamed{ID: a.ID, UserID: a.UserID, Name: "a2"})
+	revise(t, repo, ctx, 0, b.Created())
+	if revs, err := repo.Revisions(ctx, b.ID); err != nil || len(revs) != 1 || revs[0].Version != 1 || revs[0].Name != "b" {
+		t.Fatalf("unexpected revisions %+v %v", revs, err)
+	}
+}
+
+// testUnrevised checks that events saved without a DeckRevised event record
+// no revision.
+func testUnrevised(t *testing.T, repo Store) {
+	ctx := context.Background()
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	v := revise(t, repo, ctx, 0, d.Created())
+	if err := repo.Save(ctx, v, []interface{}{deck.DeckVisibilityChanged{ID: d.ID, Visibility: deck.VisibilityPublic}}); err != nil {
+		t.Fatal(err)
+	}
+	if revs, err := repo.Revisions(ctx, d.ID); err != nil || len(revs) != 1 {
+		t.Fatalf("expected a single revision got %+v %v", revs, err)
+	}
+}
+
+// testConflictRecordsNothing checks that a change rejected for its version
+// leaves no revision behind.
+func testConflictRecordsNothing(t *testing.T, repo Store) {
+	ctx := context.Background()
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	revise(t, repo, ctx, 0, d.Created())
+	err := repo.Save(ctx, 0, []interface{}{deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"}, d.Revise()})
+	if !errors.Is(err, deck.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict got %v", err)
+	}
+	if revs, err := repo.Revisions(ctx, d.ID); err != nil || len(revs) != 1 || revs[0].Name != "d" {
+		t.Fatalf("expected only the first revision got %+v %v", revs, err)
+	}
+}
+
+// testConcurrentRevisions checks that of the changes made concurrently to
+// the same version of a deck only the saved one gets a revision.
+func testConcurrentRevisions(t *testing.T, repo Store) {
+	ctx := context.Background()
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	v := revise(t, repo, ctx, 0, d.Created())
+	errs := conformance.Concurrently(5, func(int) error {
+		return repo.Save(ctx, v, []interface{}{deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"}, d.Revise()})
+	})
+	saved := 0
+	for _, err := range errs {
+		if err == nil {
+			saved++
+		} else if !errors.Is(err, deck.ErrVersionConflict) {
+			t.Fatalf("expected ErrVersionConflict got %v", err)
+		}
+	}
+	revs, err := repo.Revisions(ctx, d.ID)
+	if err != nil || saved != 1 || len(revs) != 2 || revs[1].Version != 2 || revs[1].Name != "e" {
+		t.Fatalf("expected one saved change and 2 revisions got %d %+v %v", saved, revs, err)
+	}
+}
+
+// testHistoryTenants checks that the revisions of a deck are invisible to
+// the other tenants.
+func testHistoryTenants(t *testing.T, repo Store) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	v := revise(t, repo, acme, 0, d.Created())
+	revise(t, repo, acme, v, deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"})
+	if revs, err := repo.Revisions(acme, d.ID); err != nil || len(revs) != 2 {
+		t.Fatalf("expected 2 revisions in acme got %d %v", len(revs), err)
+	}
+	for _, ctx := range []context.Context{tenant.NewContext(context.Background(), "globex"), context.Background()} {
+		if revs, err := repo.Revisions(ctx, d.ID); err != nil || len(revs) != 0 {
+			t.Fatalf("%s: expected no revisions got %+v %v", tenant.FromContext(ctx), revs, err)
+		}
+		if rev, err := repo.Revision(ctx, d.ID, 1); err != nil || rev != nil {
+			t.Fatalf("%s: expected nil, nil got %+v %v", tenant.FromContext(ctx), rev, err)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package decktest
+
+import (
+	"context"
+	"testing"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// CardNumberSuite is the conformance suite of deck.CardNumberRepository
+// implementations.
+var CardNumberSuite = conformance.Suite[deck.CardNumberRepository]{
+	{Name: "StableNumbers", Fn: testStableNumbers},
+	{Name: "CardIDs", Fn: testCardIDs},
+	{Name: "ConcurrentNumbers", Fn: testConcurrentNumbers},
+	{Name: "Tenants", Fn: testCardNumberTenants},
+}
+
+func numbers(t *testing.T, ctx context.Context, repo deck.CardNumberRepository, ids ...uuid.UUID) map[uuid.UUID]uint64 {
+	t.Helper()
+	numbers, err := repo.Numbers(ctx, ids)
+	if err != nil {
+		t.Fatalf("numbers: %v", err)
+	}
+	if len(numbers) != len(uniqueIDs(ids)) {
+		t.Fatalf("expected a number per card got %v", numbers)
+	}
+	return numbers
+}
+
+func uniqueIDs(ids []uuid.UUID) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func testStableNumbers(t *testing.T, repo deck.CardNumberRepository) {
+	ctx := context.Background()
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	first := numbers(t, ctx, repo, a, b, a)
+	if first[a] == 0 || first[b] == 0 || first[a] == first[b] {
+		t.Fatalf("expected distinct numbers got %v", first)
+	}
+	again := numbers(t, ctx, repo, c, b, a)
+	if again[a] != first[a] || again[b] != first[b] || again[c] == 0 || again[c] == first[a] || again[c] == first[b] {
+		t.Fatalf("expected numbers kept got %v then %v", first, again)
+	}
+	if empty, err := repo.Numbers(ctx, nil); err != nil || len(empty) != 0 {
+		t.Fatalf("expected no numbers got %v %v", empty, err)
+	}
+}
+
+func testCardIDs(t *testing.T, repo deck.CardNumberRepository) {
+	ctx := context.Background()
+	a, b := uuid.New(), uuid.New()
+	nums := numbers(t, ctx, repo, a, b)
+	unknown := nums[a] + nums[b] + 1000
+	ids, err := repo.CardIDs(ctx, []uint64{nums[b], unknown, nums[a]})
+	if err != nil || len(ids) != 2 || ids[nums[a]] != a || ids[nums[b]] != b {
+		t.Fatalf("unexpected cards %v %v", ids, err)
+	}
+	if empty, err := repo.CardIDs(ctx, nil); err != nil || len(empty) != 0 {
+		t.Fatalf("expected no cards got %v %v", empty, err)
+	}
+}
+
+func testConcurrentNumbers(t *testing.T, repo deck.CardNumberRepository) {
+	ctx := context.Background()
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	results := make([]map[uuid.UUID]uint64, 8)
+	errs := conformance.Concurrently(len(results), func(i int) (err error) {
+		results[i], err = repo.Numbers(ctx, ids)
+		return err
+	})
+	for i, res := range results {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		for _, id := range ids {
+			if res[id] == 0 || res[id] != results[0][id] {
+				t.Fatalf("expected every caller to get the same numbers got %v and %v", results[0], res)
+			}
+		}
+	}
+}
+
+func testCardNumberTenants(t *testing.T, repo deck.CardNumberRepository) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+	id := uuid.New()
+	a := numbers(t, acme, repo, id)[id]
+	g := numbers(t, globex, repo, id)[id]
+	if a == g {
+		t.Fatalf("expected numbers unique across tenants got %d twice", a)
+	}
+	if ids, err := repo.CardIDs(globex, []uint64{a}); err != nil || len(ids) != 0 {
+		t.Fatalf("expected the number of acme unknown in globex got %v %v", ids, err)
+	}
+	if ids, err := repo.CardIDs(acme, []uint64{a}); err != nil || ids[a] != id {
+		t.Fatalf("unexpected cards %v %v", ids, err)
+	}
+}
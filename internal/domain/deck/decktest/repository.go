@@ -0,0 +1,327 @@
+// Package decktest provides a conformance suite for deck.Repository
+// implementations.
+package decktest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+// RepositorySuite is the conformance suite of deck.Repository implementations.
+var RepositorySuite = conformance.Suite[deck.Repository]{
+	{Name: "SaveLoad", Fn: testSaveLoad},
+	{Name: "LoadNotFound", Fn: testLoadNotFound},
+	{Name: "EventOrdering", Fn: testEventOrdering},
+	{Name: "Zones", Fn: testZones},
+	{Name: "Visibility", Fn: testVisibility},
+	{Name: "Delete", Fn: testDelete},
+	{Name: "UnknownEvent", Fn: testUnknownEvent},
+	{Name: "MixedBatch", Fn: testMixedBatch},
+	{Name: "VersionConflict", Fn: testVersionConflict},
+	{Name: "ConcurrentSaves", Fn: testConcurrentSaves},
+	{Name: "ListByUser", Fn: testListByUser},
+	{Name: "Tenants", Fn: testTenants},
+	{Name: "UserTenants", Fn: testUserTenants},
+}
+
+// save saves events of a deck at version expected.
+func save(t *testing.T, repo deck.Repository, expected int, events ...interface{}) {
+	t.Helper()
+	if err := repo.Save(context.Background(), expected, events); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+}
+
+func load(t *testing.T, repo deck.Repository, id uuid.UUID) *deck.Deck {
+	t.Helper()
+	d, err := repo.Load(context.Background(), id)
+	if err != nil {
+		t.Fatalf("load %s: %v", id, err)
+	}
+	if d == nil {
+		t.Fatalf("deck %s not found", id)
+	}
+	return d
+}
+
+func sameCards(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testSaveLoad(t *testing.T, repo deck.Repository) {
+	d := deck.NewDeck(uuid.New(), "d", []uuid.UUID{uuid.New(), uuid.New()})
+	save(t, repo, 0, d.Created())
+	got := load(t, repo, d.ID)
+	if got.ID != d.ID || got.UserID != d.UserID || got.Name != d.Name || !sameCards(got.CardIDs(), d.CardIDs()) || got.Version != 1 {
+		t.Fatalf("expected %+v got %+v", d, got)
+	}
+	// the returned deck must not alias stored state
+	got.Zones[0].Cards[0].CardID = uuid.Nil
+	if again := load(t, repo, d.ID); !sameCards(again.CardIDs(), d.CardIDs()) {
+		t.Fatalf("stored deck modified through loaded copy: %+v", again)
+	}
+}
+
+func testLoadNotFound(t *testing.T, repo deck.Repository) {
+	d, err := repo.Load(context.Background(), uuid.New())
+	if err != nil || d != nil {
+		t.Fatalf("expected nil, nil got %+v %v", d, err)
+	}
+}
+
+func testEventOrdering(t *testing.T, repo deck.Repository) {
+	a, b := uuid.New(), uuid.New()
+	d := deck.NewDeck(uuid.New(), "d", []uuid.UUID{a})
+	save(t, repo, 0, d.Created())
+	save(t, repo, 1, deck.CardAddedToDeck{ID: d.ID, CardID: b, Zone: deck.ZoneMain, Quantity: 1}, deck.CardAddedToDeck{ID: d.ID, CardID: a, Zone: deck.ZoneMain, Quantity: 1})
+	save(t, repo, 3, deck.CardRemovedFromDeck{ID: d.ID, CardID: a, Zone: deck.ZoneMain, Quantity: 1})
+	save(t, repo, 4, deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"})
+	got := load(t, repo, d.ID)
+	if got.Name != "e" || !sameCards(got.CardIDs(), []uuid.UUID{a, b}) || got.Version != 5 {
+		t.Fatalf("unexpected deck %+v", got)
+	}
+}
+
+func testZones(t *testing.T, repo deck.Repository) {
+	a, b := uuid.New(), uuid.New()
+	d := deck.NewDeck(uuid.New(), "d", []uuid.UUID{a, a})
+	d.Zones = append(d.Zones, deck.Zone{Name: deck.ZoneSideboard, Cards: []deck.Entry{{CardID: b, Quantity: 2}}})
+	save(t, repo, 0, d.Created())
+	save(t, repo, 1,
+		deck.CardAddedToDeck{ID: d.ID, CardID: b, Zone: deck.ZoneCommander, Quantity: 1},
+		deck.CardRemovedFromDeck{ID: d.ID, CardID: b, Zone: deck.ZoneSideboard, Quantity: 2},
+		deck.CardAddedToDeck{ID: d.ID, CardID: a, Zone: deck.ZoneMain, Quantity: 2},
+	)
+	got := load(t, repo, d.ID)
+	if len(got.Zones) != 2 || got.Zones[0].Name != deck.ZoneMain || got.Zone(deck.ZoneMain).Quantity(a) != 4 ||
+		got.Zone(deck.ZoneCommander).Quantity(b) != 1 || got.Zone(deck.ZoneSideboard).Size() != 0 {
+		t.Fatalf("unexpected zones %+v", got.Zones)
+	}
+}
+
+func testVisibility(t *testing.T, repo deck.Repository) {
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	save(t, repo, 0, d.Created())
+	if got := load(t, repo, d.ID); got.Visibility != deck.VisibilityPrivate {
+		t.Fatalf("expected a private deck got %q", got.Visibility)
+	}
+	save(t, repo, 1, deck.DeckVisibilityChanged{ID: d.ID, Visibility: deck.VisibilityPublic})
+	if got := load(t, repo, d.ID); got.Visibility != deck.VisibilityPublic {
+		t.Fatalf("expected a public deck got %q", got.Visibility)
+	}
+}
+
+func testDelete(t *testing.T, repo deck.Repository) {
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	save(t, repo, 0, d.Created())
+	save(t, repo, 1, deck.DeckDeleted{ID: d.ID})
+	if got, err := repo.Load(context.Background(), d.ID); err != nil || got != nil {
+		t.Fatalf("expected deleted deck to be gone got %+v %v", got, err)
+	}
+}
+
+func testUnknownEvent(t *testing.T, repo deck.Repository) {
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	if err := repo.Save(context.Background(), 0, []interface{}{d.Created(), struct{}{}}); err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+	if got, err := repo.Load(context.Background(), d.ID); err != nil || got != nil {
+		t.Fatalf("expected nothing stored got %+v %v", got, err)
+	}
+}
+
+func testMixedBatch(t *testing.T, repo deck.Repository) {
+	a := deck.NewDeck(uuid.New(), "a", nil)
+	b := deck.NewDeck(uuid.New(), "b", nil)
+	if err := repo.Save(context.Background(), 0, []interface{}{a.Created(), b.Created()}); err == nil {
+		t.Fatal("expected error for events of several decks")
+	}
+	for _, d := range []*deck.Deck{a, b} {
+		if got, err := repo.Load(context.Background(), d.ID); err != nil || got != nil {
+			t.Fatalf("expected nothing stored got %+v %v", got, err)
+		}
+	}
+}
+
+// testVersionConflict checks that events saved against another version than
+// the stored one are rejected and leave the deck unchanged.
+func testVersionConflict(t *testing.T, repo deck.Repository) {
+	ctx := context.Background()
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	save(t, repo, 0, d.Created())
+	if err := repo.Save(ctx, 0, []interface{}{d.Created()}); !errors.Is(err, deck.ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict creating the deck again got %v", err)
+	}
+	for _, v := range []int{0, 2} {
+		err := repo.Save(ctx, v, []interface{}{deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"}})
+		if !errors.Is(err, deck.ErrVersionConflict) {
+			t.Fatalf("version %d: expected ErrVersionConflict got %v", v, err)
+		}
+	}
+	if got := load(t, repo, d.ID); got.Name != "d" || got.Version != 1 {
+		t.Fatalf("expected the deck unchanged got %+v", got)
+	}
+	save(t, repo, 1, deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"})
+	if got := load(t, repo, d.ID); got.Name != "e" || got.Version != 2 {
+		t.Fatalf("unexpected deck %+v", got)
+	}
+}
+
+// testConcurrentSaves checks that of the changes made concurrently to the
+// same version of a deck only one is saved.
+func testConcurrentSaves(t *testing.T, repo deck.Repository) {
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	save(t, repo, 0, d.Created())
+	errs := conformance.Concurrently(5, func(i int) error {
+		return repo.Save(context.Background(), 1, []interface{}{deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: fmt.Sprint(i)}})
+	})
+	saved := -1
+	for i, err := range errs {
+		switch {
+		case err == nil && saved < 0:
+			saved = i
+		case err == nil:
+			t.Fatalf("expected a single save got %d and %d", saved, i)
+		case !errors.Is(err, deck.ErrVersionConflict):
+			t.Fatalf("expected ErrVersionConflict got %v", err)
+		}
+	}
+	if saved < 0 {
+		t.Fatal("expected one save to succeed")
+	}
+	if got := load(t, repo, d.ID); got.Name != fmt.Sprint(saved) || got.Version != 2 {
+		t.Fatalf("expected the saved change got %+v", got)
+	}
+}
+
+func testListByUser(t *testing.T, repo deck.Repository) {
+	user := uuid.New()
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		d := deck.NewDeck(user, "d", []uuid.UUID{uuid.New()})
+		d.CreatedAt = time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)
+		save(t, repo, 0, d.Created())
+		ids = append(ids, d.ID)
+	}
+	deleted := deck.NewDeck(user, "gone", nil)
+	save(t, repo, 0, deleted.Created(), deck.DeckDeleted{ID: deleted.ID})
+	save(t, repo, 0, deck.NewDeck(uuid.New(), "other", nil).Created())
+
+	decks, total, err := repo.ListByUser(context.Background(), user, 0, 0)
+	if err != nil || total != 3 || len(decks) != 3 {
+		t.Fatalf("expected 3 decks got %d/%d %v", len(decks), total, err)
+	}
+	// newest first
+	for i, d := range decks {
+		if d.ID != ids[2-i] || d.UserID != user || len(d.CardIDs()) != 1 {
+			t.Fatalf("unexpected deck %d: %+v", i, d)
+		}
+	}
+	decks, total, err = repo.ListByUser(context.Background(), user, 1, 1)
+	if err != nil || total != 3 || len(decks) != 1 || decks[0].ID != ids[1] {
+		t.Fatalf("unexpected page %v %d %v", decks, total, err)
+	}
+	decks, total, err = repo.ListByUser(context.Background(), user, 5, 10)
+	if err != nil || total != 3 || len(decks) != 0 {
+		t.Fatalf("unexpected page %v %d %v", decks, total, err)
+	}
+	decks, total, err = repo.ListByUser(context.Background(), uuid.New(), 0, 10)
+	if err != nil || total != 0 || len(decks) != 0 {
+		t.Fatalf("expected no decks got %v %d %v", decks, total, err)
+	}
+}
+
+// testTenants checks that the decks of a tenant are invisible to the others,
+// even those of the same user.
+func testTenants(t *testing.T, repo deck.Repository) {
+	acme := tenant.NewContext(context.Background(), "acme")
+	globex := tenant.NewContext(context.Background(), "globex")
+	userID := uuid.New()
+	a := deck.NewDeck(userID, "a", []uuid.UUID{uuid.New()})
+	b := deck.NewDeck(userID, "b", []uuid.UUID{uuid.New()})
+	if err := repo.Save(acme, 0, []interface{}{a.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Save(globex, 0, []interface{}{b.Created()}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tt := range map[string]struct {
+		ctx     context.Context
+		visible *deck.Deck
+	}{
+		"acme":   {acme, a},
+		"globex": {globex, b},
+	} {
+		for _, d := range []*deck.Deck{a, b} {
+			got, err := repo.Load(tt.ctx, d.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got != nil) != (d == tt.visible) {
+				t.Fatalf("%s: unexpected deck %s: %+v", name, d.ID, got)
+			}
+		}
+		decks, total, err := repo.ListByUser(tt.ctx, userID, 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 1 || len(decks) != 1 || decks[0].ID != tt.visible.ID {
+			t.Fatalf("%s: expected only %s got %d %+v", name, tt.visible.ID, total, decks)
+		}
+	}
+	if d, _ := repo.Load(context.Background(), a.ID); d != nil {
+		t.Fatalf("expected the default tenant not to see the deck got %+v", d)
+	}
+	if decks, total, _ := repo.ListByUser(context.Background(), userID, 0, 0); total != 0 || len(decks) != 0 {
+		t.Fatalf("expected the default tenant to list no decks got %d %+v", total, decks)
+	}
+}
+
+// testUserTenants checks that Tenants lists the tenants in which a user has
+// decks left, from any tenant.
+func testUserTenants(t *testing.T, repo deck.Repository) {
+	userID := uuid.New()
+	for _, id := range []string{"acme", "globex", tenant.Default} {
+		ctx := tenant.NewContext(context.Background(), id)
+		d := deck.NewDeck(userID, "d", nil)
+		if err := repo.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+			t.Fatal(err)
+		}
+		if id == "globex" {
+			if err := repo.Save(ctx, 1, []interface{}{deck.DeckDeleted{ID: d.ID}}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := repo.Save(tenant.NewContext(context.Background(), "initech"), 0, []interface{}{deck.NewDeck(uuid.New(), "d", nil).Created()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, ctx := range []context.Context{context.Background(), tenant.NewContext(context.Background(), "initech")} {
+		tenants, err := repo.Tenants(ctx, userID)
+		sort.Strings(tenants)
+		if err != nil || len(tenants) != 2 || tenants[0] != "acme" || tenants[1] != tenant.Default {
+			t.Fatalf("expected acme and %s got %v %v", tenant.Default, tenants, err)
+		}
+	}
+	if tenants, err := repo.Tenants(context.Background(), uuid.New()); err != nil || len(tenants) != 0 {
+		t.Fatalf("expected no tenants got %v %v", tenants, err)
+	}
+}
@@ -0,0 +1,84 @@
+package deck
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeckCreated is emitted when a user creates a deck.
+type DeckCreated struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID `pii:"subject"`
+	Name      string    `pii:"data"`
+	Format    string
+	Zones     []Zone
+	CreatedAt time.Time
+}
+
+// CardAddedToDeck is emitted when copies of a card are added to a zone of a
+// deck.
+type CardAddedToDeck struct {
+	ID       uuid.UUID
+	CardID   uuid.UUID
+	Zone     string
+	Quantity int
+}
+
+// CardRemovedFromDeck is emitted when copies of a card are removed from a
+// zone of a deck.
+type CardRemovedFromDeck struct {
+	ID       uuid.UUID
+	CardID   uuid.UUID
+	Zone     string
+	Quantity int
+}
+
+// DeckRenamed is emitted when a deck is renamed.
+type DeckRenamed struct {
+	ID     uuid.UUID
+	UserID uuid.UUID `pii:"subject"`
+	Name   string    `pii:"data"`
+}
+
+// DeckVisibilityChanged is emitted when the owner of a deck shares or hides
+// it.
+type DeckVisibilityChanged struct {
+	ID         uuid.UUID
+	Visibility string
+}
+
+// DeckDeleted is emitted when a deck is deleted.
+type DeckDeleted struct {
+	ID uuid.UUID
+}
+
+// DeckRevised marks the end of a change recorded in the history of a deck:
+// the state of the deck after the events before it is its next revision.
+type DeckRevised struct {
+	ID uuid.UUID
+	At time.Time
+}
+
+// EventDeckID returns the id of the deck an event belongs to. ok is false
+// for events that are not deck events.
+func EventDeckID(evt interface{}) (id uuid.UUID, ok bool) {
+	switch e := evt.(type) {
+	case DeckCreated:
+		return e.ID, true
+	case CardAddedToDeck:
+		return e.ID, true
+	case CardRemovedFromDeck:
+		return e.ID, true
+	case DeckRenamed:
+		return e.ID, true
+	case DeckVisibilityChanged:
+		return e.ID, true
+	case DeckDeleted:
+		return e.ID, true
+	case DeckRevised:
+		return e.ID, true
+	default:
+		return uuid.Nil, false
+	}
+}
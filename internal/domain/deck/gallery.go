@@ -0,0 +1,143 @@
+package deck
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Visibilities of a deck. Private decks are only seen by their owner,
+// unlisted decks by everyone knowing their id and public decks are also
+// listed in the gallery.
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+// ErrInvalidVisibility is returned for unknown visibilities.
+var ErrInvalidVisibility = errors.New("deck: invalid visibility")
+
+// SetVisibility returns the event changing the visibility of d.
+func (d *Deck) SetVisibility(visibility string) (DeckVisibilityChanged, error) {
+	if d.Deleted {
+		return DeckVisibilityChanged{}, ErrDeckDeleted
+	}
+	switch visibility {
+	case VisibilityPrivate, VisibilityUnlisted, VisibilityPublic:
+		return DeckVisibilityChanged{ID: d.ID, Visibility: visibility}, nil
+	default:
+		return DeckVisibilityChanged{}, ErrInvalidVisibility
+	}
+}
+
+// CanView reports whether userID may see d. uuid.Nil stands for anonymous
+// users.
+func (d *Deck) CanView(userID uuid.UUID) bool {
+	return d.Visibility == VisibilityPublic || d.Visibility == VisibilityUnlisted || (userID != uuid.Nil && d.UserID == userID)
+}
+
+// Gallery sort orders.
+const (
+	SortNewest  = "new"
+	SortPopular = "popular"
+)
+
+// GalleryEntry is the read model of a public deck. Factions lists the
+// distinct factions of its cards and CardIDs its distinct cards.
+type GalleryEntry struct {
+	DeckID    uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Format    string
+	Factions  []string
+	CardIDs   []uuid.UUID
+	Likes     int
+	CreatedAt time.Time
+}
+
+// NewGalleryEntry returns the entry of d. faction returns the faction of a
+// card, or "" when it is unknown.
+func NewGalleryEntry(d *Deck, faction func(cardID uuid.UUID) string) GalleryEntry {
+	e := GalleryEntry{DeckID: d.ID, UserID: d.UserID, Name: d.Name, Format: d.Format, Factions: []string{}, CardIDs: []uuid.UUID{}, CreatedAt: d.CreatedAt}
+	seenCards := make(map[uuid.UUID]bool)
+	seenFactions := make(map[string]bool)
+	for _, id := range d.AllCardIDs() {
+		if seenCards[id] {
+			continue
+		}
+		seenCards[id] = true
+		e.CardIDs = append(e.CardIDs, id)
+		if f := faction(id); f != "" && !seenFactions[f] {
+			seenFactions[f] = true
+			e.Factions = append(e.Factions, f)
+		}
+	}
+	sort.Strings(e.Factions)
+	return e
+}
+
+// GalleryQuery selects a page of the gallery. Empty fields match every deck;
+// CardIDs matches decks holding all of the cards and LikedBy the decks a
+// user likes. Sort is SortNewest or SortPopular, which orders by likes and
+// then like SortNewest.
+type GalleryQuery struct {
+	Format  string
+	Faction string
+	CardIDs []uuid.UUID
+	LikedBy uuid.UUID
+	Sort    string
+	Offset  int
+	Limit   int
+}
+
+// Matches reports whether e is selected by the filters of q. LikedBy is not
+// checked since entries do not know who likes them.
+func (q GalleryQuery) Matches(e GalleryEntry) bool {
+	if q.Format != "" && e.Format != q.Format {
+		return false
+	}
+	if q.Faction != "" && !containsString(e.Factions, q.Faction) {
+		return false
+	}
+	for _, id := range q.CardIDs {
+		if !containsID(e.CardIDs, id) {
+			return false
+		}
+	}
+	return true
+}
+
+// SortGallery orders entries as requested by sortBy.
+func SortGallery(entries []GalleryEntry, sortBy string) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if sortBy == SortPopular && a.Likes != b.Likes {
+			return a.Likes > b.Likes
+		}
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.DeckID.String() < b.DeckID.String()
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(list []uuid.UUID, id uuid.UUID) bool {
+	for _, v := range list {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
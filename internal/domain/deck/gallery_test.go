@@ -0,0 +1,69 @@
+package deck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSetVisibility(t *testing.T) {
+	owner := uuid.New()
+	d := Replay([]interface{}{NewDeck(owner, "d", nil).Created()})
+	if d.Visibility != VisibilityPrivate || d.CanView(uuid.New()) || d.CanView(uuid.Nil) || !d.CanView(owner) {
+		t.Fatalf("expected a private deck got %+v", d)
+	}
+	if _, err := d.SetVisibility("friends"); !errors.Is(err, ErrInvalidVisibility) {
+		t.Fatalf("expected ErrInvalidVisibility got %v", err)
+	}
+	evt, err := d.SetVisibility(VisibilityUnlisted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Apply(evt)
+	if d.Visibility != VisibilityUnlisted || !d.CanView(uuid.Nil) {
+		t.Fatalf("expected an unlisted deck got %+v", d)
+	}
+	deleted, _ := d.Delete()
+	d.Apply(deleted)
+	if _, err := d.SetVisibility(VisibilityPublic); !errors.Is(err, ErrDeckDeleted) {
+		t.Fatalf("expected ErrDeckDeleted got %v", err)
+	}
+}
+
+func TestNewGalleryEntry(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{a, a, b})
+	d.Zones = append(d.Zones, ZoneOf(ZoneSideboard, []uuid.UUID{a, c}))
+	factions := map[uuid.UUID]string{a: "red", b: "blue"}
+	e := NewGalleryEntry(d, func(id uuid.UUID) string { return factions[id] })
+	if e.DeckID != d.ID || len(e.CardIDs) != 3 || e.CardIDs[2] != c || len(e.Factions) != 2 || e.Factions[0] != "blue" || e.Factions[1] != "red" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+
+	q := GalleryQuery{Faction: "red", CardIDs: []uuid.UUID{a, c}}
+	if !q.Matches(e) {
+		t.Fatalf("expected %+v to match", q)
+	}
+	for _, q := range []GalleryQuery{{Format: "std"}, {Faction: "green"}, {CardIDs: []uuid.UUID{a, uuid.New()}}} {
+		if q.Matches(e) {
+			t.Fatalf("expected %+v not to match", q)
+		}
+	}
+}
+
+func TestSortGallery(t *testing.T) {
+	now := time.Now()
+	old := GalleryEntry{DeckID: uuid.New(), Likes: 3, CreatedAt: now.Add(-time.Hour)}
+	recent := GalleryEntry{DeckID: uuid.New(), Likes: 1, CreatedAt: now}
+	entries := []GalleryEntry{old, recent}
+	SortGallery(entries, SortNewest)
+	if entries[0].DeckID != recent.DeckID {
+		t.Fatalf("expected newest first got %+v", entries)
+	}
+	SortGallery(entries, SortPopular)
+	if entries[0].DeckID != old.DeckID {
+		t.Fatalf("expected most liked first got %+v", entries)
+	}
+}
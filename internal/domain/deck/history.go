@@ -0,0 +1,126 @@
+package deck
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrVersionNotFound is returned for revisions a deck does not have.
+var ErrVersionNotFound = errors.New("deck: version not found")
+
+// Revision is the state of a deck after a saved change. Versions start at 1
+// and grow by one with every change.
+type Revision struct {
+	DeckID    uuid.UUID `json:"deck_id"`
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	Format    string    `json:"format"`
+	Zones     []Zone    `json:"zones"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// NewRevision returns the revision version of d, created at the given
+// time.
+func NewRevision(d *Deck, version int, at time.Time) Revision {
+	return Revision{
+		DeckID:    d.ID,
+		Version:   version,
+		Name:      d.Name,
+		Format:    d.Format,
+		Zones:     cloneZones(d.Zones),
+		CreatedAt: at,
+	}
+}
+
+// Revisions replays the events of a deck and returns the revisions its
+// DeckRevised events mark, oldest first.
+func Revisions(events []interface{}) []Revision {
+	d := &Deck{}
+	var revs []Revision
+	for _, evt := range events {
+		d.Apply(evt)
+		if e, ok := evt.(DeckRevised); ok {
+			revs = append(revs, NewRevision(d, len(revs)+1, e.At))
+		}
+	}
+	return revs
+}
+
+// Clone returns a deep copy of r.
+func (r *Revision) Clone() *Revision {
+	c := *r
+	c.Zones = cloneZones(r.Zones)
+	return &c
+}
+
+// Change is a difference in the copies of a card held by a zone.
+type Change struct {
+	Zone     string    `json:"zone"`
+	CardID   uuid.UUID `json:"card_id"`
+	Quantity int       `json:"quantity"`
+}
+
+// Diff lists the cards added and removed between two revisions.
+type Diff struct {
+	From    int      `json:"from"`
+	To      int      `json:"to"`
+	Name    string   `json:"name,omitempty"`
+	Added   []Change `json:"added"`
+	Removed []Change `json:"removed"`
+}
+
+// DiffRevisions compares two revisions of a deck. Name is set when the deck
+// was renamed in between. Changes are listed zone by zone in the order of
+// to, then from.
+func DiffRevisions(from, to Revision) Diff {
+	diff := Diff{From: from.Version, To: to.Version, Added: []Change{}, Removed: []Change{}}
+	if from.Name != to.Name {
+		diff.Name = to.Name
+	}
+	for _, z := range to.Zones {
+		before := findZone(from.Zones, z.Name)
+		for _, e := range z.Cards {
+			if n := e.Quantity - before.Quantity(e.CardID); n > 0 {
+				diff.Added = append(diff.Added, Change{Zone: z.Name, CardID: e.CardID, Quantity: n})
+			}
+		}
+	}
+	for _, z := range from.Zones {
+		after := findZone(to.Zones, z.Name)
+		for _, e := range z.Cards {
+			if n := e.Quantity - after.Quantity(e.CardID); n > 0 {
+				diff.Removed = append(diff.Removed, Change{Zone: z.Name, CardID: e.CardID, Quantity: n})
+			}
+		}
+	}
+	return diff
+}
+
+// Restore returns the events turning d back into the state of rev.
+func (d *Deck) Restore(rev Revision) ([]interface{}, error) {
+	if d.Deleted {
+		return nil, ErrDeckDeleted
+	}
+	var events []interface{}
+	if rev.Name != d.Name {
+		evt, err := d.Rename(rev.Name)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+	// zones the revision did not have are emptied
+	zones := cloneZones(rev.Zones)
+	for _, z := range d.Zones {
+		if len(findZone(rev.Zones, z.Name).Cards) == 0 {
+			zones = append(zones, Zone{Name: z.Name})
+		}
+	}
+	changes, err := d.SetZones(zones)
+	if err != nil {
+		return nil, err
+	}
+	return append(events, changes...), nil
+}
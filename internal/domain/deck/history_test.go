@@ -0,0 +1,98 @@
+package deck
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{a, a, b})
+	from := NewRevision(d, 1, time.Now())
+	d.Name = "e"
+	d.Zones = []Zone{
+		{Name: ZoneMain, Cards: []Entry{{CardID: a, Quantity: 3}}},
+		{Name: ZoneSideboard, Cards: []Entry{{CardID: b, Quantity: 1}, {CardID: c, Quantity: 2}}},
+	}
+	to := NewRevision(d, 2, time.Now())
+
+	diff := DiffRevisions(from, to)
+	if diff.From != 1 || diff.To != 2 || diff.Name != "e" {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+	want := []Change{{ZoneMain, a, 1}, {ZoneSideboard, b, 1}, {ZoneSideboard, c, 2}}
+	if len(diff.Added) != len(want) {
+		t.Fatalf("unexpected added %+v", diff.Added)
+	}
+	for i := range want {
+		if diff.Added[i] != want[i] {
+			t.Fatalf("added %d: expected %+v got %+v", i, want[i], diff.Added[i])
+		}
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0] != (Change{ZoneMain, b, 1}) {
+		t.Fatalf("unexpected removed %+v", diff.Removed)
+	}
+
+	same := DiffRevisions(to, to)
+	if same.Name != "" || len(same.Added) != 0 || len(same.Removed) != 0 {
+		t.Fatalf("expected empty diff got %+v", same)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	a := uuid.New()
+	d := NewDeck(uuid.New(), "d", nil)
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	revs := Revisions([]interface{}{
+		d.Created(),
+		DeckRevised{ID: d.ID, At: at},
+		CardAddedToDeck{ID: d.ID, CardID: a, Zone: ZoneMain, Quantity: 2},
+		DeckRenamed{ID: d.ID, Name: "e"},
+		DeckRevised{ID: d.ID, At: at.Add(time.Hour)},
+		DeckVisibilityChanged{ID: d.ID, Visibility: VisibilityPublic},
+	})
+	if len(revs) != 2 {
+		t.Fatalf("expected 2 revisions got %+v", revs)
+	}
+	if revs[0].Version != 1 || revs[0].Name != "d" || len(revs[0].Zones) != 0 || !revs[0].CreatedAt.Equal(at) {
+		t.Fatalf("unexpected first revision %+v", revs[0])
+	}
+	if revs[1].Version != 2 || revs[1].Name != "e" || revs[1].Zones[0].Quantity(a) != 2 || !revs[1].CreatedAt.Equal(at.Add(time.Hour)) {
+		t.Fatalf("unexpected second revision %+v", revs[1])
+	}
+}
+
+func TestRestore(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{a, a})
+	rev := NewRevision(d, 1, time.Now())
+
+	for _, evt := range []interface{}{
+		DeckRenamed{ID: d.ID, Name: "e"},
+		CardRemovedFromDeck{ID: d.ID, CardID: a, Zone: ZoneMain, Quantity: 2},
+		CardAddedToDeck{ID: d.ID, CardID: b, Zone: ZoneSideboard, Quantity: 3},
+	} {
+		d.Apply(evt)
+	}
+	events, err := d.Restore(rev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, evt := range events {
+		d.Apply(evt)
+	}
+	if d.Name != "d" || len(d.Zones) != 1 || d.Zone(ZoneMain).Quantity(a) != 2 {
+		t.Fatalf("unexpected restored deck %+v", d)
+	}
+	if events, _ := d.Restore(rev); len(events) != 0 {
+		t.Fatalf("expected no events restoring the current state got %v", events)
+	}
+
+	d.Deleted = true
+	if _, err := d.Restore(rev); !errors.Is(err, ErrDeckDeleted) {
+		t.Fatalf("expected ErrDeckDeleted got %v", err)
+	}
+}
@@ -5,8 +5,60 @@ import (
 	"github.com/google/uuid"
 )
 
-// Repository provides persistence for decks.
+// Repository provides persistence for decks via event sourcing. Load returns
+// nil for unknown and deleted decks.
 type Repository interface {
-	Save(ctx context.Context, d *Deck) error
+	// Save appends the events of a single deck, which must still be at
+	// version expected (0 for new decks), and returns ErrVersionConflict
+	// otherwise. DeckRevised events record the state of the deck at that
+	// point in its history, in the same write.
+	Save(ctx context.Context, expected int, events []interface{}) error
 	Load(ctx context.Context, id uuid.UUID) (*Deck, error)
+	// ListByUser returns a page of the decks of a user, newest first, and
+	// the total number of decks the user has. A limit <= 0 returns all decks
+	// from offset on.
+	ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*Deck, int, error)
+	// Tenants returns the tenants in which a user has decks, whatever the
+	// tenant of ctx, in any order.
+	Tenants(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
+
+// HistoryRepository reads the revisions of decks, which the Repository
+// records when saving DeckRevised events.
+type HistoryRepository interface {
+	// Revisions returns the revisions of a deck, oldest first.
+	Revisions(ctx context.Context, deckID uuid.UUID) ([]Revision, error)
+	// Revision returns a single revision, or nil when the deck has no such
+	// version.
+	Revision(ctx context.Context, deckID uuid.UUID, version int) (*Revision, error)
+}
+
+// GalleryRepository keeps the read model of the public decks and who likes
+// them.
+type GalleryRepository interface {
+	// Put adds or replaces the entry of a deck, keeping its likes.
+	Put(ctx context.Context, e GalleryEntry) error
+	// Remove drops the entry of a deck along with its likes.
+	Remove(ctx context.Context, deckID uuid.UUID) error
+	// Entry returns the entry of a deck, or nil when it is not listed.
+	Entry(ctx context.Context, deckID uuid.UUID) (*GalleryEntry, error)
+	// Search returns a page of matching entries and the total number of
+	// matches. A limit <= 0 returns all entries from offset on.
+	Search(ctx context.Context, q GalleryQuery) ([]GalleryEntry, int, error)
+	// Like records that userID likes a deck and Unlike takes it back. Both
+	// are idempotent and return the updated entry, or nil when the deck is
+	// not listed.
+	Like(ctx context.Context, deckID, userID uuid.UUID) (*GalleryEntry, error)
+	Unlike(ctx context.Context, deckID, userID uuid.UUID) (*GalleryEntry, error)
+}
+
+// CardNumberRepository numbers the cards that deck codes refer to. A card is
+// numbered the first time it is asked for and keeps its number. Numbers
+// start at 1 and are unique across tenants.
+type CardNumberRepository interface {
+	// Numbers returns the numbers of cards, numbering the cards that have
+	// none.
+	Numbers(ctx context.Context, cardIDs []uuid.UUID) (map[uuid.UUID]uint64, error)
+	// CardIDs returns the cards with numbers, leaving out unknown numbers.
+	CardIDs(ctx context.Context, numbers []uint64) (map[uint64]uuid.UUID, error)
 }
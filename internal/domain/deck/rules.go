@@ -0,0 +1,257 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+)
+
+// ErrUnknownFormat is returned for decks naming a format that is not
+// configured.
+var ErrUnknownFormat = errors.New("deck: unknown format")
+
+// Rule names reported in violations.
+const (
+	RuleUnknownCard = "unknown_card"
+	RuleMinSize     = "min_size"
+	RuleMaxSize     = "max_size"
+	RuleMaxCopies   = "max_copies"
+	RuleBanned      = "banned"
+	RuleRestricted  = "restricted"
+	RuleFaction     = "faction"
+	RuleMaxFactions = "max_factions"
+	RuleCardCost    = "card_cost"
+	RuleTotalCost   = "total_cost"
+	RuleZone        = "zone"
+	RuleZoneSize    = "zone_size"
+)
+
+// Format describes the construction rules of a named deck format. Zero limits
+// are not enforced. MinSize, MaxSize and MaxTotalCost apply to the main zone;
+// the other card rules apply to the cards of all zones together.
+type Format struct {
+	ID           string      `json:"id"`
+	Name         string      `json:"name"`
+	MinSize      int         `json:"min_size"`
+	MaxSize      int         `json:"max_size"`
+	MaxCopies    int         `json:"max_copies"`
+	Factions     []string    `json:"factions"`
+	MaxFactions  int         `json:"max_factions"`
+	Banned       []uuid.UUID `json:"banned"`
+	Restricted   []uuid.UUID `json:"restricted"`
+	MaxCardCost  int         `json:"max_card_cost"`
+	MaxTotalCost int         `json:"max_total_cost"`
+	// Zones limits the size of the zones besides the main zone. When set,
+	// decks may only use the main zone and the zones listed.
+	Zones map[string]ZoneLimits `json:"zones"`
+}
+
+// ZoneLimits limits the size of a zone.
+type ZoneLimits struct {
+	MinSize int `json:"min_size"`
+	MaxSize int `json:"max_size"`
+}
+
+// Violation is a single broken rule. CardID is set for rules about a card and
+// Zone for rules about a zone.
+type Violation struct {
+	Rule    string     `json:"rule"`
+	CardID  *uuid.UUID `json:"card_id,omitempty"`
+	Zone    string     `json:"zone,omitempty"`
+	Message string     `json:"message"`
+	// excess is how far the deck is from following the rule, in cards or,
+	// for the total cost, in cost.
+	excess int
+}
+
+func (v Violation) key() string {
+	k := v.Rule + ":" + v.Zone
+	if v.CardID != nil {
+		k += ":" + v.CardID.String()
+	}
+	return k
+}
+
+// ValidationError lists the violations that prevented a deck change.
+type ValidationError struct {
+	Format     string
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return "deck: invalid deck: " + strings.Join(msgs, "; ")
+}
+
+// Rules validates decks against the configured formats.
+type Rules struct {
+	formats map[string]Format
+}
+
+// NewRules creates a rules engine for the given formats.
+func NewRules(formats []Format) (*Rules, error) {
+	r := &Rules{formats: make(map[string]Format, len(formats))}
+	for _, f := range formats {
+		if f.ID == "" {
+			return nil, errors.New("deck: format without id")
+		}
+		if _, ok := r.formats[f.ID]; ok {
+			return nil, fmt.Errorf("deck: duplicate format %q", f.ID)
+		}
+		r.formats[f.ID] = f
+	}
+	return r, nil
+}
+
+// Format returns the format with the given id.
+func (r *Rules) Format(id string) (Format, bool) {
+	if r == nil {
+		return Format{}, false
+	}
+	f, ok := r.formats[id]
+	return f, ok
+}
+
+// Validate checks d against the rules of its format. cards holds the
+// resolved card of every id in the deck; ids missing from it are reported as
+// unknown cards. Decks without a format are only checked for unknown cards.
+func (r *Rules) Validate(d *Deck, cards map[uuid.UUID]*card.Card) ([]Violation, error) {
+	var f Format
+	if d.Format != "" {
+		var ok bool
+		if f, ok = r.Format(d.Format); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, d.Format)
+		}
+	}
+	return f.Validate(d.Zones, cards), nil
+}
+
+// Validate checks the zones of a deck against f.
+func (f Format) Validate(zones []Zone, cards map[uuid.UUID]*card.Card) []Violation {
+	var vs []Violation
+	cardRule := func(rule string, id uuid.UUID, excess int, format string, args ...interface{}) {
+		vs = append(vs, Violation{Rule: rule, CardID: &id, Message: fmt.Sprintf(format, args...), excess: excess})
+	}
+
+	main := Zone{Name: ZoneMain}
+	sizes := make(map[string]int)
+	counts := make(map[uuid.UUID]int)
+	var order []uuid.UUID
+	for _, z := range zones {
+		if z.Name == ZoneMain {
+			main = z
+		} else if _, ok := f.Zones[z.Name]; f.Zones != nil && !ok {
+			vs = append(vs, Violation{Rule: RuleZone, Zone: z.Name, Message: fmt.Sprintf("zone %q is not allowed", z.Name), excess: z.Size()})
+		}
+		sizes[z.Name] = z.Size()
+		for _, e := range z.Cards {
+			if counts[e.CardID] == 0 {
+				order = append(order, e.CardID)
+			}
+			counts[e.CardID] += e.Quantity
+		}
+	}
+	if n := main.Size(); f.MinSize > 0 && n < f.MinSize {
+		vs = append(vs, Violation{Rule: RuleMinSize, Message: fmt.Sprintf("deck has %d cards, at least %d required", n, f.MinSize), excess: f.MinSize - n})
+	}
+	if n := main.Size(); f.MaxSize > 0 && n > f.MaxSize {
+		vs = append(vs, Violation{Rule: RuleMaxSize, Message: fmt.Sprintf("deck has %d cards, at most %d allowed", n, f.MaxSize), excess: n - f.MaxSize})
+	}
+	for _, name := range sortedKeys(f.Zones) {
+		limits, n := f.Zones[name], sizes[name]
+		if limits.MinSize > 0 && n < limits.MinSize {
+			vs = append(vs, Violation{Rule: RuleZoneSize, Zone: name, Message: fmt.Sprintf("zone %q has %d cards, at least %d required", name, n, limits.MinSize), excess: limits.MinSize - n})
+		}
+		if limits.MaxSize > 0 && n > limits.MaxSize {
+			vs = append(vs, Violation{Rule: RuleZoneSize, Zone: name, Message: fmt.Sprintf("zone %q has %d cards, at most %d allowed", name, n, limits.MaxSize), excess: n - limits.MaxSize})
+		}
+	}
+
+	banned := toSet(f.Banned)
+	restricted := toSet(f.Restricted)
+	allowed := make(map[string]bool, len(f.Factions))
+	for _, fa := range f.Factions {
+		allowed[fa] = true
+	}
+	factions := make(map[string]bool)
+	for _, id := range order {
+		n := counts[id]
+		c, ok := cards[id]
+		if !ok || c == nil {
+			cardRule(RuleUnknownCard, id, n, "card %s does not exist", id)
+			continue
+		}
+		if c.Faction != "" {
+			factions[c.Faction] = true
+		}
+		switch {
+		case banned[id]:
+			cardRule(RuleBanned, id, n, "%s is banned", c.Name)
+		case restricted[id] && n > 1:
+			cardRule(RuleRestricted, id, n-1, "%s is restricted to 1 copy, deck has %d", c.Name, n)
+		case f.MaxCopies > 0 && n > f.MaxCopies:
+			cardRule(RuleMaxCopies, id, n-f.MaxCopies, "%s has %d copies, at most %d allowed", c.Name, n, f.MaxCopies)
+		}
+		if len(allowed) > 0 && !allowed[c.Faction] {
+			cardRule(RuleFaction, id, n, "%s belongs to faction %q which is not allowed", c.Name, c.Faction)
+		}
+		if f.MaxCardCost > 0 && c.Cost > f.MaxCardCost {
+			cardRule(RuleCardCost, id, n, "%s costs %d, at most %d allowed", c.Name, c.Cost, f.MaxCardCost)
+		}
+	}
+	if f.MaxFactions > 0 && len(factions) > f.MaxFactions {
+		vs = append(vs, Violation{Rule: RuleMaxFactions, Message: fmt.Sprintf("deck uses %d factions, at most %d allowed", len(factions), f.MaxFactions), excess: len(factions) - f.MaxFactions})
+	}
+	total := 0
+	for _, e := range main.Cards {
+		if c := cards[e.CardID]; c != nil {
+			total += c.Cost * e.Quantity
+		}
+	}
+	if f.MaxTotalCost > 0 && total > f.MaxTotalCost {
+		vs = append(vs, Violation{Rule: RuleTotalCost, Message: fmt.Sprintf("deck costs %d in total, at most %d allowed", total, f.MaxTotalCost), excess: total - f.MaxTotalCost})
+	}
+	return vs
+}
+
+// NewViolations returns the violations in after that are not in before or
+// that after breaks further than before, e.g. with more copies of a card. It
+// lets decks that became illegal, when the rules of their format changed,
+// change as long as a change does not take them further from the rules.
+func NewViolations(before, after []Violation) []Violation {
+	excess := make(map[string]int, len(before))
+	for _, v := range before {
+		excess[v.key()] = v.excess
+	}
+	var vs []Violation
+	for _, v := range after {
+		if prev, ok := excess[v.key()]; !ok || v.excess > prev {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+func sortedKeys(m map[string]ZoneLimits) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toSet(ids []uuid.UUID) map[uuid.UUID]bool {
+	m := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		m[id] = true
+	}
+	return m
+}
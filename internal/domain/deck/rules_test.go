@@ -0,0 +1,135 @@
+package deck
+
+import (
+	"errors"
+	"testing"
+
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+)
+
+func rulesOf(vs []Violation) map[string]int {
+	m := make(map[string]int)
+	for _, v := range vs {
+		m[v.Rule]++
+	}
+	return m
+}
+
+func TestFormatValidate(t *testing.T) {
+	human := card.NewCard("Knight", 2, "Human", "Soldier", "", "")
+	elf := card.NewCard("Archer", 5, "Elf", "Soldier", "", "")
+	orc := card.NewCard("Grunt", 1, "Orc", "Soldier", "", "")
+	banned := card.NewCard("Nuke", 1, "Human", "Spell", "", "")
+	restricted := card.NewCard("Wish", 1, "Human", "Spell", "", "")
+	cards := map[uuid.UUID]*card.Card{}
+	for _, c := range []*card.Card{human, elf, orc, banned, restricted} {
+		cards[c.ID] = c
+	}
+	f := Format{
+		ID: "f", MinSize: 3, MaxSize: 8, MaxCopies: 2, Factions: []string{"Human", "Elf"}, MaxFactions: 1,
+		Banned: []uuid.UUID{banned.ID}, Restricted: []uuid.UUID{restricted.ID}, MaxCardCost: 4, MaxTotalCost: 14,
+	}
+	unknown := uuid.New()
+	ids := []uuid.UUID{human.ID, human.ID, human.ID, elf.ID, orc.ID, banned.ID, restricted.ID, restricted.ID, unknown}
+	got := rulesOf(f.Validate(main(ids...), cards))
+	want := map[string]int{
+		RuleMaxSize: 1, RuleMaxCopies: 1, RuleFaction: 1, RuleMaxFactions: 1, RuleBanned: 1,
+		RuleRestricted: 1, RuleCardCost: 1, RuleTotalCost: 1, RuleUnknownCard: 1,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v got %v", want, got)
+	}
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Fatalf("expected %v got %v", want, got)
+		}
+	}
+	if vs := f.Validate(main(human.ID), cards); len(vs) != 1 || vs[0].Rule != RuleMinSize {
+		t.Fatalf("expected min size violation got %v", vs)
+	}
+	if vs := f.Validate(main(human.ID, human.ID, restricted.ID), cards); len(vs) != 0 {
+		t.Fatalf("expected legal deck got %v", vs)
+	}
+}
+
+func main(ids ...uuid.UUID) []Zone {
+	return []Zone{ZoneOf(ZoneMain, ids)}
+}
+
+func TestFormatValidateZones(t *testing.T) {
+	a := card.NewCard("A", 5, "", "", "", "")
+	b := card.NewCard("B", 5, "", "", "", "")
+	cards := map[uuid.UUID]*card.Card{a.ID: a, b.ID: b}
+	f := Format{ID: "f", MaxSize: 2, MaxCopies: 2, MaxTotalCost: 10, Zones: map[string]ZoneLimits{
+		ZoneSideboard: {MaxSize: 1},
+		ZoneCommander: {MinSize: 1, MaxSize: 1},
+	}}
+	zones := []Zone{
+		{Name: ZoneMain, Cards: []Entry{{CardID: a.ID, Quantity: 2}}},
+		{Name: ZoneSideboard, Cards: []Entry{{CardID: a.ID, Quantity: 1}, {CardID: b.ID, Quantity: 1}}},
+		{Name: "maybe", Cards: []Entry{{CardID: b.ID, Quantity: 1}}},
+	}
+	vs := f.Validate(zones, cards)
+	// the sideboard and commander sizes, the unknown zone and three copies
+	// of a across zones; sideboard cards do not count towards size and cost
+	got := rulesOf(vs)
+	if len(vs) != 4 || got[RuleZoneSize] != 2 || got[RuleZone] != 1 || got[RuleMaxCopies] != 1 {
+		t.Fatalf("unexpected violations %+v", vs)
+	}
+	for _, v := range vs {
+		if v.Rule == RuleZone && v.Zone != "maybe" {
+			t.Fatalf("unexpected zone violation %+v", v)
+		}
+	}
+	// zone violations are told apart by zone
+	if nv := NewViolations(vs[:1], vs); len(nv) != 3 {
+		t.Fatalf("expected 3 new violations got %+v", nv)
+	}
+}
+
+func TestRules(t *testing.T) {
+	if _, err := NewRules([]Format{{ID: "a"}, {ID: "a"}}); err == nil {
+		t.Fatal("expected error for duplicate format")
+	}
+	r, err := NewRules([]Format{{ID: "a", MaxCopies: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := card.NewCard("N", 1, "", "", "", "")
+	cards := map[uuid.UUID]*card.Card{c.ID: c}
+	d := NewDeck(uuid.New(), "d", []uuid.UUID{c.ID, c.ID})
+	if vs, err := r.Validate(d, cards); err != nil || len(vs) != 0 {
+		t.Fatalf("decks without format only check unknown cards, got %v %v", vs, err)
+	}
+	d.Format = "a"
+	if vs, err := r.Validate(d, cards); err != nil || len(vs) != 1 || vs[0].Rule != RuleMaxCopies {
+		t.Fatalf("unexpected %v %v", vs, err)
+	}
+	d.Format = "b"
+	if _, err := r.Validate(d, cards); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("expected ErrUnknownFormat got %v", err)
+	}
+}
+
+func TestNewViolations(t *testing.T) {
+	id := uuid.New()
+	before := []Violation{{Rule: RuleMinSize}, {Rule: RuleMaxCopies, CardID: &id}}
+	other := uuid.New()
+	after := []Violation{{Rule: RuleMinSize, Message: "changed"}, {Rule: RuleMaxCopies, CardID: &other}}
+	vs := NewViolations(before, after)
+	if len(vs) != 1 || *vs[0].CardID != other {
+		t.Fatalf("unexpected %v", vs)
+	}
+	// a violation broken further is new
+	if vs := NewViolations([]Violation{{Rule: RuleMinSize, excess: 2}}, []Violation{{Rule: RuleMinSize, excess: 3}}); len(vs) != 1 {
+		t.Fatalf("expected a smaller deck to be a new violation got %v", vs)
+	}
+	if vs := NewViolations([]Violation{{Rule: RuleMinSize, excess: 2}}, []Violation{{Rule: RuleMinSize, excess: 1}}); len(vs) != 0 {
+		t.Fatalf("expected a larger deck to keep its violation got %v", vs)
+	}
+	err := &ValidationError{Violations: vs}
+	if err.Error() == "" {
+		t.Fatal("expected message")
+	}
+}
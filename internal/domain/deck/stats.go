@@ -0,0 +1,116 @@
+package deck
+
+import (
+	"math"
+	"sort"
+
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+)
+
+// DefaultHandSize is the opening hand size used when none is given.
+const DefaultHandSize = 7
+
+// Stats describes the composition of a deck. Cards missing from the catalog
+// count towards Size and UnknownCards only.
+type Stats struct {
+	Size          int            `json:"size"`
+	UnknownCards  int            `json:"unknown_cards"`
+	AverageCost   float64        `json:"average_cost"`
+	CostCurve     map[int]int    `json:"cost_curve"`
+	Factions      map[string]int `json:"factions"`
+	Categories    map[string]int `json:"categories"`
+	SubCategories map[string]int `json:"subcategories"`
+	HandSize      int            `json:"hand_size"`
+	Draws         []DrawOdds     `json:"draws"`
+}
+
+// DrawOdds is the chance of drawing at least one copy of a card in the
+// opening hand.
+type DrawOdds struct {
+	CardID      uuid.UUID `json:"card_id"`
+	Copies      int       `json:"copies"`
+	Probability float64   `json:"probability"`
+}
+
+// Analyze computes the statistics of a list of cards for an opening hand of
+// handSize cards. cards holds the resolved cards; missing or nil entries are
+// unknown cards. Draw odds are sorted by decreasing number of copies, and so
+// decreasing probability, then id.
+func Analyze(cardIDs []uuid.UUID, cards map[uuid.UUID]*card.Card, handSize int) Stats {
+	if handSize > len(cardIDs) {
+		handSize = len(cardIDs)
+	}
+	s := Stats{
+		Size:          len(cardIDs),
+		CostCurve:     make(map[int]int),
+		Factions:      make(map[string]int),
+		Categories:    make(map[string]int),
+		SubCategories: make(map[string]int),
+		HandSize:      handSize,
+		Draws:         []DrawOdds{},
+	}
+	copies := make(map[uuid.UUID]int)
+	totalCost, known := 0, 0
+	for _, id := range cardIDs {
+		copies[id]++
+		c := cards[id]
+		if c == nil {
+			s.UnknownCards++
+			continue
+		}
+		known++
+		totalCost += c.Cost
+		s.CostCurve[c.Cost]++
+		s.Factions[c.Faction]++
+		s.Categories[c.Category]++
+		if c.SubCategory != "" {
+			s.SubCategories[c.SubCategory]++
+		}
+	}
+	if known > 0 {
+		s.AverageCost = float64(totalCost) / float64(known)
+	}
+	for id, n := range copies {
+		s.Draws = append(s.Draws, DrawOdds{CardID: id, Copies: n, Probability: HypergeometricAtLeast(s.Size, n, handSize, 1)})
+	}
+	sort.Slice(s.Draws, func(i, j int) bool {
+		if s.Draws[i].Copies != s.Draws[j].Copies {
+			return s.Draws[i].Copies > s.Draws[j].Copies
+		}
+		return s.Draws[i].CardID.String() < s.Draws[j].CardID.String()
+	})
+	return s
+}
+
+// HypergeometricAtLeast returns the probability of drawing at least k of the
+// successes cards when drawing draws cards without replacement from a deck
+// of population cards.
+func HypergeometricAtLeast(population, successes, draws, k int) float64 {
+	if population <= 0 || draws <= 0 || successes <= 0 {
+		if k <= 0 {
+			return 1
+		}
+		return 0
+	}
+	if k < 0 {
+		k = 0
+	}
+	p := 0.0
+	for i := k; i <= successes && i <= draws; i++ {
+		if draws-i > population-successes {
+			continue
+		}
+		p += math.Exp(logChoose(successes, i) + logChoose(population-successes, draws-i) - logChoose(population, draws))
+	}
+	return math.Min(p, 1)
+}
+
+// logChoose returns the natural logarithm of the binomial coefficient
+// n over k.
+func logChoose(n, k int) float64 {
+	a, _ := math.Lgamma(float64(n + 1))
+	b, _ := math.Lgamma(float64(k + 1))
+	c, _ := math.Lgamma(float64(n - k + 1))
+	return a - b - c
+}
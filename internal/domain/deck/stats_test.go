@@ -0,0 +1,63 @@
+package deck
+
+import (
+	"math"
+	"testing"
+
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+)
+
+func TestHypergeometricAtLeast(t *testing.T) {
+	tests := []struct {
+		population, successes, draws, k int
+		want                            float64
+	}{
+		// 1 - C(36,7)/C(40,7)
+		{40, 4, 7, 1, 1 - 8347680.0/18643560.0},
+		{40, 4, 7, 0, 1},
+		{40, 0, 7, 1, 0},
+		{10, 10, 3, 3, 1},
+		{5, 1, 5, 1, 1},
+		{0, 0, 0, 1, 0},
+		{60, 4, 7, 5, 0},
+	}
+	for _, tt := range tests {
+		got := HypergeometricAtLeast(tt.population, tt.successes, tt.draws, tt.k)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("HypergeometricAtLeast(%d, %d, %d, %d) = %v, want %v", tt.population, tt.successes, tt.draws, tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	a := card.NewCard("A", 1, "Red", "Spell", "Burn", "")
+	b := card.NewCard("B", 3, "Blue", "Unit", "", "")
+	unknown := uuid.New()
+	ids := []uuid.UUID{a.ID, a.ID, a.ID, b.ID, unknown}
+	s := Analyze(ids, map[uuid.UUID]*card.Card{a.ID: a, b.ID: b}, 10)
+
+	if s.Size != 5 || s.UnknownCards != 1 || s.HandSize != 5 {
+		t.Fatalf("unexpected stats %+v", s)
+	}
+	if s.AverageCost != 1.5 || s.CostCurve[1] != 3 || s.CostCurve[3] != 1 {
+		t.Fatalf("unexpected costs %+v", s)
+	}
+	if s.Factions["Red"] != 3 || s.Factions["Blue"] != 1 || s.Categories["Unit"] != 1 || s.SubCategories["Burn"] != 3 || len(s.SubCategories) != 1 {
+		t.Fatalf("unexpected breakdown %+v", s)
+	}
+	if len(s.Draws) != 3 || s.Draws[0].CardID != a.ID || s.Draws[0].Copies != 3 {
+		t.Fatalf("unexpected draws %+v", s.Draws)
+	}
+	// the whole deck is drawn
+	for _, d := range s.Draws {
+		if math.Abs(d.Probability-1) > 1e-9 {
+			t.Fatalf("expected certain draw got %+v", d)
+		}
+	}
+
+	empty := Analyze(nil, nil, DefaultHandSize)
+	if empty.Size != 0 || empty.AverageCost != 0 || len(empty.Draws) != 0 {
+		t.Fatalf("unexpected empty stats %+v", empty)
+	}
+}
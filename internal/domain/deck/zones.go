@@ -0,0 +1,165 @@
+package deck
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Zone names with a conventional meaning. Other names are allowed unless the
+// format of the deck restricts them.
+const (
+	ZoneMain      = "main"
+	ZoneSideboard = "sideboard"
+	ZoneCommander = "commander"
+)
+
+// ErrInvalidZone is returned for malformed zone names and quantities.
+var ErrInvalidZone = errors.New("deck: invalid zone")
+
+var zoneName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Entry is a card of a zone with its number of copies.
+type Entry struct {
+	CardID   uuid.UUID `json:"card_id"`
+	Quantity int       `json:"quantity"`
+}
+
+// Zone is a named part of a deck, e.g. the main deck or the sideboard.
+type Zone struct {
+	Name  string  `json:"name"`
+	Cards []Entry `json:"cards"`
+}
+
+// ZoneOf returns the zone holding cardIDs, one entry per distinct card in the
+// order the cards first appear.
+func ZoneOf(name string, cardIDs []uuid.UUID) Zone {
+	z := Zone{Name: name}
+	for _, id := range cardIDs {
+		z.add(id, 1)
+	}
+	return z
+}
+
+// Size returns the number of cards in z.
+func (z Zone) Size() int {
+	n := 0
+	for _, e := range z.Cards {
+		n += e.Quantity
+	}
+	return n
+}
+
+// Quantity returns the number of copies of a card in z.
+func (z Zone) Quantity(cardID uuid.UUID) int {
+	for _, e := range z.Cards {
+		if e.CardID == cardID {
+			return e.Quantity
+		}
+	}
+	return 0
+}
+
+// CardIDs returns the cards of z with one element per copy.
+func (z Zone) CardIDs() []uuid.UUID {
+	ids := make([]uuid.UUID, 0, z.Size())
+	for _, e := range z.Cards {
+		for i := 0; i < e.Quantity; i++ {
+			ids = append(ids, e.CardID)
+		}
+	}
+	return ids
+}
+
+func (z *Zone) add(cardID uuid.UUID, n int) {
+	for i := range z.Cards {
+		if z.Cards[i].CardID == cardID {
+			z.Cards[i].Quantity += n
+			return
+		}
+	}
+	z.Cards = append(z.Cards, Entry{CardID: cardID, Quantity: n})
+}
+
+func (z *Zone) remove(cardID uuid.UUID, n int) {
+	for i := range z.Cards {
+		if z.Cards[i].CardID == cardID {
+			z.Cards[i].Quantity -= n
+			if z.Cards[i].Quantity <= 0 {
+				z.Cards = append(z.Cards[:i:i], z.Cards[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// NormalizeZones checks zone names and quantities and returns the zones with
+// lower-cased names, duplicate zones and cards merged, empty zones dropped
+// and the main zone first.
+func NormalizeZones(zones []Zone) ([]Zone, error) {
+	var out []Zone
+	index := make(map[string]int)
+	for _, z := range zones {
+		name := strings.ToLower(strings.TrimSpace(z.Name))
+		if !zoneName.MatchString(name) {
+			return nil, fmt.Errorf("%w: name %q", ErrInvalidZone, z.Name)
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(out)
+			index[name] = i
+			out = append(out, Zone{Name: name})
+		}
+		for _, e := range z.Cards {
+			if e.Quantity < 0 {
+				return nil, fmt.Errorf("%w: negative quantity of %s in %s", ErrInvalidZone, e.CardID, name)
+			}
+			if e.Quantity > 0 {
+				out[i].add(e.CardID, e.Quantity)
+			}
+		}
+	}
+	return sortZones(out), nil
+}
+
+// sortZones drops empty zones and moves the main zone first, keeping the
+// order of the others.
+func sortZones(zones []Zone) []Zone {
+	var out []Zone
+	for _, z := range zones {
+		if len(z.Cards) == 0 {
+			continue
+		}
+		if z.Name == ZoneMain {
+			out = append([]Zone{z}, out...)
+		} else {
+			out = append(out, z)
+		}
+	}
+	return out
+}
+
+// findZone returns the zone with the given name, which is empty when zones
+// do not hold it.
+func findZone(zones []Zone, name string) Zone {
+	for _, z := range zones {
+		if z.Name == name {
+			return z
+		}
+	}
+	return Zone{Name: name}
+}
+
+func cloneZones(zones []Zone) []Zone {
+	if zones == nil {
+		return nil
+	}
+	out := make([]Zone, len(zones))
+	for i, z := range zones {
+		out[i] = Zone{Name: z.Name, Cards: append([]Entry(nil), z.Cards...)}
+	}
+	return out
+}
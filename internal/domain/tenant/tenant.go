@@ -0,0 +1,80 @@
+// Package tenant separates the games served by one deployment. Cards, decks
+// and events belong to the tenant of the context they were saved with, and
+// repositories only read the data of the tenant of their context. A Registry
+// lists the tenants a deployment serves.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"regexp"
+)
+
+// Default is the tenant of requests naming none, which also owns the data
+// stored before tenants existed.
+const Default = "default"
+
+// ErrInvalid is returned by Check for malformed tenant ids.
+var ErrInvalid = errors.New("tenant: invalid tenant id")
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Check validates a tenant id: 1 to 63 lowercase letters, digits and dashes,
+// not starting with a dash. Ids fit Redis keys and Kafka topic names as they
+// are.
+func Check(id string) error {
+	if !idPattern.MatchString(id) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the tenant id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant carried by ctx, Default when there is none.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
+
+// Prefix returns the prefix of the names of a tenant in namespaces shared by
+// all tenants, such as Redis keys or Kafka topics: the id followed by sep,
+// or nothing for the Default tenant so that its names stay those used
+// before tenants existed.
+func Prefix(id, sep string) string {
+	if id == Default || id == "" {
+		return ""
+	}
+	return id + sep
+}
+
+// Registry is the set of tenants a deployment serves. The Default tenant is
+// always served.
+type Registry struct {
+	ids map[string]bool
+}
+
+// NewRegistry returns a registry of the Default tenant and ids, or
+// ErrInvalid when one of ids is malformed.
+func NewRegistry(ids ...string) (*Registry, error) {
+	r := &Registry{ids: map[string]bool{Default: true}}
+	for _, id := range ids {
+		if err := Check(id); err != nil {
+			return nil, err
+		}
+		r.ids[id] = true
+	}
+	return r, nil
+}
+
+// Known reports whether the deployment serves tenant id.
+func (r *Registry) Known(id string) bool {
+	return r.ids[id]
+}
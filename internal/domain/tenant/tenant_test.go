@@ -0,0 +1,57 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	for _, id := range []string{"default", "acme", "game-2", "a"} {
+		if err := Check(id); err != nil {
+			t.Fatalf("%q: unexpected %v", id, err)
+		}
+	}
+	for _, id := range []string{"", "-acme", "Acme", "acme:cards", "acme.cards", "a b", string(make([]byte, 64))} {
+		if err := Check(id); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%q: expected ErrInvalid got %v", id, err)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if got := FromContext(ctx); got != Default {
+		t.Fatalf("expected %q got %q", Default, got)
+	}
+	if got := FromContext(NewContext(ctx, "acme")); got != "acme" {
+		t.Fatalf("expected acme got %q", got)
+	}
+	if got := FromContext(NewContext(ctx, "")); got != Default {
+		t.Fatalf("expected an empty id to be the default tenant got %q", got)
+	}
+}
+
+func TestPrefix(t *testing.T) {
+	if got := Prefix(Default, ":"); got != "" {
+		t.Fatalf("expected no prefix for the default tenant got %q", got)
+	}
+	if got := Prefix("acme", "."); got != "acme." {
+		t.Fatalf("expected acme. got %q", got)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry("acme", "globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]bool{Default: true, "acme": true, "globex": true, "initech": false, "": false} {
+		if got := r.Known(id); got != want {
+			t.Fatalf("%q: expected %v got %v", id, want, got)
+		}
+	}
+	if _, err := NewRegistry("acme", "Globex"); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package user
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository persists user accounts. Lookups return nil for unknown users;
+// usernames and email addresses are matched regardless of case.
+type Repository interface {
+	// Create stores a new user. It returns ErrUsernameTaken or
+	// ErrEmailTaken when another user has the same username or email.
+	Create(ctx context.Context, u *User) error
+	// Update stores the changes to an existing user. It returns ErrNotFound
+	// for unknown users and the errors of Create for taken names.
+	Update(ctx context.Context, u *User) error
+	// Delete removes a user, returning ErrNotFound for unknown users.
+	Delete(ctx context.Context, id uuid.UUID) error
+	ByID(ctx context.Context, id uuid.UUID) (*User, error)
+	ByUsername(ctx context.Context, username string) (*User, error)
+	ByEmail(ctx context.Context, email string) (*User, error)
+}
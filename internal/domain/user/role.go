@@ -0,0 +1,65 @@
+package user
+
+import "errors"
+
+// ErrInvalidRole is returned for unknown roles.
+var ErrInvalidRole = errors.New("user: invalid role")
+
+// Role grants a user a set of permissions. Every role has the permissions
+// of the roles before it.
+type Role string
+
+const (
+	// RolePlayer builds decks. New users are players.
+	RolePlayer Role = "player"
+	// RoleDesigner also maintains the card catalog.
+	RoleDesigner Role = "designer"
+	// RoleAdmin also manages users.
+	RoleAdmin Role = "admin"
+)
+
+// Permission is an action only some roles may take.
+type Permission string
+
+const (
+	// PermReadCards allows searching and exporting cards.
+	PermReadCards Permission = "cards:read"
+	// PermWriteCards allows creating, updating and retiring cards.
+	PermWriteCards Permission = "cards:write"
+	// PermReadDecks allows reading the decks of the user.
+	PermReadDecks Permission = "decks:read"
+	// PermWriteDecks allows changing the decks of the user.
+	PermWriteDecks Permission = "decks:write"
+	// PermAccount allows managing the account of the user itself, such as
+	// logging out or deleting it.
+	PermAccount Permission = "account"
+	// PermAdmin allows the admin endpoints, such as disabling users.
+	PermAdmin Permission = "admin"
+)
+
+var playerPermissions = []Permission{PermReadCards, PermReadDecks, PermWriteDecks, PermAccount}
+
+var rolePermissions = map[Role][]Permission{
+	RolePlayer:   playerPermissions,
+	RoleDesigner: append([]Permission{PermWriteCards}, playerPermissions...),
+	RoleAdmin:    append([]Permission{PermWriteCards, PermAdmin}, playerPermissions...),
+}
+
+// ParseRole returns the role named s or ErrInvalidRole.
+func ParseRole(s string) (Role, error) {
+	r := Role(s)
+	if _, ok := rolePermissions[r]; !ok {
+		return "", ErrInvalidRole
+	}
+	return r, nil
+}
+
+// Can reports whether the role has permission p.
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
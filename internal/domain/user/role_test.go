@@ -0,0 +1,36 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	for _, tc := range []struct {
+		role         Role
+		cards, admin bool
+	}{
+		{RolePlayer, false, false},
+		{RoleDesigner, true, false},
+		{RoleAdmin, true, true},
+		{"", false, false},
+	} {
+		if got := tc.role.Can(PermWriteCards); got != tc.cards {
+			t.Fatalf("%q: expected cards:write %v got %v", tc.role, tc.cards, got)
+		}
+		if got := tc.role.Can(PermAdmin); got != tc.admin {
+			t.Fatalf("%q: expected admin %v got %v", tc.role, tc.admin, got)
+		}
+		if got := tc.role.Can(PermWriteDecks); got != (tc.role != "") {
+			t.Fatalf("%q: expected every role to build decks got %v", tc.role, got)
+		}
+	}
+	if r, err := ParseRole("designer"); err != nil || r != RoleDesigner {
+		t.Fatalf("unexpected role %q %v", r, err)
+	}
+	for _, s := range []string{"", "Admin", "root"} {
+		if _, err := ParseRole(s); !errors.Is(err, ErrInvalidRole) {
+			t.Fatalf("%q: expected ErrInvalidRole got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package user
+
+import (
+	"errors"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound is returned for unknown users.
+	ErrNotFound = errors.New("user: not found")
+	// ErrUsernameTaken is returned when another user has the username.
+	ErrUsernameTaken = errors.New("user: username taken")
+	// ErrEmailTaken is returned when another user has the email address.
+	ErrEmailTaken = errors.New("user: email taken")
+	// ErrInvalidUsername is returned for malformed usernames.
+	ErrInvalidUsername = errors.New("user: invalid username")
+	// ErrInvalidEmail is returned for malformed email addresses.
+	ErrInvalidEmail = errors.New("user: invalid email")
+	// ErrWeakPassword is returned for passwords shorter than MinPasswordLength.
+	ErrWeakPassword = errors.New("user: password too short")
+)
+
+// MinPasswordLength is the minimum number of characters of a password.
+const MinPasswordLength = 8
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+// User is an account of the service. Usernames and email addresses are
+// unique regardless of case. Disabled users cannot log in.
+type User struct {
+	ID           uuid.UUID
+	Username     string
+	Email        string
+	PasswordHash string
+	Role         Role
+	Disabled     bool
+	CreatedAt    time.Time
+}
+
+// NewUser validates the username and email of a new account and returns
+// it with the given password hash. The email address is stored lower case
+// and the user is a player.
+func NewUser(username, email, passwordHash string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if !usernamePattern.MatchString(username) {
+		return nil, ErrInvalidUsername
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, ErrInvalidEmail
+	}
+	return &User{
+		ID:           uuid.New(),
+		Username:     username,
+		Email:        email,
+		PasswordHash: passwordHash,
+		Role:         RolePlayer,
+		CreatedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// CheckPassword returns ErrWeakPassword unless password is long enough.
+func CheckPassword(password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+	return nil
+}
+
+// Clone returns a copy of u.
+func (u *User) Clone() *User {
+	c := *u
+	return &c
+}
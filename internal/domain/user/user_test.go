@@ -0,0 +1,38 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewUser(t *testing.T) {
+	u, err := NewUser(" alice ", "Alice@Example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Username != "alice" || u.Email != "alice@example.com" || u.PasswordHash != "hash" || u.Role != RolePlayer || u.Disabled || u.CreatedAt.IsZero() {
+		t.Fatalf("unexpected user %+v", u)
+	}
+	for _, tc := range []struct {
+		username, email string
+		err             error
+	}{
+		{"al", "a@example.com", ErrInvalidUsername},
+		{"al ice", "a@example.com", ErrInvalidUsername},
+		{"alice", "not an email", ErrInvalidEmail},
+		{"alice", "Alice <a@example.com>", ErrInvalidEmail},
+	} {
+		if _, err := NewUser(tc.username, tc.email, "hash"); !errors.Is(err, tc.err) {
+			t.Fatalf("%q %q: expected %v got %v", tc.username, tc.email, tc.err, err)
+		}
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	if err := CheckPassword("short"); !errors.Is(err, ErrWeakPassword) {
+		t.Fatalf("expected ErrWeakPassword got %v", err)
+	}
+	if err := CheckPassword("long enough"); err != nil {
+		t.Fatal(err)
+	}
+}
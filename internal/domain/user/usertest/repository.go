@@ -0,0 +1,157 @@
+// Package usertest provides a conformance suite for user.Repository
+// implementations.
+package usertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/user"
+	"github.com/google/uuid"
+)
+
+// RepositorySuite is the conformance suite of user.Repository implementations.
+var RepositorySuite = conformance.Suite[user.Repository]{
+	{Name: "CreateAndLookup", Fn: testCreateAndLookup},
+	{Name: "NotFound", Fn: testNotFound},
+	{Name: "Uniqueness", Fn: testUniqueness},
+	{Name: "Update", Fn: testUpdate},
+	{Name: "Delete", Fn: testDelete},
+	{Name: "ConcurrentCreates", Fn: testConcurrentCreates},
+}
+
+func newUser(t *testing.T, username string) *user.User {
+	t.Helper()
+	u, err := user.NewUser(username, username+"@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func create(t *testing.T, repo user.Repository, u *user.User) {
+	t.Helper()
+	if err := repo.Create(context.Background(), u); err != nil {
+		t.Fatalf("create %s: %v", u.Username, err)
+	}
+}
+
+func testCreateAndLookup(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	u := newUser(t, "alice")
+	create(t, repo, u)
+	got, err := repo.ByID(ctx, u.ID)
+	if err != nil || got == nil || got.Username != "alice" || got.Email != "alice@example.com" || got.PasswordHash != "hash" || got.Role != user.RolePlayer || got.Disabled {
+		t.Fatalf("unexpected user %+v %v", got, err)
+	}
+	// the returned user must not alias stored state
+	got.Username = "mallory"
+	if got, err := repo.ByUsername(ctx, "ALICE"); err != nil || got == nil || got.ID != u.ID || got.Username != "alice" {
+		t.Fatalf("expected a case-insensitive username match got %+v %v", got, err)
+	}
+	if got, err := repo.ByEmail(ctx, "Alice@Example.com"); err != nil || got == nil || got.ID != u.ID {
+		t.Fatalf("expected a case-insensitive email match got %+v %v", got, err)
+	}
+}
+
+func testNotFound(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	if got, err := repo.ByID(ctx, uuid.New()); err != nil || got != nil {
+		t.Fatalf("expected nil, nil got %+v %v", got, err)
+	}
+	if got, err := repo.ByUsername(ctx, "nobody"); err != nil || got != nil {
+		t.Fatalf("expected nil, nil got %+v %v", got, err)
+	}
+	if got, err := repo.ByEmail(ctx, "nobody@example.com"); err != nil || got != nil {
+		t.Fatalf("expected nil, nil got %+v %v", got, err)
+	}
+	if err := repo.Update(ctx, newUser(t, "nobody")); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("expected user.ErrNotFound got %v", err)
+	}
+}
+
+func testUniqueness(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	create(t, repo, newUser(t, "alice"))
+	dup := newUser(t, "Alice")
+	dup.Email = "other@example.com"
+	if err := repo.Create(ctx, dup); !errors.Is(err, user.ErrUsernameTaken) {
+		t.Fatalf("expected user.ErrUsernameTaken got %v", err)
+	}
+	dup = newUser(t, "bob")
+	dup.Email = "alice@example.com"
+	if err := repo.Create(ctx, dup); !errors.Is(err, user.ErrEmailTaken) {
+		t.Fatalf("expected user.ErrEmailTaken got %v", err)
+	}
+	if got, _ := repo.ByUsername(ctx, "bob"); got != nil {
+		t.Fatalf("expected a failed create to store nothing got %+v", got)
+	}
+}
+
+func testUpdate(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	alice, bob := newUser(t, "alice"), newUser(t, "bob")
+	create(t, repo, alice)
+	create(t, repo, bob)
+	alice.Disabled = true
+	alice.PasswordHash = "new"
+	alice.Role = user.RoleDesigner
+	alice.Email = "alice@example.org"
+	if err := repo.Update(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.ByID(ctx, alice.ID)
+	if err != nil || !got.Disabled || got.PasswordHash != "new" || got.Role != user.RoleDesigner || got.Email != "alice@example.org" {
+		t.Fatalf("unexpected user %+v %v", got, err)
+	}
+	if got, _ := repo.ByEmail(ctx, "alice@example.com"); got != nil {
+		t.Fatalf("expected the old email to be free got %+v", got)
+	}
+	bob.Email = "alice@example.org"
+	if err := repo.Update(ctx, bob); !errors.Is(err, user.ErrEmailTaken) {
+		t.Fatalf("expected user.ErrEmailTaken got %v", err)
+	}
+	bob.Email, bob.Username = "bob@example.com", "alice"
+	if err := repo.Update(ctx, bob); !errors.Is(err, user.ErrUsernameTaken) {
+		t.Fatalf("expected user.ErrUsernameTaken got %v", err)
+	}
+}
+
+func testDelete(t *testing.T, repo user.Repository) {
+	ctx := context.Background()
+	u := newUser(t, "alice")
+	create(t, repo, u)
+	if err := repo.Delete(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.ByID(ctx, u.ID); err != nil || got != nil {
+		t.Fatalf("expected the user to be gone got %+v %v", got, err)
+	}
+	if err := repo.Delete(ctx, u.ID); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("expected user.ErrNotFound got %v", err)
+	}
+	// the username and email can be taken again
+	create(t, repo, newUser(t, "alice"))
+}
+
+func testConcurrentCreates(t *testing.T, repo user.Repository) {
+	errs := conformance.Concurrently(5, func(i int) error {
+		u, _ := user.NewUser("alice", fmt.Sprintf("alice%d@example.com", i), "hash")
+		return repo.Create(context.Background(), u)
+	})
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, user.ErrUsernameTaken):
+			t.Fatalf("expected user.ErrUsernameTaken got %v", err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("expected exactly one user created got %d", created)
+	}
+}
@@ -0,0 +1,62 @@
+// Package auditstore implements audit.Repository in memory and in MySQL.
+package auditstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"demo/internal/domain/audit"
+)
+
+// InMemoryStore is a process-local audit log.
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	entries []audit.Entry
+}
+
+// NewInMemoryStore creates the store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{}
+}
+
+// Append implements audit.Repository.
+func (s *InMemoryStore) Append(ctx context.Context, e audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// Search implements audit.Repository.
+func (s *InMemoryStore) Search(ctx context.Context, q audit.Query) ([]audit.Entry, int, error) {
+	s.mu.RLock()
+	var matches []audit.Entry
+	for _, e := range s.entries {
+		if q.Matches(e) {
+			matches = append(matches, e)
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(matches, func(i, j int) bool {
+		if !matches[i].Time.Equal(matches[j].Time) {
+			return matches[i].Time.After(matches[j].Time)
+		}
+		return matches[i].ID.String() > matches[j].ID.String()
+	})
+	total := len(matches)
+	offset := q.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= total {
+		return []audit.Entry{}, total, nil
+	}
+	matches = matches[offset:]
+	if q.Limit > 0 && q.Limit < len(matches) {
+		matches = matches[:q.Limit]
+	}
+	return matches, total, nil
+}
+
+var _ audit.Repository = (*InMemoryStore)(nil)
@@ -0,0 +1,14 @@
+package auditstore
+
+import (
+	"testing"
+
+	"demo/internal/domain/audit"
+	"demo/internal/domain/audit/audittest"
+)
+
+func TestInMemoryConformance(t *testing.T) {
+	audittest.RepositorySuite.Run(t, func(t *testing.T) audit.Repository {
+		return NewInMemoryStore()
+	})
+}
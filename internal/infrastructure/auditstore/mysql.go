@@ -0,0 +1,149 @@
+package auditstore
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"demo/internal/domain/audit"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// EntryRecord is a stored audit entry. Ids left unset are stored empty.
+type EntryRecord struct {
+	ID        string    `gorm:"primaryKey;size:36"`
+	Time      time.Time `gorm:"not null;index"`
+	Action    string    `gorm:"size:32;not null;index"`
+	ActorID   string    `gorm:"size:36;not null;index"`
+	APIKeyID  string    `gorm:"size:36;not null"`
+	Target    string    `gorm:"size:255;not null;index"`
+	Details   string    `gorm:"size:1024;not null"`
+	IP        string    `gorm:"size:45;not null"`
+	UserAgent string    `gorm:"size:512;not null"`
+	TraceID   string    `gorm:"size:32;not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (EntryRecord) TableName() string { return "audit_log" }
+
+// MySQLStore is a GORM-based audit log. It only ever inserts rows.
+type MySQLStore struct {
+	DB *gorm.DB
+}
+
+// NewMySQLStore creates the audit_log table if needed.
+func NewMySQLStore(db *gorm.DB) (*MySQLStore, error) {
+	if err := db.AutoMigrate(&EntryRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLStore{DB: db}, nil
+}
+
+func optionalID(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+	return id.String()
+}
+
+func parseOptionalID(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(s)
+}
+
+// truncate cuts s to n bytes so that long client-supplied values such as
+// user agents fit their column.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// Append implements audit.Repository.
+func (s *MySQLStore) Append(ctx context.Context, e audit.Entry) error {
+	rec := EntryRecord{
+		ID:        e.ID.String(),
+		Time:      e.Time,
+		Action:    string(e.Action),
+		ActorID:   optionalID(e.ActorID),
+		APIKeyID:  optionalID(e.APIKeyID),
+		Target:    truncate(e.Target, 255),
+		Details:   truncate(e.Details, 1024),
+		IP:        truncate(e.IP, 45),
+		UserAgent: truncate(e.UserAgent, 512),
+		TraceID:   e.TraceID,
+	}
+	return s.DB.WithContext(ctx).Create(&rec).Error
+}
+
+// Search implements audit.Repository.
+func (s *MySQLStore) Search(ctx context.Context, q audit.Query) ([]audit.Entry, int, error) {
+	filter := func() *gorm.DB {
+		tx := s.DB.WithContext(ctx).Model(&EntryRecord{})
+		if q.Action != "" {
+			tx = tx.Where("action = ?", string(q.Action))
+		}
+		if q.ActorID != uuid.Nil {
+			tx = tx.Where("actor_id = ?", q.ActorID.String())
+		}
+		if q.Target != "" {
+			tx = tx.Where("target = ?", q.Target)
+		}
+		if !q.Since.IsZero() {
+			tx = tx.Where("time >= ?", q.Since)
+		}
+		if !q.Until.IsZero() {
+			tx = tx.Where("time < ?", q.Until)
+		}
+		return tx
+	}
+	var total int64
+	if err := filter().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset, limit := q.Offset, q.Limit
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	var recs []EntryRecord
+	if err := filter().Order("time DESC, id DESC").Offset(offset).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, 0, err
+	}
+	entries := make([]audit.Entry, 0, len(recs))
+	for _, rec := range recs {
+		id, err := uuid.Parse(rec.ID)
+		if err != nil {
+			return nil, 0, err
+		}
+		actor, err := parseOptionalID(rec.ActorID)
+		if err != nil {
+			return nil, 0, err
+		}
+		key, err := parseOptionalID(rec.APIKeyID)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, audit.Entry{
+			ID:        id,
+			Time:      rec.Time.UTC(),
+			Action:    audit.Action(rec.Action),
+			ActorID:   actor,
+			APIKeyID:  key,
+			Target:    rec.Target,
+			Details:   rec.Details,
+			IP:        rec.IP,
+			UserAgent: rec.UserAgent,
+			TraceID:   rec.TraceID,
+		})
+	}
+	return entries, int(total), nil
+}
+
+var _ audit.Repository = (*MySQLStore)(nil)
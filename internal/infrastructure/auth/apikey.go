@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"demo/internal/domain/audit"
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned for unknown API keys.
+	ErrAPIKeyNotFound = errors.New("auth: api key not found")
+	// ErrInvalidScope is returned for API keys without scopes or with scopes
+	// that cannot be granted to keys.
+	ErrInvalidScope = errors.New("auth: invalid scope")
+	// ErrInvalidExpiry is returned for API keys expiring in the past.
+	ErrInvalidExpiry = errors.New("auth: invalid expiry")
+)
+
+const (
+	// APIKeyPrefix starts every API key, telling them apart from access
+	// tokens.
+	APIKeyPrefix = "ck_"
+	// apiKeyTouchInterval bounds how often the last use of a key is saved.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyScopes are the permissions an API key can be granted.
+var APIKeyScopes = []user.Permission{user.PermReadCards, user.PermWriteCards, user.PermReadDecks}
+
+// APIKey gives a service access to the API on behalf of a user, limited to
+// its scopes and to its tenant. Only a hash of the secret key is stored;
+// Prefix holds its first characters to recognise it.
+type APIKey struct {
+	ID         uuid.UUID
+	Name       string
+	Prefix     string
+	Hash       string
+	UserID     uuid.UUID
+	Tenant     string
+	Scopes     []user.Permission
+	CreatedAt  time.Time
+	ExpiresAt  time.Time // zero for keys that do not expire
+	LastUsedAt time.Time // zero for keys never used
+	RevokedAt  time.Time // zero for active keys
+}
+
+// Active reports whether the key can be used at time now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt.IsZero() && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
+
+// Clone returns a copy of k.
+func (k *APIKey) Clone() *APIKey {
+	c := *k
+	c.Scopes = append([]user.Permission(nil), k.Scopes...)
+	return &c
+}
+
+// checkScopes returns ErrInvalidScope unless scopes is a non-empty list of
+// APIKeyScopes.
+func checkScopes(scopes []user.Permission) error {
+	if len(scopes) == 0 {
+		return ErrInvalidScope
+	}
+	for _, s := range scopes {
+		valid := false
+		for _, allowed := range APIKeyScopes {
+			valid = valid || s == allowed
+		}
+		if !valid {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
+
+// APIKeyRepository stores API keys.
+type APIKeyRepository interface {
+	// Create stores a new key.
+	Create(ctx context.Context, k *APIKey) error
+	// ByID returns the key with the given id, nil when unknown.
+	ByID(ctx context.Context, id uuid.UUID) (*APIKey, error)
+	// ByHash returns the key with the given hash, nil when unknown.
+	ByHash(ctx context.Context, hash string) (*APIKey, error)
+	// List returns every key, revoked and expired ones included, oldest
+	// first.
+	List(ctx context.Context) ([]*APIKey, error)
+	// Revoke marks a key revoked at the given time. It returns
+	// ErrAPIKeyNotFound for unknown keys and keeps the time of keys already
+	// revoked.
+	Revoke(ctx context.Context, id uuid.UUID, at time.Time) error
+	// Touch records the last use of a key. Unknown keys are ignored.
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// InMemoryAPIKeyStore is a process-local API key repository.
+type InMemoryAPIKeyStore struct {
+	mu   sync.RWMutex
+	keys map[uuid.UUID]*APIKey
+}
+
+// NewInMemoryAPIKeyStore creates the store.
+func NewInMemoryAPIKeyStore() *InMemoryAPIKeyStore {
+	return &InMemoryAPIKeyStore{keys: make(map[uuid.UUID]*APIKey)}
+}
+
+// Create implements APIKeyRepository.
+func (s *InMemoryAPIKeyStore) Create(ctx context.Context, k *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[k.ID] = k.Clone()
+	return nil
+}
+
+// ByID implements APIKeyRepository.
+func (s *InMemoryAPIKeyStore) ByID(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if k, ok := s.keys[id]; ok {
+		return k.Clone(), nil
+	}
+	return nil, nil
+}
+
+// ByHash implements APIKeyRepository.
+func (s *InMemoryAPIKeyStore) ByHash(ctx context.Context, hash string) (*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, k := range s.keys {
+		if k.Hash == hash {
+			return k.Clone(), nil
+		}
+	}
+	return nil, nil
+}
+
+// List implements APIKeyRepository.
+func (s *InMemoryAPIKeyStore) List(ctx context.Context) ([]*APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]*APIKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		keys = append(keys, k.Clone())
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID.String() < keys[j].ID.String()
+	})
+	return keys, nil
+}
+
+// Revoke implements APIKeyRepository.
+func (s *InMemoryAPIKeyStore) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k, ok := s.keys[id]
+	if !ok {
+		return ErrAPIKeyNotFound
+	}
+	if k.RevokedAt.IsZero() {
+		k.RevokedAt = at
+	}
+	return nil
+}
+
+// Touch implements APIKeyRepository.
+func (s *InMemoryAPIKeyStore) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if k, ok := s.keys[id]; ok {
+		k.LastUsedAt = at
+	}
+	return nil
+}
+
+// CreateAPIKey issues an API key acting for a user within scopes in the
+// tenant of ctx, expiring at expiresAt unless that is zero. The secret key
+// is returned only here.
+func (s *Service) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []user.Permission, expiresAt time.Time) (*APIKey, string, error) {
+	if err := checkScopes(scopes); err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	if !expiresAt.IsZero() && !expiresAt.After(now) {
+		return nil, "", ErrInvalidExpiry
+	}
+	u, err := s.Users.ByID(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if u == nil {
+		return nil, "", user.ErrNotFound
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	k := &APIKey{
+		ID:        uuid.New(),
+		Name:      strings.TrimSpace(name),
+		Prefix:    key[:len(APIKeyPrefix)+6],
+		Hash:      tokenHash(key),
+		UserID:    userID,
+		Tenant:    tenant.FromContext(ctx),
+		Scopes:    append([]user.Permission(nil), scopes...),
+		CreatedAt: now,
+	}
+	if !expiresAt.IsZero() {
+		k.ExpiresAt = expiresAt.UTC()
+	}
+	if err := s.APIKeys.Create(ctx, k); err != nil {
+		return nil, "", err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.APIKeyCreated, Target: k.ID.String(), Details: fmt.Sprintf("user %s, tenant %s, scopes %v", userID, k.Tenant, scopes)})
+	return k, key, nil
+}
+
+// ListAPIKeys returns every API key, oldest first.
+func (s *Service) ListAPIKeys(ctx context.Context) ([]*APIKey, error) {
+	return s.APIKeys.List(ctx)
+}
+
+// RevokeAPIKey revokes an API key for good.
+func (s *Service) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	if err := s.APIKeys.Revoke(ctx, id, time.Now().UTC()); err != nil {
+		return err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.APIKeyRevoked, Target: id.String()})
+	return nil
+}
+
+// authenticateKey returns the principal of an active API key whose user may
+// log in, recording its use.
+func (s *Service) authenticateKey(ctx context.Context, key string) (Principal, error) {
+	k, err := s.APIKeys.ByHash(ctx, tokenHash(key))
+	if err != nil {
+		return Principal{}, err
+	}
+	now := time.Now().UTC()
+	if k == nil || !k.Active(now) {
+		return Principal{}, ErrInvalidToken
+	}
+	u, err := s.Users.ByID(ctx, k.UserID)
+	if err != nil {
+		return Principal{}, err
+	}
+	if u == nil || u.Disabled {
+		return Principal{}, ErrInvalidToken
+	}
+	if now.Sub(k.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.APIKeys.Touch(ctx, k.ID, now); err != nil {
+			return Principal{}, err
+		}
+	}
+	return Principal{UserID: u.ID, Role: u.Role, APIKeyID: k.ID, Scopes: k.Scopes, Tenant: k.Tenant}, nil
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"demo/internal/domain/user"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyRecord is a stored API key. Scopes are kept as a comma separated
+// list and unset times as NULL.
+type APIKeyRecord struct {
+	ID         string     `gorm:"primaryKey;size:36"`
+	Name       string     `gorm:"size:100;not null"`
+	Prefix     string     `gorm:"size:16;not null"`
+	Hash       string     `gorm:"size:64;not null;uniqueIndex:idx_api_keys_hash"`
+	UserID     string     `gorm:"size:36;not null;index"`
+	Tenant     string     `gorm:"size:64;not null"`
+	Scopes     string     `gorm:"size:255;not null"`
+	CreatedAt  time.Time  `gorm:"not null"`
+	ExpiresAt  *time.Time `gorm:"index"`
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+// TableName implements gorm's tabler interface.
+func (APIKeyRecord) TableName() string { return "api_keys" }
+
+// MySQLAPIKeyStore is a GORM-based API key repository.
+type MySQLAPIKeyStore struct {
+	DB *gorm.DB
+}
+
+// NewMySQLAPIKeyStore creates the api_keys table if needed.
+func NewMySQLAPIKeyStore(db *gorm.DB) (*MySQLAPIKeyStore, error) {
+	if err := db.AutoMigrate(&APIKeyRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLAPIKeyStore{DB: db}, nil
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func timeOrZero(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
+
+func apiKeyRecord(k *APIKey) APIKeyRecord {
+	scopes := make([]string, 0, len(k.Scopes))
+	for _, s := range k.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	return APIKeyRecord{
+		ID:         k.ID.String(),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Hash:       k.Hash,
+		UserID:     k.UserID.String(),
+		Tenant:     k.Tenant,
+		Scopes:     strings.Join(scopes, ","),
+		CreatedAt:  k.CreatedAt,
+		ExpiresAt:  optionalTime(k.ExpiresAt),
+		LastUsedAt: optionalTime(k.LastUsedAt),
+		RevokedAt:  optionalTime(k.RevokedAt),
+	}
+}
+
+func (r *APIKeyRecord) apiKey() (*APIKey, error) {
+	id, err := uuid.Parse(r.ID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(r.UserID)
+	if err != nil {
+		return nil, err
+	}
+	k := &APIKey{
+		ID:         id,
+		Name:       r.Name,
+		Prefix:     r.Prefix,
+		Hash:       r.Hash,
+		UserID:     userID,
+		Tenant:     r.Tenant,
+		CreatedAt:  r.CreatedAt.UTC(),
+		ExpiresAt:  timeOrZero(r.ExpiresAt),
+		LastUsedAt: timeOrZero(r.LastUsedAt),
+		RevokedAt:  timeOrZero(r.RevokedAt),
+	}
+	for _, s := range strings.Split(r.Scopes, ",") {
+		if s != "" {
+			k.Scopes = append(k.Scopes, user.Permission(s))
+		}
+	}
+	return k, nil
+}
+
+// Create implements APIKeyRepository.
+func (s *MySQLAPIKeyStore) Create(ctx context.Context, k *APIKey) error {
+	rec := apiKeyRecord(k)
+	return s.DB.WithContext(ctx).Create(&rec).Error
+}
+
+// ByID implements APIKeyRepository.
+func (s *MySQLAPIKeyStore) ByID(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	return s.find(ctx, "id = ?", id.String())
+}
+
+// ByHash implements APIKeyRepository.
+func (s *MySQLAPIKeyStore) ByHash(ctx context.Context, hash string) (*APIKey, error) {
+	return s.find(ctx, "hash = ?", hash)
+}
+
+func (s *MySQLAPIKeyStore) find(ctx context.Context, query string, arg interface{}) (*APIKey, error) {
+	var rec APIKeyRecord
+	err := s.DB.WithContext(ctx).Where(query, arg).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec.apiKey()
+}
+
+// List implements APIKeyRepository.
+func (s *MySQLAPIKeyStore) List(ctx context.Context) ([]*APIKey, error) {
+	var recs []APIKeyRecord
+	if err := s.DB.WithContext(ctx).Order("created_at, id").Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	keys := make([]*APIKey, 0, len(recs))
+	for i := range recs {
+		k, err := recs[i].apiKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Revoke implements APIKeyRepository.
+func (s *MySQLAPIKeyStore) Revoke(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&APIKeyRecord{}).Where("id = ?", id.String()).Count(&n).Error; err != nil {
+			return err
+		}
+		if n == 0 {
+			return ErrAPIKeyNotFound
+		}
+		return tx.Model(&APIKeyRecord{}).Where("id = ? AND revoked_at IS NULL", id.String()).Update("revoked_at", at).Error
+	})
+}
+
+// Touch implements APIKeyRepository.
+func (s *MySQLAPIKeyStore) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return s.DB.WithContext(ctx).Model(&APIKeyRecord{}).Where("id = ?", id.String()).Update("last_used_at", at).Error
+}
+
+var _ APIKeyRepository = (*MySQLAPIKeyStore)(nil)
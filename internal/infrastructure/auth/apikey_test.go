@@ -0,0 +1,99 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/auth"
+	"demo/internal/infrastructure/auth/authtest"
+	"demo/internal/infrastructure/userstore"
+	"github.com/google/uuid"
+)
+
+func TestInMemoryAPIKeyStoreConformance(t *testing.T) {
+	authtest.APIKeySuite.Run(t, func(t *testing.T) auth.APIKeyRepository {
+		return auth.NewInMemoryAPIKeyStore()
+	})
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	s := auth.NewService(userstore.NewInMemoryStore())
+	s.Hasher = auth.Bcrypt{Cost: 4}
+	designer, _ := s.Register(ctx, "designer", "designer@example.com", "password")
+	_ = s.SetRole(ctx, designer.ID, user.RoleDesigner)
+	player, _ := s.Register(ctx, "player", "player@example.com", "password")
+
+	k, secret, err := s.CreateAPIKey(ctx, designer.ID, " ingest ", []user.Permission{user.PermReadCards, user.PermWriteCards}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, auth.APIKeyPrefix) || !strings.HasPrefix(secret, k.Prefix) || k.Name != "ingest" || strings.Contains(k.Hash, secret) {
+		t.Fatalf("unexpected key %+v %s", k, secret)
+	}
+	p, ok := s.Authenticate(ctx, secret)
+	if !ok || p.UserID != designer.ID || p.APIKeyID != k.ID || p.Tenant != tenant.Default {
+		t.Fatalf("unexpected principal %+v %v", p, ok)
+	}
+	if !p.Can(user.PermWriteCards) || p.Can(user.PermReadDecks) || p.Can(user.PermAccount) {
+		t.Fatalf("expected the key to be limited to its scopes got %+v", p)
+	}
+	if stored, _ := s.APIKeys.ByID(ctx, k.ID); stored.LastUsedAt.IsZero() {
+		t.Fatal("expected the use of the key to be recorded")
+	}
+
+	// a key cannot do more than its user
+	k2, secret2, _ := s.CreateAPIKey(ctx, player.ID, "partner", []user.Permission{user.PermWriteCards}, time.Now().Add(time.Hour))
+	if p, ok := s.Authenticate(ctx, secret2); !ok || p.Can(user.PermWriteCards) {
+		t.Fatalf("expected a player's key to lack cards:write got %+v %v", p, ok)
+	}
+
+	if err := s.RevokeAPIKey(ctx, k.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Authenticate(ctx, secret); ok {
+		t.Fatal("expected a revoked key to be rejected")
+	}
+	if err := s.Disable(ctx, player.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Authenticate(ctx, secret2); ok {
+		t.Fatal("expected the keys of a disabled user to be rejected")
+	}
+	if keys, _ := s.ListAPIKeys(ctx); len(keys) != 2 || keys[0].ID != k.ID || keys[1].ID != k2.ID {
+		t.Fatalf("unexpected keys %+v", keys)
+	}
+
+	expiring := &auth.APIKey{ID: uuid.New(), Hash: "h", UserID: designer.ID, Scopes: []user.Permission{user.PermReadCards}, ExpiresAt: time.Now().Add(-time.Second)}
+	if expiring.Active(time.Now()) {
+		t.Fatal("expected an expired key to be inactive")
+	}
+	if _, ok := s.Authenticate(ctx, auth.APIKeyPrefix+"unknown"); ok {
+		t.Fatal("expected an unknown key to be rejected")
+	}
+
+	for _, tc := range []struct {
+		userID  uuid.UUID
+		scopes  []user.Permission
+		expires time.Time
+		err     error
+	}{
+		{designer.ID, nil, time.Time{}, auth.ErrInvalidScope},
+		{designer.ID, []user.Permission{user.PermAdmin}, time.Time{}, auth.ErrInvalidScope},
+		{designer.ID, []user.Permission{user.PermWriteDecks}, time.Time{}, auth.ErrInvalidScope},
+		{designer.ID, []user.Permission{user.PermReadCards}, time.Now().Add(-time.Hour), auth.ErrInvalidExpiry},
+		{uuid.New(), []user.Permission{user.PermReadCards}, time.Time{}, user.ErrNotFound},
+	} {
+		if _, _, err := s.CreateAPIKey(ctx, tc.userID, "k", tc.scopes, tc.expires); !errors.Is(err, tc.err) {
+			t.Fatalf("%+v: expected %v got %v", tc, tc.err, err)
+		}
+	}
+	if err := s.RevokeAPIKey(ctx, uuid.New()); !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected auth.ErrAPIKeyNotFound got %v", err)
+	}
+}
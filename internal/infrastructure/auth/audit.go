@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"demo/internal/domain/audit"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Client describes who sent a request: the address and user agent recorded
+// in the audit log. Login locks out client addresses after too many
+// failures.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+type clientKey struct{}
+
+// WithClient returns a copy of ctx carrying the client of a request.
+func WithClient(ctx context.Context, c Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// ClientFromContext returns the client carried by ctx, the zero Client if
+// none.
+func ClientFromContext(ctx context.Context) Client {
+	c, _ := ctx.Value(clientKey{}).(Client)
+	return c
+}
+
+// Record appends an entry to the audit log. Its id, time, client and trace id
+// are filled in from ctx, as are its actor and API key unless e names an
+// actor.
+func (s *Service) Record(ctx context.Context, e audit.Entry) error {
+	if s.Audit == nil {
+		return nil
+	}
+	e.ID = uuid.New()
+	e.Time = time.Now().UTC()
+	if e.ActorID == uuid.Nil {
+		p, _ := FromContext(ctx)
+		e.ActorID, e.APIKeyID = p.UserID, p.APIKeyID
+	}
+	client := ClientFromContext(ctx)
+	e.IP, e.UserAgent = client.IP, client.UserAgent
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		e.TraceID = sc.TraceID().String()
+	}
+	return s.Audit.Append(ctx, e)
+}
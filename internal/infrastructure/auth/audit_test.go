@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"demo/internal/domain/audit"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/userstore"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// auditLog returns the actions recorded by s, oldest first.
+func auditLog(t *testing.T, s *Service) []audit.Entry {
+	t.Helper()
+	entries, _, err := s.Audit.Search(context.Background(), audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries
+}
+
+func TestRecord(t *testing.T) {
+	s := NewService(userstore.NewInMemoryStore())
+	admin := Principal{UserID: uuid.New(), Role: user.RoleAdmin, APIKeyID: uuid.New()}
+	ctx := NewContext(WithClient(context.Background(), Client{IP: "10.0.0.1", UserAgent: "curl/8.0"}), admin)
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	ctx = trace.ContextWithSpanContext(ctx, sc)
+	if err := s.Record(ctx, audit.Entry{Action: audit.UserDisabled, Target: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	e := auditLog(t, s)[0]
+	if e.ID == uuid.Nil || e.Time.IsZero() || e.ActorID != admin.UserID || e.APIKeyID != admin.APIKeyID ||
+		e.IP != "10.0.0.1" || e.UserAgent != "curl/8.0" || e.TraceID != sc.TraceID().String() {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+
+	other := uuid.New()
+	_ = s.Record(ctx, audit.Entry{Action: audit.LoginSucceeded, ActorID: other})
+	if e := auditLog(t, s)[1]; e.ActorID != other || e.APIKeyID != uuid.Nil {
+		t.Fatalf("expected the actor of the entry to be kept got %+v", e)
+	}
+	s.Audit = nil
+	if err := s.Record(ctx, audit.Entry{Action: audit.UserEnabled}); err != nil {
+		t.Fatalf("expected services without a log to record nothing got %v", err)
+	}
+}
+
+func TestAuditedActions(t *testing.T) {
+	s := NewService(userstore.NewInMemoryStore())
+	s.Hasher = Bcrypt{Cost: 4}
+	ctx := WithClient(context.Background(), Client{IP: "10.0.0.1"})
+	alice, _ := s.Register(ctx, "alice", "alice@example.com", "password")
+	_, _ = s.Login(ctx, "alice", "wrong")
+	tokens, _ := s.Login(ctx, "alice", "password")
+	tokens, _ = s.Refresh(ctx, tokens.RefreshToken)
+	_ = s.Logout(ctx, tokens.AccessToken, tokens.RefreshToken)
+
+	adminID := uuid.New()
+	admin := NewContext(ctx, Principal{UserID: adminID, Role: user.RoleAdmin})
+	k, _, _ := s.CreateAPIKey(admin, alice.ID, "ci", []user.Permission{user.PermReadCards}, time.Time{})
+	_ = s.RevokeAPIKey(admin, k.ID)
+	_ = s.SetRole(admin, alice.ID, user.RoleAdmin)
+	_ = s.Disable(admin, alice.ID)
+	_ = s.Enable(admin, alice.ID)
+	_ = s.Delete(admin, alice.ID)
+
+	want := []struct {
+		action audit.Action
+		actor  uuid.UUID
+	}{
+		{audit.LoginFailed, uuid.Nil},
+		{audit.LoginSucceeded, alice.ID},
+		{audit.TokenRefreshed, alice.ID},
+		{audit.TokenRevoked, alice.ID},
+		{audit.APIKeyCreated, adminID},
+	}
+	entries := auditLog(t, s)
+	actions := make([]audit.Action, len(entries))
+	for i, e := range entries {
+		actions[i] = e.Action
+	}
+	if len(entries) != 10 {
+		t.Fatalf("unexpected log %v", actions)
+	}
+	for i, w := range want {
+		if entries[i].Action != w.action || entries[i].ActorID != w.actor || entries[i].IP != "10.0.0.1" {
+			t.Fatalf("entry %d: expected %s by %s got %+v", i, w.action, w.actor, entries[i])
+		}
+	}
+	for i, action := range []audit.Action{audit.APIKeyRevoked, audit.UserRoleChanged, audit.UserDisabled, audit.UserEnabled, audit.UserDeleted} {
+		if e := entries[len(want)+i]; e.Action != action || e.Target == "" {
+			t.Fatalf("expected %s got %v", action, actions)
+		}
+	}
+	if entries[0].Target != "alice" || entries[6].Details != string(user.RoleAdmin) {
+		t.Fatalf("unexpected details %+v", entries)
+	}
+}
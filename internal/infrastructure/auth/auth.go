@@ -1,59 +1,446 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"errors"
+	"strings"
 	"sync"
+	"time"
 
+	"demo/internal/domain/audit"
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/auditstore"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
-// User represents a simple user account.
-type User struct {
-	ID       uuid.UUID
-	Username string
-	Password string // stored as SHA256 hex
-}
+var (
+	// ErrInvalidCredentials is returned by Login for unknown users and
+	// wrong passwords.
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+	// ErrUserDisabled is returned by Login for disabled accounts.
+	ErrUserDisabled = errors.New("auth: user disabled")
+	// ErrInvalidToken is returned by Refresh for unknown, expired or used
+	// refresh tokens and by Logout for invalid access tokens.
+	ErrInvalidToken = errors.New("auth: invalid token")
+)
+
+const (
+	// DefaultAccessTTL is the lifetime of access tokens.
+	DefaultAccessTTL = 15 * time.Minute
+	// DefaultRefreshTTL is the lifetime of refresh tokens.
+	DefaultRefreshTTL = 30 * 24 * time.Hour
+)
 
-// Service manages users and sessions in memory.
+// Service registers users and issues their tokens. Accounts live in a
+// user.Repository. A session is a short-lived JWT access token, verified
+// without a lookup except for the revocation list, and an opaque refresh
+// token kept in a TokenStore that trades itself for a new pair of tokens.
 type Service struct {
-	mu       sync.RWMutex
-	users    map[string]*User
-	sessions map[string]uuid.UUID
+	Users user.Repository
+	// Hasher hashes the passwords of new users and replaces outdated hashes
+	// on login.
+	Hasher PasswordHasher
+	// Keys sign and verify access tokens.
+	Keys *KeyRing
+	// Tokens keeps refresh tokens and revoked access tokens.
+	Tokens TokenStore
+	// APIKeys stores the API keys accepted next to access tokens.
+	APIKeys APIKeyRepository
+	// OIDC, when set, logs users in with an OpenID provider, and Identities
+	// links the accounts of the provider to users.
+	OIDC       *OIDCProvider
+	Identities IdentityRepository
+	// DisablePasswords turns off registration and password login, leaving
+	// OIDC to log users in.
+	DisablePasswords bool
+	// Lockouts counts failed logins, which lock users out under UserLockout
+	// and client addresses under IPLockout.
+	Lockouts    LockoutStore
+	UserLockout LockoutPolicy
+	IPLockout   LockoutPolicy
+	// Publisher, when set, receives the lockout events.
+	Publisher EventPublisher
+	// Forget, when set, erases the personal data of deleted users.
+	Forget Forgetter
+	// Tenants lists the tenants served; requests naming another tenant are
+	// refused.
+	Tenants *tenant.Registry
+	// Audit records logins, tokens and administrative actions.
+	Audit      audit.Repository
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+
+	dummyMu sync.Mutex
+	dummy   string
+}
+
+// NewService creates an auth service on top of a user repository. Passwords
+// are hashed with argon2id, access tokens are signed with a random key and
+// tokens, API keys, identities, failed logins and the audit log are kept in
+// memory. No OpenID provider is configured and only the default tenant is
+// served.
+func NewService(users user.Repository) *Service {
+	tenants, _ := tenant.NewRegistry()
+	return &Service{
+		Tenants:     tenants,
+		Users:       users,
+		Hasher:      NewArgon2id(),
+		Keys:        NewRandomKeyRing(),
+		Tokens:      NewInMemoryTokenStore(),
+		APIKeys:     NewInMemoryAPIKeyStore(),
+		Identities:  NewInMemoryIdentityStore(),
+		Lockouts:    NewInMemoryLockoutStore(),
+		UserLockout: DefaultUserLockout,
+		IPLockout:   DefaultIPLockout,
+		Audit:       auditstore.NewInMemoryStore(),
+		AccessTTL:   DefaultAccessTTL,
+		RefreshTTL:  DefaultRefreshTTL,
+	}
+}
+
+// Tokens are the tokens of a session.
+type Tokens struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresAt is the expiry of the access token.
+	ExpiresAt time.Time
+}
+
+// Register creates an account. It returns the validation errors of the user
+// package, user.ErrUsernameTaken and user.ErrEmailTaken.
+func (s *Service) Register(ctx context.Context, username, email, password string) (*user.User, error) {
+	if s.DisablePasswords {
+		return nil, ErrPasswordLogin
+	}
+	if err := user.CheckPassword(password); err != nil {
+		return nil, err
+	}
+	hash, err := s.Hasher.Hash(password)
+	if err != nil {
+		return nil, err
+	}
+	u, err := user.NewUser(username, email, hash)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Users.Create(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Login validates credentials and starts a session. login is a
+// username or an email address. When the stored hash was made by another
+// algorithm or with other parameters than those of the Hasher, the password
+// is hashed again and saved. Users provisioned by OIDC have no password.
+//
+// Failed logins are counted per user and per client address, taken from
+// the context (WithClient): once either is locked out Login returns a
+// LockoutError without checking the password.
+func (s *Service) Login(ctx context.Context, login, password string) (*Tokens, error) {
+	if s.DisablePasswords {
+		return nil, ErrPasswordLogin
+	}
+	var u *user.User
+	var err error
+	if strings.Contains(login, "@") {
+		u, err = s.Users.ByEmail(ctx, login)
+	} else {
+		u, err = s.Users.ByUsername(ctx, login)
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID := uuid.Nil
+	if u != nil {
+		userID = u.ID
+	}
+	keys := s.lockoutKeys(ctx, login, userID)
+	if err := s.checkLockout(ctx, keys); err != nil {
+		return nil, err
+	}
+	ok := false
+	if u != nil && u.PasswordHash != "" {
+		if ok, err = VerifyPassword(password, u.PasswordHash); err != nil {
+			return nil, err
+		}
+	} else {
+		// a hash is checked anyway so that the time taken does not tell
+		// whether the account exists
+		hash, err := s.dummyHash()
+		if err != nil {
+			return nil, err
+		}
+		_, _ = VerifyPassword(password, hash)
+	}
+	if !ok {
+		_ = s.Record(ctx, audit.Entry{Action: audit.LoginFailed, Target: login, Details: "invalid credentials"})
+		if err := s.failLogin(ctx, login, userID, keys); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if u.Disabled {
+		_ = s.Record(ctx, audit.Entry{Action: audit.LoginFailed, ActorID: u.ID, Target: login, Details: "user disabled"})
+		return nil, ErrUserDisabled
+	}
+	// the client keeps its failures: it could otherwise clear them by
+	// logging in to an account of its own between guesses
+	if err := s.Lockouts.Reset(ctx, keys[0].key); err != nil {
+		return nil, err
+	}
+	if s.Hasher.NeedsRehash(u.PasswordHash) {
+		// a failed upgrade leaves the old hash in place, which still works,
+		// so it is retried on the next login instead of failing this one
+		if hash, err := s.Hasher.Hash(password); err == nil {
+			u.PasswordHash = hash
+			_ = s.Users.Update(ctx, u)
+		}
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.LoginSucceeded, ActorID: u.ID, Target: login, Details: "password"})
+	return s.issue(ctx, u)
 }
 
-// NewService creates a new auth service with a default user.
-func NewService() *Service {
-	s := &Service{users: make(map[string]*User), sessions: make(map[string]uuid.UUID)}
-	// default user: user/password
-	s.users["user"] = &User{ID: uuid.New(), Username: "user", Password: hash("password")}
-	return s
+// dummyHash returns a hash of a random password made by the Hasher, checked
+// by Login for users that do not exist or have no password. It is made
+// again when the Hasher changes.
+func (s *Service) dummyHash() (string, error) {
+	s.dummyMu.Lock()
+	defer s.dummyMu.Unlock()
+	if s.dummy == "" || s.Hasher.NeedsRehash(s.dummy) {
+		password, err := randomString(32)
+		if err != nil {
+			return "", err
+		}
+		if s.dummy, err = s.Hasher.Hash(password); err != nil {
+			return "", err
+		}
+	}
+	return s.dummy, nil
 }
 
-func hash(pw string) string {
-	h := sha256.Sum256([]byte(pw))
+// issue signs an access token for a user and stores a new refresh token.
+// The access token carries the role of the user and the tenant of ctx; both
+// tokens only work in that tenant.
+func (s *Service) issue(ctx context.Context, u *user.User) (*Tokens, error) {
+	now := time.Now()
+	expires := now.Add(s.AccessTTL)
+	access, err := s.Keys.sign(accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   u.ID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+		},
+		Role:           string(u.Role),
+		Tenant:         tenant.FromContext(ctx),
+		IssuedAtMicros: now.UnixMicro(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	refresh := base64.RawURLEncoding.EncodeToString(secret)
+	err = s.Tokens.SaveRefresh(ctx, RefreshToken{Hash: tokenHash(refresh), UserID: u.ID, Tenant: tenant.FromContext(ctx), ExpiresAt: now.Add(s.RefreshTTL)})
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{AccessToken: access, RefreshToken: refresh, ExpiresAt: expires}, nil
+}
+
+// tokenHash is the key a refresh token is stored under. The tokens are
+// random, so an unsalted digest does not help guessing them.
+func tokenHash(token string) string {
+	h := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(h[:])
 }
 
-// Login validates credentials and returns a session token.
-func (s *Service) Login(username, password string) (string, bool) {
-	s.mu.RLock()
-	u, ok := s.users[username]
-	s.mu.RUnlock()
-	if !ok || u.Password != hash(password) {
-		return "", false
+// Refresh trades a refresh token for new tokens of the same tenant. Every
+// refresh token is used once.
+func (s *Service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	t, err := s.Tokens.TakeRefresh(ctx, tokenHash(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, ErrInvalidToken
+	}
+	u, err := s.Users.ByID(ctx, t.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidToken
+	}
+	if u.Disabled {
+		return nil, ErrUserDisabled
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.TokenRefreshed, ActorID: u.ID, Target: u.ID.String()})
+	return s.issue(tenant.NewContext(ctx, t.Tenant), u)
+}
+
+// verify returns the claims of a valid access token that was not revoked.
+// Tokens without a tenant claim were issued for the default tenant.
+func (s *Service) verify(ctx context.Context, token string) (*accessClaims, Principal, error) {
+	claims, err := s.Keys.parse(token)
+	if err != nil {
+		return nil, Principal{}, ErrInvalidToken
+	}
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil || claims.ID == "" || claims.IssuedAtMicros == 0 {
+		return nil, Principal{}, ErrInvalidToken
+	}
+	revoked, err := s.Tokens.Revoked(ctx, claims.ID, id, time.UnixMicro(claims.IssuedAtMicros))
+	if err != nil {
+		return nil, Principal{}, err
+	}
+	if revoked {
+		return nil, Principal{}, ErrInvalidToken
+	}
+	return claims, Principal{UserID: id, Role: user.Role(claims.Role), Tenant: claims.Tenant}, nil
+}
+
+// Authenticate returns the principal of a valid access token or API key.
+func (s *Service) Authenticate(ctx context.Context, token string) (Principal, bool) {
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		p, err := s.authenticateKey(ctx, token)
+		return p, err == nil
+	}
+	_, p, err := s.verify(ctx, token)
+	return p, err == nil
+}
+
+// Logout ends a session: the access token is revoked and the refresh token,
+// if given, deleted.
+func (s *Service) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, p, err := s.verify(ctx, accessToken)
+	if err != nil {
+		return err
+	}
+	if err := s.Tokens.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+		return err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.TokenRevoked, ActorID: p.UserID, Target: p.UserID.String(), Details: "logout"})
+	if refreshToken == "" {
+		return nil
+	}
+	_, err = s.Tokens.TakeRefresh(ctx, tokenHash(refreshToken))
+	return err
+}
+
+// Disable prevents a user from logging in and ends their sessions.
+func (s *Service) Disable(ctx context.Context, id uuid.UUID) error {
+	if err := s.setDisabled(ctx, id, true); err != nil {
+		return err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.UserDisabled, Target: id.String()})
+	return s.endSessions(ctx, id)
+}
+
+// Enable allows a disabled user to log in again.
+func (s *Service) Enable(ctx context.Context, id uuid.UUID) error {
+	if err := s.setDisabled(ctx, id, false); err != nil {
+		return err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.UserEnabled, Target: id.String()})
+	return nil
+}
+
+func (s *Service) setDisabled(ctx context.Context, id uuid.UUID, disabled bool) error {
+	u, err := s.Users.ByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return user.ErrNotFound
+	}
+	if u.Disabled == disabled {
+		return nil
+	}
+	u.Disabled = disabled
+	return s.Users.Update(ctx, u)
+}
+
+// SetRole changes the role of a user. Their access tokens are revoked so that
+// the new role applies from their next token refresh.
+func (s *Service) SetRole(ctx context.Context, id uuid.UUID, role user.Role) error {
+	if _, err := user.ParseRole(string(role)); err != nil {
+		return err
+	}
+	u, err := s.Users.ByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return user.ErrNotFound
+	}
+	if u.Role == role {
+		return nil
+	}
+	u.Role = role
+	if err := s.Users.Update(ctx, u); err != nil {
+		return err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.UserRoleChanged, Target: id.String(), Details: string(role)})
+	return s.revokeAccess(ctx, id)
+}
+
+// Forgetter erases the personal data of a user, e.g. a
+// command.ForgetUserHandler.
+type Forgetter interface {
+	Forget(ctx context.Context, userID uuid.UUID) error
+}
+
+// Delete removes the account of a user after erasing their personal data
+// through Forget, unlinks their identities and ends their sessions.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	u, err := s.Users.ByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u == nil {
+		return user.ErrNotFound
+	}
+	// forget first, so that the account is still there to retry with when
+	// erasing fails
+	if s.Forget != nil {
+		if err := s.Forget.Forget(ctx, id); err != nil {
+			return err
+		}
+	}
+	if err := s.Users.Delete(ctx, id); err != nil {
+		return err
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.UserDeleted, Target: id.String()})
+	if err := s.Identities.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	return s.endSessions(ctx, id)
+}
+
+// endSessions revokes the access tokens issued to a user so far and deletes
+// their refresh tokens.
+func (s *Service) endSessions(ctx context.Context, id uuid.UUID) error {
+	if err := s.revokeAccess(ctx, id); err != nil {
+		return err
 	}
-	token := uuid.New().String()
-	s.mu.Lock()
-	s.sessions[token] = u.ID
-	s.mu.Unlock()
-	return token, true
+	return s.Tokens.DeleteUserRefresh(ctx, id)
 }
 
-// Authenticate returns the user ID associated with a token.
-func (s *Service) Authenticate(token string) (uuid.UUID, bool) {
-	s.mu.RLock()
-	id, ok := s.sessions[token]
-	s.mu.RUnlock()
-	return id, ok
+// revokeAccess revokes the access tokens issued to a user so far.
+func (s *Service) revokeAccess(ctx context.Context, id uuid.UUID) error {
+	now := time.Now()
+	// tokens carry their issue time in microseconds: round up so that a
+	// token issued within the microsecond before now is revoked as well
+	before := now.Truncate(time.Microsecond).Add(time.Microsecond)
+	return s.Tokens.RevokeUser(ctx, id, before, now.Add(s.AccessTTL))
 }
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/userstore"
+	"github.com/google/uuid"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	u, err := s.Register(ctx, "alice", "alice@example.com", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Register(ctx, "bob", "bob@example.com", "short"); !errors.Is(err, user.ErrWeakPassword) {
+		t.Fatalf("expected user.ErrWeakPassword got %v", err)
+	}
+	if _, err := s.Register(ctx, "ALICE", "other@example.com", "password"); !errors.Is(err, user.ErrUsernameTaken) {
+		t.Fatalf("expected user.ErrUsernameTaken got %v", err)
+	}
+
+	for _, login := range []string{"alice", "Alice@Example.com"} {
+		tokens, err := s.Login(ctx, login, "password")
+		if err != nil {
+			t.Fatalf("login %s: %v", login, err)
+		}
+		if p, ok := s.Authenticate(ctx, tokens.AccessToken); !ok || p.UserID != u.ID || p.Role != user.RolePlayer {
+			t.Fatalf("expected %s got %+v %v", u.ID, p, ok)
+		}
+	}
+	if _, err := s.Login(ctx, "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+	if _, err := s.Login(ctx, "nobody", "password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+}
+
+func TestLoginUnknownUserChecksHash(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	s.Hasher = Argon2id{Time: 2, Memory: 16 * 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+	if _, err := s.Register(ctx, "alice", "alice@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+	// the fastest of a few logins, after one making the dummy hash
+	fastest := func(login string) time.Duration {
+		_, _ = s.Login(ctx, login, "wrong")
+		var min time.Duration
+		for i := 0; i < 3; i++ {
+			start := time.Now()
+			if _, err := s.Login(ctx, login, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("expected ErrInvalidCredentials got %v", err)
+			}
+			if d := time.Since(start); min == 0 || d < min {
+				min = d
+			}
+		}
+		return min
+	}
+	known, unknown := fastest("alice"), fastest("nobody")
+	if unknown < known/2 {
+		t.Fatalf("expected unknown users to take as long as known ones got %v and %v", unknown, known)
+	}
+	if s.dummy == "" || s.Hasher.NeedsRehash(s.dummy) {
+		t.Fatalf("expected a dummy hash made by the hasher got %q", s.dummy)
+	}
+}
+
+// forgetter records the users it forgets and fails with err.
+type forgetter struct {
+	forgotten []uuid.UUID
+	err       error
+}
+
+func (f *forgetter) Forget(ctx context.Context, userID uuid.UUID) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.forgotten = append(f.forgotten, userID)
+	return nil
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	forget := &forgetter{}
+	s.Forget = forget
+	u, _ := s.Register(ctx, "alice", "alice@example.com", "password")
+	tokens, _ := s.Login(ctx, "alice", "password")
+
+	if err := s.Disable(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Authenticate(ctx, tokens.AccessToken); ok {
+		t.Fatal("expected disabling to end the sessions of the user")
+	}
+	if _, err := s.Refresh(ctx, tokens.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected disabling to delete the refresh tokens got %v", err)
+	}
+	if _, err := s.Login(ctx, "alice", "password"); !errors.Is(err, ErrUserDisabled) {
+		t.Fatalf("expected ErrUserDisabled got %v", err)
+	}
+	if err := s.Enable(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := s.Login(ctx, "alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an account whose data cannot be erased is kept
+	forget.err = errors.New("fail")
+	if err := s.Delete(ctx, u.ID); err == nil {
+		t.Fatal("expected the error of Forget")
+	}
+	if found, _ := s.Users.ByID(ctx, u.ID); found == nil {
+		t.Fatal("expected the account kept")
+	}
+	forget.err = nil
+	if err := s.Delete(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(forget.forgotten) != 1 || forget.forgotten[0] != u.ID {
+		t.Fatalf("expected the user forgotten got %v", forget.forgotten)
+	}
+	if _, ok := s.Authenticate(ctx, tokens.AccessToken); ok {
+		t.Fatal("expected deleting to end the sessions of the user")
+	}
+	if err := s.Delete(ctx, u.ID); !errors.Is(err, user.ErrNotFound) || len(forget.forgotten) != 1 {
+		t.Fatalf("expected user.ErrNotFound without forgetting got %v %v", err, forget.forgotten)
+	}
+	if _, err := s.Login(ctx, "alice", "password"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+	if err := s.Disable(ctx, u.ID); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("expected user.ErrNotFound got %v", err)
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	u, _ := s.Register(ctx, "alice", "alice@example.com", "password")
+	tokens, _ := s.Login(ctx, "alice", "password")
+
+	if err := s.SetRole(ctx, u.ID, user.RoleDesigner); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Authenticate(ctx, tokens.AccessToken); ok {
+		t.Fatal("expected a role change to revoke the access tokens of the user")
+	}
+	refreshed, err := s.Refresh(ctx, tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected the refresh tokens to be kept got %v", err)
+	}
+	if p, ok := s.Authenticate(ctx, refreshed.AccessToken); !ok || p.Role != user.RoleDesigner || !p.Can(user.PermWriteCards) {
+		t.Fatalf("expected a designer got %+v %v", p, ok)
+	}
+	if err := s.SetRole(ctx, u.ID, "root"); !errors.Is(err, user.ErrInvalidRole) {
+		t.Fatalf("expected user.ErrInvalidRole got %v", err)
+	}
+	if err := s.SetRole(ctx, uuid.New(), user.RoleAdmin); !errors.Is(err, user.ErrNotFound) {
+		t.Fatalf("expected user.ErrNotFound got %v", err)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	u, _ := s.Register(ctx, "alice", "alice@example.com", "password")
+	first, err := s.Login(ctx, "alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := s.Authenticate(ctx, second.AccessToken); !ok || p.UserID != u.ID {
+		t.Fatalf("expected the refreshed token to authenticate got %+v %v", p, ok)
+	}
+	if _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected a used refresh token to be rejected got %v", err)
+	}
+	if _, err := s.Refresh(ctx, "unknown"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken got %v", err)
+	}
+
+	if err := s.Logout(ctx, second.AccessToken, second.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Authenticate(ctx, second.AccessToken); ok {
+		t.Fatal("expected the access token to be revoked")
+	}
+	if _, err := s.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected the refresh token to be deleted got %v", err)
+	}
+	if _, ok := s.Authenticate(ctx, first.AccessToken); !ok {
+		t.Fatal("expected logging out to leave other sessions alone")
+	}
+	if err := s.Logout(ctx, second.AccessToken, ""); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken got %v", err)
+	}
+
+	s.AccessTTL = -time.Minute
+	expired, _ := s.Login(ctx, "alice", "password")
+	if _, ok := s.Authenticate(ctx, expired.AccessToken); ok {
+		t.Fatal("expected an expired token to be rejected")
+	}
+	for _, token := range []string{"", "not-a-jwt", first.AccessToken + "x"} {
+		if _, ok := s.Authenticate(ctx, token); ok {
+			t.Fatalf("expected %q to be rejected", token)
+		}
+	}
+}
+
+func TestTokenTenant(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	_, _ = s.Register(ctx, "alice", "alice@example.com", "password")
+
+	tokens, err := s.Login(tenant.NewContext(ctx, "acme"), "alice", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.Authenticate(ctx, tokens.AccessToken); p.Tenant != "acme" {
+		t.Fatalf("expected tenant acme got %q", p.Tenant)
+	}
+	// the refresh token keeps the tenant whatever the tenant of the request
+	refreshed, err := s.Refresh(tenant.NewContext(ctx, "globex"), tokens.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.Authenticate(ctx, refreshed.AccessToken); p.Tenant != "acme" {
+		t.Fatalf("expected the refreshed token to keep tenant acme got %q", p.Tenant)
+	}
+
+	tokens, _ = s.Login(ctx, "alice", "password")
+	if p, _ := s.Authenticate(ctx, tokens.AccessToken); p.Tenant != tenant.Default {
+		t.Fatalf("expected the default tenant got %q", p.Tenant)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(userstore.NewInMemoryStore())
+	_, _ = s.Register(ctx, "alice", "alice@example.com", "password")
+	old, err := ParseKeyRing("k1=" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Keys = old
+	tokens, _ := s.Login(ctx, "alice", "password")
+
+	rotated, err := ParseKeyRing("k2=" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("b", 32))) +
+		", k1=" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32))))
+	if err != nil || rotated.Current != "k2" {
+		t.Fatalf("unexpected ring %+v %v", rotated, err)
+	}
+	s.Keys = rotated
+	if _, ok := s.Authenticate(ctx, tokens.AccessToken); !ok {
+		t.Fatal("expected tokens of a retired key to verify during rotation")
+	}
+	s.Keys = &KeyRing{Current: "k2", Keys: map[string][]byte{"k2": rotated.Keys["k2"]}}
+	if _, ok := s.Authenticate(ctx, tokens.AccessToken); ok {
+		t.Fatal("expected tokens of a removed key to be rejected")
+	}
+
+	for _, bad := range []string{"", "k1", "k1=short", "k1=%%%", "=" + base64.StdEncoding.EncodeToString(make([]byte, 32))} {
+		if _, err := ParseKeyRing(bad); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package authtest provides conformance suites for the repositories of the
+// auth package and a mock OpenID provider to test logins against.
+package authtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"demo/internal/conformance"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/auth"
+	"github.com/google/uuid"
+)
+
+// APIKeySuite is the conformance suite of auth.APIKeyRepository
+// implementations.
+var APIKeySuite = conformance.Suite[auth.APIKeyRepository]{
+	{Name: "CreateAndLookup", Fn: testCreateAndLookup},
+	{Name: "List", Fn: testList},
+	{Name: "Revoke", Fn: testRevoke},
+	{Name: "Touch", Fn: testTouch},
+}
+
+func newKey(name string, age int) *auth.APIKey {
+	return &auth.APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		Prefix:    name,
+		Hash:      "hash-" + name,
+		UserID:    uuid.New(),
+		Tenant:    "acme",
+		Scopes:    []user.Permission{user.PermReadCards, user.PermReadDecks},
+		CreatedAt: conformance.Base.Add(-time.Duration(age) * time.Minute),
+	}
+}
+
+func create(t *testing.T, repo auth.APIKeyRepository, keys ...*auth.APIKey) {
+	t.Helper()
+	for _, k := range keys {
+		if err := repo.Create(context.Background(), k); err != nil {
+			t.Fatalf("create %s: %v", k.Name, err)
+		}
+	}
+}
+
+func testCreateAndLookup(t *testing.T, repo auth.APIKeyRepository) {
+	ctx := context.Background()
+	k := newKey("ingest", 0)
+	k.ExpiresAt = conformance.Base.Add(time.Hour)
+	create(t, repo, k)
+	got, err := repo.ByID(ctx, k.ID)
+	if err != nil || got == nil {
+		t.Fatalf("expected key got %+v %v", got, err)
+	}
+	if got.Name != "ingest" || got.Hash != k.Hash || got.UserID != k.UserID || got.Tenant != "acme" || !got.CreatedAt.Equal(k.CreatedAt) ||
+		!got.ExpiresAt.Equal(k.ExpiresAt) || !got.LastUsedAt.IsZero() || !got.RevokedAt.IsZero() ||
+		len(got.Scopes) != 2 || got.Scopes[0] != user.PermReadCards || got.Scopes[1] != user.PermReadDecks {
+		t.Fatalf("unexpected key %+v", got)
+	}
+	// the returned key must not alias stored state
+	got.Scopes[0] = user.PermWriteCards
+	if got, err := repo.ByHash(ctx, k.Hash); err != nil || got == nil || got.ID != k.ID || got.Scopes[0] != user.PermReadCards {
+		t.Fatalf("unexpected key by hash %+v %v", got, err)
+	}
+	if got, err := repo.ByID(ctx, uuid.New()); err != nil || got != nil {
+		t.Fatalf("expected nil, nil got %+v %v", got, err)
+	}
+	if got, err := repo.ByHash(ctx, "unknown"); err != nil || got != nil {
+		t.Fatalf("expected nil, nil got %+v %v", got, err)
+	}
+}
+
+func testList(t *testing.T, repo auth.APIKeyRepository) {
+	ctx := context.Background()
+	if keys, err := repo.List(ctx); err != nil || len(keys) != 0 {
+		t.Fatalf("expected no keys got %v %v", keys, err)
+	}
+	create(t, repo, newKey("new", 1), newKey("old", 3), newKey("mid", 2))
+	keys, err := repo.List(ctx)
+	if err != nil || len(keys) != 3 || keys[0].Name != "old" || keys[1].Name != "mid" || keys[2].Name != "new" {
+		t.Fatalf("unexpected keys %v %v", keys, err)
+	}
+}
+
+func testRevoke(t *testing.T, repo auth.APIKeyRepository) {
+	ctx := context.Background()
+	k := newKey("ingest", 0)
+	create(t, repo, k)
+	if err := repo.Revoke(ctx, k.ID, conformance.Base); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Revoke(ctx, k.ID, conformance.Base.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	got, err := repo.ByID(ctx, k.ID)
+	if err != nil || !got.RevokedAt.Equal(conformance.Base) || got.Active(conformance.Base) {
+		t.Fatalf("expected the first revocation to be kept got %+v %v", got, err)
+	}
+	if err := repo.Revoke(ctx, uuid.New(), conformance.Base); !errors.Is(err, auth.ErrAPIKeyNotFound) {
+		t.Fatalf("expected auth.ErrAPIKeyNotFound got %v", err)
+	}
+}
+
+func testTouch(t *testing.T, repo auth.APIKeyRepository) {
+	ctx := context.Background()
+	k := newKey("ingest", 0)
+	create(t, repo, k)
+	if err := repo.Touch(ctx, k.ID, conformance.Base.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repo.ByID(ctx, k.ID); err != nil || !got.LastUsedAt.Equal(conformance.Base.Add(time.Minute)) {
+		t.Fatalf("unexpected last use %+v %v", got, err)
+	}
+	if err := repo.Touch(ctx, uuid.New(), conformance.Base); err != nil {
+		t.Fatalf("expected unknown keys to be ignored got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package authtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"demo/internal/conformance"
+	"demo/internal/infrastructure/auth"
+	"github.com/google/uuid"
+)
+
+// IdentitySuite is the conformance suite of auth.IdentityRepository
+// implementations.
+var IdentitySuite = conformance.Suite[auth.IdentityRepository]{
+	{Name: "LinkAndLookup", Fn: testLinkAndLookup},
+	{Name: "Relink", Fn: testRelink},
+	{Name: "DeleteUser", Fn: testDeleteUser},
+}
+
+func link(t *testing.T, repo auth.IdentityRepository, ids ...auth.Identity) {
+	t.Helper()
+	for _, id := range ids {
+		if err := repo.Link(context.Background(), id); err != nil {
+			t.Fatalf("link %s: %v", id.Subject, err)
+		}
+	}
+}
+
+func testLinkAndLookup(t *testing.T, repo auth.IdentityRepository) {
+	ctx := context.Background()
+	alice := auth.Identity{Issuer: "https://idp.example.com", Subject: "alice", UserID: uuid.New(), CreatedAt: conformance.Base}
+	link(t, repo, alice)
+	got, err := repo.ByIdentity(ctx, alice.Issuer, alice.Subject)
+	if err != nil || got == nil || got.UserID != alice.UserID || !got.CreatedAt.Equal(conformance.Base) {
+		t.Fatalf("unexpected identity %+v %v", got, err)
+	}
+	for _, key := range [][2]string{
+		{alice.Issuer, "bob"},
+		{"https://other.example.com", alice.Subject},
+		{alice.Issuer, "Alice"},
+	} {
+		if got, err := repo.ByIdentity(ctx, key[0], key[1]); err != nil || got != nil {
+			t.Fatalf("%v: expected nil, nil got %+v %v", key, got, err)
+		}
+	}
+}
+
+func testRelink(t *testing.T, repo auth.IdentityRepository) {
+	ctx := context.Background()
+	id := auth.Identity{Issuer: "https://idp.example.com", Subject: "alice", UserID: uuid.New(), CreatedAt: conformance.Base}
+	link(t, repo, id)
+	id.UserID = uuid.New()
+	id.CreatedAt = conformance.Base.Add(time.Minute)
+	link(t, repo, id)
+	if got, err := repo.ByIdentity(ctx, id.Issuer, id.Subject); err != nil || got == nil || got.UserID != id.UserID {
+		t.Fatalf("expected the link to be replaced got %+v %v", got, err)
+	}
+}
+
+func testDeleteUser(t *testing.T, repo auth.IdentityRepository) {
+	ctx := context.Background()
+	alice, bob := uuid.New(), uuid.New()
+	link(t, repo,
+		auth.Identity{Issuer: "https://idp.example.com", Subject: "a1", UserID: alice, CreatedAt: conformance.Base},
+		auth.Identity{Issuer: "https://other.example.com", Subject: "a2", UserID: alice, CreatedAt: conformance.Base},
+		auth.Identity{Issuer: "https://idp.example.com", Subject: "b1", UserID: bob, CreatedAt: conformance.Base},
+	)
+	if err := repo.DeleteUser(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range [][2]string{{"https://idp.example.com", "a1"}, {"https://other.example.com", "a2"}} {
+		if got, _ := repo.ByIdentity(ctx, key[0], key[1]); got != nil {
+			t.Fatalf("%v: expected the identity to be unlinked got %+v", key, got)
+		}
+	}
+	if got, _ := repo.ByIdentity(ctx, "https://idp.example.com", "b1"); got == nil || got.UserID != bob {
+		t.Fatalf("expected the identity of bob to be kept got %+v", got)
+	}
+	if err := repo.DeleteUser(ctx, uuid.New()); err != nil {
+		t.Fatalf("expected unknown users to be ignored got %v", err)
+	}
+}
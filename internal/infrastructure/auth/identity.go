@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Identity links the account of an OpenID provider, named by the issuer and
+// subject of its ID tokens, to a user.
+type Identity struct {
+	Issuer    string
+	Subject   string
+	UserID    uuid.UUID
+	CreatedAt time.Time
+}
+
+// IdentityRepository stores the links between provider accounts and users.
+type IdentityRepository interface {
+	// Link stores an identity, replacing any link of the same issuer and
+	// subject.
+	Link(ctx context.Context, id Identity) error
+	// ByIdentity returns the identity with the given issuer and subject, nil
+	// when unknown.
+	ByIdentity(ctx context.Context, issuer, subject string) (*Identity, error)
+	// DeleteUser removes every identity linked to a user.
+	DeleteUser(ctx context.Context, userID uuid.UUID) error
+}
+
+type identityKey struct {
+	issuer, subject string
+}
+
+// InMemoryIdentityStore is a process-local identity repository.
+type InMemoryIdentityStore struct {
+	mu         sync.RWMutex
+	identities map[identityKey]Identity
+}
+
+// NewInMemoryIdentityStore creates the store.
+func NewInMemoryIdentityStore() *InMemoryIdentityStore {
+	return &InMemoryIdentityStore{identities: make(map[identityKey]Identity)}
+}
+
+// Link implements IdentityRepository.
+func (s *InMemoryIdentityStore) Link(ctx context.Context, id Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identities[identityKey{id.Issuer, id.Subject}] = id
+	return nil
+}
+
+// ByIdentity implements IdentityRepository.
+func (s *InMemoryIdentityStore) ByIdentity(ctx context.Context, issuer, subject string) (*Identity, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if id, ok := s.identities[identityKey{issuer, subject}]; ok {
+		return &id, nil
+	}
+	return nil, nil
+}
+
+// DeleteUser implements IdentityRepository.
+func (s *InMemoryIdentityStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, id := range s.identities {
+		if id.UserID == userID {
+			delete(s.identities, k)
+		}
+	}
+	return nil
+}
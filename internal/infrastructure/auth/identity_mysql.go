@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdentityRecord is a stored link between a provider account and a user.
+type IdentityRecord struct {
+	Issuer    string    `gorm:"primaryKey;size:255"`
+	Subject   string    `gorm:"primaryKey;size:255"`
+	UserID    string    `gorm:"size:36;not null;index"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (IdentityRecord) TableName() string { return "user_identities" }
+
+// MySQLIdentityStore is a GORM-based identity repository.
+type MySQLIdentityStore struct {
+	DB *gorm.DB
+}
+
+// NewMySQLIdentityStore creates the user_identities table if needed.
+func NewMySQLIdentityStore(db *gorm.DB) (*MySQLIdentityStore, error) {
+	if err := db.AutoMigrate(&IdentityRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLIdentityStore{DB: db}, nil
+}
+
+// Link implements IdentityRepository.
+func (s *MySQLIdentityStore) Link(ctx context.Context, id Identity) error {
+	rec := IdentityRecord{Issuer: id.Issuer, Subject: id.Subject, UserID: id.UserID.String(), CreatedAt: id.CreatedAt}
+	return s.DB.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&rec).Error
+}
+
+// ByIdentity implements IdentityRepository.
+func (s *MySQLIdentityStore) ByIdentity(ctx context.Context, issuer, subject string) (*Identity, error) {
+	var rec IdentityRecord
+	err := s.DB.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(rec.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{Issuer: rec.Issuer, Subject: rec.Subject, UserID: userID, CreatedAt: rec.CreatedAt.UTC()}, nil
+}
+
+// DeleteUser implements IdentityRepository.
+func (s *MySQLIdentityStore) DeleteUser(ctx context.Context, userID uuid.UUID) error {
+	return s.DB.WithContext(ctx).Where("user_id = ?", userID.String()).Delete(&IdentityRecord{}).Error
+}
+
+var _ IdentityRepository = (*MySQLIdentityStore)(nil)
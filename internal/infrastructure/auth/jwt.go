@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// minKeyLen is the shortest HMAC key accepted, the size of a SHA-256 digest.
+const minKeyLen = 32
+
+// KeyRing holds the HMAC keys signing access tokens. Tokens are signed with
+// the Current key and name it in their kid header, while any key of the ring
+// verifies them: a key is rotated by adding a new Current key and removing
+// the old one once the tokens it signed have expired.
+type KeyRing struct {
+	Current string
+	Keys    map[string][]byte
+}
+
+// ParseKeyRing parses a comma separated list of kid=secret pairs, the
+// secrets being base64 encoded keys of at least 32 bytes. The first key
+// signs new tokens.
+func ParseKeyRing(s string) (*KeyRing, error) {
+	ring := &KeyRing{Keys: make(map[string][]byte)}
+	for _, pair := range strings.Split(s, ",") {
+		kid, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || kid == "" {
+			return nil, fmt.Errorf("auth: invalid key %q, want kid=secret", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil {
+			return nil, fmt.Errorf("auth: key %s: %w", kid, err)
+		}
+		if len(key) < minKeyLen {
+			return nil, fmt.Errorf("auth: key %s is shorter than %d bytes", kid, minKeyLen)
+		}
+		if _, dup := ring.Keys[kid]; dup {
+			return nil, fmt.Errorf("auth: duplicate key %s", kid)
+		}
+		if ring.Current == "" {
+			ring.Current = kid
+		}
+		ring.Keys[kid] = key
+	}
+	return ring, nil
+}
+
+// NewRandomKeyRing creates a ring holding a single random key. The tokens it
+// signs neither survive a restart nor verify on other replicas.
+func NewRandomKeyRing() *KeyRing {
+	key := make([]byte, minKeyLen)
+	_, _ = rand.Read(key)
+	kid := uuid.NewString()
+	return &KeyRing{Current: kid, Keys: map[string][]byte{kid: key}}
+}
+
+// accessClaims are the claims of an access token. The subject is the user
+// id. iat only has a precision of one second, so the issue time is repeated
+// in microseconds for comparing it with revocations.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	Role           string `json:"role,omitempty"`
+	Tenant         string `json:"tenant"`
+	IssuedAtMicros int64  `json:"iat_us"`
+}
+
+// sign returns an access token for claims signed with the current key.
+func (r *KeyRing) sign(claims jwt.Claims) (string, error) {
+	key, ok := r.Keys[r.Current]
+	if !ok {
+		return "", fmt.Errorf("auth: unknown current key %s", r.Current)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = r.Current
+	return token.SignedString(key)
+}
+
+// parse verifies the signature and expiry of an access token and returns its
+// claims.
+func (r *KeyRing) parse(token string) (*accessClaims, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := r.Keys[kid]
+		if !ok {
+			return nil, errors.New("auth: unknown key")
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuedAt(), jwt.WithLeeway(5*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
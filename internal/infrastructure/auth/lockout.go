@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"demo/internal/domain/audit"
+	"github.com/google/uuid"
+)
+
+// ErrLockedOut is matched by the LockoutError returned by Login while a user
+// or client is locked out.
+var ErrLockedOut = errors.New("auth: too many failed logins")
+
+// LockoutError is returned by Login for users or clients locked out after too
+// many failed logins.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%v, retry in %v", ErrLockedOut, e.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrLockedOut) hold.
+func (e *LockoutError) Is(target error) bool { return target == ErrLockedOut }
+
+// LockoutPolicy locks a user or client out once it failed to log in Attempts
+// times within Window. The lockout lasts Delay and doubles with every failure
+// after it, up to MaxDelay.
+type LockoutPolicy struct {
+	Attempts int
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Window   time.Duration
+}
+
+var (
+	// DefaultUserLockout guards each account, whichever client logs in.
+	DefaultUserLockout = LockoutPolicy{Attempts: 5, Delay: time.Minute, MaxDelay: time.Hour, Window: 24 * time.Hour}
+	// DefaultIPLockout guards against a client trying many accounts. It is
+	// more lenient as several users may share an address.
+	DefaultIPLockout = LockoutPolicy{Attempts: 50, Delay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+)
+
+// lockout returns the lockout following the failures-th failure, zero while
+// more attempts are allowed.
+func (p LockoutPolicy) lockout(failures int) time.Duration {
+	if p.Attempts <= 0 || failures < p.Attempts {
+		return 0
+	}
+	d := p.Delay
+	for i := p.Attempts; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// LockoutStore counts failed logins and keeps lockouts, by key. Keys name a
+// user or a client address.
+type LockoutStore interface {
+	// Lockout returns how long key stays locked out, zero when it is not.
+	Lockout(ctx context.Context, key string) (time.Duration, error)
+	// Fail counts a failed login of key and returns the failures counted
+	// since the last reset. The count is forgotten window after the last
+	// failure.
+	Fail(ctx context.Context, key string, window time.Duration) (int, error)
+	// Lock locks key out for d.
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// Reset forgets the failures and lockout of key.
+	Reset(ctx context.Context, key string) error
+}
+
+// LoginLockedOut is published on the auth_events topic when a user or client
+// gets locked out.
+type LoginLockedOut struct {
+	// Login is the username or email address tried, empty for clients.
+	Login string
+	// UserID is the locked user, uuid.Nil for clients and unknown users.
+	UserID   uuid.UUID
+	IP       string
+	Failures int
+	Until    time.Time
+}
+
+// EventPublisher publishes the events of the service.
+type EventPublisher interface {
+	Publish(ctx context.Context, topic string, event interface{}) error
+}
+
+// lockoutKey is a key of the LockoutStore with the policy applying to it.
+type lockoutKey struct {
+	key    string
+	policy LockoutPolicy
+}
+
+// lockoutKeys returns the keys guarding a login: the user, or the login
+// itself when no user has it, and the client address.
+func (s *Service) lockoutKeys(ctx context.Context, login string, userID uuid.UUID) []lockoutKey {
+	keys := []lockoutKey{{"login:" + strings.ToLower(login), s.UserLockout}}
+	if userID != uuid.Nil {
+		keys[0].key = "user:" + userID.String()
+	}
+	if ip := ClientFromContext(ctx).IP; ip != "" {
+		keys = append(keys, lockoutKey{"ip:" + ip, s.IPLockout})
+	}
+	return keys
+}
+
+// checkLockout returns a LockoutError if any of keys is locked out.
+func (s *Service) checkLockout(ctx context.Context, keys []lockoutKey) error {
+	var wait time.Duration
+	for _, k := range keys {
+		d, err := s.Lockouts.Lockout(ctx, k.key)
+		if err != nil {
+			return err
+		}
+		if d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &LockoutError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// failLogin counts a failed login against keys, locking out those that
+// reached the attempts of their policy.
+func (s *Service) failLogin(ctx context.Context, login string, userID uuid.UUID, keys []lockoutKey) error {
+	for i, k := range keys {
+		failures, err := s.Lockouts.Fail(ctx, k.key, k.policy.Window)
+		if err != nil {
+			return err
+		}
+		d := k.policy.lockout(failures)
+		if d == 0 {
+			continue
+		}
+		if err := s.Lockouts.Lock(ctx, k.key, d); err != nil {
+			return err
+		}
+		evt := LoginLockedOut{IP: ClientFromContext(ctx).IP, Failures: failures, Until: time.Now().Add(d).UTC()}
+		target := "ip:" + evt.IP
+		// the first key is the user, the others the client
+		if i == 0 {
+			evt.Login, evt.UserID = login, userID
+			target = login
+		}
+		if s.Publisher != nil {
+			_ = s.Publisher.Publish(ctx, "auth_events", evt)
+		}
+		_ = s.Record(ctx, audit.Entry{Action: audit.LoginLockedOut, Target: target, Details: fmt.Sprintf("%d failures, locked until %s", failures, evt.Until.Format(time.RFC3339))})
+	}
+	return nil
+}
+
+// lockoutEntry is the state of a key of the InMemoryLockoutStore.
+type lockoutEntry struct {
+	failures    int
+	forgetAt    time.Time
+	lockedUntil time.Time
+}
+
+// InMemoryLockoutStore is a process-local lockout store.
+type InMemoryLockoutStore struct {
+	mu      sync.Mutex
+	entries map[string]*lockoutEntry
+}
+
+// NewInMemoryLockoutStore creates the store.
+func NewInMemoryLockoutStore() *InMemoryLockoutStore {
+	return &InMemoryLockoutStore{entries: make(map[string]*lockoutEntry)}
+}
+
+// prune drops the entries whose failures and lockout are over. The caller
+// holds the lock.
+func (s *InMemoryLockoutStore) prune(now time.Time) {
+	for key, e := range s.entries {
+		if !now.Before(e.forgetAt) && !now.Before(e.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Lockout implements LockoutStore.
+func (s *InMemoryLockoutStore) Lockout(ctx context.Context, key string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		return 0, nil
+	}
+	if d := time.Until(e.lockedUntil); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+// Fail implements LockoutStore.
+func (s *InMemoryLockoutStore) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.prune(now)
+	e, ok := s.entries[key]
+	if !ok {
+		e = &lockoutEntry{}
+		s.entries[key] = e
+	}
+	if !now.Before(e.forgetAt) {
+		e.failures = 0
+	}
+	e.failures++
+	e.forgetAt = now.Add(window)
+	return e.failures, nil
+}
+
+// Lock implements LockoutStore.
+func (s *InMemoryLockoutStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &lockoutEntry{}
+		s.entries[key] = e
+	}
+	e.lockedUntil = time.Now().Add(d)
+	return nil
+}
+
+// Reset implements LockoutStore.
+func (s *InMemoryLockoutStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
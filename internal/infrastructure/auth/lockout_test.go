@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"demo/internal/infrastructure/userstore"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestLockoutStores(t *testing.T) {
+	for name, newStore := range map[string]func(t *testing.T) LockoutStore{
+		"memory": func(t *testing.T) LockoutStore { return NewInMemoryLockoutStore() },
+		"redis": func(t *testing.T) LockoutStore {
+			s := miniredis.RunT(t)
+			return &RedisLockoutStore{Redis: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testLockoutStore(t, newStore(t))
+		})
+	}
+}
+
+func testLockoutStore(t *testing.T, store LockoutStore) {
+	ctx := context.Background()
+	for i := 1; i <= 3; i++ {
+		if n, err := store.Fail(ctx, "user:a", time.Hour); err != nil || n != i {
+			t.Fatalf("expected %d failures got %d %v", i, n, err)
+		}
+	}
+	if n, _ := store.Fail(ctx, "ip:b", time.Hour); n != 1 {
+		t.Fatalf("expected keys to be counted apart got %d", n)
+	}
+	if d, err := store.Lockout(ctx, "user:a"); err != nil || d != 0 {
+		t.Fatalf("expected no lockout got %v %v", d, err)
+	}
+	if err := store.Lock(ctx, "user:a", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := store.Lockout(ctx, "user:a"); err != nil || d <= 50*time.Second || d > time.Minute {
+		t.Fatalf("expected a lockout of a minute got %v %v", d, err)
+	}
+	if d, _ := store.Lockout(ctx, "ip:b"); d != 0 {
+		t.Fatalf("expected other keys not to be locked got %v", d)
+	}
+	if err := store.Reset(ctx, "user:a"); err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := store.Lockout(ctx, "user:a"); d != 0 {
+		t.Fatalf("expected the lockout to be reset got %v", d)
+	}
+	if n, _ := store.Fail(ctx, "user:a", time.Hour); n != 1 {
+		t.Fatalf("expected the failures to be reset got %d", n)
+	}
+}
+
+func TestLockoutPolicy(t *testing.T) {
+	p := LockoutPolicy{Attempts: 3, Delay: time.Minute, MaxDelay: 10 * time.Minute}
+	for failures, want := range map[int]time.Duration{
+		1:  0,
+		2:  0,
+		3:  time.Minute,
+		4:  2 * time.Minute,
+		6:  8 * time.Minute,
+		7:  10 * time.Minute,
+		50: 10 * time.Minute,
+	} {
+		if got := p.lockout(failures); got != want {
+			t.Fatalf("%d failures: expected %v got %v", failures, want, got)
+		}
+	}
+	if got := (LockoutPolicy{}).lockout(100); got != 0 {
+		t.Fatalf("expected a zero policy never to lock out got %v", got)
+	}
+}
+
+// recordingPublisher keeps the published events.
+type recordingPublisher struct {
+	mu     sync.Mutex
+	events []interface{}
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, event interface{}) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, event)
+	return nil
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := NewService(userstore.NewInMemoryStore())
+	s.Hasher = Bcrypt{Cost: 4}
+	s.UserLockout = LockoutPolicy{Attempts: 3, Delay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	s.IPLockout = LockoutPolicy{Attempts: 5, Delay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	pub := &recordingPublisher{}
+	s.Publisher = pub
+	ctx := WithClient(context.Background(), Client{IP: "10.0.0.1"})
+	alice, _ := s.Register(ctx, "alice", "alice@example.com", "password")
+	_, _ = s.Register(ctx, "bob", "bob@example.com", "password")
+
+	if _, err := s.Login(ctx, "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+	if _, err := s.Login(ctx, "alice", "password"); err != nil {
+		t.Fatalf("expected a success to clear the failures got %v", err)
+	}
+	// the email address names the same user as the username
+	for _, login := range []string{"alice", "ALICE@example.com", "alice"} {
+		if _, err := s.Login(ctx, login, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("expected ErrInvalidCredentials got %v", err)
+		}
+	}
+	_, err := s.Login(ctx, "alice", "password")
+	var lockout *LockoutError
+	if !errors.As(err, &lockout) || !errors.Is(err, ErrLockedOut) || lockout.RetryAfter <= 0 || lockout.RetryAfter > time.Minute {
+		t.Fatalf("expected a lockout of a minute got %v", err)
+	}
+	if len(pub.events) != 1 {
+		t.Fatalf("expected a lockout event got %+v", pub.events)
+	}
+	if evt, ok := pub.events[0].(LoginLockedOut); !ok || evt.UserID != alice.ID || evt.IP != "10.0.0.1" || evt.Failures != 3 {
+		t.Fatalf("unexpected event %+v", pub.events[0])
+	}
+	if _, err := s.Login(WithClient(context.Background(), Client{IP: "10.0.0.2"}), "alice", "password"); !errors.Is(err, ErrLockedOut) {
+		t.Fatalf("expected the user to be locked out from any client got %v", err)
+	}
+	if _, err := s.Login(ctx, "bob", "password"); err != nil {
+		t.Fatalf("expected other users of the client to log in got %v", err)
+	}
+
+	// failures against unknown users count as well: this is the fifth
+	// failure of the client
+	if _, err := s.Login(ctx, "nobody", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+	if _, err := s.Login(ctx, "bob", "password"); !errors.Is(err, ErrLockedOut) {
+		t.Fatalf("expected the client to be locked out got %v", err)
+	}
+	if _, err := s.Login(WithClient(context.Background(), Client{IP: "10.0.0.2"}), "bob", "password"); err != nil {
+		t.Fatalf("expected other clients to log in got %v", err)
+	}
+	if len(pub.events) != 2 || pub.events[1].(LoginLockedOut).UserID != uuid.Nil {
+		t.Fatalf("expected a client lockout event got %+v", pub.events)
+	}
+}
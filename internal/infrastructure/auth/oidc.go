@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"demo/internal/domain/audit"
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// ErrOIDCDisabled is returned by the OpenID Connect logins of a service
+	// without a provider.
+	ErrOIDCDisabled = errors.New("auth: openid connect not configured")
+	// ErrOIDCLogin is returned for OpenID Connect logins that cannot be
+	// completed: unknown or expired states, codes refused by the provider and
+	// invalid ID tokens.
+	ErrOIDCLogin = errors.New("auth: openid connect login failed")
+	// ErrPasswordLogin is returned by Register and Login when passwords are
+	// disabled.
+	ErrPasswordLogin = errors.New("auth: password login disabled")
+)
+
+// oidcLoginTTL bounds the time a user has to log in with the provider.
+const oidcLoginTTL = 10 * time.Minute
+
+// OIDCConfig describes the client registered with an OpenID provider.
+type OIDCConfig struct {
+	// IssuerURL is where the discovery document of the provider is found.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback endpoint the provider sends users back to.
+	RedirectURL string
+}
+
+// OIDCProvider logs users in with an OpenID provider using the
+// authorization code flow with PKCE.
+type OIDCProvider struct {
+	Issuer   string
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider fetches the discovery document of the provider. The
+// signing keys of ID tokens are fetched from its JWKS endpoint when first
+// needed and again whenever a token names an unknown key.
+func NewOIDCProvider(ctx context.Context, cfg OIDCConfig) (*OIDCProvider, error) {
+	p, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCProvider{
+		Issuer: cfg.IssuerURL,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     p.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: p.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// idClaims are the claims of an ID token used to provision users.
+type idClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// randomString returns n random bytes encoded in base64url.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// OIDCLoginURL starts a login with the provider and returns the URL to send
+// the user to. The state, nonce and PKCE verifier of the login are kept in
+// the TokenStore until the user comes back.
+func (s *Service) OIDCLoginURL(ctx context.Context) (string, error) {
+	if s.OIDC == nil {
+		return "", ErrOIDCDisabled
+	}
+	state, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomString(32)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+	err = s.Tokens.SaveLogin(ctx, PendingLogin{
+		StateHash: tokenHash(state),
+		Verifier:  verifier,
+		Nonce:     nonce,
+		Tenant:    tenant.FromContext(ctx),
+		ExpiresAt: time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.OIDC.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// OIDCCallback completes a login started by OIDCLoginURL: the authorization
+// code is traded for an ID token, which is verified, and a session is started
+// for the user it names in the tenant the login was started in. Each state
+// works once.
+func (s *Service) OIDCCallback(ctx context.Context, state, code string) (*Tokens, error) {
+	if s.OIDC == nil {
+		return nil, ErrOIDCDisabled
+	}
+	u, tenantID, err := s.oidcCallbackUser(ctx, state, code)
+	if err != nil {
+		_ = s.Record(ctx, audit.Entry{Action: audit.LoginFailed, Target: s.OIDC.Issuer, Details: err.Error()})
+		return nil, err
+	}
+	if u.Disabled {
+		_ = s.Record(ctx, audit.Entry{Action: audit.LoginFailed, ActorID: u.ID, Target: s.OIDC.Issuer, Details: "user disabled"})
+		return nil, ErrUserDisabled
+	}
+	_ = s.Record(ctx, audit.Entry{Action: audit.LoginSucceeded, ActorID: u.ID, Target: s.OIDC.Issuer, Details: "oidc"})
+	return s.issue(tenant.NewContext(ctx, tenantID), u)
+}
+
+// oidcCallbackUser verifies the ID token of an OIDC callback and returns the
+// user it names and the tenant of the login.
+func (s *Service) oidcCallbackUser(ctx context.Context, state, code string) (*user.User, string, error) {
+	l, err := s.Tokens.TakeLogin(ctx, tokenHash(state))
+	if err != nil {
+		return nil, "", err
+	}
+	if l == nil {
+		return nil, "", fmt.Errorf("%w: unknown state", ErrOIDCLogin)
+	}
+	token, err := s.OIDC.oauth.Exchange(ctx, code, oauth2.VerifierOption(l.Verifier))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, "", fmt.Errorf("%w: no id token", ErrOIDCLogin)
+	}
+	idToken, err := s.OIDC.verifier.Verify(ctx, raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+	if idToken.Nonce != l.Nonce {
+		return nil, "", fmt.Errorf("%w: nonce mismatch", ErrOIDCLogin)
+	}
+	var claims idClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrOIDCLogin, err)
+	}
+	u, err := s.oidcUser(ctx, idToken.Issuer, idToken.Subject, claims)
+	return u, l.Tenant, err
+}
+
+// oidcUser returns the user linked to a provider account. On first login the
+// account is linked to the user with the same email address if the provider
+// verified it, or else to a new user without a password.
+func (s *Service) oidcUser(ctx context.Context, issuer, subject string, claims idClaims) (*user.User, error) {
+	id, err := s.Identities.ByIdentity(ctx, issuer, subject)
+	if err != nil {
+		return nil, err
+	}
+	if id != nil {
+		u, err := s.Users.ByID(ctx, id.UserID)
+		if err != nil || u != nil {
+			return u, err
+		}
+	}
+	var u *user.User
+	if claims.Email != "" && claims.EmailVerified {
+		if u, err = s.Users.ByEmail(ctx, claims.Email); err != nil {
+			return nil, err
+		}
+	}
+	if u == nil {
+		if u, err = s.provision(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+	err = s.Identities.Link(ctx, Identity{Issuer: issuer, Subject: subject, UserID: u.ID, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// provision creates the user of a provider account. The username is the
+// preferred username of the account, or the local part of its email
+// address, made valid and followed by a random suffix when taken.
+func (s *Service) provision(ctx context.Context, claims idClaims) (*user.User, error) {
+	if claims.Email == "" {
+		return nil, fmt.Errorf("%w: no email", ErrOIDCLogin)
+	}
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+	base = strings.Map(func(r rune) rune {
+		if r < 128 && (r == '_' || r == '.' || r == '-' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, base)
+	if len(base) > 27 {
+		base = base[:27]
+	}
+	for len(base) < 3 {
+		base += "_"
+	}
+	username := base
+	for attempt := 0; ; attempt++ {
+		u, err := user.NewUser(username, claims.Email, "")
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrOIDCLogin, err)
+		}
+		err = s.Users.Create(ctx, u)
+		if err == nil {
+			return u, nil
+		}
+		if !errors.Is(err, user.ErrUsernameTaken) || attempt == 4 {
+			return nil, err
+		}
+		suffix := make([]byte, 2)
+		if _, err := rand.Read(suffix); err != nil {
+			return nil, err
+		}
+		username = base + "-" + hex.EncodeToString(suffix)
+	}
+}
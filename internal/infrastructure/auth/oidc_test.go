@@ -0,0 +1,215 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/auth"
+	"demo/internal/infrastructure/auth/authtest"
+	"demo/internal/infrastructure/userstore"
+)
+
+func TestInMemoryIdentityStoreConformance(t *testing.T) {
+	authtest.IdentitySuite.Run(t, func(t *testing.T) auth.IdentityRepository {
+		return auth.NewInMemoryIdentityStore()
+	})
+}
+
+// oidcService returns a service logging users in with a mock provider.
+func oidcService(t *testing.T) (*auth.Service, *authtest.OIDCProvider) {
+	t.Helper()
+	p := authtest.NewOIDCProvider(t)
+	s := auth.NewService(userstore.NewInMemoryStore())
+	s.Hasher = auth.Bcrypt{Cost: 4}
+	var err error
+	s.OIDC, err = auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		IssuerURL:    p.Issuer(),
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  "http://cards.example.com/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, p
+}
+
+// oidcLogin logs in the user signed in with the provider.
+func oidcLogin(t *testing.T, s *auth.Service, p *authtest.OIDCProvider) (*auth.Tokens, error) {
+	t.Helper()
+	ctx := context.Background()
+	loginURL, err := s.OIDCLoginURL(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := p.Authorize(t, loginURL)
+	return s.OIDCCallback(ctx, callback.Query().Get("state"), callback.Query().Get("code"))
+}
+
+func TestOIDCProvisioning(t *testing.T) {
+	ctx := context.Background()
+	s, p := oidcService(t)
+	userOf := func(tokens *auth.Tokens, err error) *user.User {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pr, ok := s.Authenticate(ctx, tokens.AccessToken)
+		if !ok {
+			t.Fatal("expected a valid access token")
+		}
+		u, _ := s.Users.ByID(ctx, pr.UserID)
+		return u
+	}
+
+	p.SignIn(map[string]interface{}{"sub": "1", "email": "Alice@Corp.example.com", "email_verified": true, "preferred_username": "alice smith"})
+	alice := userOf(oidcLogin(t, s, p))
+	if alice.Username != "alice_smith" || alice.Email != "alice@corp.example.com" || alice.Role != user.RolePlayer || alice.PasswordHash != "" {
+		t.Fatalf("unexpected user %+v", alice)
+	}
+	if _, err := s.Login(ctx, "alice_smith", ""); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Fatalf("expected provisioned users to have no password got %v", err)
+	}
+	p.SignIn(map[string]interface{}{"sub": "1", "email": "alice@elsewhere.example.com"})
+	if u := userOf(oidcLogin(t, s, p)); u.ID != alice.ID {
+		t.Fatalf("expected the linked user %s got %s", alice.ID, u.ID)
+	}
+
+	bob, _ := s.Register(ctx, "bob", "bob@example.com", "password")
+	p.SignIn(map[string]interface{}{"sub": "2", "email": "bob@example.com", "email_verified": false})
+	if _, err := oidcLogin(t, s, p); !errors.Is(err, user.ErrEmailTaken) {
+		t.Fatalf("expected unverified emails not to be linked got %v", err)
+	}
+	p.SignIn(map[string]interface{}{"sub": "2", "email": "bob@example.com", "email_verified": true})
+	if u := userOf(oidcLogin(t, s, p)); u.ID != bob.ID {
+		t.Fatalf("expected the verified email to link %s got %s", bob.ID, u.ID)
+	}
+
+	p.SignIn(map[string]interface{}{"sub": "3", "email": "robert@example.com", "preferred_username": "Bob"})
+	robert := userOf(oidcLogin(t, s, p))
+	if !regexp.MustCompile(`^Bob-[0-9a-f]{4}$`).MatchString(robert.Username) {
+		t.Fatalf("expected a suffix for the taken username got %q", robert.Username)
+	}
+	p.SignIn(map[string]interface{}{"sub": "4"})
+	if _, err := oidcLogin(t, s, p); !errors.Is(err, auth.ErrOIDCLogin) {
+		t.Fatalf("expected accounts without email to be refused got %v", err)
+	}
+
+	if err := s.Disable(ctx, robert.ID); err != nil {
+		t.Fatal(err)
+	}
+	p.SignIn(map[string]interface{}{"sub": "3", "email": "robert@example.com"})
+	if _, err := oidcLogin(t, s, p); !errors.Is(err, auth.ErrUserDisabled) {
+		t.Fatalf("expected auth.ErrUserDisabled got %v", err)
+	}
+	if err := s.Delete(ctx, robert.ID); err != nil {
+		t.Fatal(err)
+	}
+	if u := userOf(oidcLogin(t, s, p)); u.ID == robert.ID {
+		t.Fatal("expected deleting a user to unlink their identities")
+	}
+}
+
+func TestOIDCVerification(t *testing.T) {
+	ctx := context.Background()
+	s, p := oidcService(t)
+	signIn := func(extra map[string]interface{}) {
+		claims := map[string]interface{}{"sub": "1", "email": "alice@example.com", "email_verified": true}
+		for k, v := range extra {
+			claims[k] = v
+		}
+		p.SignIn(claims)
+	}
+	signIn(nil)
+	if _, err := oidcLogin(t, s, p); err != nil {
+		t.Fatal(err)
+	}
+
+	loginURL, _ := s.OIDCLoginURL(ctx)
+	callback := p.Authorize(t, loginURL).Query()
+	if _, err := s.OIDCCallback(ctx, callback.Get("state"), callback.Get("code")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OIDCCallback(ctx, callback.Get("state"), callback.Get("code")); !errors.Is(err, auth.ErrOIDCLogin) {
+		t.Fatalf("expected states to work once got %v", err)
+	}
+
+	// a code is bound to the PKCE challenge of the login that asked for it
+	first, _ := s.OIDCLoginURL(ctx)
+	second, _ := s.OIDCLoginURL(ctx)
+	code := p.Authorize(t, first).Query().Get("code")
+	state := p.Authorize(t, second).Query().Get("state")
+	if _, err := s.OIDCCallback(ctx, state, code); !errors.Is(err, auth.ErrOIDCLogin) {
+		t.Fatalf("expected the verifier of another login to be refused got %v", err)
+	}
+
+	for name, claims := range map[string]map[string]interface{}{
+		"audience": {"aud": "other-client"},
+		"issuer":   {"iss": "https://evil.example.com"},
+		"expired":  {"exp": time.Now().Add(-time.Hour).Unix()},
+		"nonce":    {"nonce": "replayed"},
+	} {
+		signIn(claims)
+		if _, err := oidcLogin(t, s, p); !errors.Is(err, auth.ErrOIDCLogin) {
+			t.Fatalf("%s: expected auth.ErrOIDCLogin got %v", name, err)
+		}
+	}
+
+	signIn(nil)
+	p.RotateKey(t, true)
+	if _, err := oidcLogin(t, s, p); err != nil {
+		t.Fatalf("expected keys added to the JWKS to be fetched got %v", err)
+	}
+	p.RotateKey(t, false)
+	if _, err := oidcLogin(t, s, p); !errors.Is(err, auth.ErrOIDCLogin) {
+		t.Fatalf("expected keys missing from the JWKS to be refused got %v", err)
+	}
+
+	p.SignIn(nil)
+	if _, err := oidcLogin(t, s, p); !errors.Is(err, auth.ErrOIDCLogin) {
+		t.Fatalf("expected denied logins to fail got %v", err)
+	}
+}
+
+func TestPasswordsDisabled(t *testing.T) {
+	ctx := context.Background()
+	s := auth.NewService(userstore.NewInMemoryStore())
+	s.Hasher = auth.Bcrypt{Cost: 4}
+	if _, err := s.Register(ctx, "alice", "alice@example.com", "password"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.OIDCLoginURL(ctx); !errors.Is(err, auth.ErrOIDCDisabled) {
+		t.Fatalf("expected auth.ErrOIDCDisabled got %v", err)
+	}
+	s.DisablePasswords = true
+	if _, err := s.Register(ctx, "bob", "bob@example.com", "password"); !errors.Is(err, auth.ErrPasswordLogin) {
+		t.Fatalf("expected auth.ErrPasswordLogin got %v", err)
+	}
+	if _, err := s.Login(ctx, "alice", "password"); !errors.Is(err, auth.ErrPasswordLogin) {
+		t.Fatalf("expected auth.ErrPasswordLogin got %v", err)
+	}
+}
+
+func TestOIDCTenant(t *testing.T) {
+	ctx := context.Background()
+	s, p := oidcService(t)
+	p.SignIn(map[string]interface{}{"sub": "1", "email": "alice@example.com", "email_verified": true})
+	loginURL, err := s.OIDCLoginURL(tenant.NewContext(ctx, "acme"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	callback := p.Authorize(t, loginURL).Query()
+	// the provider redirects back without the tenant
+	tokens, err := s.OIDCCallback(ctx, callback.Get("state"), callback.Get("code"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr, _ := s.Authenticate(ctx, tokens.AccessToken); pr.Tenant != "acme" {
+		t.Fatalf("expected the tenant of the login acme got %q", pr.Tenant)
+	}
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownHash is returned by VerifyPassword for stored hashes of no
+// supported algorithm.
+var ErrUnknownHash = errors.New("auth: unknown password hash")
+
+// PasswordHasher hashes passwords for storage. Hashes are self-describing:
+// they carry the algorithm, its parameters and the salt, so VerifyPassword
+// checks them whatever hasher is configured at the time.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password with a fresh salt.
+	Hash(password string) (string, error)
+	// NeedsRehash reports whether encoded was made by another algorithm or
+	// with other parameters than those of the hasher.
+	NeedsRehash(encoded string) bool
+}
+
+// Argon2id hashes passwords with argon2id. Hashes are encoded in the PHC
+// string format: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+type Argon2id struct {
+	Time    uint32
+	Memory  uint32 // in KiB
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+// NewArgon2id returns an argon2id hasher with the parameters recommended by
+// RFC 9106 for memory-constrained environments.
+func NewArgon2id() Argon2id {
+	return Argon2id{Time: 3, Memory: 64 * 1024, Threads: 4, KeyLen: 32, SaltLen: 16}
+}
+
+// Hash implements PasswordHasher.
+func (a Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Time, a.Memory, a.Threads, a.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, a.Memory, a.Time, a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// NeedsRehash implements PasswordHasher.
+func (a Argon2id) NeedsRehash(encoded string) bool {
+	h, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return h.params != a
+}
+
+// argon2idHash is a decoded argon2id hash.
+type argon2idHash struct {
+	params    Argon2id
+	salt, key []byte
+}
+
+func decodeArgon2id(encoded string) (*argon2idHash, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return nil, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("auth: unsupported argon2id version %q", parts[2])
+	}
+	var h argon2idHash
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.params.Memory, &h.params.Time, &h.params.Threads); err != nil {
+		return nil, fmt.Errorf("auth: invalid argon2id parameters %q", parts[3])
+	}
+	var err error
+	if h.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("auth: invalid argon2id salt: %w", err)
+	}
+	if h.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(h.key) == 0 {
+		return nil, errors.New("auth: invalid argon2id key")
+	}
+	h.params.SaltLen, h.params.KeyLen = uint32(len(h.salt)), uint32(len(h.key))
+	return &h, nil
+}
+
+// Bcrypt hashes passwords with bcrypt at the given cost. Only the first 72
+// bytes of a password are used.
+type Bcrypt struct {
+	Cost int
+}
+
+// Hash implements PasswordHasher.
+func (b Bcrypt) Hash(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	return string(h), err
+}
+
+// NeedsRehash implements PasswordHasher.
+func (b Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != b.Cost
+}
+
+// isLegacySHA256 reports whether encoded is an unsalted hex SHA-256 digest,
+// the format passwords were stored in before hashers were configurable.
+func isLegacySHA256(encoded string) bool {
+	if len(encoded) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+// VerifyPassword reports whether password matches a hash produced by any
+// supported hasher or a legacy SHA-256 digest. Keys are compared in
+// constant time.
+func VerifyPassword(password, encoded string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		h, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, err
+		}
+		key := argon2.IDKey([]byte(password), h.salt, h.params.Time, h.params.Memory, h.params.Threads, h.params.KeyLen)
+		return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+	case strings.HasPrefix(encoded, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	case isLegacySHA256(encoded):
+		want, _ := hex.DecodeString(encoded)
+		sum := sha256.Sum256([]byte(password))
+		return subtle.ConstantTimeCompare(sum[:], want) == 1, nil
+	default:
+		return false, ErrUnknownHash
+	}
+}
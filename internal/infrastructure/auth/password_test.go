@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/userstore"
+)
+
+// cheapArgon2id keeps the tests fast.
+var cheapArgon2id = Argon2id{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+
+func TestPasswordHashers(t *testing.T) {
+	for name, h := range map[string]PasswordHasher{
+		"argon2id": cheapArgon2id,
+		"bcrypt":   Bcrypt{Cost: 4},
+	} {
+		encoded, err := h.Hash("password")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if again, _ := h.Hash("password"); again == encoded {
+			t.Fatalf("%s: expected a fresh salt per hash", name)
+		}
+		if ok, err := VerifyPassword("password", encoded); err != nil || !ok {
+			t.Fatalf("%s: expected a match got %v %v", name, ok, err)
+		}
+		if ok, err := VerifyPassword("wrong", encoded); err != nil || ok {
+			t.Fatalf("%s: expected a mismatch got %v %v", name, ok, err)
+		}
+		if h.NeedsRehash(encoded) {
+			t.Fatalf("%s: expected no rehash for its own hash", name)
+		}
+	}
+
+	encoded, _ := cheapArgon2id.Hash("password")
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected encoding %s", encoded)
+	}
+	upgraded := cheapArgon2id
+	upgraded.Time = 2
+	if !upgraded.NeedsRehash(encoded) || !(Bcrypt{Cost: 4}).NeedsRehash(encoded) {
+		t.Fatal("expected a rehash for other parameters or algorithms")
+	}
+	if bc, _ := (Bcrypt{Cost: 4}).Hash("password"); !(Bcrypt{Cost: 5}).NeedsRehash(bc) || !cheapArgon2id.NeedsRehash(bc) {
+		t.Fatal("expected a rehash for another cost or algorithm")
+	}
+
+	legacy := sha256.Sum256([]byte("password"))
+	if ok, err := VerifyPassword("password", hex.EncodeToString(legacy[:])); err != nil || !ok {
+		t.Fatalf("expected legacy hashes to match got %v %v", ok, err)
+	}
+	for _, bad := range []string{"", "hash", "$argon2id$v=18$m=1,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=x$c2FsdA$a2V5"} {
+		if _, err := VerifyPassword("password", bad); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestLoginRehashes(t *testing.T) {
+	ctx := context.Background()
+	users := userstore.NewInMemoryStore()
+	legacy := sha256.Sum256([]byte("password"))
+	u, _ := user.NewUser("alice", "alice@example.com", hex.EncodeToString(legacy[:]))
+	if err := users.Create(ctx, u); err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(users)
+	s.Hasher = Bcrypt{Cost: 4}
+	hashAfterLogin := func(password string) string {
+		t.Helper()
+		if _, err := s.Login(ctx, "alice", password); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := users.ByID(ctx, u.ID)
+		return got.PasswordHash
+	}
+
+	if _, err := s.Login(ctx, "alice", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials got %v", err)
+	}
+	if got, _ := users.ByID(ctx, u.ID); got.PasswordHash != u.PasswordHash {
+		t.Fatal("expected a failed login to keep the hash")
+	}
+	bc := hashAfterLogin("password")
+	if !strings.HasPrefix(bc, "$2a$04$") {
+		t.Fatalf("expected the legacy hash to be replaced got %s", bc)
+	}
+	if got := hashAfterLogin("password"); got != bc {
+		t.Fatal("expected an up to date hash to be kept")
+	}
+	s.Hasher = cheapArgon2id
+	if got := hashAfterLogin("password"); !strings.HasPrefix(got, "$argon2id$") {
+		t.Fatalf("expected an argon2id hash got %s", got)
+	}
+}
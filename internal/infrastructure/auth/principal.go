@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+
+	"demo/internal/domain/user"
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID uuid.UUID
+	Role   user.Role
+	// APIKeyID and Scopes are set for requests made with an API key, which
+	// acts for its user within its scopes.
+	APIKeyID uuid.UUID
+	Scopes   []user.Permission
+	// Tenant is the tenant the access token or API key was issued for.
+	Tenant string
+}
+
+// Can reports whether the principal has permission perm. API keys need both
+// the scope and a user whose role has the permission.
+func (p Principal) Can(perm user.Permission) bool {
+	if !p.Role.Can(perm) {
+		return false
+	}
+	if p.APIKeyID == uuid.Nil {
+		return true
+	}
+	for _, s := range p.Scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying the principal p.
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx by NewContext.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLockoutStore counts failed logins and keeps lockouts in Redis, so
+// that every replica of the service enforces them.
+type RedisLockoutStore struct {
+	Redis *redis.Client
+}
+
+// NewRedisLockoutStore creates a Redis-backed lockout store.
+func NewRedisLockoutStore(addr string) *RedisLockoutStore {
+	return &RedisLockoutStore{Redis: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func loginFailuresKey(key string) string { return "login_failures:" + key }
+
+func loginLockoutKey(key string) string { return "login_lockout:" + key }
+
+// Lockout implements LockoutStore.
+func (s *RedisLockoutStore) Lockout(ctx context.Context, key string) (time.Duration, error) {
+	d, err := s.Redis.PTTL(ctx, loginLockoutKey(key)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// missing keys have a negative ttl
+	if d < 0 {
+		return 0, nil
+	}
+	return d, nil
+}
+
+// Fail implements LockoutStore.
+func (s *RedisLockoutStore) Fail(ctx context.Context, key string, window time.Duration) (int, error) {
+	var failures *redis.IntCmd
+	_, err := s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		failures = pipe.Incr(ctx, loginFailuresKey(key))
+		pipe.PExpire(ctx, loginFailuresKey(key), window)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(failures.Val()), nil
+}
+
+// Lock implements LockoutStore.
+func (s *RedisLockoutStore) Lock(ctx context.Context, key string, d time.Duration) error {
+	return s.Redis.Set(ctx, loginLockoutKey(key), 1, d).Err()
+}
+
+// Reset implements LockoutStore.
+func (s *RedisLockoutStore) Reset(ctx context.Context, key string) error {
+	return s.Redis.Del(ctx, loginFailuresKey(key), loginLockoutKey(key)).Err()
+}
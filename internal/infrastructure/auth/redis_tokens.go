@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisTokenStore keeps refresh tokens and revoked access tokens in Redis,
+// where every replica of the service sees them. Each entry expires with the
+// token it describes.
+type RedisTokenStore struct {
+	Redis *redis.Client
+}
+
+// NewRedisTokenStore creates a Redis-backed token store.
+func NewRedisTokenStore(addr string) *RedisTokenStore {
+	return &RedisTokenStore{Redis: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func refreshKey(hash string) string { return "refresh_token:" + hash }
+
+func userRefreshKey(userID uuid.UUID) string { return "user_refresh_tokens:" + userID.String() }
+
+func revokedKey(jti string) string { return "revoked_token:" + jti }
+
+func revokedUserKey(userID uuid.UUID) string { return "revoked_user:" + userID.String() }
+
+func loginKey(stateHash string) string { return "oidc_login:" + stateHash }
+
+// SaveRefresh implements TokenStore. The token is indexed in a set of the
+// tokens of its user, which lives as long as the newest of them.
+func (s *RedisTokenStore) SaveRefresh(ctx context.Context, t RefreshToken) error {
+	ttl := time.Until(t.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.Redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, refreshKey(t.Hash), data, ttl)
+		pipe.SAdd(ctx, userRefreshKey(t.UserID), t.Hash)
+		pipe.Expire(ctx, userRefreshKey(t.UserID), ttl)
+		return nil
+	})
+	return err
+}
+
+// TakeRefresh implements TokenStore.
+func (s *RedisTokenStore) TakeRefresh(ctx context.Context, hash string) (*RefreshToken, error) {
+	data, err := s.Redis.GetDel(ctx, refreshKey(hash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var t RefreshToken
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if err := s.Redis.SRem(ctx, userRefreshKey(t.UserID), hash).Err(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// DeleteUserRefresh implements TokenStore.
+func (s *RedisTokenStore) DeleteUserRefresh(ctx context.Context, userID uuid.UUID) error {
+	hashes, err := s.Redis.SMembers(ctx, userRefreshKey(userID)).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{userRefreshKey(userID)}
+	for _, hash := range hashes {
+		keys = append(keys, refreshKey(hash))
+	}
+	return s.Redis.Del(ctx, keys...).Err()
+}
+
+// Revoke implements TokenStore.
+func (s *RedisTokenStore) Revoke(ctx context.Context, jti string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.Redis.Set(ctx, revokedKey(jti), 1, ttl).Err()
+}
+
+// RevokeUser implements TokenStore.
+func (s *RedisTokenStore) RevokeUser(ctx context.Context, userID uuid.UUID, before, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.Redis.Set(ctx, revokedUserKey(userID), before.UnixNano(), ttl).Err()
+}
+
+// Revoked implements TokenStore.
+func (s *RedisTokenStore) Revoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	var exists *redis.IntCmd
+	var before *redis.StringCmd
+	_, err := s.Redis.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		exists = pipe.Exists(ctx, revokedKey(jti))
+		before = pipe.Get(ctx, revokedUserKey(userID))
+		return nil
+	})
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return false, err
+	}
+	if exists.Val() > 0 {
+		return true, nil
+	}
+	if errors.Is(before.Err(), redis.Nil) {
+		return false, nil
+	}
+	nanos, err := strconv.ParseInt(before.Val(), 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedAt.Before(time.Unix(0, nanos)), nil
+}
+
+// SaveLogin implements TokenStore.
+func (s *RedisTokenStore) SaveLogin(ctx context.Context, l PendingLogin) error {
+	ttl := time.Until(l.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	return s.Redis.Set(ctx, loginKey(l.StateHash), data, ttl).Err()
+}
+
+// TakeLogin implements TokenStore.
+func (s *RedisTokenStore) TakeLogin(ctx context.Context, stateHash string) (*PendingLogin, error) {
+	data, err := s.Redis.GetDel(ctx, loginKey(stateHash)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var l PendingLogin
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
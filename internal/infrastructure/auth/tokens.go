@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RefreshToken is the server-side record of an opaque refresh token. Only a
+// hash of the token is kept.
+type RefreshToken struct {
+	Hash   string
+	UserID uuid.UUID
+	// Tenant is the tenant the session was issued for.
+	Tenant    string
+	ExpiresAt time.Time
+}
+
+// PendingLogin is an OpenID Connect login waiting for the user to come back
+// from the provider, stored under a hash of its state parameter.
+type PendingLogin struct {
+	StateHash string
+	// Verifier is the PKCE code verifier sent with the authorization code.
+	Verifier string
+	Nonce    string
+	// Tenant is the tenant the login was started in. The provider redirects
+	// back without it, so the session is issued for this one.
+	Tenant    string
+	ExpiresAt time.Time
+}
+
+// TokenStore keeps refresh tokens, the revocation list of access tokens and
+// pending OpenID Connect logins. Entries are dropped once the tokens they
+// describe have expired.
+type TokenStore interface {
+	// SaveRefresh stores a refresh token until it expires.
+	SaveRefresh(ctx context.Context, t RefreshToken) error
+	// TakeRefresh removes and returns the refresh token with the given
+	// hash. It returns nil for unknown or expired tokens; of concurrent calls
+	// for the same token only one gets it.
+	TakeRefresh(ctx context.Context, hash string) (*RefreshToken, error)
+	// DeleteUserRefresh removes every refresh token of a user.
+	DeleteUserRefresh(ctx context.Context, userID uuid.UUID) error
+	// Revoke revokes the access token with the given id until it expires.
+	Revoke(ctx context.Context, jti string, until time.Time) error
+	// RevokeUser revokes the access tokens of a user issued before before,
+	// remembering it until until.
+	RevokeUser(ctx context.Context, userID uuid.UUID, before, until time.Time) error
+	// Revoked reports whether the access token jti of userID, issued at
+	// issuedAt, was revoked.
+	Revoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error)
+	// SaveLogin stores a pending login until it expires.
+	SaveLogin(ctx context.Context, l PendingLogin) error
+	// TakeLogin removes and returns the pending login with the given state
+	// hash. It returns nil for unknown or expired logins.
+	TakeLogin(ctx context.Context, stateHash string) (*PendingLogin, error)
+}
+
+// userRevocation revokes the tokens of a user issued before a time.
+type userRevocation struct {
+	before, until time.Time
+}
+
+// InMemoryTokenStore is a process-local token store.
+type InMemoryTokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]RefreshToken
+	revoked map[string]time.Time
+	users   map[uuid.UUID]userRevocation
+	logins  map[string]PendingLogin
+}
+
+// NewInMemoryTokenStore creates the store.
+func NewInMemoryTokenStore() *InMemoryTokenStore {
+	return &InMemoryTokenStore{
+		refresh: make(map[string]RefreshToken),
+		revoked: make(map[string]time.Time),
+		users:   make(map[uuid.UUID]userRevocation),
+		logins:  make(map[string]PendingLogin),
+	}
+}
+
+// prune drops the expired entries. The caller holds the lock.
+func (s *InMemoryTokenStore) prune(now time.Time) {
+	for hash, t := range s.refresh {
+		if !now.Before(t.ExpiresAt) {
+			delete(s.refresh, hash)
+		}
+	}
+	for jti, until := range s.revoked {
+		if !now.Before(until) {
+			delete(s.revoked, jti)
+		}
+	}
+	for id, r := range s.users {
+		if !now.Before(r.until) {
+			delete(s.users, id)
+		}
+	}
+	for hash, l := range s.logins {
+		if !now.Before(l.ExpiresAt) {
+			delete(s.logins, hash)
+		}
+	}
+}
+
+// SaveRefresh implements TokenStore.
+func (s *InMemoryTokenStore) SaveRefresh(ctx context.Context, t RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	s.refresh[t.Hash] = t
+	return nil
+}
+
+// TakeRefresh implements TokenStore.
+func (s *InMemoryTokenStore) TakeRefresh(ctx context.Context, hash string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.refresh[hash]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.refresh, hash)
+	if !time.Now().Before(t.ExpiresAt) {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+// DeleteUserRefresh implements TokenStore.
+func (s *InMemoryTokenStore) DeleteUserRefresh(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for hash, t := range s.refresh {
+		if t.UserID == userID {
+			delete(s.refresh, hash)
+		}
+	}
+	return nil
+}
+
+// Revoke implements TokenStore.
+func (s *InMemoryTokenStore) Revoke(ctx context.Context, jti string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	s.revoked[jti] = until
+	return nil
+}
+
+// RevokeUser implements TokenStore.
+func (s *InMemoryTokenStore) RevokeUser(ctx context.Context, userID uuid.UUID, before, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	s.users[userID] = userRevocation{before: before, until: until}
+	return nil
+}
+
+// Revoked implements TokenStore.
+func (s *InMemoryTokenStore) Revoked(ctx context.Context, jti string, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if until, ok := s.revoked[jti]; ok && now.Before(until) {
+		return true, nil
+	}
+	r, ok := s.users[userID]
+	return ok && now.Before(r.until) && issuedAt.Before(r.before), nil
+}
+
+// SaveLogin implements TokenStore.
+func (s *InMemoryTokenStore) SaveLogin(ctx context.Context, l PendingLogin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(time.Now())
+	s.logins[l.StateHash] = l
+	return nil
+}
+
+// TakeLogin implements TokenStore.
+func (s *InMemoryTokenStore) TakeLogin(ctx context.Context, stateHash string) (*PendingLogin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.logins[stateHash]
+	if !ok {
+		return nil, nil
+	}
+	delete(s.logins, stateHash)
+	if !time.Now().Before(l.ExpiresAt) {
+		return nil, nil
+	}
+	return &l, nil
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestTokenStores(t *testing.T) {
+	for name, newStore := range map[string]func(t *testing.T) TokenStore{
+		"memory": func(t *testing.T) TokenStore { return NewInMemoryTokenStore() },
+		"redis": func(t *testing.T) TokenStore {
+			s := miniredis.RunT(t)
+			return &RedisTokenStore{Redis: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testTokenStore(t, newStore(t))
+		})
+	}
+}
+
+func testTokenStore(t *testing.T, store TokenStore) {
+	ctx := context.Background()
+	now := time.Now()
+	alice, bob := uuid.New(), uuid.New()
+	for _, rt := range []RefreshToken{
+		{Hash: "a1", UserID: alice, Tenant: "acme", ExpiresAt: now.Add(time.Hour)},
+		{Hash: "a2", UserID: alice, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "b1", UserID: bob, ExpiresAt: now.Add(time.Hour)},
+		{Hash: "old", UserID: bob, ExpiresAt: now.Add(-time.Hour)},
+	} {
+		if err := store.SaveRefresh(ctx, rt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := store.TakeRefresh(ctx, "a1")
+	if err != nil || got == nil || got.UserID != alice || got.Tenant != "acme" || !got.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("unexpected token %+v %v", got, err)
+	}
+	for _, hash := range []string{"a1", "old", "unknown"} {
+		if got, err := store.TakeRefresh(ctx, hash); err != nil || got != nil {
+			t.Fatalf("%s: expected nil, nil got %+v %v", hash, got, err)
+		}
+	}
+	if err := store.DeleteUserRefresh(ctx, alice); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := store.TakeRefresh(ctx, "a2"); got != nil {
+		t.Fatalf("expected the tokens of alice to be deleted got %+v", got)
+	}
+	if got, _ := store.TakeRefresh(ctx, "b1"); got == nil {
+		t.Fatal("expected the tokens of bob to be kept")
+	}
+
+	if err := store.Revoke(ctx, "jti", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke(ctx, "expired", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.RevokeUser(ctx, alice, now, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		jti      string
+		userID   uuid.UUID
+		issuedAt time.Time
+		want     bool
+	}{
+		{"jti", bob, now, true},
+		{"expired", bob, now, false},
+		{"other", alice, now.Add(-time.Second), true},
+		{"other", alice, now.Add(time.Second), false},
+		{"other", bob, now.Add(-time.Second), false},
+	}
+	for _, tt := range tests {
+		if got, err := store.Revoked(ctx, tt.jti, tt.userID, tt.issuedAt); err != nil || got != tt.want {
+			t.Fatalf("%+v: expected %v got %v %v", tt, tt.want, got, err)
+		}
+	}
+	for _, l := range []PendingLogin{
+		{StateHash: "s1", Verifier: "v1", Nonce: "n1", Tenant: "acme", ExpiresAt: now.Add(time.Minute)},
+		{StateHash: "old", Verifier: "v2", Nonce: "n2", ExpiresAt: now.Add(-time.Minute)},
+	} {
+		if err := store.SaveLogin(ctx, l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l, err := store.TakeLogin(ctx, "s1")
+	if err != nil || l == nil || l.Verifier != "v1" || l.Nonce != "n1" || l.Tenant != "acme" {
+		t.Fatalf("unexpected login %+v %v", l, err)
+	}
+	for _, hash := range []string{"s1", "old", "unknown"} {
+		if got, err := store.TakeLogin(ctx, hash); err != nil || got != nil {
+			t.Fatalf("%s: expected nil, nil got %+v %v", hash, got, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -21,23 +22,26 @@ func NewRedisRepository(repo card.Repository, addr string) *RedisRepository {
 	return &RedisRepository{Repo: repo, Redis: rdb}
 }
 
-func key(id string) string { return "card:" + id }
+// key returns the cache key of a card of a tenant. The cards of the default
+// tenant keep the keys they had before tenants existed.
+func key(tenantID, id string) string { return tenant.Prefix(tenantID, ":") + "card:" + id }
 
 // Save persists events and updates the cache based on those events.
 func (r *RedisRepository) Save(ctx context.Context, events []interface{}) error {
 	if err := r.Repo.Save(ctx, events); err != nil {
 		return err
 	}
+	t := tenant.FromContext(ctx)
 	for _, evt := range events {
 		switch e := evt.(type) {
 		case card.CardCreated:
 			c := card.Card(e)
 			data, _ := json.Marshal(c)
-			r.Redis.Set(ctx, key(e.ID.String()), data, time.Hour)
+			r.Redis.Set(ctx, key(t, e.ID.String()), data, time.Hour)
 		case card.CardUpdated:
 			c := card.Card(e)
 			data, _ := json.Marshal(c)
-			r.Redis.Set(ctx, key(e.ID.String()), data, time.Hour)
+			r.Redis.Set(ctx, key(t, e.ID.String()), data, time.Hour)
 		}
 	}
 	return nil
@@ -45,7 +49,7 @@ func (r *RedisRepository) Save(ctx context.Context, events []interface{}) error
 
 // Load first checks Redis and falls back to the underlying repository.
 func (r *RedisRepository) Load(ctx context.Context, id string) (*card.Card, error) {
-	val, err := r.Redis.Get(ctx, key(id)).Result()
+	val, err := r.Redis.Get(ctx, key(tenant.FromContext(ctx), id)).Result()
 	if err == nil {
 		var c card.Card
 		if err := json.Unmarshal([]byte(val), &c); err == nil {
@@ -57,7 +61,7 @@ func (r *RedisRepository) Load(ctx context.Context, id string) (*card.Card, erro
 		return c, err
 	}
 	data, _ := json.Marshal(c)
-	r.Redis.Set(ctx, key(id), data, time.Hour)
+	r.Redis.Set(ctx, key(tenant.FromContext(ctx), id), data, time.Hour)
 	return c, nil
 }
 
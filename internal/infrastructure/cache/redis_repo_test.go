@@ -6,6 +6,9 @@ import (
 	"testing"
 
 	"demo/internal/domain/card"
+	"demo/internal/domain/card/cardtest"
+	"demo/internal/domain/tenant"
+	"demo/internal/infrastructure/eventstore"
 	"github.com/alicebob/miniredis/v2"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
@@ -62,9 +65,36 @@ func TestRedisRepoLoadCached(t *testing.T) {
 	// prime cache
 	_ = r.Save(context.Background(), []interface{}{card.CardCreated{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Name: "N"}})
 	// manually set invalid json to ensure it falls back to repo
-	rdb.Set(context.Background(), key("x"), "bad", 0)
+	rdb.Set(context.Background(), key(tenant.Default, "x"), "bad", 0)
 	c, err := r.Load(context.Background(), "x")
 	if err != nil || c == nil || c.Name != "N" {
 		t.Fatalf("unexpected %v %v", c, err)
 	}
 }
+
+func TestRedisRepoConformance(t *testing.T) {
+	cardtest.RepositorySuite.Run(t, func(t *testing.T) card.Repository {
+		s := miniredis.RunT(t)
+		rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+		return &RedisRepository{Repo: eventstore.NewInMemoryStore(), Redis: rdb}
+	})
+}
+
+func TestRedisRepoTenantKeys(t *testing.T) {
+	s := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	r := &RedisRepository{Repo: eventstore.NewInMemoryStore(), Redis: rdb}
+	id := uuid.New()
+	acme := tenant.NewContext(context.Background(), "acme")
+	_ = r.Save(acme, []interface{}{card.CardCreated{ID: id, Name: "A"}})
+	_ = r.Save(context.Background(), []interface{}{card.CardCreated{ID: uuid.New(), Name: "D"}})
+	if !s.Exists("acme:card:" + id.String()) {
+		t.Fatalf("expected the card to be cached under the tenant prefix got %v", s.Keys())
+	}
+	if key(tenant.Default, "x") != "card:x" {
+		t.Fatalf("expected the default tenant to keep unprefixed keys got %q", key(tenant.Default, "x"))
+	}
+	if c, _ := r.Load(context.Background(), id.String()); c != nil {
+		t.Fatalf("expected the cache not to serve other tenants got %+v", c)
+	}
+}
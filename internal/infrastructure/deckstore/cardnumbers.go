@@ -0,0 +1,163 @@
+package deckstore
+
+import (
+	"context"
+	"sync"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantCard is a card of the catalog of a tenant.
+type tenantCard struct {
+	tenant string
+	cardID uuid.UUID
+}
+
+// InMemoryCardNumbers is a process-local card number repository.
+type InMemoryCardNumbers struct {
+	mu      sync.Mutex
+	numbers map[tenantCard]uint64
+	cards   map[uint64]tenantCard
+}
+
+// NewInMemoryCardNumbers creates the repository.
+func NewInMemoryCardNumbers() *InMemoryCardNumbers {
+	return &InMemoryCardNumbers{
+		numbers: make(map[tenantCard]uint64),
+		cards:   make(map[uint64]tenantCard),
+	}
+}
+
+// Numbers implements deck.CardNumberRepository.
+func (r *InMemoryCardNumbers) Numbers(ctx context.Context, cardIDs []uuid.UUID) (map[uuid.UUID]uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := tenant.FromContext(ctx)
+	numbers := make(map[uuid.UUID]uint64, len(cardIDs))
+	for _, id := range cardIDs {
+		key := tenantCard{t, id}
+		n, ok := r.numbers[key]
+		if !ok {
+			n = uint64(len(r.numbers) + 1)
+			r.numbers[key] = n
+			r.cards[n] = key
+		}
+		numbers[id] = n
+	}
+	return numbers, nil
+}
+
+// CardIDs implements deck.CardNumberRepository.
+func (r *InMemoryCardNumbers) CardIDs(ctx context.Context, numbers []uint64) (map[uint64]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := tenant.FromContext(ctx)
+	ids := make(map[uint64]uuid.UUID, len(numbers))
+	for _, n := range numbers {
+		if key, ok := r.cards[n]; ok && key.tenant == t {
+			ids[n] = key.cardID
+		}
+	}
+	return ids, nil
+}
+
+// CardNumberRecord is a numbered card in the card_numbers table.
+type CardNumberRecord struct {
+	Number uint64 `gorm:"primaryKey;autoIncrement"`
+	Tenant string `gorm:"size:64;not null;uniqueIndex:idx_tenant_card,priority:1"`
+	CardID string `gorm:"size:36;not null;uniqueIndex:idx_tenant_card,priority:2"`
+}
+
+// TableName implements gorm's tabler interface.
+func (CardNumberRecord) TableName() string { return "card_numbers" }
+
+// MySQLCardNumbers is a GORM-based card number repository numbering cards
+// with the auto-increment key of the card_numbers table.
+type MySQLCardNumbers struct {
+	DB *gorm.DB
+}
+
+// NewMySQLCardNumbers creates the card_numbers table if needed.
+func NewMySQLCardNumbers(db *gorm.DB) (*MySQLCardNumbers, error) {
+	if err := db.AutoMigrate(&CardNumberRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLCardNumbers{DB: db}, nil
+}
+
+// Numbers implements deck.CardNumberRepository.
+func (r *MySQLCardNumbers) Numbers(ctx context.Context, cardIDs []uuid.UUID) (map[uuid.UUID]uint64, error) {
+	numbers, err := r.find(ctx, cardIDs)
+	if err != nil || len(numbers) == len(cardIDs) {
+		return numbers, err
+	}
+	var missing []CardNumberRecord
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range cardIDs {
+		if _, ok := numbers[id]; !ok && !seen[id] {
+			seen[id] = true
+			missing = append(missing, CardNumberRecord{Tenant: tenant.FromContext(ctx), CardID: id.String()})
+		}
+	}
+	if len(missing) == 0 {
+		return numbers, nil
+	}
+	// cards numbered concurrently keep the number they were given first
+	if err := r.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&missing).Error; err != nil {
+		return nil, err
+	}
+	return r.find(ctx, cardIDs)
+}
+
+// find returns the numbers of the cards that have one.
+func (r *MySQLCardNumbers) find(ctx context.Context, cardIDs []uuid.UUID) (map[uuid.UUID]uint64, error) {
+	numbers := make(map[uuid.UUID]uint64, len(cardIDs))
+	if len(cardIDs) == 0 {
+		return numbers, nil
+	}
+	ids := make([]string, 0, len(cardIDs))
+	for _, id := range cardIDs {
+		ids = append(ids, id.String())
+	}
+	var recs []CardNumberRecord
+	if err := r.DB.WithContext(ctx).Where("tenant = ? AND card_id IN ?", tenant.FromContext(ctx), ids).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		id, err := uuid.Parse(rec.CardID)
+		if err != nil {
+			return nil, err
+		}
+		numbers[id] = rec.Number
+	}
+	return numbers, nil
+}
+
+// CardIDs implements deck.CardNumberRepository.
+func (r *MySQLCardNumbers) CardIDs(ctx context.Context, numbers []uint64) (map[uint64]uuid.UUID, error) {
+	ids := make(map[uint64]uuid.UUID, len(numbers))
+	if len(numbers) == 0 {
+		return ids, nil
+	}
+	var recs []CardNumberRecord
+	if err := r.DB.WithContext(ctx).Where("tenant = ? AND number IN ?", tenant.FromContext(ctx), numbers).Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	for _, rec := range recs {
+		id, err := uuid.Parse(rec.CardID)
+		if err != nil {
+			return nil, err
+		}
+		ids[rec.Number] = id
+	}
+	return ids, nil
+}
+
+var (
+	_ deck.CardNumberRepository = (*InMemoryCardNumbers)(nil)
+	_ deck.CardNumberRepository = (*MySQLCardNumbers)(nil)
+)
@@ -0,0 +1,59 @@
+package deckstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"demo/internal/domain/deck/decktest"
+)
+
+func TestInMemoryErasureConformance(t *testing.T) {
+	decktest.ErasureSuite.Run(t, func(t *testing.T) decktest.Erasable {
+		s := NewInMemoryStore()
+		return decktest.Erasable{Store: s, Dump: func(t *testing.T) string {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			var b strings.Builder
+			for _, decks := range s.decks {
+				for _, d := range decks {
+					fmt.Fprintf(&b, "%+v\n", *d)
+				}
+			}
+			for _, revs := range s.revisions {
+				fmt.Fprintf(&b, "%+v\n", revs)
+			}
+			return b.String()
+		}}
+	})
+}
+
+func TestRedisErasureConformance(t *testing.T) {
+	decktest.ErasureSuite.Run(t, func(t *testing.T) decktest.Erasable {
+		s := newRedisStore(t)
+		return decktest.Erasable{Store: s, Dump: func(t *testing.T) string {
+			ctx := context.Background()
+			keys, err := s.Redis.Keys(ctx, "*").Result()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var b strings.Builder
+			for _, key := range keys {
+				var val interface{}
+				switch s.Redis.Type(ctx, key).Val() {
+				case "string":
+					val = s.Redis.Get(ctx, key).Val()
+				case "list":
+					val = s.Redis.LRange(ctx, key, 0, -1).Val()
+				case "zset":
+					val = s.Redis.ZRange(ctx, key, 0, -1).Val()
+				default:
+					t.Fatalf("unexpected key %s", key)
+				}
+				fmt.Fprintf(&b, "%s %v\n", key, val)
+			}
+			return b.String()
+		}}
+	})
+}
@@ -0,0 +1,102 @@
+package deckstore
+
+import (
+	"context"
+	"errors"
+
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/eventstore"
+	"github.com/google/uuid"
+)
+
+// EventLog is the stream level API of the event stores in
+// internal/infrastructure/eventstore.
+type EventLog interface {
+	// Append appends events to stream id, which must hold expected events,
+	// and returns eventstore.ErrVersionConflict otherwise.
+	Append(ctx context.Context, id string, expected int, events []interface{}) error
+	Events(ctx context.Context, id string) ([]interface{}, error)
+}
+
+// EventStore persists decks as event streams in an EventLog, next to the
+// card streams. The version of a deck is the length of its stream and its
+// revisions are the DeckRevised events in it. Index lists the decks of every
+// user.
+type EventStore struct {
+	Log   EventLog
+	Index UserIndex
+}
+
+// NewEventStore creates a deck repository on top of log with an index kept
+// in memory. Logs outliving the process need a persistent index.
+func NewEventStore(log EventLog) *EventStore {
+	return &EventStore{Log: log, Index: NewInMemoryUserIndex()}
+}
+
+// Save appends the events to the stream of their deck and then updates the
+// index. A failed index update leaves the deck out of its user's list.
+func (s *EventStore) Save(ctx context.Context, expected int, events []interface{}) error {
+	id, err := checkEvents(events)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	if err := s.Log.Append(ctx, id.String(), expected, events); err != nil {
+		if errors.Is(err, eventstore.ErrVersionConflict) {
+			return deck.ErrVersionConflict
+		}
+		return err
+	}
+	for _, evt := range events {
+		var err error
+		switch e := evt.(type) {
+		case deck.DeckCreated:
+			err = s.Index.Add(ctx, e.UserID, e.ID, e.CreatedAt)
+		case deck.DeckDeleted:
+			err = s.Index.Remove(ctx, e.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Load replays the events of a deck.
+func (s *EventStore) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
+	events, err := s.Log.Events(ctx, id.String())
+	if err != nil {
+		return nil, err
+	}
+	d := deck.Replay(events)
+	if d == nil || d.Deleted || d.ID != id {
+		return nil, nil
+	}
+	return d, nil
+}
+
+// ListByUser pages over the decks of userID in the index and replays only
+// those.
+func (s *EventStore) ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*deck.Deck, int, error) {
+	ids, total, err := s.Index.Page(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	var decks []*deck.Deck
+	for _, id := range ids {
+		d, err := s.Load(ctx, id)
+		if err != nil {
+			return nil, 0, err
+		}
+		if d != nil {
+			decks = append(decks, d)
+		}
+	}
+	return decks, total, nil
+}
+
+// Tenants returns the tenants in which userID has decks in the index.
+func (s *EventStore) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.Index.Tenants(ctx, userID)
+}
+
+var _ deck.Repository = (*EventStore)(nil)
@@ -0,0 +1,122 @@
+package deckstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"demo/internal/application/command"
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/deck/decktest"
+	"demo/internal/infrastructure/eventstore"
+	"demo/internal/infrastructure/shredding"
+	"github.com/google/uuid"
+)
+
+func TestEventStoreConformance(t *testing.T) {
+	decktest.RepositorySuite.Run(t, func(t *testing.T) deck.Repository {
+		return NewEventStore(eventstore.NewInMemoryStore())
+	})
+}
+
+func TestEventStoreFileLogConformance(t *testing.T) {
+	decktest.RepositorySuite.Run(t, func(t *testing.T) deck.Repository {
+		log, err := eventstore.NewFileStore(t.TempDir(), eventstore.FileOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = log.Close() })
+		return NewEventStore(log)
+	})
+}
+
+// countingLog counts the streams replayed from an EventLog.
+type countingLog struct {
+	EventLog
+	replays int
+}
+
+func (l *countingLog) Events(ctx context.Context, id string) ([]interface{}, error) {
+	l.replays++
+	return l.EventLog.Events(ctx, id)
+}
+
+func TestEventStoreListReplaysOwnDecks(t *testing.T) {
+	ctx := context.Background()
+	log := &countingLog{EventLog: eventstore.NewInMemoryStore()}
+	decks := NewEventStore(log)
+	for i := 0; i < 10; i++ {
+		d := deck.NewDeck(uuid.New(), "other", nil)
+		if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mine := deck.NewDeck(uuid.New(), "mine", nil)
+	if err := decks.Save(ctx, 0, []interface{}{mine.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	list, total, err := decks.ListByUser(ctx, mine.UserID, 0, 20)
+	if err != nil || total != 1 || len(list) != 1 || list[0].ID != mine.ID {
+		t.Fatalf("unexpected decks %+v %d %v", list, total, err)
+	}
+	if log.replays != 1 {
+		t.Fatalf("expected only the deck of the user to be replayed got %d replays", log.replays)
+	}
+}
+
+func TestEventStoreSharedWithCards(t *testing.T) {
+	ctx := context.Background()
+	log := eventstore.NewInMemoryStore()
+	decks := NewEventStore(log)
+	c := card.NewCard("N", 1, "F", "C", "S", "D")
+	if err := log.Save(ctx, []interface{}{card.CardCreated{ID: c.ID, Name: c.Name}}); err != nil {
+		t.Fatal(err)
+	}
+	d := deck.NewDeck(uuid.New(), "d", []uuid.UUID{c.ID})
+	if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := decks.Save(ctx, 0, []interface{}{card.CardUpdated{ID: c.ID}}); err == nil {
+		t.Fatal("expected error saving card events through the deck repository")
+	}
+	if got, err := decks.Load(ctx, c.ID); err != nil || got != nil {
+		t.Fatalf("card stream must not load as deck: %+v %v", got, err)
+	}
+	cards, err := log.Search(ctx, "", 0, "", "", "")
+	if err != nil || len(cards) != 1 || cards[0].ID != c.ID {
+		t.Fatalf("deck streams must not appear in card search: %v %v", cards, err)
+	}
+	if got, err := log.Load(ctx, d.ID.String()); err != nil || got != nil {
+		t.Fatalf("deck stream must not load as card: %+v %v", got, err)
+	}
+}
+
+func TestEventStoreForgetUser(t *testing.T) {
+	ctx := context.Background()
+	keys, err := shredding.NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log, err := eventstore.NewFileStore(t.TempDir(), eventstore.FileOptions{Cipher: shredding.NewProtector(keys)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = log.Close() }()
+	decks := NewEventStore(log)
+	d := deck.NewDeck(uuid.New(), "my secret deck", nil)
+	if err := decks.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decks.Load(ctx, d.ID); err != nil || got.Name != "my secret deck" {
+		t.Fatalf("unexpected deck %+v %v", got, err)
+	}
+	forget := &command.ForgetUserHandler{Keys: keys}
+	if err := forget.Handle(ctx, command.ForgetUserCommand{UserID: d.UserID}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := decks.Load(ctx, d.ID)
+	if err != nil || got == nil || got.Name != shredding.Redacted || got.UserID != d.UserID {
+		t.Fatalf("expected redacted deck got %+v %v", got, err)
+	}
+}
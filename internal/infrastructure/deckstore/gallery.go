@@ -0,0 +1,439 @@
+package deckstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantDeck names a deck of a tenant in the in-memory gallery and history.
+type tenantDeck struct {
+	tenant string
+	deckID uuid.UUID
+}
+
+// InMemoryGallery is a process-local gallery read model. Every tenant has
+// a gallery of its own.
+type InMemoryGallery struct {
+	mu      sync.RWMutex
+	entries map[tenantDeck]deck.GalleryEntry
+	likes   map[tenantDeck]map[uuid.UUID]bool
+}
+
+// NewInMemoryGallery creates the gallery.
+func NewInMemoryGallery() *InMemoryGallery {
+	return &InMemoryGallery{entries: make(map[tenantDeck]deck.GalleryEntry), likes: make(map[tenantDeck]map[uuid.UUID]bool)}
+}
+
+// Put adds or replaces the entry of a deck, keeping its likes.
+func (g *InMemoryGallery) Put(ctx context.Context, e deck.GalleryEntry) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := tenantDeck{tenant.FromContext(ctx), e.DeckID}
+	e = cloneEntry(e)
+	e.Likes = len(g.likes[k])
+	g.entries[k] = e
+	return nil
+}
+
+// Remove drops the entry of a deck along with its likes.
+func (g *InMemoryGallery) Remove(ctx context.Context, deckID uuid.UUID) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	k := tenantDeck{tenant.FromContext(ctx), deckID}
+	delete(g.entries, k)
+	delete(g.likes, k)
+	return nil
+}
+
+// Entry returns the entry of a deck.
+func (g *InMemoryGallery) Entry(ctx context.Context, deckID uuid.UUID) (*deck.GalleryEntry, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	e, ok := g.entries[tenantDeck{tenant.FromContext(ctx), deckID}]
+	if !ok {
+		return nil, nil
+	}
+	e = cloneEntry(e)
+	return &e, nil
+}
+
+// Search scans every entry for matches.
+func (g *InMemoryGallery) Search(ctx context.Context, q deck.GalleryQuery) ([]deck.GalleryEntry, int, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	t := tenant.FromContext(ctx)
+	var entries []deck.GalleryEntry
+	for k, e := range g.entries {
+		if k.tenant == t && q.Matches(e) && (q.LikedBy == uuid.Nil || g.likes[k][q.LikedBy]) {
+			entries = append(entries, cloneEntry(e))
+		}
+	}
+	deck.SortGallery(entries, q.Sort)
+	total := len(entries)
+	offset := q.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if q.Limit > 0 && offset+q.Limit < total {
+		end = offset + q.Limit
+	}
+	return entries[offset:end], total, nil
+}
+
+// Like records that userID likes a deck.
+func (g *InMemoryGallery) Like(ctx context.Context, deckID, userID uuid.UUID) (*deck.GalleryEntry, error) {
+	return g.setLike(tenantDeck{tenant.FromContext(ctx), deckID}, userID, true)
+}
+
+// Unlike takes back the like of userID.
+func (g *InMemoryGallery) Unlike(ctx context.Context, deckID, userID uuid.UUID) (*deck.GalleryEntry, error) {
+	return g.setLike(tenantDeck{tenant.FromContext(ctx), deckID}, userID, false)
+}
+
+func (g *InMemoryGallery) setLike(k tenantDeck, userID uuid.UUID, like bool) (*deck.GalleryEntry, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	e, ok := g.entries[k]
+	if !ok {
+		return nil, nil
+	}
+	if like {
+		if g.likes[k] == nil {
+			g.likes[k] = make(map[uuid.UUID]bool)
+		}
+		g.likes[k][userID] = true
+	} else {
+		delete(g.likes[k], userID)
+	}
+	e.Likes = len(g.likes[k])
+	g.entries[k] = e
+	e = cloneEntry(e)
+	return &e, nil
+}
+
+func cloneEntry(e deck.GalleryEntry) deck.GalleryEntry {
+	e.Factions = append([]string{}, e.Factions...)
+	e.CardIDs = append([]uuid.UUID{}, e.CardIDs...)
+	return e
+}
+
+// GalleryDeckRecord is a public deck of the gallery of a tenant. Likes
+// counts its rows in gallery_likes so that popular decks can be listed from
+// an index.
+type GalleryDeckRecord struct {
+	DeckID    string    `gorm:"primaryKey;size:36"`
+	Tenant    string    `gorm:"size:64;not null;index"`
+	UserID    string    `gorm:"size:36;not null"`
+	Name      string    `gorm:"not null"`
+	Format    string    `gorm:"size:64;not null;index"`
+	Likes     int       `gorm:"not null;default:0;index:idx_gallery_popular,priority:1"`
+	CreatedAt time.Time `gorm:"not null;index;index:idx_gallery_popular,priority:2"`
+}
+
+// TableName implements gorm's tabler interface.
+func (GalleryDeckRecord) TableName() string { return "gallery_decks" }
+
+// GalleryCardRecord is a distinct card of a gallery deck. Position keeps the
+// order of the cards within the deck.
+type GalleryCardRecord struct {
+	DeckID   string `gorm:"primaryKey;size:36"`
+	CardID   string `gorm:"primaryKey;size:36;index"`
+	Position int    `gorm:"not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (GalleryCardRecord) TableName() string { return "gallery_deck_cards" }
+
+// GalleryFactionRecord is a faction of the cards of a gallery deck.
+type GalleryFactionRecord struct {
+	DeckID  string `gorm:"primaryKey;size:36"`
+	Faction string `gorm:"primaryKey;size:64;index"`
+}
+
+// TableName implements gorm's tabler interface.
+func (GalleryFactionRecord) TableName() string { return "gallery_deck_factions" }
+
+// GalleryLikeRecord records that a user likes a gallery deck.
+type GalleryLikeRecord struct {
+	DeckID string `gorm:"primaryKey;size:36"`
+	UserID string `gorm:"primaryKey;size:36;index"`
+}
+
+// TableName implements gorm's tabler interface.
+func (GalleryLikeRecord) TableName() string { return "gallery_likes" }
+
+// MySQLGallery is a GORM-based gallery read model. Searches filter the
+// factions, cards and likes of decks with subqueries on their tables.
+type MySQLGallery struct {
+	DB *gorm.DB
+}
+
+// NewMySQLGallery creates the gallery tables if needed.
+func NewMySQLGallery(db *gorm.DB) (*MySQLGallery, error) {
+	if err := db.AutoMigrate(&GalleryDeckRecord{}, &GalleryCardRecord{}, &GalleryFactionRecord{}, &GalleryLikeRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLGallery{DB: db}, nil
+}
+
+// Put adds or replaces the entry of a deck, keeping its likes.
+func (g *MySQLGallery) Put(ctx context.Context, e deck.GalleryEntry) error {
+	id := e.DeckID.String()
+	return g.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rec := GalleryDeckRecord{DeckID: id, Tenant: tenant.FromContext(ctx), UserID: e.UserID.String(), Name: e.Name, Format: e.Format, CreatedAt: e.CreatedAt}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "deck_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"user_id", "name", "format", "created_at"}),
+		}).Create(&rec).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("deck_id = ?", id).Delete(&GalleryCardRecord{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("deck_id = ?", id).Delete(&GalleryFactionRecord{}).Error; err != nil {
+			return err
+		}
+		if len(e.CardIDs) > 0 {
+			cards := make([]GalleryCardRecord, 0, len(e.CardIDs))
+			for i, cardID := range e.CardIDs {
+				cards = append(cards, GalleryCardRecord{DeckID: id, CardID: cardID.String(), Position: i})
+			}
+			if err := tx.Create(&cards).Error; err != nil {
+				return err
+			}
+		}
+		if len(e.Factions) > 0 {
+			factions := make([]GalleryFactionRecord, 0, len(e.Factions))
+			for _, f := range e.Factions {
+				factions = append(factions, GalleryFactionRecord{DeckID: id, Faction: f})
+			}
+			if err := tx.Create(&factions).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Remove drops the entry of a deck along with its likes.
+func (g *MySQLGallery) Remove(ctx context.Context, deckID uuid.UUID) error {
+	id := deckID.String()
+	return g.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("deck_id = ? AND tenant = ?", id, tenant.FromContext(ctx)).Delete(&GalleryDeckRecord{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		for _, model := range []interface{}{&GalleryCardRecord{}, &GalleryFactionRecord{}, &GalleryLikeRecord{}} {
+			if err := tx.Where("deck_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Entry returns the entry of a deck.
+func (g *MySQLGallery) Entry(ctx context.Context, deckID uuid.UUID) (*deck.GalleryEntry, error) {
+	db := g.DB.WithContext(ctx)
+	var rec GalleryDeckRecord
+	err := db.Where("deck_id = ? AND tenant = ?", deckID.String(), tenant.FromContext(ctx)).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := galleryEntries(db, []GalleryDeckRecord{rec})
+	if err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// Search returns a page of the matching decks, ordered with the created_at
+// or (likes, created_at) index.
+func (g *MySQLGallery) Search(ctx context.Context, q deck.GalleryQuery) ([]deck.GalleryEntry, int, error) {
+	db := g.DB.WithContext(ctx)
+	filter := func() *gorm.DB {
+		tx := db.Model(&GalleryDeckRecord{}).Where("tenant = ?", tenant.FromContext(ctx))
+		if q.Format != "" {
+			tx = tx.Where("format = ?", q.Format)
+		}
+		if q.Faction != "" {
+			tx = tx.Where("deck_id IN (?)", db.Model(&GalleryFactionRecord{}).Select("deck_id").Where("faction = ?", q.Faction))
+		}
+		if ids := distinctIDs(q.CardIDs); len(ids) > 0 {
+			tx = tx.Where("deck_id IN (?)", db.Model(&GalleryCardRecord{}).Select("deck_id").
+				Where("card_id IN ?", ids).Group("deck_id").Having("COUNT(*) = ?", len(ids)))
+		}
+		if q.LikedBy != uuid.Nil {
+			tx = tx.Where("deck_id IN (?)", db.Model(&GalleryLikeRecord{}).Select("deck_id").Where("user_id = ?", q.LikedBy.String()))
+		}
+		return tx
+	}
+	var total int64
+	if err := filter().Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset, limit := q.Offset, q.Limit
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	order := "created_at DESC, deck_id"
+	if q.Sort == deck.SortPopular {
+		order = "likes DESC, " + order
+	}
+	var recs []GalleryDeckRecord
+	if err := filter().Order(order).Offset(offset).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(recs) == 0 {
+		return nil, int(total), nil
+	}
+	entries, err := galleryEntries(db, recs)
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, int(total), nil
+}
+
+// Like records that userID likes a deck.
+func (g *MySQLGallery) Like(ctx context.Context, deckID, userID uuid.UUID) (*deck.GalleryEntry, error) {
+	return g.setLike(ctx, deckID, userID, true)
+}
+
+// Unlike takes back the like of userID.
+func (g *MySQLGallery) Unlike(ctx context.Context, deckID, userID uuid.UUID) (*deck.GalleryEntry, error) {
+	return g.setLike(ctx, deckID, userID, false)
+}
+
+// setLike adds or removes the like of a user in a transaction locking the
+// deck row, keeping the likes counter in step with the like rows.
+func (g *MySQLGallery) setLike(ctx context.Context, deckID, userID uuid.UUID, like bool) (*deck.GalleryEntry, error) {
+	var entry *deck.GalleryEntry
+	err := g.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rec GalleryDeckRecord
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("deck_id = ? AND tenant = ?", deckID.String(), tenant.FromContext(ctx)).Take(&rec).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		likeRec := GalleryLikeRecord{DeckID: rec.DeckID, UserID: userID.String()}
+		var res *gorm.DB
+		delta := 1
+		if like {
+			res = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&likeRec)
+		} else {
+			res = tx.Where("deck_id = ? AND user_id = ?", likeRec.DeckID, likeRec.UserID).Delete(&GalleryLikeRecord{})
+			delta = -1
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected > 0 {
+			if err := tx.Model(&GalleryDeckRecord{}).Where("deck_id = ?", rec.DeckID).
+				Update("likes", gorm.Expr("likes + ?", delta)).Error; err != nil {
+				return err
+			}
+			rec.Likes += delta
+		}
+		entries, err := galleryEntries(tx, []GalleryDeckRecord{rec})
+		if err != nil {
+			return err
+		}
+		entry = &entries[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// galleryEntries loads the cards and factions of the decks of recs and
+// returns their entries in the same order.
+func galleryEntries(db *gorm.DB, recs []GalleryDeckRecord) ([]deck.GalleryEntry, error) {
+	ids := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		ids = append(ids, rec.DeckID)
+	}
+	var cards []GalleryCardRecord
+	if err := db.Where("deck_id IN ?", ids).Order("deck_id, position").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	var factions []GalleryFactionRecord
+	if err := db.Where("deck_id IN ?", ids).Order("deck_id, faction").Find(&factions).Error; err != nil {
+		return nil, err
+	}
+	cardsByDeck := make(map[string][]uuid.UUID)
+	for _, c := range cards {
+		id, err := uuid.Parse(c.CardID)
+		if err != nil {
+			return nil, err
+		}
+		cardsByDeck[c.DeckID] = append(cardsByDeck[c.DeckID], id)
+	}
+	factionsByDeck := make(map[string][]string)
+	for _, f := range factions {
+		factionsByDeck[f.DeckID] = append(factionsByDeck[f.DeckID], f.Faction)
+	}
+	entries := make([]deck.GalleryEntry, 0, len(recs))
+	for _, rec := range recs {
+		deckID, err := uuid.Parse(rec.DeckID)
+		if err != nil {
+			return nil, err
+		}
+		userID, err := uuid.Parse(rec.UserID)
+		if err != nil {
+			return nil, err
+		}
+		e := deck.GalleryEntry{
+			DeckID:    deckID,
+			UserID:    userID,
+			Name:      rec.Name,
+			Format:    rec.Format,
+			Factions:  append([]string{}, factionsByDeck[rec.DeckID]...),
+			CardIDs:   append([]uuid.UUID{}, cardsByDeck[rec.DeckID]...),
+			Likes:     rec.Likes,
+			CreatedAt: rec.CreatedAt.UTC(),
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// distinctIDs returns the distinct ids as strings.
+func distinctIDs(ids []uuid.UUID) []string {
+	seen := make(map[uuid.UUID]bool)
+	var strs []string
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			strs = append(strs, id.String())
+		}
+	}
+	return strs
+}
+
+var (
+	_ deck.GalleryRepository = (*InMemoryGallery)(nil)
+	_ deck.GalleryRepository = (*MySQLGallery)(nil)
+)
@@ -0,0 +1,80 @@
+package deckstore
+
+import (
+	"context"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+// GalleryProjection wraps another deck.Repository and keeps the gallery in
+// step with the decks it saves: public decks are put into the gallery and
+// decks that are no longer public are removed from it.
+type GalleryProjection struct {
+	Repo    deck.Repository
+	Gallery deck.GalleryRepository
+	// Cards resolves the factions of the cards of a deck.
+	Cards card.Repository
+}
+
+// NewGalleryProjection creates a projecting repository.
+func NewGalleryProjection(repo deck.Repository, gallery deck.GalleryRepository, cards card.Repository) *GalleryProjection {
+	return &GalleryProjection{Repo: repo, Gallery: gallery, Cards: cards}
+}
+
+// Save persists the events and then updates the gallery entry of their
+// deck.
+func (p *GalleryProjection) Save(ctx context.Context, expected int, events []interface{}) error {
+	if err := p.Repo.Save(ctx, expected, events); err != nil || len(events) == 0 {
+		return err
+	}
+	id, _ := deck.EventDeckID(events[0])
+	return p.project(ctx, id)
+}
+
+func (p *GalleryProjection) project(ctx context.Context, id uuid.UUID) error {
+	d, err := p.Repo.Load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if d == nil || d.Visibility != deck.VisibilityPublic {
+		e, err := p.Gallery.Entry(ctx, id)
+		if err != nil || e == nil {
+			return err
+		}
+		return p.Gallery.Remove(ctx, id)
+	}
+	factions := make(map[uuid.UUID]string)
+	for _, cardID := range d.AllCardIDs() {
+		if _, ok := factions[cardID]; ok {
+			continue
+		}
+		c, err := p.Cards.Load(ctx, cardID.String())
+		if err != nil {
+			return err
+		}
+		factions[cardID] = ""
+		if c != nil {
+			factions[cardID] = c.Faction
+		}
+	}
+	return p.Gallery.Put(ctx, deck.NewGalleryEntry(d, func(cardID uuid.UUID) string { return factions[cardID] }))
+}
+
+// Load delegates to the underlying repository.
+func (p *GalleryProjection) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
+	return p.Repo.Load(ctx, id)
+}
+
+// ListByUser delegates to the underlying repository.
+func (p *GalleryProjection) ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*deck.Deck, int, error) {
+	return p.Repo.ListByUser(ctx, userID, offset, limit)
+}
+
+// Tenants delegates to the underlying repository.
+func (p *GalleryProjection) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return p.Repo.Tenants(ctx, userID)
+}
+
+var _ deck.Repository = (*GalleryProjection)(nil)
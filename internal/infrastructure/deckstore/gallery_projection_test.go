@@ -0,0 +1,59 @@
+package deckstore
+
+import (
+	"context"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/eventstore"
+	"github.com/google/uuid"
+)
+
+func TestGalleryProjection(t *testing.T) {
+	ctx := context.Background()
+	cards := eventstore.NewInMemoryStore()
+	red := card.NewCard("r", 1, "red", "unit", "", "")
+	if err := cards.Save(ctx, []interface{}{card.CardCreated(*red)}); err != nil {
+		t.Fatal(err)
+	}
+	gallery := NewInMemoryGallery()
+	repo := NewGalleryProjection(NewInMemoryStore(), gallery, cards)
+
+	d := deck.NewDeck(uuid.New(), "d", []uuid.UUID{red.ID, uuid.New()})
+	if err := repo.Save(ctx, 0, []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := gallery.Entry(ctx, d.ID); e != nil {
+		t.Fatalf("expected private decks to stay out of the gallery got %+v", e)
+	}
+
+	if err := repo.Save(ctx, 1, []interface{}{deck.DeckVisibilityChanged{ID: d.ID, Visibility: deck.VisibilityPublic}}); err != nil {
+		t.Fatal(err)
+	}
+	e, _ := gallery.Entry(ctx, d.ID)
+	if e == nil || e.Name != "d" || len(e.CardIDs) != 2 || len(e.Factions) != 1 || e.Factions[0] != "red" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if err := repo.Save(ctx, 2, []interface{}{deck.DeckRenamed{ID: d.ID, UserID: d.UserID, Name: "e"}}); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := gallery.Entry(ctx, d.ID); e == nil || e.Name != "e" {
+		t.Fatalf("expected the entry to follow the deck got %+v", e)
+	}
+
+	version := 3
+	for _, evt := range []interface{}{
+		deck.DeckVisibilityChanged{ID: d.ID, Visibility: deck.VisibilityUnlisted},
+		deck.DeckDeleted{ID: d.ID},
+	} {
+		_ = repo.Save(ctx, version, []interface{}{deck.DeckVisibilityChanged{ID: d.ID, Visibility: deck.VisibilityPublic}})
+		if err := repo.Save(ctx, version+1, []interface{}{evt}); err != nil {
+			t.Fatal(err)
+		}
+		version += 2
+		if e, _ := gallery.Entry(ctx, d.ID); e != nil {
+			t.Fatalf("expected %T to remove the entry got %+v", evt, e)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package deckstore
+
+import (
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/deck/decktest"
+)
+
+func TestInMemoryGalleryConformance(t *testing.T) {
+	decktest.GallerySuite.Run(t, func(t *testing.T) deck.GalleryRepository {
+		return NewInMemoryGallery()
+	})
+}
@@ -0,0 +1,213 @@
+package deckstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// The deck repositories keep the history of their decks themselves: Save
+// records the revisions marked by DeckRevised events in the same write as
+// the events, so that a deck never changes without its revision.
+
+// Revisions returns the revisions of a deck, oldest first.
+func (s *InMemoryStore) Revisions(ctx context.Context, deckID uuid.UUID) ([]deck.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored := s.revisions[tenantDeck{tenant.FromContext(ctx), deckID}]
+	revs := make([]deck.Revision, 0, len(stored))
+	for _, rev := range stored {
+		revs = append(revs, *rev.Clone())
+	}
+	return revs, nil
+}
+
+// Revision returns a single revision of a deck.
+func (s *InMemoryStore) Revision(ctx context.Context, deckID uuid.UUID, version int) (*deck.Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revs := s.revisions[tenantDeck{tenant.FromContext(ctx), deckID}]
+	if version < 1 || version > len(revs) {
+		return nil, nil
+	}
+	return revs[version-1].Clone(), nil
+}
+
+// DeckRevisionRecord is a stored deck revision. Zones holds the cards of the
+// revision as JSON and Tenant the tenant of the deck; deck ids are unique
+// across tenants.
+type DeckRevisionRecord struct {
+	Tenant    string    `gorm:"size:64;not null;index"`
+	DeckID    string    `gorm:"primaryKey;size:36"`
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	Format    string    `gorm:"not null"`
+	Zones     []byte    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (DeckRevisionRecord) TableName() string { return "deck_revisions" }
+
+// addRevisions inserts the revisions of a deck of a tenant.
+func addRevisions(tx *gorm.DB, tenantID string, revs []deck.Revision) error {
+	if len(revs) == 0 {
+		return nil
+	}
+	recs := make([]DeckRevisionRecord, 0, len(revs))
+	for _, rev := range revs {
+		zones, err := json.Marshal(rev.Zones)
+		if err != nil {
+			return err
+		}
+		recs = append(recs, DeckRevisionRecord{
+			Tenant:    tenantID,
+			DeckID:    rev.DeckID.String(),
+			Version:   rev.Version,
+			Name:      rev.Name,
+			Format:    rev.Format,
+			Zones:     zones,
+			CreatedAt: rev.CreatedAt,
+		})
+	}
+	return tx.Create(&recs).Error
+}
+
+// lastRevision returns the version of the last revision of a deck of a
+// tenant, 0 when it has none.
+func lastRevision(tx *gorm.DB, tenantID string, id uuid.UUID) (int, error) {
+	var last int
+	err := tx.Model(&DeckRevisionRecord{}).Where("tenant = ? AND deck_id = ?", tenantID, id.String()).
+		Select("COALESCE(MAX(version), 0)").Scan(&last).Error
+	return last, err
+}
+
+// Revisions returns the revisions of a deck, oldest first.
+func (s *MySQLStore) Revisions(ctx context.Context, deckID uuid.UUID) ([]deck.Revision, error) {
+	var recs []DeckRevisionRecord
+	if err := s.DB.WithContext(ctx).Where("tenant = ? AND deck_id = ?", tenant.FromContext(ctx), deckID.String()).Order("version").Find(&recs).Error; err != nil {
+		return nil, err
+	}
+	revs := make([]deck.Revision, 0, len(recs))
+	for _, rec := range recs {
+		rev, err := rec.revision()
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, *rev)
+	}
+	return revs, nil
+}
+
+// Revision returns a single revision of a deck.
+func (s *MySQLStore) Revision(ctx context.Context, deckID uuid.UUID, version int) (*deck.Revision, error) {
+	var rec DeckRevisionRecord
+	err := s.DB.WithContext(ctx).Where("tenant = ? AND deck_id = ? AND version = ?", tenant.FromContext(ctx), deckID.String(), version).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return rec.revision()
+}
+
+func (rec DeckRevisionRecord) revision() (*deck.Revision, error) {
+	id, err := uuid.Parse(rec.DeckID)
+	if err != nil {
+		return nil, err
+	}
+	rev := &deck.Revision{DeckID: id, Version: rec.Version, Name: rec.Name, Format: rec.Format, CreatedAt: rec.CreatedAt.UTC()}
+	if err := json.Unmarshal(rec.Zones, &rev.Zones); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// revisionsKey is the list holding the revisions of a deck as JSON
+// documents. The version of a revision is its position in the list.
+func revisionsKey(tenantID string, id uuid.UUID) string {
+	return tenant.Prefix(tenantID, ":") + "deck_revisions:" + id.String()
+}
+
+// Revisions returns the revisions of a deck, oldest first.
+func (s *RedisStore) Revisions(ctx context.Context, deckID uuid.UUID) ([]deck.Revision, error) {
+	vals, err := s.Redis.LRange(ctx, revisionsKey(tenant.FromContext(ctx), deckID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	revs := make([]deck.Revision, 0, len(vals))
+	for i, v := range vals {
+		rev, err := decodeRevision([]byte(v), i+1)
+		if err != nil {
+			return nil, err
+		}
+		revs = append(revs, *rev)
+	}
+	return revs, nil
+}
+
+// Revision returns a single revision of a deck.
+func (s *RedisStore) Revision(ctx context.Context, deckID uuid.UUID, version int) (*deck.Revision, error) {
+	if version < 1 {
+		return nil, nil
+	}
+	data, err := s.Redis.LIndex(ctx, revisionsKey(tenant.FromContext(ctx), deckID), int64(version-1)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRevision(data, version)
+}
+
+func decodeRevision(data []byte, version int) (*deck.Revision, error) {
+	var rev deck.Revision
+	if err := json.Unmarshal(data, &rev); err != nil {
+		return nil, err
+	}
+	rev.Version = version
+	return &rev, nil
+}
+
+// Revisions replays the stream of a deck and returns the revisions its
+// DeckRevised events mark, oldest first. Deleted decks have none, like in
+// the other stores.
+func (s *EventStore) Revisions(ctx context.Context, deckID uuid.UUID) ([]deck.Revision, error) {
+	events, err := s.Log.Events(ctx, deckID.String())
+	if err != nil {
+		return nil, err
+	}
+	if d := deck.Replay(events); d == nil || d.Deleted || d.ID != deckID {
+		return []deck.Revision{}, nil
+	}
+	revs := deck.Revisions(events)
+	if revs == nil {
+		revs = []deck.Revision{}
+	}
+	return revs, nil
+}
+
+// Revision returns a single revision of a deck.
+func (s *EventStore) Revision(ctx context.Context, deckID uuid.UUID, version int) (*deck.Revision, error) {
+	revs, err := s.Revisions(ctx, deckID)
+	if err != nil || version < 1 || version > len(revs) {
+		return nil, err
+	}
+	return &revs[version-1], nil
+}
+
+var (
+	_ deck.HistoryRepository = (*InMemoryStore)(nil)
+	_ deck.HistoryRepository = (*MySQLStore)(nil)
+	_ deck.HistoryRepository = (*RedisStore)(nil)
+	_ deck.HistoryRepository = (*EventStore)(nil)
+)
@@ -0,0 +1,26 @@
+package deckstore
+
+import (
+	"testing"
+
+	"demo/internal/domain/deck/decktest"
+	"demo/internal/infrastructure/eventstore"
+)
+
+func TestInMemoryHistoryConformance(t *testing.T) {
+	decktest.HistorySuite.Run(t, func(t *testing.T) decktest.Store {
+		return NewInMemoryStore()
+	})
+}
+
+func TestRedisHistoryConformance(t *testing.T) {
+	decktest.HistorySuite.Run(t, func(t *testing.T) decktest.Store {
+		return newRedisStore(t)
+	})
+}
+
+func TestEventStoreHistoryConformance(t *testing.T) {
+	decktest.HistorySuite.Run(t, func(t *testing.T) decktest.Store {
+		return NewEventStore(eventstore.NewInMemoryStore())
+	})
+}
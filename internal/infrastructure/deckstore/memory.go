@@ -2,28 +2,52 @@ package deckstore
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 
 	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
 	"github.com/google/uuid"
 )
 
-// InMemoryStore is a simple in-memory deck repository.
+// InMemoryStore is a simple in-memory deck repository keeping the current
+// state and the revisions of every deck, per tenant.
 type InMemoryStore struct {
-	mu    sync.RWMutex
-	decks map[uuid.UUID]*deck.Deck
+	mu        sync.RWMutex
+	decks     map[string]map[uuid.UUID]*deck.Deck
+	revisions map[tenantDeck][]deck.Revision
 }
 
 // NewInMemoryStore creates the store.
 func NewInMemoryStore() *InMemoryStore {
-	return &InMemoryStore{decks: make(map[uuid.UUID]*deck.Deck)}
+	return &InMemoryStore{decks: make(map[string]map[uuid.UUID]*deck.Deck), revisions: make(map[tenantDeck][]deck.Revision)}
 }
 
-// Save stores or updates a deck.
-func (s *InMemoryStore) Save(ctx context.Context, d *deck.Deck) error {
+// Save applies the events to a deck of the tenant of ctx.
+func (s *InMemoryStore) Save(ctx context.Context, expected int, events []interface{}) error {
+	id, err := checkEvents(events)
+	if err != nil || len(events) == 0 {
+		return err
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.decks[d.ID] = d
+	stored, ok := s.decks[tenant.FromContext(ctx)]
+	if !ok {
+		stored = make(map[uuid.UUID]*deck.Deck)
+		s.decks[tenant.FromContext(ctx)] = stored
+	}
+	key := tenantDeck{tenant.FromContext(ctx), id}
+	d, revs, err := applyEvents(stored[id], id, expected, len(s.revisions[key]), events)
+	if err != nil {
+		return err
+	}
+	stored[id] = d
+	if d.Deleted {
+		delete(s.revisions, key)
+	} else {
+		s.revisions[key] = append(s.revisions[key], revs...)
+	}
 	return nil
 }
 
@@ -31,11 +55,102 @@ func (s *InMemoryStore) Save(ctx context.Context, d *deck.Deck) error {
 func (s *InMemoryStore) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	if d, ok := s.decks[id]; ok {
-		copy := *d
-		return &copy, nil
+	if d, ok := s.decks[tenant.FromContext(ctx)][id]; ok && !d.Deleted {
+		return d.Clone(), nil
 	}
 	return nil, nil
 }
 
+// ListByUser returns the decks of userID.
+func (s *InMemoryStore) ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*deck.Deck, int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var decks []*deck.Deck
+	for _, d := range s.decks[tenant.FromContext(ctx)] {
+		if !d.Deleted && d.UserID == userID {
+			decks = append(decks, d.Clone())
+		}
+	}
+	return page(decks, offset, limit)
+}
+
+// Tenants returns the tenants in which userID has decks.
+func (s *InMemoryStore) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var tenants []string
+	for t, decks := range s.decks {
+		for _, d := range decks {
+			if !d.Deleted && d.UserID == userID {
+				tenants = append(tenants, t)
+				break
+			}
+		}
+	}
+	return tenants, nil
+}
+
+// page sorts decks newest first and returns the requested window along with
+// the total count.
+func page(decks []*deck.Deck, offset, limit int) ([]*deck.Deck, int, error) {
+	sort.Slice(decks, func(i, j int) bool {
+		if !decks[i].CreatedAt.Equal(decks[j].CreatedAt) {
+			return decks[i].CreatedAt.After(decks[j].CreatedAt)
+		}
+		return decks[i].ID.String() < decks[j].ID.String()
+	})
+	total := len(decks)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return decks[offset:end], total, nil
+}
+
+// checkEvents rejects batches containing anything but the events of a
+// single deck and returns the id of the deck.
+func checkEvents(events []interface{}) (uuid.UUID, error) {
+	var id uuid.UUID
+	for i, evt := range events {
+		evtID, ok := deck.EventDeckID(evt)
+		if !ok {
+			return uuid.Nil, fmt.Errorf("unknown event type %T", evt)
+		}
+		if i > 0 && evtID != id {
+			return uuid.Nil, fmt.Errorf("events of decks %s and %s in one batch", id, evtID)
+		}
+		id = evtID
+	}
+	return id, nil
+}
+
+// applyEvents folds the events of deck id into its stored state, nil when
+// there is none, after checking that the deck is at version expected. It
+// returns the new state and the revisions marked by the DeckRevised events,
+// numbered after the last revision of the deck. The stores drop the
+// revisions of deleted decks, which keep no name.
+func applyEvents(stored *deck.Deck, id uuid.UUID, expected, last int, events []interface{}) (*deck.Deck, []deck.Revision, error) {
+	d := &deck.Deck{ID: id}
+	if stored != nil {
+		d = stored.Clone()
+	}
+	if d.Version != expected {
+		return nil, nil, deck.ErrVersionConflict
+	}
+	var revs []deck.Revision
+	for _, evt := range events {
+		d.Apply(evt)
+		if e, ok := evt.(deck.DeckRevised); ok {
+			revs = append(revs, deck.NewRevision(d, last+len(revs)+1, e.At))
+		}
+	}
+	return d, revs, nil
+}
+
 var _ deck.Repository = (*InMemoryStore)(nil)
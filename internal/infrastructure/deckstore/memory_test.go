@@ -0,0 +1,20 @@
+package deckstore
+
+import (
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/deck/decktest"
+)
+
+func TestInMemoryConformance(t *testing.T) {
+	decktest.RepositorySuite.Run(t, func(t *testing.T) deck.Repository {
+		return NewInMemoryStore()
+	})
+}
+
+func TestInMemoryCardNumbersConformance(t *testing.T) {
+	decktest.CardNumberSuite.Run(t, func(t *testing.T) deck.CardNumberRepository {
+		return NewInMemoryCardNumbers()
+	})
+}
@@ -0,0 +1,230 @@
+package deckstore
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mysqlDuplicateEntry is the MySQL error number of unique index violations.
+const mysqlDuplicateEntry = 1062
+
+// DeckRecord is the stored state of a deck. Deleted decks keep their row,
+// blanked, so that late events cannot bring them back. Deck ids are unique
+// across tenants.
+type DeckRecord struct {
+	ID         string    `gorm:"primaryKey;size:36"`
+	Tenant     string    `gorm:"size:64;not null"`
+	UserID     string    `gorm:"size:36;not null;index:idx_decks_user_created,priority:1"`
+	Name       string    `gorm:"not null"`
+	Format     string    `gorm:"not null"`
+	Visibility string    `gorm:"size:16;not null;default:private"`
+	CreatedAt  time.Time `gorm:"not null;index:idx_decks_user_created,priority:2"`
+	Deleted    bool      `gorm:"not null;default:false"`
+	Version    int       `gorm:"not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (DeckRecord) TableName() string { return "decks" }
+
+// DeckCardRecord holds the copies of a card in a zone of a deck. Position
+// keeps the order of the zones and cards within the deck.
+type DeckCardRecord struct {
+	DeckID   string `gorm:"primaryKey;size:36"`
+	Position int    `gorm:"primaryKey;autoIncrement:false"`
+	Zone     string `gorm:"size:32;not null"`
+	CardID   string `gorm:"size:36;not null;index"`
+	Quantity int    `gorm:"not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (DeckCardRecord) TableName() string { return "deck_cards" }
+
+// MySQLStore is a GORM-based deck repository keeping the current state of
+// every deck in a decks table, its cards in a deck_cards join table and its
+// revisions in a deck_revisions table.
+type MySQLStore struct {
+	DB *gorm.DB
+}
+
+// NewMySQLStore creates the deck tables if needed.
+func NewMySQLStore(db *gorm.DB) (*MySQLStore, error) {
+	if err := db.AutoMigrate(&DeckRecord{}, &DeckCardRecord{}, &DeckRevisionRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLStore{DB: db}, nil
+}
+
+// Save applies the events to a deck of the tenant of ctx and records its
+// revisions in a single transaction, locking the row of the deck. A new
+// deck whose row another transaction inserted first is a conflict.
+func (s *MySQLStore) Save(ctx context.Context, expected int, events []interface{}) error {
+	id, err := checkEvents(events)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	t := tenant.FromContext(ctx)
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stored, err := findDeck(tx.Clauses(clause.Locking{Strength: "UPDATE"}), t, id)
+		if err != nil {
+			return err
+		}
+		last, err := lastRevision(tx, t, id)
+		if err != nil {
+			return err
+		}
+		d, revs, err := applyEvents(stored, id, expected, last, events)
+		if err != nil {
+			return err
+		}
+		rec := DeckRecord{ID: d.ID.String(), Tenant: t, UserID: d.UserID.String(), Name: d.Name, Format: d.Format, Visibility: d.Visibility, CreatedAt: d.CreatedAt, Deleted: d.Deleted, Version: d.Version}
+		if stored == nil {
+			err = tx.Create(&rec).Error
+		} else {
+			err = tx.Save(&rec).Error
+		}
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("deck_id = ?", rec.ID).Delete(&DeckCardRecord{}).Error; err != nil {
+			return err
+		}
+		if !d.Deleted && len(d.Zones) > 0 {
+			var cards []DeckCardRecord
+			for _, z := range d.Zones {
+				for _, e := range z.Cards {
+					cards = append(cards, DeckCardRecord{DeckID: rec.ID, Position: len(cards), Zone: z.Name, CardID: e.CardID.String(), Quantity: e.Quantity})
+				}
+			}
+			if err := tx.Create(&cards).Error; err != nil {
+				return err
+			}
+		}
+		if d.Deleted {
+			return tx.Where("tenant = ? AND deck_id = ?", t, rec.ID).Delete(&DeckRevisionRecord{}).Error
+		}
+		return addRevisions(tx, t, revs)
+	})
+	var merr *mysql.MySQLError
+	if errors.As(err, &merr) && merr.Number == mysqlDuplicateEntry {
+		return deck.ErrVersionConflict
+	}
+	return err
+}
+
+// Load retrieves a deck by id.
+func (s *MySQLStore) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
+	d, err := findDeck(s.DB.WithContext(ctx), tenant.FromContext(ctx), id)
+	if err != nil || d == nil || d.Deleted {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListByUser returns the decks of userID using the (user_id, created_at)
+// index.
+func (s *MySQLStore) ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*deck.Deck, int, error) {
+	db := s.DB.WithContext(ctx)
+	t := tenant.FromContext(ctx)
+	var total int64
+	if err := db.Model(&DeckRecord{}).Where("user_id = ? AND tenant = ? AND deleted = ?", userID.String(), t, false).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	var recs []DeckRecord
+	if err := db.Where("user_id = ? AND tenant = ? AND deleted = ?", userID.String(), t, false).
+		Order("created_at DESC, id").Offset(offset).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(recs) == 0 {
+		return nil, int(total), nil
+	}
+	ids := make([]string, 0, len(recs))
+	for _, rec := range recs {
+		ids = append(ids, rec.ID)
+	}
+	var cards []DeckCardRecord
+	if err := db.Where("deck_id IN ?", ids).Order("deck_id, position").Find(&cards).Error; err != nil {
+		return nil, 0, err
+	}
+	byDeck := make(map[string][]DeckCardRecord)
+	for _, c := range cards {
+		byDeck[c.DeckID] = append(byDeck[c.DeckID], c)
+	}
+	decks := make([]*deck.Deck, 0, len(recs))
+	for _, rec := range recs {
+		d, err := rec.deck(byDeck[rec.ID])
+		if err != nil {
+			return nil, 0, err
+		}
+		decks = append(decks, d)
+	}
+	return decks, int(total), nil
+}
+
+// Tenants returns the tenants in which userID has decks.
+func (s *MySQLStore) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var tenants []string
+	err := s.DB.WithContext(ctx).Model(&DeckRecord{}).Where("user_id = ? AND deleted = ?", userID.String(), false).
+		Distinct().Pluck("tenant", &tenants).Error
+	return tenants, err
+}
+
+// findDeck loads the stored state of a deck of a tenant, including deleted
+// decks. It returns nil when the tenant has no such deck.
+func findDeck(db *gorm.DB, tenantID string, id uuid.UUID) (*deck.Deck, error) {
+	var rec DeckRecord
+	err := db.Where("id = ? AND tenant = ?", id.String(), tenantID).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cards []DeckCardRecord
+	if err := db.Where("deck_id = ?", rec.ID).Order("position").Find(&cards).Error; err != nil {
+		return nil, err
+	}
+	return rec.deck(cards)
+}
+
+// deck turns a record and its cards, ordered by position, into a deck.
+func (rec DeckRecord) deck(cards []DeckCardRecord) (*deck.Deck, error) {
+	id, err := uuid.Parse(rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(rec.UserID)
+	if err != nil {
+		return nil, err
+	}
+	d := &deck.Deck{ID: id, UserID: userID, Name: rec.Name, Format: rec.Format, Visibility: rec.Visibility, CreatedAt: rec.CreatedAt.UTC(), Deleted: rec.Deleted, Version: rec.Version}
+	zones := make([]deck.Zone, 0, len(cards))
+	for _, c := range cards {
+		cardID, err := uuid.Parse(c.CardID)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, deck.Zone{Name: c.Zone, Cards: []deck.Entry{{CardID: cardID, Quantity: c.Quantity}}})
+	}
+	// groups the rows into zones
+	if d.Zones, err = deck.NormalizeZones(zones); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+var _ deck.Repository = (*MySQLStore)(nil)
@@ -0,0 +1,178 @@
+package deckstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore is a deck repository keeping the current state of every deck as
+// JSON in Redis, next to the list of its revisions. The decks of a user are
+// indexed in a sorted set scored by creation time. The keys of a tenant
+// other than the default one start with its id.
+type RedisStore struct {
+	Redis *redis.Client
+}
+
+// NewRedisStore creates a Redis-backed deck repository.
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{Redis: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func deckKey(tenantID, id string) string { return tenant.Prefix(tenantID, ":") + "deck:" + id }
+
+func userDecksKey(tenantID string, userID uuid.UUID) string {
+	return tenant.Prefix(tenantID, ":") + "user_decks:" + userID.String()
+}
+
+// Save applies the events to a deck and appends its revisions in a single
+// MULTI/EXEC transaction. A concurrent change to the deck aborts the
+// transaction and is reported as a conflict.
+func (s *RedisStore) Save(ctx context.Context, expected int, events []interface{}) error {
+	id, err := checkEvents(events)
+	if err != nil || len(events) == 0 {
+		return err
+	}
+	t := tenant.FromContext(ctx)
+	err = s.Redis.Watch(ctx, func(tx *redis.Tx) error {
+		stored, err := getDeck(ctx, tx, t, id)
+		if err != nil {
+			return err
+		}
+		last, err := tx.LLen(ctx, revisionsKey(t, id)).Result()
+		if err != nil {
+			return err
+		}
+		d, revs, err := applyEvents(stored, id, expected, int(last), events)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, deckKey(t, d.ID.String()), data, 0)
+			if d.Deleted {
+				pipe.ZRem(ctx, userDecksKey(t, d.UserID), d.ID.String())
+				pipe.Del(ctx, revisionsKey(t, id))
+				return nil
+			}
+			// negated so that ties are ordered by id like the other stores
+			pipe.ZAdd(ctx, userDecksKey(t, d.UserID), redis.Z{Score: -float64(d.CreatedAt.UnixMicro()), Member: d.ID.String()})
+			for _, rev := range revs {
+				data, err := json.Marshal(rev)
+				if err != nil {
+					return err
+				}
+				pipe.RPush(ctx, revisionsKey(t, id), data)
+			}
+			return nil
+		})
+		return err
+	}, deckKey(t, id.String()))
+	if errors.Is(err, redis.TxFailedErr) {
+		return deck.ErrVersionConflict
+	}
+	return err
+}
+
+// Load retrieves a deck by id.
+func (s *RedisStore) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
+	d, err := getDeck(ctx, s.Redis, tenant.FromContext(ctx), id)
+	if err != nil || d == nil || d.Deleted {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListByUser returns the decks of userID from the user's sorted set.
+func (s *RedisStore) ListByUser(ctx context.Context, userID uuid.UUID, offset, limit int) ([]*deck.Deck, int, error) {
+	t := tenant.FromContext(ctx)
+	total, err := s.Redis.ZCard(ctx, userDecksKey(t, userID)).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	stop := int64(-1)
+	if limit > 0 {
+		stop = int64(offset + limit - 1)
+	}
+	ids, err := s.Redis.ZRange(ctx, userDecksKey(t, userID), int64(offset), stop).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, int(total), err
+	}
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, deckKey(t, id))
+	}
+	vals, err := s.Redis.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	decks := make([]*deck.Deck, 0, len(vals))
+	for _, v := range vals {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		d, err := decodeDeck([]byte(data))
+		if err != nil {
+			return nil, 0, err
+		}
+		decks = append(decks, d)
+	}
+	return decks, int(total), nil
+}
+
+// Tenants returns the tenants in which userID has decks, scanning for the
+// sorted sets of the user, which Redis drops once empty.
+func (s *RedisStore) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	suffix := userDecksKey(tenant.Default, userID)
+	var tenants []string
+	seen := make(map[string]bool)
+	iter := s.Redis.Scan(ctx, 0, "*"+suffix, 0).Iterator()
+	for iter.Next(ctx) {
+		t := strings.TrimSuffix(strings.TrimSuffix(iter.Val(), suffix), ":")
+		if t == "" {
+			t = tenant.Default
+		}
+		// scans may return a key more than once
+		if !seen[t] {
+			seen[t] = true
+			tenants = append(tenants, t)
+		}
+	}
+	return tenants, iter.Err()
+}
+
+// getDeck reads the stored state of a deck, including deleted decks. It
+// returns nil when the deck does not exist.
+func getDeck(ctx context.Context, c redis.Cmdable, tenantID string, id uuid.UUID) (*deck.Deck, error) {
+	data, err := c.Get(ctx, deckKey(tenantID, id.String())).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeDeck(data)
+}
+
+func decodeDeck(data []byte) (*deck.Deck, error) {
+	d := &deck.Deck{}
+	if err := json.Unmarshal(data, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+var _ deck.Repository = (*RedisStore)(nil)
@@ -0,0 +1,21 @@
+package deckstore
+
+import (
+	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/deck/decktest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newRedisStore(t *testing.T) *RedisStore {
+	s := miniredis.RunT(t)
+	return &RedisStore{Redis: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+}
+
+func TestRedisStoreConformance(t *testing.T) {
+	decktest.RepositorySuite.Run(t, func(t *testing.T) deck.Repository {
+		return newRedisStore(t)
+	})
+}
@@ -0,0 +1,240 @@
+package deckstore
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserIndex lists the decks of every user, newest first, so that
+// EventStore.ListByUser only replays the decks of one user. Decks are
+// indexed in the tenant of the context.
+type UserIndex interface {
+	// Add indexes a deck of a user. Adding a deck again keeps one entry.
+	Add(ctx context.Context, userID, deckID uuid.UUID, createdAt time.Time) error
+	// Remove drops a deck from the index.
+	Remove(ctx context.Context, deckID uuid.UUID) error
+	// Page returns the ids of a page of the decks of a user, newest first,
+	// and the total number of decks the user has. A limit <= 0 returns all
+	// decks from offset on.
+	Page(ctx context.Context, userID uuid.UUID, offset, limit int) ([]uuid.UUID, int, error)
+	// Len returns the number of decks indexed.
+	Len(ctx context.Context) (int, error)
+	// Tenants returns the tenants in which a user has decks indexed,
+	// whatever the tenant of ctx.
+	Tenants(ctx context.Context, userID uuid.UUID) ([]string, error)
+}
+
+// indexEntry is a deck of the InMemoryUserIndex.
+type indexEntry struct {
+	userID    uuid.UUID
+	deckID    uuid.UUID
+	createdAt time.Time
+}
+
+// InMemoryUserIndex is a process-local user index.
+type InMemoryUserIndex struct {
+	mu      sync.RWMutex
+	entries map[tenantDeck]indexEntry
+	// byUser holds the decks of every user of every tenant.
+	byUser map[string]map[uuid.UUID]map[uuid.UUID]bool
+}
+
+// NewInMemoryUserIndex creates the index.
+func NewInMemoryUserIndex() *InMemoryUserIndex {
+	return &InMemoryUserIndex{
+		entries: make(map[tenantDeck]indexEntry),
+		byUser:  make(map[string]map[uuid.UUID]map[uuid.UUID]bool),
+	}
+}
+
+// Add implements UserIndex.
+func (x *InMemoryUserIndex) Add(ctx context.Context, userID, deckID uuid.UUID, createdAt time.Time) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	t := tenant.FromContext(ctx)
+	if _, ok := x.entries[tenantDeck{t, deckID}]; ok {
+		return nil
+	}
+	x.entries[tenantDeck{t, deckID}] = indexEntry{userID: userID, deckID: deckID, createdAt: createdAt}
+	users, ok := x.byUser[t]
+	if !ok {
+		users = make(map[uuid.UUID]map[uuid.UUID]bool)
+		x.byUser[t] = users
+	}
+	if users[userID] == nil {
+		users[userID] = make(map[uuid.UUID]bool)
+	}
+	users[userID][deckID] = true
+	return nil
+}
+
+// Remove implements UserIndex.
+func (x *InMemoryUserIndex) Remove(ctx context.Context, deckID uuid.UUID) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	t := tenant.FromContext(ctx)
+	e, ok := x.entries[tenantDeck{t, deckID}]
+	if !ok {
+		return nil
+	}
+	delete(x.entries, tenantDeck{t, deckID})
+	delete(x.byUser[t][e.userID], deckID)
+	return nil
+}
+
+// Page implements UserIndex.
+func (x *InMemoryUserIndex) Page(ctx context.Context, userID uuid.UUID, offset, limit int) ([]uuid.UUID, int, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	t := tenant.FromContext(ctx)
+	var entries []indexEntry
+	for id := range x.byUser[t][userID] {
+		entries = append(entries, x.entries[tenantDeck{t, id}])
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].createdAt.Equal(entries[j].createdAt) {
+			return entries[i].createdAt.After(entries[j].createdAt)
+		}
+		return entries[i].deckID.String() < entries[j].deckID.String()
+	})
+	total := len(entries)
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	ids := make([]uuid.UUID, 0, end-offset)
+	for _, e := range entries[offset:end] {
+		ids = append(ids, e.deckID)
+	}
+	return ids, total, nil
+}
+
+// Len implements UserIndex.
+func (x *InMemoryUserIndex) Len(ctx context.Context) (int, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	n := 0
+	for _, decks := range x.byUser[tenant.FromContext(ctx)] {
+		n += len(decks)
+	}
+	return n, nil
+}
+
+// Tenants implements UserIndex.
+func (x *InMemoryUserIndex) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	var tenants []string
+	for t, users := range x.byUser {
+		if len(users[userID]) > 0 {
+			tenants = append(tenants, t)
+		}
+	}
+	return tenants, nil
+}
+
+// UserDeckRecord is a deck in the user_decks table. Deck ids are unique
+// across tenants.
+type UserDeckRecord struct {
+	DeckID    string    `gorm:"primaryKey;size:36"`
+	Tenant    string    `gorm:"size:64;not null;index:idx_user_decks,priority:1"`
+	UserID    string    `gorm:"size:36;not null;index:idx_user_decks,priority:2"`
+	CreatedAt time.Time `gorm:"not null;index:idx_user_decks,priority:3"`
+}
+
+// TableName implements gorm's tabler interface.
+func (UserDeckRecord) TableName() string { return "user_decks" }
+
+// MySQLUserIndex is a GORM-based user index keeping the decks of every user
+// in a user_decks table.
+type MySQLUserIndex struct {
+	DB *gorm.DB
+}
+
+// NewMySQLUserIndex creates the user_decks table if needed.
+func NewMySQLUserIndex(db *gorm.DB) (*MySQLUserIndex, error) {
+	if err := db.AutoMigrate(&UserDeckRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLUserIndex{DB: db}, nil
+}
+
+// Add implements UserIndex.
+func (x *MySQLUserIndex) Add(ctx context.Context, userID, deckID uuid.UUID, createdAt time.Time) error {
+	return x.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&UserDeckRecord{
+		DeckID:    deckID.String(),
+		Tenant:    tenant.FromContext(ctx),
+		UserID:    userID.String(),
+		CreatedAt: createdAt,
+	}).Error
+}
+
+// Remove implements UserIndex.
+func (x *MySQLUserIndex) Remove(ctx context.Context, deckID uuid.UUID) error {
+	return x.DB.WithContext(ctx).Where("tenant = ? AND deck_id = ?", tenant.FromContext(ctx), deckID.String()).
+		Delete(&UserDeckRecord{}).Error
+}
+
+// Page implements UserIndex.
+func (x *MySQLUserIndex) Page(ctx context.Context, userID uuid.UUID, offset, limit int) ([]uuid.UUID, int, error) {
+	db := x.DB.WithContext(ctx)
+	t := tenant.FromContext(ctx)
+	var total int64
+	if err := db.Model(&UserDeckRecord{}).Where("tenant = ? AND user_id = ?", t, userID.String()).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if limit <= 0 {
+		limit = math.MaxInt32
+	}
+	var recs []UserDeckRecord
+	if err := db.Where("tenant = ? AND user_id = ?", t, userID.String()).
+		Order("created_at DESC, deck_id").Offset(offset).Limit(limit).Find(&recs).Error; err != nil {
+		return nil, 0, err
+	}
+	ids := make([]uuid.UUID, 0, len(recs))
+	for _, rec := range recs {
+		id, err := uuid.Parse(rec.DeckID)
+		if err != nil {
+			return nil, 0, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, int(total), nil
+}
+
+// Len implements UserIndex.
+func (x *MySQLUserIndex) Len(ctx context.Context) (int, error) {
+	var n int64
+	err := x.DB.WithContext(ctx).Model(&UserDeckRecord{}).Where("tenant = ?", tenant.FromContext(ctx)).Count(&n).Error
+	return int(n), err
+}
+
+// Tenants implements UserIndex.
+func (x *MySQLUserIndex) Tenants(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var tenants []string
+	err := x.DB.WithContext(ctx).Model(&UserDeckRecord{}).Where("user_id = ?", userID.String()).
+		Distinct().Pluck("tenant", &tenants).Error
+	return tenants, err
+}
+
+var (
+	_ UserIndex = (*InMemoryUserIndex)(nil)
+	_ UserIndex = (*MySQLUserIndex)(nil)
+)
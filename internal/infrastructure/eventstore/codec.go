@@ -0,0 +1,177 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+)
+
+// ErrVersionConflict is returned when another writer appended to the same
+// stream concurrently.
+var ErrVersionConflict = errors.New("eventstore: stream version conflict")
+
+// Cipher transforms events on their way into and out of a store, e.g. to
+// encrypt personal data. See shredding.Protector.
+type Cipher interface {
+	Encrypt(ctx context.Context, evt interface{}) (interface{}, error)
+	Decrypt(ctx context.Context, evt interface{}) (interface{}, error)
+}
+
+func encrypt(ctx context.Context, c Cipher, evt interface{}) (interface{}, error) {
+	if c == nil {
+		return evt, nil
+	}
+	return c.Encrypt(ctx, evt)
+}
+
+func decrypt(ctx context.Context, c Cipher, evt interface{}) (interface{}, error) {
+	if c == nil {
+		return evt, nil
+	}
+	return c.Decrypt(ctx, evt)
+}
+
+// eventStreamID returns the id of the stream (card or deck) an event belongs
+// to.
+func eventStreamID(evt interface{}) (string, error) {
+	switch e := evt.(type) {
+	case card.CardCreated:
+		return e.ID.String(), nil
+	case card.CardUpdated:
+		return e.ID.String(), nil
+	case deck.DeckCreated:
+		return e.ID.String(), nil
+	case deck.CardAddedToDeck:
+		return e.ID.String(), nil
+	case deck.CardRemovedFromDeck:
+		return e.ID.String(), nil
+	case deck.DeckRenamed:
+		return e.ID.String(), nil
+	case deck.DeckVisibilityChanged:
+		return e.ID.String(), nil
+	case deck.DeckDeleted:
+		return e.ID.String(), nil
+	case deck.DeckRevised:
+		return e.ID.String(), nil
+	default:
+		return "", fmt.Errorf("unknown event type %T", evt)
+	}
+}
+
+// checkStream rejects events that do not belong to stream id.
+func checkStream(id string, events []interface{}) error {
+	for _, evt := range events {
+		streamID, err := eventStreamID(evt)
+		if err != nil {
+			return err
+		}
+		if streamID != id {
+			return fmt.Errorf("event %T of stream %s appended to stream %s", evt, streamID, id)
+		}
+	}
+	return nil
+}
+
+// isCardEvent reports whether a stored type name is a card event, used to
+// keep deck streams out of card searches.
+func isCardEvent(typ string) bool {
+	return strings.HasPrefix(typ, cardEventPrefix)
+}
+
+const cardEventPrefix = "card."
+
+// encodeEvent returns the stream id, type name and JSON payload of an event.
+func encodeEvent(evt interface{}) (id, typ string, payload []byte, err error) {
+	id, err = eventStreamID(evt)
+	if err != nil {
+		return "", "", nil, err
+	}
+	payload, err = json.Marshal(evt)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return id, fmt.Sprintf("%T", evt), payload, nil
+}
+
+// decodeEvent turns a stored type name and payload back into an event.
+func decodeEvent(typ string, payload []byte) (interface{}, error) {
+	switch typ {
+	case "card.CardCreated":
+		return decodeAs[card.CardCreated](payload)
+	case "card.CardUpdated":
+		return decodeAs[card.CardUpdated](payload)
+	case "deck.DeckCreated":
+		return decodeAs[deck.DeckCreated](payload)
+	case "deck.CardAddedToDeck":
+		return decodeAs[deck.CardAddedToDeck](payload)
+	case "deck.CardRemovedFromDeck":
+		return decodeAs[deck.CardRemovedFromDeck](payload)
+	case "deck.DeckRenamed":
+		return decodeAs[deck.DeckRenamed](payload)
+	case "deck.DeckVisibilityChanged":
+		return decodeAs[deck.DeckVisibilityChanged](payload)
+	case "deck.DeckDeleted":
+		return decodeAs[deck.DeckDeleted](payload)
+	case "deck.DeckRevised":
+		return decodeAs[deck.DeckRevised](payload)
+	default:
+		return nil, fmt.Errorf("unknown event type %s", typ)
+	}
+}
+
+func decodeAs[T any](payload []byte) (interface{}, error) {
+	var evt T
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, err
+	}
+	return evt, nil
+}
+
+// replayCard rebuilds a card from its events. It returns nil for empty
+// streams.
+func replayCard(events []interface{}) *card.Card {
+	if len(events) == 0 {
+		return nil
+	}
+	c := &card.Card{}
+	for _, e := range events {
+		applyEvent(c, e)
+	}
+	return c
+}
+
+// applyEvent folds a single event into the card state.
+func applyEvent(c *card.Card, evt interface{}) {
+	switch e := evt.(type) {
+	case card.CardCreated:
+		c.ID = e.ID
+		c.Name = e.Name
+		c.Cost = e.Cost
+		c.Faction = e.Faction
+		c.Category = e.Category
+		c.SubCategory = e.SubCategory
+		c.Description = e.Description
+	case card.CardUpdated:
+		c.Name = e.Name
+		c.Cost = e.Cost
+		c.Faction = e.Faction
+		c.Category = e.Category
+		c.SubCategory = e.SubCategory
+		c.Description = e.Description
+	}
+}
+
+// matches reports whether c satisfies the search filters. Empty strings and a
+// zero cost match anything.
+func matches(c *card.Card, name string, cost int, faction, category, sub string) bool {
+	return (name == "" || c.Name == name) &&
+		(cost == 0 || c.Cost == cost) &&
+		(faction == "" || c.Faction == faction) &&
+		(category == "" || c.Category == category) &&
+		(sub == "" || c.SubCategory == sub)
+}
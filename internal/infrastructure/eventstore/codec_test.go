@@ -0,0 +1,34 @@
+package eventstore
+
+import (
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+	"testing"
+)
+
+func TestEventStreamID(t *testing.T) {
+	c := card.CardCreated{ID: uuid.New()}
+	id, err := eventStreamID(c)
+	if err != nil || id == "" {
+		t.Fatalf("unexpected result %s %v", id, err)
+	}
+	_, err = eventStreamID(struct{}{})
+	if err == nil {
+		t.Fatal("expected error for unknown event")
+	}
+}
+
+func TestEncodeDecodeEvent(t *testing.T) {
+	evt := card.CardUpdated{ID: uuid.New(), Name: "N", Cost: 2}
+	id, typ, data, err := encodeEvent(evt)
+	if err != nil || id != evt.ID.String() || typ != "card.CardUpdated" {
+		t.Fatalf("unexpected result %s %s %v", id, typ, err)
+	}
+	decoded, err := decodeEvent(typ, data)
+	if err != nil || decoded != evt {
+		t.Fatalf("unexpected decoded event %+v %v", decoded, err)
+	}
+	if _, err := decodeEvent("card.Unknown", data); err == nil {
+		t.Fatal("expected error for unknown type")
+	}
+}
@@ -0,0 +1,506 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
+)
+
+// SyncPolicy controls when the file store flushes segments to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs the active segment before Save returns.
+	SyncAlways SyncPolicy = iota
+	// SyncInterval fsyncs the active segment periodically in the background.
+	SyncInterval
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+// FileOptions configures a FileStore. Zero values select the defaults.
+type FileOptions struct {
+	// SegmentSize is the size in bytes after which a new segment is started.
+	SegmentSize int64
+	// Sync is the fsync policy.
+	Sync SyncPolicy
+	// SyncInterval is the flush period used with SyncInterval.
+	SyncInterval time.Duration
+	// Cipher, when set, encrypts events before they are written and
+	// decrypts them after they are read.
+	Cipher Cipher
+}
+
+const (
+	defaultSegmentSize  = 64 << 20
+	defaultSyncInterval = time.Second
+
+	segmentExt = ".seg"
+	// recordHeaderSize is the length and CRC prefix of every record.
+	recordHeaderSize = 8
+	// flagCommit marks the last record of a Save batch.
+	flagCommit byte = 1
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// errTornRecord reports a record that is incomplete or fails its checksum.
+var errTornRecord = errors.New("eventstore: torn record")
+
+// fileRecord is the JSON body of a record on disk. CardID is the id of the
+// stream the event belongs to and Tenant the tenant of the stream.
+type fileRecord struct {
+	Tenant  string          `json:"tenant"`
+	CardID  string          `json:"card_id"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type segment struct {
+	id   int
+	f    *os.File
+	size int64
+}
+
+type recordPos struct {
+	typ    string
+	seg    *segment
+	offset int64
+	size   int64
+}
+
+// FileStore is an event store backed by append-only segment files. The
+// index of the records is kept per tenant and stream.
+//
+// Each record is stored as a 4-byte big-endian body length, a 4-byte CRC-32C
+// of the body and the body itself: one flag byte followed by the JSON encoded
+// event. The last record of every Save carries the commit flag. On startup the
+// index is rebuilt by scanning all segments and anything after the last
+// committed record of the newest segment, such as a torn write, is truncated.
+type FileStore struct {
+	mu       sync.RWMutex
+	dir      string
+	opts     FileOptions
+	segments []*segment
+	index    map[string]map[string][]recordPos
+	dirty    bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewFileStore opens or creates a file event store in dir.
+func NewFileStore(dir string, opts FileOptions) (*FileStore, error) {
+	if opts.SegmentSize <= 0 {
+		opts.SegmentSize = defaultSegmentSize
+	}
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = defaultSyncInterval
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &FileStore{dir: dir, opts: opts, index: make(map[string]map[string][]recordPos), done: make(chan struct{})}
+	if err := s.recover(); err != nil {
+		_ = s.closeSegments()
+		return nil, err
+	}
+	if opts.Sync == SyncInterval {
+		s.wg.Add(1)
+		go s.syncLoop()
+	}
+	return s, nil
+}
+
+func (s *FileStore) recover() error {
+	ids, err := s.segmentIDs()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return s.rotate(1)
+	}
+	for i, id := range ids {
+		f, err := os.OpenFile(s.segmentPath(id), os.O_RDWR, 0o644)
+		if err != nil {
+			return err
+		}
+		seg := &segment{id: id, f: f}
+		s.segments = append(s.segments, seg)
+		last := i == len(ids)-1
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		valid, err := s.scan(seg, info.Size(), last)
+		if err != nil {
+			return err
+		}
+		if valid < info.Size() {
+			if err := f.Truncate(valid); err != nil {
+				return err
+			}
+			if err := f.Sync(); err != nil {
+				return err
+			}
+		}
+		seg.size = valid
+	}
+	return nil
+}
+
+// scan indexes the committed records of seg, a file of size bytes, and
+// returns the offset just past the last committed record. Torn records are
+// only tolerated in the newest segment since older segments are closed after
+// a committed batch.
+func (s *FileStore) scan(seg *segment, size int64, last bool) (int64, error) {
+	var offset, committed int64
+	var pending []fileRecord
+	var pendingPos []recordPos
+	for {
+		flags, rec, n, err := readRecord(seg.f, offset, size)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if errors.Is(err, errTornRecord) && last {
+				break
+			}
+			return 0, fmt.Errorf("segment %d at offset %d: %w", seg.id, offset, err)
+		}
+		pending = append(pending, rec)
+		pendingPos = append(pendingPos, recordPos{typ: rec.Type, seg: seg, offset: offset, size: n})
+		offset += n
+		if flags&flagCommit != 0 {
+			for i, r := range pending {
+				s.indexRecord(r, pendingPos[i])
+			}
+			pending, pendingPos = pending[:0], pendingPos[:0]
+			committed = offset
+		}
+	}
+	if len(pending) > 0 && !last {
+		return 0, fmt.Errorf("segment %d: %w", seg.id, errTornRecord)
+	}
+	return committed, nil
+}
+
+// indexRecord adds the position of a record to the index. The caller holds
+// the lock.
+func (s *FileStore) indexRecord(r fileRecord, p recordPos) {
+	streams, ok := s.index[r.Tenant]
+	if !ok {
+		streams = make(map[string][]recordPos)
+		s.index[r.Tenant] = streams
+	}
+	streams[r.CardID] = append(streams[r.CardID], p)
+}
+
+// readRecord reads the record at offset of a segment file of size bytes. A
+// length running past the end of the file is a torn header, so it is
+// rejected before the body is allocated.
+func readRecord(f *os.File, offset, size int64) (byte, fileRecord, int64, error) {
+	var rec fileRecord
+	header := make([]byte, recordHeaderSize)
+	n, err := f.ReadAt(header, offset)
+	if n == 0 && err == io.EOF {
+		return 0, rec, 0, io.EOF
+	}
+	if n < recordHeaderSize {
+		return 0, rec, 0, errTornRecord
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	sum := binary.BigEndian.Uint32(header[4:8])
+	if length == 0 || int64(length) > size-offset-recordHeaderSize {
+		return 0, rec, 0, errTornRecord
+	}
+	body := make([]byte, length)
+	if n, _ := f.ReadAt(body, offset+recordHeaderSize); n < int(length) {
+		return 0, rec, 0, errTornRecord
+	}
+	if crc32.Checksum(body, crcTable) != sum {
+		return 0, rec, 0, errTornRecord
+	}
+	if err := json.Unmarshal(body[1:], &rec); err != nil {
+		return 0, rec, 0, err
+	}
+	return body[0], rec, recordHeaderSize + int64(length), nil
+}
+
+func encodeRecord(buf []byte, rec fileRecord, flags byte) ([]byte, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	var header [recordHeaderSize]byte
+	body := append([]byte{flags}, data...)
+	binary.BigEndian.PutUint32(header[0:4], uint32(len(body)))
+	binary.BigEndian.PutUint32(header[4:8], crc32.Checksum(body, crcTable))
+	buf = append(buf, header[:]...)
+	return append(buf, body...), nil
+}
+
+func (s *FileStore) segmentIDs() ([]int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		var id int
+		if _, err := fmt.Sscanf(strings.TrimSuffix(name, segmentExt), "%d", &id); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+func (s *FileStore) segmentPath(id int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%08d%s", id, segmentExt))
+}
+
+func (s *FileStore) active() *segment { return s.segments[len(s.segments)-1] }
+
+// rotate starts a new segment with the given id. The previous segment is
+// synced first so that only the newest segment can contain torn records.
+func (s *FileStore) rotate(id int) error {
+	if len(s.segments) > 0 {
+		if err := s.active().f.Sync(); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(s.segmentPath(id), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	s.segments = append(s.segments, &segment{id: id, f: f})
+	return syncDir(s.dir)
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Save appends the batch as a single write to the streams of the tenant of
+// ctx.
+func (s *FileStore) Save(ctx context.Context, events []interface{}) error {
+	return s.write(ctx, events, nil)
+}
+
+// Append appends events to stream id of the tenant of ctx as a single
+// write. The stream must hold expected events; Append returns
+// ErrVersionConflict otherwise.
+func (s *FileStore) Append(ctx context.Context, id string, expected int, events []interface{}) error {
+	if err := checkStream(id, events); err != nil {
+		return err
+	}
+	return s.write(ctx, events, func() error {
+		if len(s.index[tenant.FromContext(ctx)][id]) != expected {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// write appends events as a single write. check, when set, is called with
+// the lock held before anything is written.
+func (s *FileStore) write(ctx context.Context, events []interface{}, check func() error) error {
+	if len(events) == 0 {
+		return nil
+	}
+	t := tenant.FromContext(ctx)
+	recs := make([]fileRecord, 0, len(events))
+	for _, evt := range events {
+		evt, err := encrypt(ctx, s.opts.Cipher, evt)
+		if err != nil {
+			return err
+		}
+		id, typ, data, err := encodeEvent(evt)
+		if err != nil {
+			return err
+		}
+		recs = append(recs, fileRecord{Tenant: t, CardID: id, Type: typ, Payload: data})
+	}
+	var buf []byte
+	sizes := make([]int64, len(recs))
+	for i, r := range recs {
+		var flags byte
+		if i == len(recs)-1 {
+			flags = flagCommit
+		}
+		prev := len(buf)
+		var err error
+		if buf, err = encodeRecord(buf, r, flags); err != nil {
+			return err
+		}
+		sizes[i] = int64(len(buf) - prev)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if check != nil {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	if s.active().size >= s.opts.SegmentSize {
+		if err := s.rotate(s.active().id + 1); err != nil {
+			return err
+		}
+	}
+	seg := s.active()
+	if _, err := seg.f.WriteAt(buf, seg.size); err != nil {
+		// drop whatever part of the batch made it to disk
+		_ = seg.f.Truncate(seg.size)
+		return err
+	}
+	switch s.opts.Sync {
+	case SyncAlways:
+		if err := seg.f.Sync(); err != nil {
+			return err
+		}
+	case SyncInterval:
+		s.dirty = true
+	}
+	offset := seg.size
+	for i, r := range recs {
+		s.indexRecord(r, recordPos{typ: r.Type, seg: seg, offset: offset, size: sizes[i]})
+		offset += sizes[i]
+	}
+	seg.size = offset
+	return nil
+}
+
+func (s *FileStore) syncLoop() {
+	defer s.wg.Done()
+	t := time.NewTicker(s.opts.SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.mu.Lock()
+			if s.dirty {
+				_ = s.active().f.Sync()
+				s.dirty = false
+			}
+			s.mu.Unlock()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Events returns the decoded events of a stream in order.
+func (s *FileStore) Events(ctx context.Context, id string) ([]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.events(ctx, id)
+}
+
+func (s *FileStore) events(ctx context.Context, id string) ([]interface{}, error) {
+	positions := s.index[tenant.FromContext(ctx)][id]
+	events := make([]interface{}, 0, len(positions))
+	for _, p := range positions {
+		_, rec, _, err := readRecord(p.seg.f, p.offset, p.seg.size)
+		if err != nil {
+			return nil, err
+		}
+		evt, err := decodeEvent(rec.Type, rec.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if evt, err = decrypt(ctx, s.opts.Cipher, evt); err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+	return events, nil
+}
+
+// Load rebuilds the card state from its records.
+func (s *FileStore) Load(ctx context.Context, id string) (*card.Card, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if p := s.index[tenant.FromContext(ctx)][id]; len(p) == 0 || !isCardEvent(p[0].typ) {
+		return nil, nil
+	}
+	events, err := s.events(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return replayCard(events), nil
+}
+
+// Search loads all cards and filters them.
+func (s *FileStore) Search(ctx context.Context, name string, cost int, faction, category, sub string) ([]*card.Card, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var cards []*card.Card
+	for id, p := range s.index[tenant.FromContext(ctx)] {
+		if !isCardEvent(p[0].typ) {
+			continue
+		}
+		events, err := s.events(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if c := replayCard(events); matches(c, name, cost, faction, category, sub) {
+			cards = append(cards, c)
+		}
+	}
+	return cards, nil
+}
+
+// Close flushes and closes all segments. Later calls return the result of
+// the first.
+func (s *FileStore) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.closeErr = s.active().f.Sync()
+		if err := s.closeSegments(); s.closeErr == nil {
+			s.closeErr = err
+		}
+	})
+	return s.closeErr
+}
+
+func (s *FileStore) closeSegments() error {
+	var err error
+	for _, seg := range s.segments {
+		if cerr := seg.f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+var _ card.Repository = (*FileStore)(nil)
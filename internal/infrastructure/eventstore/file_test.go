@@ -0,0 +1,321 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/card/cardtest"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+func newFileStore(t *testing.T, dir string, opts FileOptions) *FileStore {
+	t.Helper()
+	s, err := NewFileStore(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestFileConformance(t *testing.T) {
+	cardtest.RepositorySuite.Run(t, func(t *testing.T) card.Repository {
+		return newFileStore(t, t.TempDir(), FileOptions{SegmentSize: 512})
+	})
+}
+
+func TestFileReopenRebuildsIndex(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, FileOptions{Sync: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	evts := []interface{}{card.CardCreated{ID: id, Name: "N", Cost: 1}, card.CardUpdated{ID: id, Name: "M", Cost: 2}}
+	if err := s.Save(context.Background(), evts); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s = newFileStore(t, dir, FileOptions{})
+	c, err := s.Load(context.Background(), id.String())
+	if err != nil || c == nil || c.Name != "M" || c.Cost != 2 {
+		t.Fatalf("unexpected card %+v %v", c, err)
+	}
+}
+
+func TestFileAppend(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, FileOptions{Sync: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	id := d.ID.String()
+	if err := s.Append(ctx, id, 0, []interface{}{d.Created(), d.Revise()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Append(ctx, id, 0, []interface{}{deck.DeckDeleted{ID: d.ID}}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict got %v", err)
+	}
+	if err := s.Append(ctx, id, 2, []interface{}{deck.DeckDeleted{ID: uuid.New()}}); err == nil {
+		t.Fatal("expected error for an event of another stream")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the versions of the streams survive a restart
+	s = newFileStore(t, dir, FileOptions{})
+	if err := s.Append(ctx, id, 1, []interface{}{deck.DeckDeleted{ID: d.ID}}); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("expected ErrVersionConflict got %v", err)
+	}
+	if err := s.Append(ctx, id, 2, []interface{}{deck.DeckDeleted{ID: d.ID}}); err != nil {
+		t.Fatal(err)
+	}
+	if events, err := s.Events(ctx, id); err != nil || len(events) != 3 {
+		t.Fatalf("expected 3 events got %v %v", events, err)
+	}
+}
+
+func TestFileReopenKeepsTenants(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, FileOptions{Sync: SyncNever})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acme := tenant.NewContext(context.Background(), "acme")
+	a, d := uuid.New(), uuid.New()
+	if err := s.Save(acme, []interface{}{card.CardCreated{ID: a, Name: "A"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: d, Name: "D"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	s = newFileStore(t, dir, FileOptions{})
+	if c, _ := s.Load(acme, a.String()); c == nil || c.Name != "A" {
+		t.Fatalf("unexpected card %+v", c)
+	}
+	if c, _ := s.Load(context.Background(), a.String()); c != nil {
+		t.Fatalf("expected the card of acme to stay in acme got %+v", c)
+	}
+	if c, _ := s.Load(context.Background(), d.String()); c == nil || c.Name != "D" {
+		t.Fatalf("unexpected card %+v", c)
+	}
+}
+
+func TestFileSegmentRotation(t *testing.T) {
+	dir := t.TempDir()
+	s := newFileStore(t, dir, FileOptions{SegmentSize: 1})
+	var ids []uuid.UUID
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		ids = append(ids, id)
+		if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: id, Name: "N"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	segs, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if len(segs) != 3 {
+		t.Fatalf("expected 3 segments got %d", len(segs))
+	}
+	for _, id := range ids {
+		if c, err := s.Load(context.Background(), id.String()); err != nil || c == nil {
+			t.Fatalf("load %s failed: %v", id, err)
+		}
+	}
+}
+
+func TestFileTornWriteTruncated(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: id, Name: "N"}}); err != nil {
+		t.Fatal(err)
+	}
+	// an uncommitted batch followed by half a record, as left by a crash
+	other := uuid.New()
+	buf, _ := encodeRecord(nil, fileRecord{CardID: other.String(), Type: "card.CardCreated", Payload: []byte(`{}`)}, 0)
+	buf, _ = encodeRecord(buf, fileRecord{CardID: other.String(), Type: "card.CardCreated", Payload: []byte(`{}`)}, flagCommit)
+	seg := s.active()
+	size := seg.size
+	if _, err := seg.f.WriteAt(buf[:len(buf)-3], size); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = newFileStore(t, dir, FileOptions{})
+	info, err := os.Stat(s.segmentPath(1))
+	if err != nil || info.Size() != size {
+		t.Fatalf("expected segment truncated to %d got %v %v", size, info, err)
+	}
+	if c, _ := s.Load(context.Background(), other.String()); c != nil {
+		t.Fatalf("torn batch should be dropped, got %+v", c)
+	}
+	if err := s.Save(context.Background(), []interface{}{card.CardUpdated{ID: id, Name: "M"}}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := s.Load(context.Background(), id.String())
+	if err != nil || c == nil || c.Name != "M" {
+		t.Fatalf("unexpected card %+v %v", c, err)
+	}
+}
+
+func TestFileTornHeaderLength(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, FileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: id, Name: "N"}}); err != nil {
+		t.Fatal(err)
+	}
+	// a torn header claiming a body of almost 4 GiB
+	seg := s.active()
+	size := seg.size
+	if _, err := seg.f.WriteAt([]byte{0xff, 0xff, 0xff, 0xfe, 0, 0, 0, 0, 1}, size); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = newFileStore(t, dir, FileOptions{})
+	info, err := os.Stat(s.segmentPath(1))
+	if err != nil || info.Size() != size {
+		t.Fatalf("expected segment truncated to %d got %v %v", size, info, err)
+	}
+	if c, err := s.Load(context.Background(), id.String()); err != nil || c == nil || c.Name != "N" {
+		t.Fatalf("unexpected card %+v %v", c, err)
+	}
+}
+
+func TestFileCloseTwice(t *testing.T) {
+	s, err := NewFileStore(t.TempDir(), FileOptions{Sync: SyncInterval})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("expected the second close to return the first result got %v", err)
+	}
+}
+
+func TestFileCorruptOlderSegment(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir, FileOptions{SegmentSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: uuid.New()}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "00000001"+segmentExt), []byte("garbage!!"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(dir, FileOptions{}); err == nil {
+		t.Fatal("expected error for corrupt sealed segment")
+	}
+}
+
+func TestFileSyncInterval(t *testing.T) {
+	s := newFileStore(t, t.TempDir(), FileOptions{Sync: SyncInterval, SyncInterval: time.Millisecond})
+	if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: uuid.New()}}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.RLock()
+		dirty := s.dirty
+		s.mu.RUnlock()
+		if !dirty {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("segment was not synced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// reverseCipher stands in for shredding.Protector by reversing card names.
+type reverseCipher struct{}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func (reverseCipher) Encrypt(ctx context.Context, evt interface{}) (interface{}, error) {
+	e := evt.(card.CardCreated)
+	e.Name = reverse(e.Name)
+	return e, nil
+}
+
+func (reverseCipher) Decrypt(ctx context.Context, evt interface{}) (interface{}, error) {
+	return reverseCipher{}.Encrypt(ctx, evt)
+}
+
+func TestFileCipher(t *testing.T) {
+	dir := t.TempDir()
+	s := newFileStore(t, dir, FileOptions{Cipher: reverseCipher{}})
+	id := uuid.New()
+	if err := s.Save(context.Background(), []interface{}{card.CardCreated{ID: id, Name: "secret"}}); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(s.segmentPath(1))
+	if err != nil || !strings.Contains(string(raw), "terces") || strings.Contains(string(raw), "secret") {
+		t.Fatalf("expected transformed payload on disk: %q %v", raw, err)
+	}
+	c, err := s.Load(context.Background(), id.String())
+	if err != nil || c == nil || c.Name != "secret" {
+		t.Fatalf("unexpected card %+v %v", c, err)
+	}
+}
+
+func TestFileSearchSkipsDeckStreams(t *testing.T) {
+	s := newFileStore(t, t.TempDir(), FileOptions{})
+	d := deck.NewDeck(uuid.New(), "d", nil)
+	if err := s.Save(context.Background(), []interface{}{d.Created()}); err != nil {
+		t.Fatal(err)
+	}
+	cards, err := s.Search(context.Background(), "", 0, "", "", "")
+	if err != nil || len(cards) != 0 {
+		t.Fatalf("expected no cards got %v %v", cards, err)
+	}
+	if c, err := s.Load(context.Background(), d.ID.String()); err != nil || c != nil {
+		t.Fatalf("deck stream must not load as card: %+v %v", c, err)
+	}
+	events, err := s.Events(context.Background(), d.ID.String())
+	if err != nil || len(events) != 1 {
+		t.Fatalf("unexpected events %v %v", events, err)
+	}
+}
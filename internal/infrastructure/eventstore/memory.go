@@ -2,103 +2,102 @@ package eventstore
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
 )
 
-type inMemoryStore struct {
+// InMemoryStore is a process-local event store. Streams are kept apart per
+// tenant.
+type InMemoryStore struct {
 	mu     sync.RWMutex
-	events map[string][]interface{}
+	events map[string]map[string][]interface{}
 }
 
 // NewInMemoryStore creates an in-memory event store.
-func NewInMemoryStore() card.Repository {
-	return &inMemoryStore{events: make(map[string][]interface{})}
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{events: make(map[string]map[string][]interface{})}
 }
 
-func (s *inMemoryStore) Save(ctx context.Context, events []interface{}) error {
+// Save appends a batch of events, which may span several streams, to the
+// streams of the tenant of ctx.
+func (s *InMemoryStore) Save(ctx context.Context, events []interface{}) error {
+	ids := make([]string, len(events))
+	for i, evt := range events {
+		id, err := eventStreamID(evt)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if len(events) == 0 {
-		return nil
+	streams := s.streams(ctx)
+	for i, evt := range events {
+		streams[ids[i]] = append(streams[ids[i]], evt)
+	}
+	return nil
+}
+
+// Append appends events to stream id of the tenant of ctx, which must hold
+// expected events, and returns ErrVersionConflict otherwise.
+func (s *InMemoryStore) Append(ctx context.Context, id string, expected int, events []interface{}) error {
+	if err := checkStream(id, events); err != nil {
+		return err
 	}
-	var id string
-	switch e := events[0].(type) {
-	case card.CardCreated:
-		id = e.ID.String()
-	case card.CardUpdated:
-		id = e.ID.String()
-	default:
-		return nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	streams := s.streams(ctx)
+	if len(streams[id]) != expected {
+		return ErrVersionConflict
 	}
-	s.events[id] = append(s.events[id], events...)
+	streams[id] = append(streams[id], events...)
 	return nil
 }
 
-func (s *inMemoryStore) Load(ctx context.Context, id string) (*card.Card, error) {
+// streams returns the streams of the tenant of ctx. The caller must hold
+// the write lock.
+func (s *InMemoryStore) streams(ctx context.Context) map[string][]interface{} {
+	streams, ok := s.events[tenant.FromContext(ctx)]
+	if !ok {
+		streams = make(map[string][]interface{})
+		s.events[tenant.FromContext(ctx)] = streams
+	}
+	return streams
+}
+
+// Events returns the events of a stream in order.
+func (s *InMemoryStore) Events(ctx context.Context, id string) ([]interface{}, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	evs := s.events[id]
-	if len(evs) == 0 {
-		return nil, nil
-	}
-	c := &card.Card{}
-	for _, e := range evs {
-		switch evt := e.(type) {
-		case card.CardCreated:
-			c.ID = evt.ID
-			c.Name = evt.Name
-			c.Cost = evt.Cost
-			c.Faction = evt.Faction
-			c.Category = evt.Category
-			c.SubCategory = evt.SubCategory
-			c.Description = evt.Description
-		case card.CardUpdated:
-			c.Name = evt.Name
-			c.Cost = evt.Cost
-			c.Faction = evt.Faction
-			c.Category = evt.Category
-			c.SubCategory = evt.SubCategory
-			c.Description = evt.Description
-		}
+	return append([]interface{}(nil), s.events[tenant.FromContext(ctx)][id]...), nil
+}
+
+// Load rebuilds the card state from its events.
+func (s *InMemoryStore) Load(ctx context.Context, id string) (*card.Card, error) {
+	evs, err := s.Events(ctx, id)
+	if err != nil || len(evs) == 0 || !isCardEvent(fmt.Sprintf("%T", evs[0])) {
+		return nil, err
 	}
-	return c, nil
+	return replayCard(evs), nil
 }
 
-func (s *inMemoryStore) Search(ctx context.Context, name string, cost int, faction, category, sub string) ([]*card.Card, error) {
+// Search rebuilds all cards and filters them.
+func (s *InMemoryStore) Search(ctx context.Context, name string, cost int, faction, category, sub string) ([]*card.Card, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	var cards []*card.Card
-	for _, evs := range s.events {
-		c := &card.Card{}
-		for _, e := range evs {
-			switch evt := e.(type) {
-			case card.CardCreated:
-				c.ID = evt.ID
-				c.Name = evt.Name
-				c.Cost = evt.Cost
-				c.Faction = evt.Faction
-				c.Category = evt.Category
-				c.SubCategory = evt.SubCategory
-				c.Description = evt.Description
-			case card.CardUpdated:
-				c.Name = evt.Name
-				c.Cost = evt.Cost
-				c.Faction = evt.Faction
-				c.Category = evt.Category
-				c.SubCategory = evt.SubCategory
-				c.Description = evt.Description
-			}
+	for _, evs := range s.events[tenant.FromContext(ctx)] {
+		if !isCardEvent(fmt.Sprintf("%T", evs[0])) {
+			continue
 		}
-		if (name == "" || c.Name == name) &&
-			(cost == 0 || c.Cost == cost) &&
-			(faction == "" || c.Faction == faction) &&
-			(category == "" || c.Category == category) &&
-			(sub == "" || c.SubCategory == sub) {
-			tmp := *c
-			cards = append(cards, &tmp)
+		if c := replayCard(evs); matches(c, name, cost, faction, category, sub) {
+			cards = append(cards, c)
 		}
 	}
 	return cards, nil
 }
+
+var _ card.Repository = (*InMemoryStore)(nil)
@@ -2,23 +2,37 @@ package eventstore
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 
 	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
+	gomysql "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
+// mysqlDuplicateEntry is the MySQL error number of unique index violations.
+const mysqlDuplicateEntry = 1062
+
+// EventRecord is a stored event. CardID holds the id of the stream the event
+// belongs to, which is a deck id for deck events, Version its position
+// within that stream, unique per stream, and Tenant the tenant of the
+// stream. Rows written before tenants existed belong to the default tenant.
 type EventRecord struct {
 	ID      uint   `gorm:"primaryKey"`
-	CardID  string `gorm:"index"`
+	Tenant  string `gorm:"size:64;not null;default:default;index;uniqueIndex:idx_tenant_card_version,priority:1"`
+	CardID  string `gorm:"index;uniqueIndex:idx_tenant_card_version,priority:2"`
+	Version int    `gorm:"not null;uniqueIndex:idx_tenant_card_version,priority:3"`
 	Type    string
 	Payload []byte
 }
 
 type MySQLStore struct {
 	DB *gorm.DB
+	// Cipher, when set, encrypts events before they are written and
+	// decrypts them after they are read.
+	Cipher Cipher
 }
 
 // NewMySQLStore creates a GORM-based MySQL event store.
@@ -27,89 +41,148 @@ func NewMySQLStore(dsn string) (*MySQLStore, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := versionEvents(db); err != nil {
+		return nil, err
+	}
 	if err := db.AutoMigrate(&EventRecord{}); err != nil {
 		return nil, err
 	}
 	return &MySQLStore{DB: db}, nil
 }
 
-func eventCardID(evt interface{}) (string, error) {
-	switch e := evt.(type) {
-	case card.CardCreated:
-		return e.ID.String(), nil
-	case card.CardUpdated:
-		return e.ID.String(), nil
-	default:
-		return "", fmt.Errorf("unknown event type %T", evt)
+// versionEvents numbers the events stored before streams were versioned by
+// their position in their stream, so that the unique index on the versions
+// can be created.
+func versionEvents(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&EventRecord{}) || m.HasColumn(&EventRecord{}, "Version") {
+		return nil
+	}
+	if err := m.AddColumn(&EventRecord{}, "Version"); err != nil {
+		return err
 	}
+	return db.Exec(`UPDATE event_records e JOIN (
+		SELECT id, ROW_NUMBER() OVER (PARTITION BY tenant, card_id ORDER BY id) AS version FROM event_records
+	) v ON v.id = e.id SET e.version = v.version`).Error
 }
 
-// Save stores events in MySQL.
+// Save stores a batch of events, which may span several cards, atomically
+// using a single multi-row INSERT inside a transaction. The events go to the
+// streams of the tenant of ctx and get the next versions of their streams.
 func (s *MySQLStore) Save(ctx context.Context, events []interface{}) error {
+	return s.write(ctx, events, nil)
+}
+
+// Append is Save for the events of stream id, which must be at version
+// expected. It returns ErrVersionConflict otherwise, including when a
+// concurrent append takes the same versions first.
+func (s *MySQLStore) Append(ctx context.Context, id string, expected int, events []interface{}) error {
+	if err := checkStream(id, events); err != nil {
+		return err
+	}
+	return s.write(ctx, events, func(versions map[string]int) error {
+		if versions[id] != expected {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// write inserts events with the next versions of their streams. check, when
+// set, is called with the current versions before anything is inserted.
+func (s *MySQLStore) write(ctx context.Context, events []interface{}, check func(versions map[string]int) error) error {
 	if len(events) == 0 {
 		return nil
 	}
+	records := make([]EventRecord, 0, len(events))
+	ids := make([]string, 0, len(events))
 	for _, evt := range events {
-		id, err := eventCardID(evt)
+		evt, err := encrypt(ctx, s.Cipher, evt)
 		if err != nil {
 			return err
 		}
-		data, err := json.Marshal(evt)
+		id, typ, data, err := encodeEvent(evt)
 		if err != nil {
 			return err
 		}
-		rec := EventRecord{CardID: id, Type: fmt.Sprintf("%T", evt), Payload: data}
-		if err := s.DB.WithContext(ctx).Create(&rec).Error; err != nil {
+		records = append(records, EventRecord{Tenant: tenant.FromContext(ctx), CardID: id, Type: typ, Payload: data})
+		ids = append(ids, id)
+	}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		versions, err := streamVersions(tx.Model(&EventRecord{}), tenant.FromContext(ctx), ids)
+		if err != nil {
 			return err
 		}
+		if check != nil {
+			if err := check(versions); err != nil {
+				return err
+			}
+		}
+		for i := range records {
+			versions[records[i].CardID]++
+			records[i].Version = versions[records[i].CardID]
+		}
+		return tx.Create(&records).Error
+	})
+	var merr *gomysql.MySQLError
+	if errors.As(err, &merr) && merr.Number == mysqlDuplicateEntry {
+		return ErrVersionConflict
 	}
-	return nil
+	return err
 }
 
-// Load rebuilds the card state from events.
-func (s *MySQLStore) Load(ctx context.Context, id string) (*card.Card, error) {
-	var records []EventRecord
-	if err := s.DB.WithContext(ctx).Where("card_id = ?", id).Order("id").Find(&records).Error; err != nil {
+// streamVersions returns the current version of each of the streams ids of
+// a tenant, 0 for streams without events. db is scoped to the event table.
+func streamVersions(db *gorm.DB, tenantID string, ids []string) (map[string]int, error) {
+	var rows []struct {
+		CardID  string
+		Version int
+	}
+	if err := db.Select("card_id, MAX(version) AS version").Where("tenant = ? AND card_id IN ?", tenantID, ids).
+		Group("card_id").Scan(&rows).Error; err != nil {
 		return nil, err
 	}
-	if len(records) == 0 {
-		return nil, nil
+	versions := make(map[string]int, len(rows))
+	for _, r := range rows {
+		versions[r.CardID] = r.Version
 	}
-	c := &card.Card{}
+	return versions, nil
+}
+
+// Events returns the decoded events of a stream in order.
+func (s *MySQLStore) Events(ctx context.Context, id string) ([]interface{}, error) {
+	var records []EventRecord
+	if err := s.DB.WithContext(ctx).Where("tenant = ? AND card_id = ?", tenant.FromContext(ctx), id).Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	events := make([]interface{}, 0, len(records))
 	for _, r := range records {
-		switch r.Type {
-		case "card.CardCreated":
-			var evt card.CardCreated
-			if err := json.Unmarshal(r.Payload, &evt); err != nil {
-				return nil, err
-			}
-			c.ID = evt.ID
-			c.Name = evt.Name
-			c.Cost = evt.Cost
-			c.Faction = evt.Faction
-			c.Category = evt.Category
-			c.SubCategory = evt.SubCategory
-			c.Description = evt.Description
-		case "card.CardUpdated":
-			var evt card.CardUpdated
-			if err := json.Unmarshal(r.Payload, &evt); err != nil {
-				return nil, err
-			}
-			c.Name = evt.Name
-			c.Cost = evt.Cost
-			c.Faction = evt.Faction
-			c.Category = evt.Category
-			c.SubCategory = evt.SubCategory
-			c.Description = evt.Description
+		evt, err := decodeEvent(r.Type, r.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if evt, err = decrypt(ctx, s.Cipher, evt); err != nil {
+			return nil, err
 		}
+		events = append(events, evt)
+	}
+	return events, nil
+}
+
+// Load rebuilds the card state from events.
+func (s *MySQLStore) Load(ctx context.Context, id string) (*card.Card, error) {
+	events, err := s.Events(ctx, id)
+	if err != nil || len(events) == 0 || !isCardEvent(fmt.Sprintf("%T", events[0])) {
+		return nil, err
 	}
-	return c, nil
+	return replayCard(events), nil
 }
 
 // Search loads all cards and filters them.
 func (s *MySQLStore) Search(ctx context.Context, name string, cost int, faction, category, sub string) ([]*card.Card, error) {
 	var ids []string
-	if err := s.DB.WithContext(ctx).Model(&EventRecord{}).Distinct("card_id").Find(&ids).Error; err != nil {
+	if err := s.DB.WithContext(ctx).Model(&EventRecord{}).
+		Where("tenant = ? AND type LIKE ?", tenant.FromContext(ctx), cardEventPrefix+"%").Distinct("card_id").Find(&ids).Error; err != nil {
 		return nil, err
 	}
 	var cards []*card.Card
@@ -118,11 +191,7 @@ func (s *MySQLStore) Search(ctx context.Context, name string, cost int, faction,
 		if err != nil || c == nil {
 			continue
 		}
-		if (name == "" || c.Name == name) &&
-			(cost == 0 || c.Cost == cost) &&
-			(faction == "" || c.Faction == faction) &&
-			(category == "" || c.Category == category) &&
-			(sub == "" || c.SubCategory == sub) {
+		if matches(c, name, cost, faction, category, sub) {
 			tmp := *c
 			cards = append(cards, &tmp)
 		}
@@ -0,0 +1,230 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
+	"github.com/jackc/pgx/v5"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// NotifyChannel is the Postgres channel new events are announced on.
+const NotifyChannel = "card_events"
+
+// PostgresEventRecord is a stored event. CardID holds the id of the stream
+// the event belongs to and Version its position within that stream, unique
+// per stream. Tenant is the tenant of the stream, which streams are
+// versioned within.
+type PostgresEventRecord struct {
+	ID      uint64          `gorm:"primaryKey"`
+	Tenant  string          `gorm:"size:64;not null;uniqueIndex:idx_tenant_card_version,priority:1"`
+	CardID  string          `gorm:"not null;uniqueIndex:idx_tenant_card_version,priority:2"`
+	Version int             `gorm:"not null;uniqueIndex:idx_tenant_card_version,priority:3"`
+	Type    string          `gorm:"not null"`
+	Payload json.RawMessage `gorm:"type:jsonb;not null"`
+}
+
+// TableName keeps the table name in line with the MySQL store.
+func (PostgresEventRecord) TableName() string { return "event_records" }
+
+// Notification announces an event appended to a stream.
+type Notification struct {
+	Tenant  string `json:"tenant"`
+	CardID  string `json:"card_id"`
+	Version int    `json:"version"`
+	Type    string `json:"type"`
+}
+
+// PostgresStore is a GORM-based Postgres event store.
+type PostgresStore struct {
+	DB *gorm.DB
+	// Cipher, when set, encrypts events before they are written and
+	// decrypts them after they are read.
+	Cipher Cipher
+	dsn    string
+}
+
+// NewPostgresStore creates a Postgres event store and migrates its schema.
+func NewPostgresStore(dsn string) (*PostgresStore, error) {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.AutoMigrate(&PostgresEventRecord{}); err != nil {
+		return nil, err
+	}
+	return &PostgresStore{DB: db, dsn: dsn}, nil
+}
+
+// Save appends events to the streams of the tenant of ctx in a single
+// transaction. Each event gets the next version of its stream and a
+// notification is sent on NotifyChannel once the transaction commits.
+func (s *PostgresStore) Save(ctx context.Context, events []interface{}) error {
+	return s.write(ctx, events, nil)
+}
+
+// Append is Save for the events of stream id, which must be at version
+// expected. It returns ErrVersionConflict otherwise, including when a
+// concurrent append takes the same versions first.
+func (s *PostgresStore) Append(ctx context.Context, id string, expected int, events []interface{}) error {
+	if err := checkStream(id, events); err != nil {
+		return err
+	}
+	return s.write(ctx, events, func(versions map[string]int) error {
+		if versions[id] != expected {
+			return ErrVersionConflict
+		}
+		return nil
+	})
+}
+
+// write inserts events with the next versions of their streams. check, when
+// set, is called with the current versions before anything is inserted.
+func (s *PostgresStore) write(ctx context.Context, events []interface{}, check func(versions map[string]int) error) error {
+	if len(events) == 0 {
+		return nil
+	}
+	records := make([]PostgresEventRecord, 0, len(events))
+	ids := make([]string, 0, len(events))
+	for _, evt := range events {
+		evt, err := encrypt(ctx, s.Cipher, evt)
+		if err != nil {
+			return err
+		}
+		id, typ, data, err := encodeEvent(evt)
+		if err != nil {
+			return err
+		}
+		records = append(records, PostgresEventRecord{Tenant: tenant.FromContext(ctx), CardID: id, Type: typ, Payload: data})
+		ids = append(ids, id)
+	}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		versions, err := streamVersions(tx.Model(&PostgresEventRecord{}), tenant.FromContext(ctx), ids)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(versions); err != nil {
+				return err
+			}
+		}
+		for i := range records {
+			versions[records[i].CardID]++
+			records[i].Version = versions[records[i].CardID]
+		}
+		if err := tx.Create(&records).Error; err != nil {
+			return err
+		}
+		for _, r := range records {
+			msg, err := json.Marshal(Notification{Tenant: r.Tenant, CardID: r.CardID, Version: r.Version, Type: r.Type})
+			if err != nil {
+				return err
+			}
+			if err := tx.Exec("SELECT pg_notify(?, ?)", NotifyChannel, string(msg)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrVersionConflict
+	}
+	return err
+}
+
+// Events returns the decoded events of a stream in version order.
+func (s *PostgresStore) Events(ctx context.Context, id string) ([]interface{}, error) {
+	var records []PostgresEventRecord
+	if err := s.DB.WithContext(ctx).Where("tenant = ? AND card_id = ?", tenant.FromContext(ctx), id).Order("version").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	events := make([]interface{}, 0, len(records))
+	for _, r := range records {
+		evt, err := decodeEvent(r.Type, r.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if evt, err = decrypt(ctx, s.Cipher, evt); err != nil {
+			return nil, err
+		}
+		events = append(events, evt)
+	}
+	return events, nil
+}
+
+// Load rebuilds the card state from its stream.
+func (s *PostgresStore) Load(ctx context.Context, id string) (*card.Card, error) {
+	events, err := s.Events(ctx, id)
+	if err != nil || len(events) == 0 || !isCardEvent(fmt.Sprintf("%T", events[0])) {
+		return nil, err
+	}
+	return replayCard(events), nil
+}
+
+// Search loads all cards and filters them.
+func (s *PostgresStore) Search(ctx context.Context, name string, cost int, faction, category, sub string) ([]*card.Card, error) {
+	var ids []string
+	if err := s.DB.WithContext(ctx).Model(&PostgresEventRecord{}).
+		Where("tenant = ? AND type LIKE ?", tenant.FromContext(ctx), cardEventPrefix+"%").Distinct("card_id").Find(&ids).Error; err != nil {
+		return nil, err
+	}
+	var cards []*card.Card
+	for _, id := range ids {
+		c, err := s.Load(ctx, id)
+		if err != nil || c == nil {
+			continue
+		}
+		if matches(c, name, cost, faction, category, sub) {
+			cards = append(cards, c)
+		}
+	}
+	return cards, nil
+}
+
+// Subscribe listens on NotifyChannel using a dedicated connection and
+// delivers notifications until ctx is cancelled or the connection fails, at
+// which point the channel is closed.
+func (s *PostgresStore) Subscribe(ctx context.Context) (<-chan Notification, error) {
+	conn, err := pgx.Connect(ctx, s.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{NotifyChannel}.Sanitize()); err != nil {
+		_ = conn.Close(context.Background())
+		return nil, err
+	}
+	ch := make(chan Notification)
+	go func() {
+		defer close(ch)
+		defer func() { _ = conn.Close(context.Background()) }()
+		for {
+			n, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				return
+			}
+			msg, err := parseNotification(n.Payload)
+			if err != nil {
+				continue
+			}
+			select {
+			case ch <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func parseNotification(payload string) (Notification, error) {
+	var n Notification
+	err := json.Unmarshal([]byte(payload), &n)
+	return n, err
+}
+
+var _ card.Repository = (*PostgresStore)(nil)
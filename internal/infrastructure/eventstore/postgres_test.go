@@ -0,0 +1,13 @@
+package eventstore
+
+import "testing"
+
+func TestParseNotification(t *testing.T) {
+	n, err := parseNotification(`{"tenant":"acme","card_id":"c1","version":3,"type":"card.CardUpdated"}`)
+	if err != nil || n.Tenant != "acme" || n.CardID != "c1" || n.Version != 3 || n.Type != "card.CardUpdated" {
+		t.Fatalf("unexpected notification %+v %v", n, err)
+	}
+	if _, err := parseNotification("bad"); err == nil {
+		t.Fatal("expected error for invalid payload")
+	}
+}
@@ -4,14 +4,25 @@ import (
 	"context"
 	"encoding/json"
 
+	"demo/internal/domain/tenant"
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill-kafka/v2/pkg/kafka"
 	"github.com/ThreeDotsLabs/watermill/message"
 )
 
+// Encrypter encrypts the personal data of events, e.g. a
+// shredding.Protector.
+type Encrypter interface {
+	Encrypt(ctx context.Context, evt interface{}) (interface{}, error)
+}
+
 // Publisher wraps a Watermill Kafka publisher.
 type Publisher struct {
-	pub *kafka.Publisher
+	pub message.Publisher
+	// Cipher, when set, encrypts events before they are published, so that
+	// their personal data is shredded along with the copies in the event
+	// store.
+	Cipher Encrypter
 }
 
 // NewPublisher creates a new Kafka publisher.
@@ -23,12 +34,28 @@ func NewPublisher(brokers []string) (*Publisher, error) {
 	return &Publisher{pub: pub}, nil
 }
 
-// Publish encodes the event and sends it to Kafka.
+// Topic returns the Kafka topic the events of a topic are sent to for the
+// tenant of ctx: the topic prefixed with the tenant id and a dot, or the
+// topic itself for the default tenant.
+func Topic(ctx context.Context, topic string) string {
+	return tenant.Prefix(tenant.FromContext(ctx), ".") + topic
+}
+
+// Publish encodes the event and sends it to the topic of the tenant of ctx.
+// The message names the tenant in its tenant metadata. Events that cannot be
+// encrypted are not sent.
 func (p *Publisher) Publish(ctx context.Context, topic string, event interface{}) error {
+	if p.Cipher != nil {
+		var err error
+		if event, err = p.Cipher.Encrypt(ctx, event); err != nil {
+			return err
+		}
+	}
 	payload, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
 	msg := message.NewMessage(watermill.NewUUID(), payload)
-	return p.pub.Publish(topic, msg)
+	msg.Metadata.Set("tenant", tenant.FromContext(ctx))
+	return p.pub.Publish(Topic(ctx, topic), msg)
 }
@@ -2,13 +2,64 @@ package messaging
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"demo/internal/domain/deck"
+	"demo/internal/domain/tenant"
+	"demo/internal/infrastructure/shredding"
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+	"github.com/google/uuid"
 )
 
 func TestPublishMarshalError(t *testing.T) {
 	p := &Publisher{}
-	err := p.Publish(context.Background(), "t", map[interface{}]string{1: "x"})
+	err := p.Publish(context.Background(), "t", make(chan int))
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
+
+func TestTopic(t *testing.T) {
+	if got := Topic(context.Background(), "card_events"); got != "card_events" {
+		t.Fatalf("expected the default tenant to keep the topic got %q", got)
+	}
+	if got := Topic(tenant.NewContext(context.Background(), "acme"), "card_events"); got != "acme.card_events" {
+		t.Fatalf("expected acme.card_events got %q", got)
+	}
+}
+
+func TestPublishEncrypts(t *testing.T) {
+	ctx := context.Background()
+	pubSub := gochannel.NewGoChannel(gochannel.Config{OutputChannelBuffer: 1}, watermill.NopLogger{})
+	defer pubSub.Close()
+	messages, err := pubSub.Subscribe(ctx, "deck_events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := shredding.NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Publisher{pub: pubSub, Cipher: shredding.NewProtector(keys)}
+	userID := uuid.New()
+	if err := p.Publish(ctx, "deck_events", deck.DeckRenamed{ID: uuid.New(), UserID: userID, Name: "Secret deck"}); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	msg.Ack()
+	if strings.Contains(string(msg.Payload), "Secret deck") || !strings.Contains(string(msg.Payload), userID.String()) {
+		t.Fatalf("expected the name encrypted got %s", msg.Payload)
+	}
+
+	// the events of forgotten users are not sent
+	if err := keys.Delete(ctx, userID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Publish(ctx, "deck_events", deck.DeckRenamed{ID: uuid.New(), UserID: userID, Name: "Secret deck"}); !errors.Is(err, shredding.ErrKeyDestroyed) {
+		t.Fatalf("expected shredding.ErrKeyDestroyed got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// InMemoryLimiter is a process-local limiter: every replica of the service
+// limits on its own.
+type InMemoryLimiter struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+	// pruneAt is when the full buckets are next dropped.
+	pruneAt time.Time
+}
+
+// NewInMemoryLimiter creates the limiter.
+func NewInMemoryLimiter() *InMemoryLimiter {
+	return &InMemoryLimiter{tats: make(map[string]time.Time)}
+}
+
+// pruneInterval is how often the buckets that are full again are dropped.
+const pruneInterval = time.Minute
+
+// prune drops the keys whose buckets are full again. The caller holds the
+// lock.
+func (l *InMemoryLimiter) prune(now time.Time) {
+	if now.Before(l.pruneAt) {
+		return
+	}
+	for key, tat := range l.tats {
+		if !now.Before(tat) {
+			delete(l.tats, key)
+		}
+	}
+	l.pruneAt = now.Add(pruneInterval)
+}
+
+// Allow implements Limiter.
+func (l *InMemoryLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	if p.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.prune(now)
+	tat, r := gcra(now, l.tats[key], p)
+	if r.Allowed {
+		l.tats[key] = tat
+	}
+	return r, nil
+}
+
+var _ Limiter = (*InMemoryLimiter)(nil)
@@ -0,0 +1,78 @@
+// Package ratelimit limits the rate of requests per key with the generic
+// cell rate algorithm (GCRA), a token bucket that keeps a single timestamp
+// per key: the theoretical arrival time (TAT) of the next request at the
+// sustained rate.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy allows Limit requests per Period, Burst of them at once. A zero
+// Limit allows every request and a zero Burst is taken as Limit.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+	Burst  int
+}
+
+// Unlimited reports whether the policy allows every request.
+func (p Policy) Unlimited() bool { return p.Limit <= 0 || p.Period <= 0 }
+
+// burst returns the number of requests allowed at once.
+func (p Policy) burst() int {
+	if p.Burst <= 0 {
+		return p.Limit
+	}
+	return p.Burst
+}
+
+// interval returns the time the bucket takes to regain one request.
+func (p Policy) interval() time.Duration { return p.Period / time.Duration(p.Limit) }
+
+// Result is the decision on a request.
+type Result struct {
+	Allowed bool
+	// Limit is the number of requests allowed at once, the burst of the
+	// policy.
+	Limit int
+	// Remaining is the number of requests allowed right after this one.
+	Remaining int
+	// RetryAfter is how long a denied request has to wait, zero when it is
+	// allowed.
+	RetryAfter time.Duration
+	// ResetAfter is how long the bucket takes to be full again.
+	ResetAfter time.Duration
+}
+
+// Limiter decides on requests, by key. Keys name a client and the bucket it
+// draws from, such as a route.
+type Limiter interface {
+	// Allow takes a request of key from its bucket under policy p. Denied
+	// requests are not counted. Unlimited policies allow every request.
+	Allow(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// gcra decides on a request at now for a key whose TAT is tat, zero for
+// keys without requests. It returns the TAT to keep, which is tat itself for
+// denied requests.
+func gcra(now, tat time.Time, p Policy) (time.Time, Result) {
+	interval := p.interval()
+	// the bucket is full once the TAT is burst intervals ahead of now
+	tolerance := interval * time.Duration(p.burst())
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	allowAt := next.Add(-tolerance)
+	if now.Before(allowAt) {
+		return tat, Result{Limit: p.burst(), RetryAfter: allowAt.Sub(now), ResetAfter: tat.Sub(now)}
+	}
+	return next, Result{
+		Allowed:    true,
+		Limit:      p.burst(),
+		Remaining:  int(now.Sub(allowAt) / interval),
+		ResetAfter: next.Sub(now),
+	}
+}
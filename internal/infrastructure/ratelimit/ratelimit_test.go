@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestGCRA(t *testing.T) {
+	p := Policy{Limit: 10, Period: time.Second, Burst: 3}
+	now := time.Now()
+	var tat time.Time
+	for i := 2; i >= 0; i-- {
+		var r Result
+		tat, r = gcra(now, tat, p)
+		if !r.Allowed || r.Remaining != i || r.Limit != 3 {
+			t.Fatalf("expected %d remaining got %+v", i, r)
+		}
+	}
+	if _, r := gcra(now, tat, p); r.Allowed || r.RetryAfter != 100*time.Millisecond || r.ResetAfter != 300*time.Millisecond {
+		t.Fatalf("expected a retry after one interval got %+v", r)
+	}
+	if _, r := gcra(now.Add(100*time.Millisecond), tat, p); !r.Allowed || r.Remaining != 0 {
+		t.Fatalf("expected one request to be regained got %+v", r)
+	}
+	if _, r := gcra(now.Add(time.Hour), tat, p); !r.Allowed || r.Remaining != 2 {
+		t.Fatalf("expected the bucket to be full again got %+v", r)
+	}
+	// without a burst the whole limit is allowed at once
+	if _, r := gcra(now, time.Time{}, Policy{Limit: 5, Period: time.Second}); r.Limit != 5 || r.Remaining != 4 {
+		t.Fatalf("expected the limit as burst got %+v", r)
+	}
+}
+
+func TestLimiters(t *testing.T) {
+	for name, newLimiter := range map[string]func(t *testing.T) Limiter{
+		"memory": func(t *testing.T) Limiter { return NewInMemoryLimiter() },
+		"redis": func(t *testing.T) Limiter {
+			s := miniredis.RunT(t)
+			return &RedisLimiter{Redis: redis.NewClient(&redis.Options{Addr: s.Addr()})}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			testLimiter(t, newLimiter(t))
+		})
+	}
+}
+
+func testLimiter(t *testing.T, l Limiter) {
+	ctx := context.Background()
+	p := Policy{Limit: 2, Period: 200 * time.Millisecond}
+	for i := 1; i >= 0; i-- {
+		if r, err := l.Allow(ctx, "ip:a", p); err != nil || !r.Allowed || r.Remaining != i || r.Limit != 2 {
+			t.Fatalf("expected %d remaining got %+v %v", i, r, err)
+		}
+	}
+	r, err := l.Allow(ctx, "ip:a", p)
+	if err != nil || r.Allowed || r.RetryAfter <= 0 || r.RetryAfter > 100*time.Millisecond {
+		t.Fatalf("expected a denial for up to 100ms got %+v %v", r, err)
+	}
+	if r, _ := l.Allow(ctx, "ip:b", p); !r.Allowed {
+		t.Fatal("expected keys to be limited apart")
+	}
+	if r, _ := l.Allow(ctx, "ip:a", Policy{}); !r.Allowed {
+		t.Fatal("expected unlimited policies to allow every request")
+	}
+	time.Sleep(r.RetryAfter + 10*time.Millisecond)
+	if r, err := l.Allow(ctx, "ip:a", p); err != nil || !r.Allowed {
+		t.Fatalf("expected a request after the retry got %+v %v", r, err)
+	}
+}
@@ -0,0 +1,63 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLimiter keeps the buckets in Redis, so that every replica of the
+// service draws from the same ones. The decision is taken by a script on the
+// clock of the Redis server.
+type RedisLimiter struct {
+	Redis *redis.Client
+}
+
+// NewRedisLimiter creates a Redis-backed limiter.
+func NewRedisLimiter(addr string) *RedisLimiter {
+	return &RedisLimiter{Redis: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func rateLimitKey(key string) string { return "rate_limit:" + key }
+
+// gcraScript is gcra in Lua. The TAT is kept in microseconds until the bucket
+// is full again. It returns whether the request is allowed, the remaining
+// requests, the retry after and the reset after in microseconds.
+var gcraScript = redis.NewScript(`
+local interval = tonumber(ARGV[1])
+local tolerance = interval * tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local tat = tonumber(redis.call("GET", KEYS[1]) or 0)
+if tat < now then
+	tat = now
+end
+local new_tat = tat + interval
+local allow_at = new_tat - tolerance
+if now < allow_at then
+	return {0, 0, allow_at - now, tat - now}
+end
+redis.call("SET", KEYS[1], string.format("%d", new_tat), "PX", math.ceil((new_tat - now) / 1000))
+return {1, math.floor((now - allow_at) / interval), 0, new_tat - now}
+`)
+
+// Allow implements Limiter.
+func (l *RedisLimiter) Allow(ctx context.Context, key string, p Policy) (Result, error) {
+	if p.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	res, err := gcraScript.Run(ctx, l.Redis, []string{rateLimitKey(key)}, p.interval().Microseconds(), p.burst()).Int64Slice()
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{
+		Allowed:    res[0] == 1,
+		Limit:      p.burst(),
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Microsecond,
+		ResetAfter: time.Duration(res[3]) * time.Microsecond,
+	}, nil
+}
+
+var _ Limiter = (*RedisLimiter)(nil)
@@ -0,0 +1,205 @@
+package shredding
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// KeySize is the length of subject keys in bytes (AES-256).
+const KeySize = 32
+
+var (
+	// ErrKeyNotFound is returned when a subject has no key yet.
+	ErrKeyNotFound = errors.New("shredding: key not found")
+	// ErrKeyDestroyed is returned when the key of a subject has been deleted.
+	ErrKeyDestroyed = errors.New("shredding: key destroyed")
+)
+
+// KeyStore manages per-subject encryption keys.
+type KeyStore interface {
+	// Key returns the key of subject, generating one on first use.
+	Key(ctx context.Context, subject string) ([]byte, error)
+	// Lookup returns the key of subject without generating one.
+	Lookup(ctx context.Context, subject string) ([]byte, error)
+	// Delete destroys the key of subject. A tombstone is kept so that no new
+	// key is issued for a forgotten subject.
+	Delete(ctx context.Context, subject string) error
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// FileKeyStore keeps keys in a JSON file on local disk.
+type FileKeyStore struct {
+	mu   sync.Mutex
+	path string
+	// keys maps subjects to keys; a nil key is a tombstone.
+	keys map[string][]byte
+}
+
+// NewFileKeyStore opens the key file at path, creating it when missing.
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	s := &FileKeyStore{path: path, keys: make(map[string][]byte)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.keys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Key returns the key of subject, generating and persisting one if needed.
+func (s *FileKeyStore) Key(ctx context.Context, subject string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[subject]
+	if ok && key == nil {
+		return nil, ErrKeyDestroyed
+	}
+	if ok {
+		return key, nil
+	}
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	s.keys[subject] = key
+	if err := s.flush(); err != nil {
+		delete(s.keys, subject)
+		return nil, err
+	}
+	return key, nil
+}
+
+// Lookup returns the key of subject.
+func (s *FileKeyStore) Lookup(ctx context.Context, subject string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[subject]
+	switch {
+	case !ok:
+		return nil, ErrKeyNotFound
+	case key == nil:
+		return nil, ErrKeyDestroyed
+	}
+	return key, nil
+}
+
+// Delete destroys the key of subject.
+func (s *FileKeyStore) Delete(ctx context.Context, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[subject] = nil
+	return s.flush()
+}
+
+// flush atomically replaces the key file with the current keys.
+func (s *FileKeyStore) flush() error {
+	data, err := json.Marshal(s.keys)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// SubjectKey is a stored subject key. Key is nil once destroyed.
+type SubjectKey struct {
+	Subject string `gorm:"primaryKey;size:191"`
+	Key     []byte
+}
+
+// GormKeyStore keeps keys in a database table.
+type GormKeyStore struct {
+	DB *gorm.DB
+}
+
+// NewGormKeyStore creates the key table if needed.
+func NewGormKeyStore(db *gorm.DB) (*GormKeyStore, error) {
+	if err := db.AutoMigrate(&SubjectKey{}); err != nil {
+		return nil, err
+	}
+	return &GormKeyStore{DB: db}, nil
+}
+
+// Key returns the key of subject, generating one if needed. Concurrent
+// callers agree on a single key because inserts never overwrite.
+func (s *GormKeyStore) Key(ctx context.Context, subject string) ([]byte, error) {
+	key, err := s.Lookup(ctx, subject)
+	if !errors.Is(err, ErrKeyNotFound) {
+		return key, err
+	}
+	if key, err = newKey(); err != nil {
+		return nil, err
+	}
+	rec := SubjectKey{Subject: subject, Key: key}
+	if err := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rec).Error; err != nil {
+		return nil, err
+	}
+	return s.Lookup(ctx, subject)
+}
+
+// Lookup returns the key of subject.
+func (s *GormKeyStore) Lookup(ctx context.Context, subject string) ([]byte, error) {
+	var rec SubjectKey
+	err := s.DB.WithContext(ctx).Where("subject = ?", subject).Take(&rec).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrKeyNotFound
+	case err != nil:
+		return nil, err
+	case rec.Key == nil:
+		return nil, ErrKeyDestroyed
+	}
+	return rec.Key, nil
+}
+
+// Delete destroys the key of subject.
+func (s *GormKeyStore) Delete(ctx context.Context, subject string) error {
+	rec := SubjectKey{Subject: subject}
+	return s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subject"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"key": nil}),
+	}).Create(&rec).Error
+}
+
+var (
+	_ KeyStore = (*FileKeyStore)(nil)
+	_ KeyStore = (*GormKeyStore)(nil)
+)
@@ -0,0 +1,47 @@
+package shredding
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileKeyStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	s, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Lookup(ctx, "u1"); !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("expected ErrKeyNotFound got %v", err)
+	}
+	key, err := s.Key(ctx, "u1")
+	if err != nil || len(key) != KeySize {
+		t.Fatalf("unexpected key %v %v", key, err)
+	}
+	again, _ := s.Key(ctx, "u1")
+	if !bytes.Equal(key, again) {
+		t.Fatal("key changed between calls")
+	}
+
+	reopened, err := NewFileKeyStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Lookup(ctx, "u1"); err != nil || !bytes.Equal(got, key) {
+		t.Fatalf("key not persisted %v %v", got, err)
+	}
+	if err := reopened.Delete(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	reopened, _ = NewFileKeyStore(path)
+	if _, err := reopened.Lookup(ctx, "u1"); !errors.Is(err, ErrKeyDestroyed) {
+		t.Fatalf("expected ErrKeyDestroyed got %v", err)
+	}
+	if _, err := reopened.Key(ctx, "u1"); !errors.Is(err, ErrKeyDestroyed) {
+		t.Fatalf("forgotten subject must not get a new key, got %v", err)
+	}
+}
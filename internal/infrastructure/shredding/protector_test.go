@@ -0,0 +1,99 @@
+package shredding
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type profileChanged struct {
+	UserID uuid.UUID `pii:"subject"`
+	Email  string    `pii:"data"`
+	Name   string    `pii:"data"`
+	Public string
+}
+
+type noSubject struct {
+	Email string `pii:"data"`
+}
+
+func newProtector(t *testing.T) *Protector {
+	t.Helper()
+	keys, err := NewFileKeyStore(filepath.Join(t.TempDir(), "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewProtector(keys)
+}
+
+func TestProtectorRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	p := newProtector(t)
+	evt := profileChanged{UserID: uuid.New(), Email: "a@example.com", Name: "Alice", Public: "x"}
+	enc, err := p.Encrypt(ctx, evt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := enc.(profileChanged)
+	if !strings.HasPrefix(e.Email, prefix) || !strings.HasPrefix(e.Name, prefix) || e.Public != "x" || e.UserID != evt.UserID {
+		t.Fatalf("unexpected encrypted event %+v", e)
+	}
+	dec, err := p.Decrypt(ctx, enc)
+	if err != nil || dec != evt {
+		t.Fatalf("unexpected decrypted event %+v %v", dec, err)
+	}
+}
+
+func TestProtectorForget(t *testing.T) {
+	ctx := context.Background()
+	p := newProtector(t)
+	evt := profileChanged{UserID: uuid.New(), Email: "a@example.com"}
+	enc, _ := p.Encrypt(ctx, evt)
+	if err := p.Keys.Delete(ctx, evt.UserID.String()); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := p.Decrypt(ctx, enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := dec.(profileChanged); e.Email != Redacted || e.Name != Redacted {
+		t.Fatalf("expected redacted event got %+v", e)
+	}
+	if _, err := p.Encrypt(ctx, evt); err == nil {
+		t.Fatal("expected error encrypting for forgotten subject")
+	}
+}
+
+func TestProtectorPassThrough(t *testing.T) {
+	ctx := context.Background()
+	p := newProtector(t)
+	type plain struct{ Name string }
+	if out, err := p.Encrypt(ctx, plain{Name: "n"}); err != nil || out != (plain{Name: "n"}) {
+		t.Fatalf("unexpected %v %v", out, err)
+	}
+	// plaintext written before encryption was enabled still loads
+	legacy := profileChanged{UserID: uuid.New(), Email: "old@example.com"}
+	if out, err := p.Decrypt(ctx, legacy); err != nil || out != legacy {
+		t.Fatalf("unexpected %v %v", out, err)
+	}
+	if _, err := p.Encrypt(ctx, noSubject{Email: "e"}); err == nil {
+		t.Fatal("expected error for missing subject field")
+	}
+}
+
+func TestProtectorWrongSubject(t *testing.T) {
+	ctx := context.Background()
+	p := newProtector(t)
+	enc, _ := p.Encrypt(ctx, profileChanged{UserID: uuid.New(), Email: "a@example.com"})
+	e := enc.(profileChanged)
+	// ciphertext moved to another subject must not decrypt
+	other := uuid.New()
+	_, _ = p.Keys.Key(ctx, other.String())
+	e.UserID = other
+	if _, err := p.Decrypt(ctx, e); err == nil {
+		t.Fatal("expected error for ciphertext of another subject")
+	}
+}
@@ -0,0 +1,107 @@
+package userstore
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"demo/internal/domain/user"
+	"github.com/google/uuid"
+)
+
+// InMemoryStore is a process-local user repository.
+type InMemoryStore struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]*user.User
+}
+
+// NewInMemoryStore creates the store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{users: make(map[uuid.UUID]*user.User)}
+}
+
+// Create stores a new user.
+func (s *InMemoryStore) Create(ctx context.Context, u *user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkUnique(u); err != nil {
+		return err
+	}
+	s.users[u.ID] = u.Clone()
+	return nil
+}
+
+// Update stores the changes to an existing user.
+func (s *InMemoryStore) Update(ctx context.Context, u *user.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[u.ID]; !ok {
+		return user.ErrNotFound
+	}
+	if err := s.checkUnique(u); err != nil {
+		return err
+	}
+	s.users[u.ID] = u.Clone()
+	return nil
+}
+
+// checkUnique returns an error when another user has the username or email
+// of u.
+func (s *InMemoryStore) checkUnique(u *user.User) error {
+	for _, other := range s.users {
+		if other.ID == u.ID {
+			continue
+		}
+		if strings.EqualFold(other.Username, u.Username) {
+			return user.ErrUsernameTaken
+		}
+		if strings.EqualFold(other.Email, u.Email) {
+			return user.ErrEmailTaken
+		}
+	}
+	return nil
+}
+
+// Delete removes a user.
+func (s *InMemoryStore) Delete(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; !ok {
+		return user.ErrNotFound
+	}
+	delete(s.users, id)
+	return nil
+}
+
+// ByID returns the user with the given id.
+func (s *InMemoryStore) ByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if u, ok := s.users[id]; ok {
+		return u.Clone(), nil
+	}
+	return nil, nil
+}
+
+// ByUsername returns the user with the given username.
+func (s *InMemoryStore) ByUsername(ctx context.Context, username string) (*user.User, error) {
+	return s.find(func(u *user.User) bool { return strings.EqualFold(u.Username, username) }), nil
+}
+
+// ByEmail returns the user with the given email address.
+func (s *InMemoryStore) ByEmail(ctx context.Context, email string) (*user.User, error) {
+	return s.find(func(u *user.User) bool { return strings.EqualFold(u.Email, email) }), nil
+}
+
+func (s *InMemoryStore) find(match func(u *user.User) bool) *user.User {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, u := range s.users {
+		if match(u) {
+			return u.Clone()
+		}
+	}
+	return nil
+}
+
+var _ user.Repository = (*InMemoryStore)(nil)
@@ -0,0 +1,14 @@
+package userstore
+
+import (
+	"testing"
+
+	"demo/internal/domain/user"
+	"demo/internal/domain/user/usertest"
+)
+
+func TestInMemoryConformance(t *testing.T) {
+	usertest.RepositorySuite.Run(t, func(t *testing.T) user.Repository {
+		return NewInMemoryStore()
+	})
+}
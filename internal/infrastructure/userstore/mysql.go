@@ -0,0 +1,148 @@
+package userstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"demo/internal/domain/user"
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// mysqlDuplicateEntry is the MySQL error number of unique index violations.
+const mysqlDuplicateEntry = 1062
+
+// UserRecord is a stored user. UsernameKey holds the lower case username so
+// that uniqueness does not depend on the collation of the table.
+type UserRecord struct {
+	ID           string    `gorm:"primaryKey;size:36"`
+	Username     string    `gorm:"size:32;not null"`
+	UsernameKey  string    `gorm:"size:32;not null;uniqueIndex:idx_users_username"`
+	Email        string    `gorm:"size:254;not null;uniqueIndex:idx_users_email"`
+	PasswordHash string    `gorm:"not null"`
+	Role         string    `gorm:"size:16;not null;default:player"`
+	Disabled     bool      `gorm:"not null;default:false"`
+	CreatedAt    time.Time `gorm:"not null"`
+}
+
+// TableName implements gorm's tabler interface.
+func (UserRecord) TableName() string { return "users" }
+
+// MySQLStore is a GORM-based user repository.
+type MySQLStore struct {
+	DB *gorm.DB
+}
+
+// NewMySQLStore creates the users table if needed.
+func NewMySQLStore(db *gorm.DB) (*MySQLStore, error) {
+	if err := db.AutoMigrate(&UserRecord{}); err != nil {
+		return nil, err
+	}
+	return &MySQLStore{DB: db}, nil
+}
+
+func record(u *user.User) UserRecord {
+	return UserRecord{
+		ID:           u.ID.String(),
+		Username:     u.Username,
+		UsernameKey:  strings.ToLower(u.Username),
+		Email:        strings.ToLower(u.Email),
+		PasswordHash: u.PasswordHash,
+		Role:         string(u.Role),
+		Disabled:     u.Disabled,
+		CreatedAt:    u.CreatedAt,
+	}
+}
+
+// Create stores a new user, relying on the unique indexes to reject taken
+// usernames and emails.
+func (s *MySQLStore) Create(ctx context.Context, u *user.User) error {
+	rec := record(u)
+	return uniqueError(s.DB.WithContext(ctx).Create(&rec).Error)
+}
+
+// Update stores the changes to an existing user.
+func (s *MySQLStore) Update(ctx context.Context, u *user.User) error {
+	rec := record(u)
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current UserRecord
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", rec.ID).Take(&current).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return uniqueError(tx.Save(&rec).Error)
+	})
+}
+
+// uniqueError turns unique index violations into the errors of the user
+// package.
+func uniqueError(err error) error {
+	var merr *mysql.MySQLError
+	if !errors.As(err, &merr) || merr.Number != mysqlDuplicateEntry {
+		return err
+	}
+	if strings.Contains(merr.Message, "idx_users_email") {
+		return user.ErrEmailTaken
+	}
+	return user.ErrUsernameTaken
+}
+
+// Delete removes a user.
+func (s *MySQLStore) Delete(ctx context.Context, id uuid.UUID) error {
+	res := s.DB.WithContext(ctx).Where("id = ?", id.String()).Delete(&UserRecord{})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return user.ErrNotFound
+	}
+	return nil
+}
+
+// ByID returns the user with the given id.
+func (s *MySQLStore) ByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	return s.find(ctx, "id = ?", id.String())
+}
+
+// ByUsername returns the user with the given username.
+func (s *MySQLStore) ByUsername(ctx context.Context, username string) (*user.User, error) {
+	return s.find(ctx, "username_key = ?", strings.ToLower(username))
+}
+
+// ByEmail returns the user with the given email address.
+func (s *MySQLStore) ByEmail(ctx context.Context, email string) (*user.User, error) {
+	return s.find(ctx, "email = ?", strings.ToLower(email))
+}
+
+func (s *MySQLStore) find(ctx context.Context, query string, arg interface{}) (*user.User, error) {
+	var rec UserRecord
+	err := s.DB.WithContext(ctx).Where(query, arg).Take(&rec).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := uuid.Parse(rec.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &user.User{
+		ID:           id,
+		Username:     rec.Username,
+		Email:        rec.Email,
+		PasswordHash: rec.PasswordHash,
+		Role:         user.Role(rec.Role),
+		Disabled:     rec.Disabled,
+		CreatedAt:    rec.CreatedAt.UTC(),
+	}, nil
+}
+
+var _ user.Repository = (*MySQLStore)(nil)
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"demo/internal/domain/user"
+	"demo/internal/i18n"
+	"demo/internal/infrastructure/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// optionalTimeJSON formats t in RFC 3339, or returns nil when t is zero.
+func optionalTimeJSON(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+	return t.Format(time.RFC3339)
+}
+
+// apiKeyJSON is the response body describing an API key, without its secret.
+func apiKeyJSON(k *auth.APIKey) gin.H {
+	scopes := make([]string, 0, len(k.Scopes))
+	for _, s := range k.Scopes {
+		scopes = append(scopes, string(s))
+	}
+	return gin.H{
+		"id":         k.ID.String(),
+		"name":       k.Name,
+		"prefix":     k.Prefix,
+		"userID":     k.UserID.String(),
+		"tenant":     k.Tenant,
+		"scopes":     scopes,
+		"createdAt":  k.CreatedAt.Format(time.RFC3339),
+		"expiresAt":  optionalTimeJSON(k.ExpiresAt),
+		"lastUsedAt": optionalTimeJSON(k.LastUsedAt),
+		"revokedAt":  optionalTimeJSON(k.RevokedAt),
+	}
+}
+
+// adminRoutes registers the user and API key administration endpoints on a
+// group requiring the admin permission.
+func adminRoutes(r gin.IRoutes, authSvc *auth.Service) {
+	userAction := func(action func(c *gin.Context, id uuid.UUID) error) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			lang := c.GetHeader("Accept-Language")
+			id, err := uuid.Parse(c.Param("id"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+				return
+			}
+			if err := action(c, id); err != nil {
+				userError(c, lang, err)
+				return
+			}
+			c.Status(http.StatusNoContent)
+		}
+	}
+
+	r.POST("/users/:id/disable", userAction(func(c *gin.Context, id uuid.UUID) error {
+		return authSvc.Disable(c.Request.Context(), id)
+	}))
+
+	r.POST("/users/:id/enable", userAction(func(c *gin.Context, id uuid.UUID) error {
+		return authSvc.Enable(c.Request.Context(), id)
+	}))
+
+	r.DELETE("/users/:id", userAction(func(c *gin.Context, id uuid.UUID) error {
+		return authSvc.Delete(c.Request.Context(), id)
+	}))
+
+	r.PUT("/users/:id/role", userAction(func(c *gin.Context, id uuid.UUID) error {
+		var body struct {
+			Role string `json:"role" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			return user.ErrInvalidRole
+		}
+		return authSvc.SetRole(c.Request.Context(), id, user.Role(body.Role))
+	}))
+
+	r.POST("/api-keys", func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		var body struct {
+			Name      string     `json:"name" binding:"required"`
+			UserID    string     `json:"userID"`
+			Scopes    []string   `json:"scopes"`
+			ExpiresAt *time.Time `json:"expiresAt"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+			return
+		}
+		userID := currentUser(c)
+		if body.UserID != "" {
+			id, err := uuid.Parse(body.UserID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+				return
+			}
+			userID = id
+		}
+		scopes := make([]user.Permission, 0, len(body.Scopes))
+		for _, s := range body.Scopes {
+			scopes = append(scopes, user.Permission(s))
+		}
+		var expiresAt time.Time
+		if body.ExpiresAt != nil {
+			expiresAt = *body.ExpiresAt
+		}
+		k, key, err := authSvc.CreateAPIKey(c.Request.Context(), userID, body.Name, scopes, expiresAt)
+		if err != nil {
+			userError(c, lang, err)
+			return
+		}
+		resp := apiKeyJSON(k)
+		resp["key"] = key
+		c.JSON(http.StatusCreated, resp)
+	})
+
+	r.GET("/api-keys", func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		keys, err := authSvc.ListAPIKeys(c.Request.Context())
+		if err != nil {
+			userError(c, lang, err)
+			return
+		}
+		list := make([]gin.H, 0, len(keys))
+		for _, k := range keys {
+			list = append(list, apiKeyJSON(k))
+		}
+		c.JSON(http.StatusOK, list)
+	})
+
+	r.DELETE("/api-keys/:id", userAction(func(c *gin.Context, id uuid.UUID) error {
+		return authSvc.RevokeAPIKey(c.Request.Context(), id)
+	}))
+}
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestAdminRoutes(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	ctx := context.Background()
+	player, _ := authSvc.Users.ByUsername(ctx, "user")
+	admin := loginAs(t, authSvc, "admin")
+	userPath := "/admin/users/" + player.ID.String()
+
+	for _, token := range []string{login(t, authSvc), loginAs(t, authSvc, "designer")} {
+		if got := do("POST", userPath+"/disable", "", token); got != http.StatusForbidden {
+			t.Fatalf("expected 403 got %d", got)
+		}
+	}
+
+	token := login(t, authSvc)
+	if got := do("POST", userPath+"/disable", "", admin); got != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", got)
+	}
+	if got := do("GET", "/decks", "", token); got != http.StatusUnauthorized {
+		t.Fatalf("expected disabling to end the sessions of the user got %d", got)
+	}
+	if _, err := authSvc.Login(ctx, "user", "password"); err == nil {
+		t.Fatal("expected a disabled user to be unable to log in")
+	}
+	if got := do("POST", userPath+"/enable", "", admin); got != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", got)
+	}
+
+	if got := do("PUT", userPath+"/role", `{"role":"designer"}`, admin); got != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", got)
+	}
+	if u, _ := authSvc.Users.ByID(ctx, player.ID); u.Role != user.RoleDesigner {
+		t.Fatalf("expected a designer got %q", u.Role)
+	}
+	if got := do("POST", "/cards", `{"name":"n"}`, login(t, authSvc)); got != http.StatusOK {
+		t.Fatalf("expected the new role to apply got %d", got)
+	}
+
+	for path, code := range map[string]int{
+		"/admin/users/x/disable":                        http.StatusBadRequest,
+		"/admin/users/" + uuid.NewString() + "/disable": http.StatusNotFound,
+	} {
+		if got := do("POST", path, "", admin); got != code {
+			t.Fatalf("POST %s: expected %d got %d", path, code, got)
+		}
+	}
+	for _, body := range []string{`{"role":"root"}`, `{}`} {
+		if got := do("PUT", userPath+"/role", body, admin); got != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d", body, got)
+		}
+	}
+
+	if got := do("DELETE", userPath, "", login(t, authSvc)); got != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", got)
+	}
+	if got := do("DELETE", userPath, "", admin); got != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", got)
+	}
+	if u, _ := authSvc.Users.ByID(ctx, player.ID); u != nil {
+		t.Fatalf("expected the user deleted got %+v", u)
+	}
+	if got := do("DELETE", userPath, "", admin); got != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", got)
+	}
+}
+
+func TestAdminAPIKeys(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	ctx := context.Background()
+	player, _ := authSvc.Users.ByUsername(ctx, "user")
+	admin := loginAs(t, authSvc, "admin")
+
+	if w := do("POST", "/admin/api-keys", `{"name":"ci","scopes":["cards:read"]}`, login(t, authSvc)); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", w.Code)
+	}
+	w := do("POST", "/admin/api-keys", `{"name":"ci","userID":"`+player.ID.String()+`","scopes":["decks:read"],"expiresAt":"2099-01-01T00:00:00Z"}`, admin)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 got %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID, Key, Prefix, UserID, ExpiresAt string
+		Scopes                             []string
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Key, created.Prefix) || created.UserID != player.ID.String() || created.ExpiresAt != "2099-01-01T00:00:00Z" || len(created.Scopes) != 1 {
+		t.Fatalf("unexpected key %+v", created)
+	}
+	if w := do("GET", "/decks", "", created.Key); w.Code != http.StatusOK {
+		t.Fatalf("expected the key to read decks got %d", w.Code)
+	}
+
+	w = do("GET", "/admin/api-keys", "", admin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), created.Key) {
+		t.Fatal("expected listed keys to leave out the secret")
+	}
+	var listed []map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &listed); err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 1 || listed[0]["id"] != created.ID || listed[0]["lastUsedAt"] == nil {
+		t.Fatalf("unexpected keys %v", listed)
+	}
+
+	if w := do("DELETE", "/admin/api-keys/"+created.ID, "", admin); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", w.Code)
+	}
+	if w := do("GET", "/decks", "", created.Key); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a revoked key to be rejected got %d", w.Code)
+	}
+
+	for body, code := range map[string]int{
+		`{"scopes":["cards:read"]}`:       http.StatusBadRequest,
+		`{"name":"k","scopes":[]}`:        http.StatusBadRequest,
+		`{"name":"k","scopes":["admin"]}`: http.StatusBadRequest,
+		`{"name":"k","scopes":["cards:read"],"expiresAt":"2000-01-01T00:00:00Z"}`:  http.StatusBadRequest,
+		`{"name":"k","scopes":["cards:read"],"userID":"x"}`:                        http.StatusBadRequest,
+		`{"name":"k","scopes":["cards:read"],"userID":"` + uuid.NewString() + `"}`: http.StatusNotFound,
+	} {
+		if w := do("POST", "/admin/api-keys", body, admin); w.Code != code {
+			t.Fatalf("%s: expected %d got %d", body, code, w.Code)
+		}
+	}
+	if w := do("DELETE", "/admin/api-keys/"+uuid.NewString(), "", admin); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", w.Code)
+	}
+}
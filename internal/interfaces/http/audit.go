@@ -0,0 +1,89 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"demo/internal/domain/audit"
+	"demo/internal/i18n"
+	"demo/internal/infrastructure/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// optionalUUIDJSON formats id, or returns nil when it is uuid.Nil.
+func optionalUUIDJSON(id uuid.UUID) interface{} {
+	if id == uuid.Nil {
+		return nil
+	}
+	return id.String()
+}
+
+// auditEntryJSON is the response body describing an audit log entry.
+func auditEntryJSON(e audit.Entry) gin.H {
+	return gin.H{
+		"id":        e.ID.String(),
+		"time":      e.Time.Format(time.RFC3339Nano),
+		"action":    string(e.Action),
+		"actorID":   optionalUUIDJSON(e.ActorID),
+		"apiKeyID":  optionalUUIDJSON(e.APIKeyID),
+		"target":    e.Target,
+		"details":   e.Details,
+		"ip":        e.IP,
+		"userAgent": e.UserAgent,
+		"traceID":   e.TraceID,
+	}
+}
+
+// auditQuery reads the filters of an audit log search from the query string:
+// action, actor, target and the RFC 3339 times since and until.
+func auditQuery(c *gin.Context) (audit.Query, bool) {
+	q := audit.Query{Action: audit.Action(c.Query("action")), Target: c.Query("target")}
+	if s := c.Query("actor"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return q, false
+		}
+		q.ActorID = id
+	}
+	for _, t := range []struct {
+		param string
+		time  *time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		if s := c.Query(t.param); s != "" {
+			v, err := time.Parse(time.RFC3339, s)
+			if err != nil {
+				return q, false
+			}
+			*t.time = v
+		}
+	}
+	return q, true
+}
+
+// auditRoutes registers the audit log search on a group requiring the admin
+// permission.
+func auditRoutes(r gin.IRoutes, authSvc *auth.Service) {
+	r.GET("/audit", func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		q, ok := auditQuery(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_audit_query")})
+			return
+		}
+		if q.Offset, q.Limit, ok = pageParams(c); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_page")})
+			return
+		}
+		entries, total, err := authSvc.Audit.Search(c.Request.Context(), q)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
+			return
+		}
+		list := make([]gin.H, 0, len(entries))
+		for _, e := range entries {
+			list = append(list, auditEntryJSON(e))
+		}
+		c.JSON(http.StatusOK, gin.H{"entries": list, "total": total, "offset": q.Offset, "limit": q.Limit})
+	})
+}
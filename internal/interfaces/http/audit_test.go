@@ -0,0 +1,92 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"demo/internal/domain/audit"
+	"demo/internal/infrastructure/deckstore"
+)
+
+func TestAuditRoutes(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("User-Agent", "audit-test")
+		req.RemoteAddr = "10.0.0.1:1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type page struct {
+		Entries []struct {
+			Action    string
+			ActorID   *string
+			Target    string
+			Details   string
+			IP        string
+			UserAgent string
+		}
+		Total int
+	}
+	search := func(query url.Values, token string) page {
+		t.Helper()
+		w := do("GET", "/audit?"+query.Encode(), "", token)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200 got %d: %s", w.Code, w.Body)
+		}
+		var p page
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	admin := loginAs(t, authSvc, "admin")
+	player := login(t, authSvc)
+
+	if w := do("GET", "/audit", "", player); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 got %d", w.Code)
+	}
+	if w := do("GET", "/audit", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+	p := search(url.Values{"action": {string(audit.PermissionDenied)}}, admin)
+	if p.Total != 1 || p.Entries[0].Target != "GET /audit" || p.Entries[0].Details != "admin" ||
+		p.Entries[0].IP != "10.0.0.1" || p.Entries[0].UserAgent != "audit-test" || p.Entries[0].ActorID == nil {
+		t.Fatalf("expected the denial to be recorded got %+v", p)
+	}
+
+	designer := loginAs(t, authSvc, "designer")
+	if w := do("POST", "/cards", `{"name":"Fireball"}`, designer); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+	if w := do("POST", "/cards", `{`, designer); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+	p = search(url.Values{"action": {string(audit.CardCreated)}}, admin)
+	if p.Total != 1 || p.Entries[0].Details != "Fireball" {
+		t.Fatalf("expected the card creation to be recorded got %+v", p)
+	}
+
+	p = search(url.Values{"action": {string(audit.LoginSucceeded)}, "limit": {"2"}}, admin)
+	if p.Total != 3 || len(p.Entries) != 2 || p.Entries[0].Target != "designer" {
+		t.Fatalf("expected the newest logins first got %+v", p)
+	}
+	p = search(url.Values{"until": {time.Now().Add(-time.Hour).Format(time.RFC3339)}}, admin)
+	if p.Total != 0 {
+		t.Fatalf("expected no entries before the test got %+v", p)
+	}
+
+	for _, query := range []string{"actor=x", "since=yesterday", "until=2024-01-01", "limit=0"} {
+		if w := do("GET", "/audit?"+query, "", admin); w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400 got %d", query, w.Code)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	appcmd "demo/internal/application/command"
+	appquery "demo/internal/application/query"
+	"demo/internal/domain/deck"
+	"demo/internal/i18n"
+	"demo/internal/interfaces/transfer"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// deckError writes the response for a failed deck command or query.
+func deckError(c *gin.Context, lang string, err error) {
+	var verr *deck.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": i18n.Translate(lang, "invalid_deck"), "violations": verr.Violations})
+	case errors.Is(err, deck.ErrInvalidCode), errors.Is(err, deck.ErrCodeVersion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_deck_code")})
+	case errors.Is(err, deck.ErrUnknownFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "unknown_format")})
+	case errors.Is(err, deck.ErrInvalidVisibility):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_visibility")})
+	case errors.Is(err, deck.ErrInvalidZone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_zone")})
+	case errors.Is(err, deck.ErrCardNotInDeck):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "card_not_in_deck")})
+	case errors.Is(err, deck.ErrNotFound), errors.Is(err, deck.ErrDeckDeleted):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(lang, "deck_not_found")})
+	case errors.Is(err, deck.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(lang, "deck_version_not_found")})
+	case errors.Is(err, deck.ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.Translate(lang, "not_deck_owner")})
+	case errors.Is(err, deck.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(lang, "deck_changed")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
+	}
+}
+
+// deckJSON is the response body describing a deck. cardIDs lists the main
+// zone, one element per copy, for clients predating zones.
+func deckJSON(d *deck.Deck) gin.H {
+	main := d.CardIDs()
+	ids := make([]string, 0, len(main))
+	for _, id := range main {
+		ids = append(ids, id.String())
+	}
+	zones := d.Zones
+	if zones == nil {
+		zones = []deck.Zone{}
+	}
+	return gin.H{
+		"id":         d.ID.String(),
+		"name":       d.Name,
+		"format":     d.Format,
+		"visibility": d.Visibility,
+		"cardIDs":    ids,
+		"zones":      zones,
+		"createdAt":  d.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// deckDetailsJSON is the response body describing a deck with its cards
+// translated and the rules of its format it breaks.
+func deckDetailsJSON(lang string, details *appquery.DeckDetails) gin.H {
+	cards := make([]map[string]interface{}, 0, len(details.Cards))
+	for _, dc := range details.Cards {
+		entry := i18n.TranslateCard(lang, dc.Card)
+		if entry == nil {
+			entry = map[string]interface{}{i18n.Translate(lang, "id"): dc.ID.String()}
+		}
+		entry["count"] = dc.Count
+		entry["zone"] = dc.Zone
+		cards = append(cards, entry)
+	}
+	violations := details.Violations
+	if violations == nil {
+		violations = []deck.Violation{}
+	}
+	resp := deckJSON(details.Deck)
+	resp["cards"] = cards
+	resp["legal"] = len(violations) == 0
+	resp["violations"] = violations
+	return resp
+}
+
+// revisionJSON is the response body describing a deck revision. Revisions
+// do not record the visibility of the deck.
+func revisionJSON(rev *deck.Revision) gin.H {
+	resp := deckJSON(&deck.Deck{ID: rev.DeckID, Name: rev.Name, Format: rev.Format, Zones: rev.Zones, CreatedAt: rev.CreatedAt})
+	delete(resp, "visibility")
+	resp["version"] = rev.Version
+	return resp
+}
+
+// versionParam reads a revision number from the path or query. ok is false
+// when it is not a positive number.
+func versionParam(s string) (int, bool) {
+	v, err := strconv.Atoi(s)
+	return v, err == nil && v > 0
+}
+
+// parseCardIDs parses the card ids of a request body.
+func parseCardIDs(strs []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(strs))
+	for _, s := range strs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// pageParams reads the offset and limit query parameters.
+func pageParams(c *gin.Context) (offset, limit int, ok bool) {
+	var err error
+	if s := c.Query("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return 0, 0, false
+		}
+	}
+	limit = defaultPageSize
+	if s := c.Query("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 {
+			return 0, 0, false
+		}
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return offset, limit, true
+}
+
+// listContentTypes maps deck list formats to their media types.
+var listContentTypes = map[string]string{
+	transfer.ListText: "text/plain; charset=utf-8",
+	transfer.ListCSV:  "text/csv; charset=utf-8",
+}
+
+// importDeckList creates a deck from the deck list in the request body. The
+// name, format and dry_run query parameters set the deck name and format and
+// whether the deck is only checked.
+func importDeckList(c *gin.Context, lang string, userID uuid.UUID, h *appcmd.ImportDeckListHandler, listFormat string) {
+	lines, rowErrs, err := transfer.ReadDeckList(c.Request.Body, listFormat)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+		return
+	}
+	if len(rowErrs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": i18n.Translate(lang, "invalid_deck_list"), "errors": sortedRowErrors(rowErrs)})
+		return
+	}
+	dryRun := c.Query("dry_run") == "true"
+	res, err := h.Handle(c.Request.Context(), appcmd.ImportDeckListCommand{
+		UserID: userID,
+		Name:   c.Query("name"),
+		Format: c.Query("format"),
+		Lines:  lines,
+		DryRun: dryRun,
+	})
+	if err != nil {
+		deckError(c, lang, err)
+		return
+	}
+	if len(res.Errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": i18n.Translate(lang, "invalid_deck_list"), "errors": sortedRowErrors(res.Errors)})
+		return
+	}
+	resp := deckJSON(res.Deck)
+	resp["dryRun"] = dryRun
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+	c.JSON(status, resp)
+}
+
+// deckRoutes registers the deck endpoints on a group requiring a user who
+// may read or, for other methods than GET, write decks. They only give
+// access to that user's decks.
+func deckRoutes(r gin.IRoutes, h Handlers) {
+	r.POST("/decks", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		var body struct {
+			Name    string
+			Format  string
+			CardIDs []string
+			Zones   []deck.Zone
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+			return
+		}
+		ids, err := parseCardIDs(body.CardIDs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_card_id")})
+			return
+		}
+		cmd := appcmd.CreateDeckCommand{UserID: userID, Name: body.Name, Format: body.Format, CardIDs: ids, Zones: body.Zones}
+		d, err := h.CreateDeck.Handle(c.Request.Context(), cmd)
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": d.ID.String()})
+	})
+
+	r.GET("/decks", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		offset, limit, ok := pageParams(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_page")})
+			return
+		}
+		page, err := h.ListDecks.Handle(c.Request.Context(), appquery.ListDecksQuery{UserID: userID, Offset: offset, Limit: limit})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		decks := make([]gin.H, 0, len(page.Decks))
+		for _, d := range page.Decks {
+			decks = append(decks, deckJSON(d))
+		}
+		c.JSON(http.StatusOK, gin.H{"decks": decks, "total": page.Total, "offset": offset, "limit": limit})
+	})
+
+	r.GET("/decks/:id", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		details, err := h.GetDeck.Handle(c.Request.Context(), appquery.GetDeckQuery{DeckID: id, UserID: userID})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, deckDetailsJSON(lang, details))
+	})
+
+	update := func(partial bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			userID := currentUser(c)
+			lang := c.GetHeader("Accept-Language")
+			id, err := uuid.Parse(c.Param("id"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+				return
+			}
+			var body struct {
+				Name    *string
+				CardIDs *[]string
+				Zones   []deck.Zone
+			}
+			if err := c.ShouldBindJSON(&body); err != nil || (!partial && (body.Name == nil || (body.CardIDs == nil && body.Zones == nil))) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+				return
+			}
+			cmd := appcmd.UpdateDeckCommand{DeckID: id, UserID: userID, Name: body.Name, Zones: body.Zones}
+			if body.CardIDs != nil {
+				if cmd.CardIDs, err = parseCardIDs(*body.CardIDs); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_card_id")})
+					return
+				}
+			}
+			d, err := h.UpdateDeck.Handle(c.Request.Context(), cmd)
+			if err != nil {
+				deckError(c, lang, err)
+				return
+			}
+			c.JSON(http.StatusOK, deckJSON(d))
+		}
+	}
+	r.PUT("/decks/:id", update(false))
+	r.PATCH("/decks/:id", update(true))
+
+	r.DELETE("/decks/:id", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		if err := h.DeleteDeck.Handle(c.Request.Context(), appcmd.DeleteDeckCommand{DeckID: id, UserID: userID}); err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	r.PUT("/decks/:id/visibility", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		var body struct {
+			Visibility string `binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+			return
+		}
+		d, err := h.SetVisibility.Handle(c.Request.Context(), appcmd.SetDeckVisibilityCommand{DeckID: id, UserID: userID, Visibility: body.Visibility})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, deckJSON(d))
+	})
+
+	r.POST("/decks/:id/clone", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		var body struct {
+			Name string
+		}
+		// the body is optional
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&body); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+				return
+			}
+		}
+		d, err := h.CloneDeck.Handle(c.Request.Context(), appcmd.CloneDeckCommand{DeckID: id, UserID: userID, Name: body.Name})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusCreated, deckJSON(d))
+	})
+
+	r.GET("/decks/:id/code", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		code, err := h.DeckCode.Handle(c.Request.Context(), appquery.GetDeckCodeQuery{DeckID: id, UserID: userID})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"code": code})
+	})
+
+	r.POST("/decks/import", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		switch c.ContentType() {
+		case "text/plain":
+			importDeckList(c, lang, userID, h.ImportList, transfer.ListText)
+			return
+		case "text/csv":
+			importDeckList(c, lang, userID, h.ImportList, transfer.ListCSV)
+			return
+		}
+		var body struct {
+			Name string
+			Code string `binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+			return
+		}
+		d, err := h.ImportDeck.Handle(c.Request.Context(), appcmd.ImportDeckCommand{UserID: userID, Name: body.Name, Code: body.Code})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusCreated, deckJSON(d))
+	})
+
+	r.GET("/decks/:id/export", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		format := c.DefaultQuery("format", transfer.ListText)
+		contentType, ok := listContentTypes[format]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "unknown_list_format")})
+			return
+		}
+		details, err := h.GetDeck.Handle(c.Request.Context(), appquery.GetDeckQuery{DeckID: id, UserID: userID})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		var buf bytes.Buffer
+		if err := transfer.WriteDeckList(&buf, details.Cards, format); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
+			return
+		}
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	})
+
+	r.GET("/decks/:id/stats", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		hand, err := strconv.Atoi(c.DefaultQuery("hand", "0"))
+		if err != nil || hand < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_hand_size")})
+			return
+		}
+		stats, err := h.DeckStats.Handle(c.Request.Context(), appquery.DeckStatsQuery{DeckID: id, UserID: userID, HandSize: hand})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+
+	r.GET("/decks/:id/versions", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		revs, err := h.DeckHistory.Versions(c.Request.Context(), appquery.DeckVersionsQuery{DeckID: id, UserID: userID})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		versions := make([]gin.H, 0, len(revs))
+		for i := range revs {
+			versions = append(versions, revisionJSON(&revs[i]))
+		}
+		c.JSON(http.StatusOK, gin.H{"versions": versions})
+	})
+
+	r.GET("/decks/:id/versions/:version", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		version, ok := versionParam(c.Param("version"))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_version")})
+			return
+		}
+		rev, err := h.DeckHistory.Version(c.Request.Context(), appquery.DeckVersionQuery{DeckID: id, UserID: userID, Version: version})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, revisionJSON(rev))
+	})
+
+	r.POST("/decks/:id/versions/:version/restore", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		version, ok := versionParam(c.Param("version"))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_version")})
+			return
+		}
+		d, err := h.UpdateDeck.Restore(c.Request.Context(), appcmd.RestoreDeckCommand{DeckID: id, UserID: userID, Version: version})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, deckJSON(d))
+	})
+
+	r.GET("/decks/:id/diff", func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		from, ok := versionParam(c.Query("from"))
+		to := 0
+		if s := c.Query("to"); ok && s != "" {
+			to, ok = versionParam(s)
+		}
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_version")})
+			return
+		}
+		diff, err := h.DeckHistory.Diff(c.Request.Context(), appquery.DeckDiffQuery{DeckID: id, UserID: userID, From: from, To: to})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, diff)
+	})
+}
+
+// deckAnalysisRoutes registers the endpoints computing statistics of unsaved
+// decks on a group requiring a user who may read decks: they save nothing.
+func deckAnalysisRoutes(r gin.IRoutes, h Handlers) {
+	r.POST("/decks/stats", func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		var body struct {
+			CardIDs []string
+			Hand    int
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.Hand < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+			return
+		}
+		ids, err := parseCardIDs(body.CardIDs)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_card_id")})
+			return
+		}
+		stats, err := h.DeckStats.Analyze(c.Request.Context(), appquery.AnalyzeCardsQuery{CardIDs: ids, HandSize: body.Hand})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+}
@@ -0,0 +1,466 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	appcmd "demo/internal/application/command"
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+	"github.com/google/uuid"
+)
+
+type deckResponse struct {
+	ID      string                   `json:"id"`
+	Name    string                   `json:"name"`
+	CardIDs []string                 `json:"cardIDs"`
+	Zones   []deck.Zone              `json:"zones"`
+	Cards   []map[string]interface{} `json:"cards"`
+}
+
+func TestDeckRoutes(t *testing.T) {
+	a := uuid.New()
+	repo := &mockRepo{LoadFn: func(ctx context.Context, id string) (*card.Card, error) {
+		if id == a.String() {
+			return &card.Card{ID: a, Name: "A"}, nil
+		}
+		return nil, nil
+	}}
+	authSvc := testAuth(t)
+	decks := deckstore.NewInMemoryStore()
+	r := Router(authSvc, testHandlers(repo, decks), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/decks", `{"name":"d","cardIDs":["`+a.String()+`","`+a.String()+`"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+	var created deckResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	_ = do("POST", "/decks", `{"name":"e"}`)
+
+	w = do("GET", "/decks?limit=1", "")
+	var page struct {
+		Decks []deckResponse `json:"decks"`
+		Total int            `json:"total"`
+		Limit int            `json:"limit"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK || page.Total != 2 || len(page.Decks) != 1 || page.Limit != 1 {
+		t.Fatalf("unexpected list %d %s", w.Code, w.Body.String())
+	}
+	if w = do("GET", "/decks?limit=x", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+
+	w = do("GET", "/decks/"+created.ID, "")
+	var got deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK || len(got.Cards) != 1 {
+		t.Fatalf("unexpected deck %d %s", w.Code, w.Body.String())
+	}
+	if got.Cards[0]["name"] != "A" || got.Cards[0]["count"] != float64(2) {
+		t.Fatalf("unexpected card %v", got.Cards[0])
+	}
+
+	if w = do("PUT", "/decks/"+created.ID, `{"name":"x"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for incomplete PUT got %d", w.Code)
+	}
+	w = do("PUT", "/decks/"+created.ID, `{"name":"x","cardIDs":["`+a.String()+`"]}`)
+	var updated deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil || w.Code != http.StatusOK || updated.Name != "x" || len(updated.CardIDs) != 1 {
+		t.Fatalf("unexpected PUT %d %s", w.Code, w.Body.String())
+	}
+	w = do("PATCH", "/decks/"+created.ID, `{"name":"y"}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil || w.Code != http.StatusOK || updated.Name != "y" || len(updated.CardIDs) != 1 {
+		t.Fatalf("unexpected PATCH %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("POST", "/decks/"+created.ID+"/clone", "")
+	var clone deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &clone); err != nil || w.Code != http.StatusCreated || clone.ID == created.ID || clone.Name != "y (copy)" {
+		t.Fatalf("unexpected clone %d %s", w.Code, w.Body.String())
+	}
+
+	side := `"zones":[{"name":"sideboard","cards":[{"card_id":"` + a.String() + `","quantity":2}]}]`
+	w = do("PATCH", "/decks/"+created.ID, `{`+side+`}`)
+	if err := json.Unmarshal(w.Body.Bytes(), &updated); err != nil || w.Code != http.StatusOK || len(updated.CardIDs) != 1 ||
+		len(updated.Zones) != 2 || updated.Zones[1].Name != deck.ZoneSideboard || updated.Zones[1].Quantity(a) != 2 {
+		t.Fatalf("unexpected zones PATCH %d %s", w.Code, w.Body.String())
+	}
+	w = do("GET", "/decks/"+created.ID, "")
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || len(got.Cards) != 2 || got.Cards[1]["zone"] != deck.ZoneSideboard || got.Cards[1]["count"] != float64(2) {
+		t.Fatalf("unexpected deck %d %s", w.Code, w.Body.String())
+	}
+	if w = do("PATCH", "/decks/"+created.ID, `{"zones":[{"name":"Side Board"}]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid zone got %d", w.Code)
+	}
+	w = do("POST", "/decks", `{"name":"z",`+side+`}`)
+	var zoned deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &zoned); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected create %d %s", w.Code, w.Body.String())
+	}
+	w = do("GET", "/decks/"+zoned.ID, "")
+	if err := json.Unmarshal(w.Body.Bytes(), &zoned); err != nil || len(zoned.CardIDs) != 0 || len(zoned.Zones) != 1 || zoned.Zones[0].Size() != 2 {
+		t.Fatalf("unexpected zoned deck %d %s", w.Code, w.Body.String())
+	}
+
+	if w = do("DELETE", "/decks/"+created.ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", w.Code)
+	}
+	if w = do("GET", "/decks/"+created.ID, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", w.Code)
+	}
+}
+
+func TestDeckRoutesOwnership(t *testing.T) {
+	authSvc := testAuth(t)
+	decks := deckstore.NewInMemoryStore()
+	r := Router(authSvc, testHandlers(&mockRepo{}, decks), RateLimits{})
+	token := login(t, authSvc)
+	other := deck.NewDeck(uuid.New(), "theirs", nil)
+	if err := decks.Save(context.Background(), 0, []interface{}{other.Created()}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct{ method, path, body string }{
+		{"GET", "/decks/" + other.ID.String(), ""},
+		{"PATCH", "/decks/" + other.ID.String(), `{"name":"mine"}`},
+		{"DELETE", "/decks/" + other.ID.String(), ""},
+		{"POST", "/decks/" + other.ID.String() + "/clone", ""},
+		{"GET", "/decks/" + other.ID.String() + "/versions", ""},
+		{"POST", "/decks/" + other.ID.String() + "/versions/1/restore", ""},
+		{"PUT", "/decks/" + other.ID.String() + "/visibility", `{"visibility":"public"}`},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s %s: expected 403 got %d", tc.method, tc.path, w.Code)
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/decks", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+}
+
+func TestDeckCodeRoutes(t *testing.T) {
+	authSvc := testAuth(t)
+	decks := deckstore.NewInMemoryStore()
+	r := Router(authSvc, testHandlers(&mockRepo{}, decks), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	a := uuid.New()
+	w := do("POST", "/decks", `{"name":"d","cardIDs":["`+a.String()+`","`+a.String()+`"]}`)
+	var created deckResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = do("GET", "/decks/"+created.ID+"/code", "")
+	var resp struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK || resp.Code == "" {
+		t.Fatalf("unexpected code response %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("POST", "/decks/import", `{"name":"copy","code":"`+resp.Code+`"}`)
+	var imported deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &imported); err != nil || w.Code != http.StatusCreated || imported.Name != "copy" || len(imported.CardIDs) != 2 {
+		t.Fatalf("unexpected import %d %s", w.Code, w.Body.String())
+	}
+	if w = do("POST", "/decks/import", `{"code":"garbage"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+	if w = do("POST", "/decks/import", `{}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+}
+
+func TestDeckListImportExport(t *testing.T) {
+	ctx := context.Background()
+	cards := eventstore.NewInMemoryStore()
+	fireball := card.NewCard("Fireball", 3, "Red", "Spell", "", "")
+	if err := cards.Save(ctx, []interface{}{card.CardCreated(*fireball)}); err != nil {
+		t.Fatal(err)
+	}
+	authSvc := testAuth(t)
+	decks := deckstore.NewInMemoryStore()
+	r := Router(authSvc, testHandlers(cards, decks), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/decks/import?name=burn&dry_run=true", "text/plain", "3x Fireball\n")
+	var d deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil || w.Code != http.StatusOK || len(d.CardIDs) != 3 {
+		t.Fatalf("unexpected dry run %d %s", w.Code, w.Body.String())
+	}
+	p, _ := authSvc.Authenticate(context.Background(), token)
+	if _, total, _ := decks.ListByUser(ctx, p.UserID, 0, 0); total != 0 {
+		t.Fatal("dry run stored a deck")
+	}
+
+	w = do("POST", "/decks/import?name=burn", "text/csv", "count,name\n2,Fireball\n")
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil || w.Code != http.StatusCreated || d.Name != "burn" || len(d.CardIDs) != 2 {
+		t.Fatalf("unexpected import %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("POST", "/decks/import", "text/plain", "1x Fireball\nfoo bar\nx2 Fireball\n")
+	var failed struct {
+		Errors []appcmd.RowError `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &failed); err != nil || w.Code != http.StatusUnprocessableEntity || len(failed.Errors) != 2 || failed.Errors[0].Row != 2 {
+		t.Fatalf("unexpected failed import %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("GET", "/decks/"+d.ID+"/export", "", "")
+	if w.Code != http.StatusOK || w.Body.String() != "2x Fireball\n" {
+		t.Fatalf("unexpected text export %d %q", w.Code, w.Body.String())
+	}
+	w = do("GET", "/decks/"+d.ID+"/export?format=csv", "", "")
+	if w.Code != http.StatusOK || w.Body.String() != "count,id,name,zone\n2,"+fireball.ID.String()+",Fireball,main\n" {
+		t.Fatalf("unexpected csv export %d %q", w.Code, w.Body.String())
+	}
+	if w = do("GET", "/decks/"+d.ID+"/export?format=xml", "", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+}
+
+func TestDeckStatsRoutes(t *testing.T) {
+	ctx := context.Background()
+	cards := eventstore.NewInMemoryStore()
+	a := card.NewCard("A", 2, "Red", "Spell", "Burn", "")
+	b := card.NewCard("B", 4, "Blue", "Unit", "", "")
+	for _, c := range []*card.Card{a, b} {
+		if err := cards.Save(ctx, []interface{}{card.CardCreated(*c)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(cards, deckstore.NewInMemoryStore()), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	list := `"` + a.ID.String() + `","` + a.ID.String() + `","` + b.ID.String() + `"`
+
+	w := do("POST", "/decks/stats", `{"cardIDs":[`+list+`],"hand":1}`)
+	var stats deck.Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected stats %d %s", w.Code, w.Body.String())
+	}
+	if stats.Size != 3 || stats.CostCurve[2] != 2 || stats.Factions["Blue"] != 1 || stats.HandSize != 1 || stats.Draws[0].CardID != a.ID {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	w = do("POST", "/decks", `{"name":"d","cardIDs":[`+list+`]}`)
+	var created deckResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	w = do("GET", "/decks/"+created.ID+"/stats?hand=2", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil || w.Code != http.StatusOK || stats.HandSize != 2 || stats.AverageCost < 2.66 || stats.AverageCost > 2.67 {
+		t.Fatalf("unexpected stats %d %s", w.Code, w.Body.String())
+	}
+	if w = do("GET", "/decks/"+created.ID+"/stats?hand=x", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+}
+
+func TestDeckHistoryRoutes(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/decks", `{"name":"d","cardIDs":["`+a.String()+`","`+a.String()+`"]}`)
+	var d deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected create %d %s", w.Code, w.Body.String())
+	}
+	_ = do("PUT", "/decks/"+d.ID, `{"name":"e","cardIDs":["`+b.String()+`"]}`)
+
+	w = do("GET", "/decks/"+d.ID+"/versions", "")
+	var versions struct {
+		Versions []struct {
+			Version int      `json:"version"`
+			Name    string   `json:"name"`
+			CardIDs []string `json:"cardIDs"`
+		} `json:"versions"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &versions); err != nil || w.Code != http.StatusOK || len(versions.Versions) != 2 ||
+		versions.Versions[0].Version != 1 || versions.Versions[0].Name != "d" || versions.Versions[1].Name != "e" {
+		t.Fatalf("unexpected versions %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("GET", "/decks/"+d.ID+"/versions/1", "")
+	var rev deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &rev); err != nil || w.Code != http.StatusOK || rev.Name != "d" || len(rev.CardIDs) != 2 {
+		t.Fatalf("unexpected version %d %s", w.Code, w.Body.String())
+	}
+	for path, code := range map[string]int{
+		"/decks/" + d.ID + "/versions/3":           http.StatusNotFound,
+		"/decks/" + d.ID + "/versions/x":           http.StatusBadRequest,
+		"/decks/" + d.ID + "/diff":                 http.StatusBadRequest,
+		"/decks/" + d.ID + "/diff?from=1&to=0":     http.StatusBadRequest,
+		"/decks/" + d.ID + "/diff?from=1&to=9":     http.StatusNotFound,
+		"/decks/" + uuid.NewString() + "/versions": http.StatusNotFound,
+	} {
+		if w = do("GET", path, ""); w.Code != code {
+			t.Fatalf("GET %s: expected %d got %d", path, code, w.Code)
+		}
+	}
+
+	w = do("GET", "/decks/"+d.ID+"/diff?from=1", "")
+	var diff deck.Diff
+	if err := json.Unmarshal(w.Body.Bytes(), &diff); err != nil || w.Code != http.StatusOK || diff.To != 2 || diff.Name != "e" ||
+		len(diff.Added) != 1 || diff.Added[0].CardID != b || len(diff.Removed) != 1 || diff.Removed[0].Quantity != 2 {
+		t.Fatalf("unexpected diff %d %s", w.Code, w.Body.String())
+	}
+
+	w = do("POST", "/decks/"+d.ID+"/versions/1/restore", "")
+	var restored deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &restored); err != nil || w.Code != http.StatusOK || restored.Name != "d" || len(restored.CardIDs) != 2 {
+		t.Fatalf("unexpected restore %d %s", w.Code, w.Body.String())
+	}
+	w = do("GET", "/decks/"+d.ID+"/versions/3", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &rev); err != nil || w.Code != http.StatusOK || rev.Name != "d" {
+		t.Fatalf("expected restore as version 3 got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// failingDecks is a deck repository whose loads fail.
+type failingDecks struct {
+	*deckstore.InMemoryStore
+}
+
+func (failingDecks) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestDeckRepoError(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, failingDecks{deckstore.NewInMemoryStore()}), RateLimits{})
+	req := httptest.NewRequest("GET", "/decks/"+uuid.NewString(), nil)
+	req.Header.Set("Authorization", "Bearer "+login(t, authSvc))
+	req.Header.Set("Accept-Language", "zh")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var body struct{ Error string }
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusInternalServerError || body.Error != "內部伺服器錯誤" {
+		t.Fatalf("expected a translated 500 got %d %s", w.Code, w.Body.String())
+	}
+}
+
+// staleDecks is a deck repository loading decks at their previous version,
+// as if another request changed them since.
+type staleDecks struct {
+	*deckstore.InMemoryStore
+}
+
+func (s staleDecks) Load(ctx context.Context, id uuid.UUID) (*deck.Deck, error) {
+	d, err := s.InMemoryStore.Load(ctx, id)
+	if d != nil {
+		d.Version--
+	}
+	return d, err
+}
+
+func TestDeckChangedConcurrently(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, staleDecks{deckstore.NewInMemoryStore()}), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	w := do("POST", "/decks", `{"name":"d"}`)
+	var created deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+	w = do("PATCH", "/decks/"+created.ID, `{"name":"e"}`)
+	var body struct{ Error string }
+	_ = json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusConflict || body.Error == "" {
+		t.Fatalf("expected 409 got %d %s", w.Code, w.Body.String())
+	}
+	w = do("GET", "/decks/"+created.ID, "")
+	var got deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Name != "d" {
+		t.Fatalf("expected the deck unchanged got %s", w.Body.String())
+	}
+}
+
+func TestDeckLegality(t *testing.T) {
+	a := uuid.New()
+	repo := &mockRepo{LoadFn: func(ctx context.Context, id string) (*card.Card, error) {
+		return &card.Card{ID: a, Name: "A"}, nil
+	}}
+	authSvc := testAuth(t)
+	h := testHandlers(repo, deckstore.NewInMemoryStore())
+	// the deck was made before the format allowed a single copy
+	h.GetDeck.Rules, _ = deck.NewRules([]deck.Format{{ID: "std", MaxCopies: 1}})
+	r := Router(authSvc, h, RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	var created deckResponse
+	_ = json.Unmarshal(do("POST", "/decks", `{"name":"d","format":"std","cardIDs":["`+a.String()+`","`+a.String()+`"]}`).Body.Bytes(), &created)
+
+	w := do("GET", "/decks/"+created.ID, "")
+	var got struct {
+		Legal      bool             `json:"legal"`
+		Violations []deck.Violation `json:"violations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Legal || len(got.Violations) != 1 || got.Violations[0].Rule != deck.RuleMaxCopies {
+		t.Fatalf("unexpected deck %d %s", w.Code, w.Body.String())
+	}
+}
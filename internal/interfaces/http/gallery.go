@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	appcmd "demo/internal/application/command"
+	appquery "demo/internal/application/query"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/user"
+	"demo/internal/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// galleryJSON is the response body describing a gallery entry.
+func galleryJSON(e *deck.GalleryEntry) gin.H {
+	ids := make([]string, 0, len(e.CardIDs))
+	for _, id := range e.CardIDs {
+		ids = append(ids, id.String())
+	}
+	return gin.H{
+		"id":        e.DeckID.String(),
+		"userID":    e.UserID.String(),
+		"name":      e.Name,
+		"format":    e.Format,
+		"factions":  e.Factions,
+		"cardIDs":   ids,
+		"likes":     e.Likes,
+		"createdAt": e.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// galleryRoutes registers the endpoints of the public deck gallery. Browsing
+// and reading shared decks works without logging in; liking needs a user.
+func galleryRoutes(r *gin.Engine, h Handlers) {
+	r.GET("/gallery", restrict(user.PermReadDecks), func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		offset, limit, ok := pageParams(c)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_page")})
+			return
+		}
+		q := appquery.SearchGalleryQuery{
+			UserID:  userID,
+			Format:  c.Query("format"),
+			Faction: c.Query("faction"),
+			Liked:   c.Query("liked") == "true",
+			Sort:    c.DefaultQuery("sort", deck.SortNewest),
+			Offset:  offset,
+			Limit:   limit,
+		}
+		if q.Sort != deck.SortNewest && q.Sort != deck.SortPopular {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_sort")})
+			return
+		}
+		if q.Liked && userID == uuid.Nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		ids, err := parseCardIDs(c.QueryArray("card"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_card_id")})
+			return
+		}
+		q.CardIDs = ids
+		page, err := h.Gallery.Search(c.Request.Context(), q)
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		decks := make([]gin.H, 0, len(page.Entries))
+		for i := range page.Entries {
+			decks = append(decks, galleryJSON(&page.Entries[i]))
+		}
+		c.JSON(http.StatusOK, gin.H{"decks": decks, "total": page.Total, "offset": offset, "limit": limit})
+	})
+
+	r.GET("/gallery/:id", restrict(user.PermReadDecks), func(c *gin.Context) {
+		userID := currentUser(c)
+		lang := c.GetHeader("Accept-Language")
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+			return
+		}
+		details, err := h.Gallery.Deck(c.Request.Context(), appquery.SharedDeckQuery{DeckID: id, UserID: userID})
+		if err != nil {
+			deckError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, deckDetailsJSON(lang, details))
+	})
+
+	like := func(unlike bool) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			userID := currentUser(c)
+			lang := c.GetHeader("Accept-Language")
+			id, err := uuid.Parse(c.Param("id"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_id")})
+				return
+			}
+			cmd := appcmd.LikeDeckCommand{DeckID: id, UserID: userID}
+			var e *deck.GalleryEntry
+			if unlike {
+				e, err = h.LikeDeck.Unlike(c.Request.Context(), cmd)
+			} else {
+				e, err = h.LikeDeck.Like(c.Request.Context(), cmd)
+			}
+			if err != nil {
+				deckError(c, lang, err)
+				return
+			}
+			c.JSON(http.StatusOK, galleryJSON(e))
+		}
+	}
+	r.PUT("/gallery/:id/like", requirePermission(user.PermWriteDecks), like(false))
+	r.DELETE("/gallery/:id/like", requirePermission(user.PermWriteDecks), like(true))
+}
@@ -0,0 +1,126 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/deckstore"
+	"github.com/google/uuid"
+)
+
+func TestGalleryRoutes(t *testing.T) {
+	a := uuid.New()
+	repo := &mockRepo{LoadFn: func(ctx context.Context, id string) (*card.Card, error) {
+		if id == a.String() {
+			return &card.Card{ID: a, Name: "A", Faction: "red"}, nil
+		}
+		return nil, nil
+	}}
+	authSvc := testAuth(t)
+	decks := deckstore.NewInMemoryStore()
+	r := Router(authSvc, testHandlers(repo, decks), RateLimits{})
+	token := login(t, authSvc)
+	do := func(method, path, body string, auth bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if auth {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type galleryPage struct {
+		Decks []struct {
+			ID       string   `json:"id"`
+			Name     string   `json:"name"`
+			Factions []string `json:"factions"`
+			Likes    int      `json:"likes"`
+		} `json:"decks"`
+		Total int `json:"total"`
+	}
+	browse := func(path string, auth bool) galleryPage {
+		t.Helper()
+		w := do("GET", path, "", auth)
+		var page galleryPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+			t.Fatalf("GET %s: unexpected %d %s", path, w.Code, w.Body.String())
+		}
+		return page
+	}
+
+	w := do("POST", "/decks", `{"name":"d","cardIDs":["`+a.String()+`"]}`, true)
+	var created struct{ ID string }
+	_ = json.Unmarshal(w.Body.Bytes(), &created)
+	if page := browse("/gallery", false); page.Total != 0 {
+		t.Fatalf("expected private decks to stay out of the gallery got %+v", page)
+	}
+	if w := do("GET", "/gallery/"+created.ID, "", false); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a private deck got %d", w.Code)
+	}
+	if w := do("PUT", "/decks/"+created.ID+"/visibility", `{"visibility":"friends"}`, true); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+
+	w = do("PUT", "/decks/"+created.ID+"/visibility", `{"visibility":"public"}`, true)
+	var shared struct{ Visibility string }
+	if err := json.Unmarshal(w.Body.Bytes(), &shared); err != nil || w.Code != http.StatusOK || shared.Visibility != deck.VisibilityPublic {
+		t.Fatalf("unexpected visibility change %d %s", w.Code, w.Body.String())
+	}
+	page := browse("/gallery?format=&faction=red&card="+a.String(), false)
+	if page.Total != 1 || page.Decks[0].ID != created.ID || page.Decks[0].Factions[0] != "red" {
+		t.Fatalf("unexpected gallery %+v", page)
+	}
+	if page := browse("/gallery?faction=blue", false); page.Total != 0 {
+		t.Fatalf("expected no blue decks got %+v", page)
+	}
+	w = do("GET", "/gallery/"+created.ID, "", false)
+	var details deckResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &details); err != nil || w.Code != http.StatusOK || len(details.Cards) != 1 {
+		t.Fatalf("unexpected shared deck %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do("PUT", "/gallery/"+created.ID+"/like", "", false); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+	for i := 0; i < 2; i++ {
+		if w := do("PUT", "/gallery/"+created.ID+"/like", "", true); w.Code != http.StatusOK {
+			t.Fatalf("expected 200 got %d", w.Code)
+		}
+	}
+	if page := browse("/gallery?sort=popular&liked=true", true); page.Total != 1 || page.Decks[0].Likes != 1 {
+		t.Fatalf("expected a single like got %+v", page)
+	}
+	if w := do("DELETE", "/gallery/"+created.ID+"/like", "", true); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+	if page := browse("/gallery?liked=true", true); page.Total != 0 {
+		t.Fatalf("expected no liked decks got %+v", page)
+	}
+
+	_ = do("PUT", "/decks/"+created.ID+"/visibility", `{"visibility":"unlisted"}`, true)
+	if page := browse("/gallery", false); page.Total != 0 {
+		t.Fatalf("expected unlisted decks to stay out of the gallery got %+v", page)
+	}
+	if w := do("GET", "/gallery/"+created.ID, "", false); w.Code != http.StatusOK {
+		t.Fatalf("expected unlisted decks to be shared got %d", w.Code)
+	}
+	for path, code := range map[string]int{
+		"/gallery?sort=oldest":         http.StatusBadRequest,
+		"/gallery?card=x":              http.StatusBadRequest,
+		"/gallery?liked=true":          http.StatusUnauthorized,
+		"/gallery/" + uuid.NewString(): http.StatusNotFound,
+	} {
+		if w := do("GET", path, "", false); w.Code != code {
+			t.Fatalf("GET %s: expected %d got %d", path, code, w.Code)
+		}
+	}
+	if w := do("PUT", "/gallery/"+created.ID+"/like", "", true); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for liking an unlisted deck got %d", w.Code)
+	}
+}
@@ -1,55 +1,82 @@
 package http
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
+	"sort"
 
 	appcmd "demo/internal/application/command"
 	appquery "demo/internal/application/query"
+	"demo/internal/domain/audit"
+	"demo/internal/domain/user"
 	"demo/internal/i18n"
 	"demo/internal/infrastructure/auth"
+	"demo/internal/interfaces/transfer"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-// Router sets up HTTP routes using Gin.
-func Router(authSvc *auth.Service, createHandler *appcmd.CreateCardHandler, updateHandler *appcmd.UpdateCardHandler, searchHandler *appquery.SearchCardsHandler, deckHandler *appcmd.CreateDeckHandler) http.Handler {
+// sortedRowErrors merges row errors of an import in row order. It never
+// returns nil so that responses hold an empty list.
+func sortedRowErrors(lists ...[]appcmd.RowError) []appcmd.RowError {
+	all := []appcmd.RowError{}
+	for _, l := range lists {
+		all = append(all, l...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Row < all[j].Row })
+	return all
+}
+
+// Handlers groups the application handlers served by the router.
+type Handlers struct {
+	CreateCard    *appcmd.CreateCardHandler
+	UpdateCard    *appcmd.UpdateCardHandler
+	SearchCards   *appquery.SearchCardsHandler
+	ImportCards   *appcmd.ImportCardsHandler
+	CreateDeck    *appcmd.CreateDeckHandler
+	UpdateDeck    *appcmd.UpdateDeckHandler
+	DeleteDeck    *appcmd.DeleteDeckHandler
+	CloneDeck     *appcmd.CloneDeckHandler
+	ListDecks     *appquery.ListDecksHandler
+	GetDeck       *appquery.GetDeckHandler
+	DeckCode      *appquery.GetDeckCodeHandler
+	ImportDeck    *appcmd.ImportDeckHandler
+	ImportList    *appcmd.ImportDeckListHandler
+	DeckStats     *appquery.DeckStatsHandler
+	DeckHistory   *appquery.DeckHistoryHandler
+	SetVisibility *appcmd.SetDeckVisibilityHandler
+	LikeDeck      *appcmd.LikeDeckHandler
+	Gallery       *appquery.GalleryHandler
+}
+
+// Router sets up HTTP routes using Gin, rate limited by limits.
+func Router(authSvc *auth.Service, h Handlers, limits RateLimits) http.Handler {
 	r := gin.New()
-	r.Use(otelgin.Middleware("card_service"))
+	r.Use(otelgin.Middleware("card_service"), authenticate(authSvc), tenancy(authSvc.Tenants), rateLimit(limits))
 
-	r.POST("/login", func(c *gin.Context) {
-		var body struct {
-			Username string `json:"username"`
-			Password string `json:"password"`
-		}
-		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
-			return
-		}
-		if token, ok := authSvc.Login(body.Username, body.Password); ok {
-			c.JSON(http.StatusOK, gin.H{"token": token})
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
-		}
-	})
+	userRoutes(r, authSvc)
+	adminRoutes(r.Group("/admin", requirePermission(user.PermAdmin)), authSvc)
+	auditRoutes(r.Group("", requirePermission(user.PermAdmin)), authSvc)
 
-	r.POST("/cards", func(c *gin.Context) {
+	r.POST("/cards", requirePermission(user.PermWriteCards), func(c *gin.Context) {
 		lang := c.GetHeader("Accept-Language")
 		var cmd appcmd.CreateCardCommand
 		if err := c.ShouldBindJSON(&cmd); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
 			return
 		}
-		card, err := createHandler.Handle(c.Request.Context(), cmd)
+		card, err := h.CreateCard.Handle(c.Request.Context(), cmd)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
 			return
 		}
+		_ = authSvc.Record(c.Request.Context(), audit.Entry{Action: audit.CardCreated, Target: card.ID.String(), Details: card.Name})
 		c.JSON(http.StatusOK, i18n.TranslateCard(lang, card))
 	})
 
-	r.PUT("/cards/:id", func(c *gin.Context) {
+	r.PUT("/cards/:id", requirePermission(user.PermWriteCards), func(c *gin.Context) {
 		lang := c.GetHeader("Accept-Language")
 		idStr := c.Param("id")
 		id, err := uuid.Parse(idStr)
@@ -78,15 +105,19 @@ func Router(authSvc *auth.Service, createHandler *appcmd.CreateCardHandler, upda
 			SubCategory: body.SubCategory,
 			Description: body.Description,
 		}
-		card, err := updateHandler.Handle(c.Request.Context(), cmd)
+		card, err := h.UpdateCard.Handle(c.Request.Context(), cmd)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
 			return
 		}
+		// unknown cards are not updated
+		if card != nil {
+			_ = authSvc.Record(c.Request.Context(), audit.Entry{Action: audit.CardUpdated, Target: card.ID.String(), Details: card.Name})
+		}
 		c.JSON(http.StatusOK, i18n.TranslateCard(lang, card))
 	})
 
-	r.GET("/cards", func(c *gin.Context) {
+	r.GET("/cards", restrict(user.PermReadCards), func(c *gin.Context) {
 		lang := c.GetHeader("Accept-Language")
 		q := appquery.SearchCardsQuery{
 			Name:     c.Query("name"),
@@ -97,7 +128,7 @@ func Router(authSvc *auth.Service, createHandler *appcmd.CreateCardHandler, upda
 		if cost := c.Query("cost"); cost != "" {
 			_, _ = fmt.Sscanf(cost, "%d", &q.Cost)
 		}
-		cards, err := searchHandler.Handle(c.Request.Context(), q)
+		cards, err := h.SearchCards.Handle(c.Request.Context(), q)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
 			return
@@ -105,40 +136,48 @@ func Router(authSvc *auth.Service, createHandler *appcmd.CreateCardHandler, upda
 		c.JSON(http.StatusOK, i18n.TranslateCards(lang, cards))
 	})
 
-	r.POST("/decks", func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		var token string
-		fmt.Sscanf(authHeader, "Bearer %s", &token)
-		userID, ok := authSvc.Authenticate(token)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	r.POST("/cards/import", requirePermission(user.PermWriteCards), func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		rows, rowErrs, err := transfer.ReadCardsCSV(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
 			return
 		}
-		var body struct {
-			Name    string
-			CardIDs []string
-		}
-		if err := c.ShouldBindJSON(&body); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+		dryRun := c.Query("dry_run") == "true"
+		res, err := h.ImportCards.Handle(c.Request.Context(), appcmd.ImportCardsCommand{Rows: rows, DryRun: dryRun})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
 			return
 		}
-		var ids []uuid.UUID
-		for _, s := range body.CardIDs {
-			id, err := uuid.Parse(s)
-			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid card id"})
-				return
-			}
-			ids = append(ids, id)
-		}
-		cmd := appcmd.CreateDeckCommand{UserID: userID, Name: body.Name, CardIDs: ids}
-		d, err := deckHandler.Handle(c.Request.Context(), cmd)
+		if !dryRun && len(res.Cards) > 0 {
+			_ = authSvc.Record(c.Request.Context(), audit.Entry{Action: audit.CardsImported, Details: fmt.Sprintf("%d cards", len(res.Cards))})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"dryRun": dryRun,
+			"cards":  i18n.TranslateCards(lang, res.Cards),
+			"errors": sortedRowErrors(rowErrs, res.Errors),
+		})
+	})
+
+	r.GET("/cards/export", restrict(user.PermReadCards), func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		cards, err := h.SearchCards.Handle(c.Request.Context(), appquery.SearchCardsQuery{})
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"id": d.ID.String()})
+		var buf bytes.Buffer
+		if err := transfer.WriteCardsCSV(&buf, cards); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="cards.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
 	})
 
+	deckRoutes(r.Group("", requireAccess(user.PermReadDecks, user.PermWriteDecks)), h)
+	deckAnalysisRoutes(r.Group("", requirePermission(user.PermReadDecks)), h)
+	galleryRoutes(r, h)
+
 	return r
 }
@@ -7,13 +7,20 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	appcmd "demo/internal/application/command"
 	appquery "demo/internal/application/query"
 	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/deck/decktest"
+	"demo/internal/domain/user"
 	"demo/internal/infrastructure/auth"
 	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+	"demo/internal/infrastructure/userstore"
+	"github.com/google/uuid"
 )
 
 type mockRepo struct {
@@ -41,12 +48,78 @@ func (m *mockRepo) Search(ctx context.Context, name string, cost int, faction, c
 	return nil, nil
 }
 
+// testAuth returns an auth service with the accounts user, designer and
+// admin, holding the roles of their names. Their password is "password".
+func testAuth(t *testing.T) *auth.Service {
+	t.Helper()
+	ctx := context.Background()
+	svc := auth.NewService(userstore.NewInMemoryStore())
+	// the default argon2id parameters make every login slow
+	svc.Hasher = auth.Argon2id{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+	for name, role := range map[string]user.Role{"user": user.RolePlayer, "designer": user.RoleDesigner, "admin": user.RoleAdmin} {
+		u, err := svc.Register(ctx, name, name+"@example.com", "password")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := svc.SetRole(ctx, u.ID, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return svc
+}
+
+// login returns an access token of the player registered by testAuth.
+func login(t *testing.T, authSvc *auth.Service) string {
+	t.Helper()
+	return loginAs(t, authSvc, "user")
+}
+
+// loginAs returns an access token of one of the users registered by
+// testAuth.
+func loginAs(t *testing.T, authSvc *auth.Service, username string) string {
+	t.Helper()
+	tokens, err := authSvc.Login(context.Background(), username, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokens.AccessToken
+}
+
+// testHandlers wires every handler to the given repositories.
+func testHandlers(repo card.Repository, store decktest.Store) Handlers {
+	var decks deck.Repository = store
+	gallery := deckstore.NewInMemoryGallery()
+	numbers := deckstore.NewInMemoryCardNumbers()
+	decks = deckstore.NewGalleryProjection(decks, gallery, repo)
+	return Handlers{
+		CreateCard:    &appcmd.CreateCardHandler{Repo: repo},
+		UpdateCard:    &appcmd.UpdateCardHandler{Repo: repo},
+		SearchCards:   &appquery.SearchCardsHandler{Repo: repo},
+		ImportCards:   &appcmd.ImportCardsHandler{Create: &appcmd.CreateCardHandler{Repo: repo}, Update: &appcmd.UpdateCardHandler{Repo: repo}},
+		CreateDeck:    &appcmd.CreateDeckHandler{Repo: decks},
+		UpdateDeck:    &appcmd.UpdateDeckHandler{Repo: decks, History: store},
+		DeleteDeck:    &appcmd.DeleteDeckHandler{Repo: decks},
+		CloneDeck:     &appcmd.CloneDeckHandler{Repo: decks},
+		ListDecks:     &appquery.ListDecksHandler{Decks: decks},
+		GetDeck:       &appquery.GetDeckHandler{Decks: decks, Cards: repo},
+		DeckCode:      &appquery.GetDeckCodeHandler{Decks: decks, Numbers: numbers},
+		ImportDeck:    &appcmd.ImportDeckHandler{Repo: decks, Numbers: numbers},
+		ImportList:    &appcmd.ImportDeckListHandler{Repo: decks, Cards: repo},
+		DeckStats:     &appquery.DeckStatsHandler{Decks: decks, Cards: repo},
+		DeckHistory:   &appquery.DeckHistoryHandler{Decks: decks, History: store},
+		SetVisibility: &appcmd.SetDeckVisibilityHandler{Repo: decks},
+		LikeDeck:      &appcmd.LikeDeckHandler{Gallery: gallery},
+		Gallery:       &appquery.GalleryHandler{Gallery: gallery, Decks: decks, Cards: repo},
+	}
+}
+
 func TestPostInvalidBody(t *testing.T) {
 	repo := &mockRepo{}
-	authSvc := auth.NewService()
+	authSvc := testAuth(t)
 	deckRepo := deckstore.NewInMemoryStore()
-	r := Router(authSvc, &appcmd.CreateCardHandler{Repo: repo}, &appcmd.UpdateCardHandler{Repo: repo}, &appquery.SearchCardsHandler{Repo: repo}, &appcmd.CreateDeckHandler{Repo: deckRepo})
+	r := Router(authSvc, testHandlers(repo, deckRepo), RateLimits{})
 	req := httptest.NewRequest("POST", "/cards", bytes.NewBufferString("{"))
+	req.Header.Set("Authorization", "Bearer "+loginAs(t, authSvc, "designer"))
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusBadRequest {
@@ -56,11 +129,12 @@ func TestPostInvalidBody(t *testing.T) {
 
 func TestPostRepoError(t *testing.T) {
 	repo := &mockRepo{SaveFn: func(ctx context.Context, evts []interface{}) error { return errors.New("fail") }}
-	authSvc := auth.NewService()
+	authSvc := testAuth(t)
 	deckRepo := deckstore.NewInMemoryStore()
-	r := Router(authSvc, &appcmd.CreateCardHandler{Repo: repo}, &appcmd.UpdateCardHandler{Repo: repo}, &appquery.SearchCardsHandler{Repo: repo}, &appcmd.CreateDeckHandler{Repo: deckRepo})
+	r := Router(authSvc, testHandlers(repo, deckRepo), RateLimits{})
 	body := `{"name":"n"}`
 	req := httptest.NewRequest("POST", "/cards", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer "+loginAs(t, authSvc, "designer"))
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusInternalServerError {
@@ -70,10 +144,11 @@ func TestPostRepoError(t *testing.T) {
 
 func TestPutInvalidID(t *testing.T) {
 	repo := &mockRepo{}
-	authSvc := auth.NewService()
+	authSvc := testAuth(t)
 	deckRepo := deckstore.NewInMemoryStore()
-	r := Router(authSvc, &appcmd.CreateCardHandler{Repo: repo}, &appcmd.UpdateCardHandler{Repo: repo}, &appquery.SearchCardsHandler{Repo: repo}, &appcmd.CreateDeckHandler{Repo: deckRepo})
+	r := Router(authSvc, testHandlers(repo, deckRepo), RateLimits{})
 	req := httptest.NewRequest("PUT", "/cards/bad", bytes.NewBufferString("{}"))
+	req.Header.Set("Authorization", "Bearer "+loginAs(t, authSvc, "designer"))
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusBadRequest {
@@ -85,9 +160,9 @@ func TestGetRepoError(t *testing.T) {
 	repo := &mockRepo{SearchFn: func(ctx context.Context, name string, cost int, f, c, s string) ([]*card.Card, error) {
 		return nil, errors.New("fail")
 	}}
-	authSvc := auth.NewService()
+	authSvc := testAuth(t)
 	deckRepo := deckstore.NewInMemoryStore()
-	r := Router(authSvc, &appcmd.CreateCardHandler{Repo: repo}, &appcmd.UpdateCardHandler{Repo: repo}, &appquery.SearchCardsHandler{Repo: repo}, &appcmd.CreateDeckHandler{Repo: deckRepo})
+	r := Router(authSvc, testHandlers(repo, deckRepo), RateLimits{})
 	req := httptest.NewRequest("GET", "/cards", nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
@@ -100,9 +175,9 @@ func TestGetSuccess(t *testing.T) {
 	repo := &mockRepo{SearchFn: func(ctx context.Context, name string, cost int, f, c, s string) ([]*card.Card, error) {
 		return []*card.Card{{Name: "N"}}, nil
 	}}
-	authSvc := auth.NewService()
+	authSvc := testAuth(t)
 	deckRepo := deckstore.NewInMemoryStore()
-	r := Router(authSvc, &appcmd.CreateCardHandler{Repo: repo}, &appcmd.UpdateCardHandler{Repo: repo}, &appquery.SearchCardsHandler{Repo: repo}, &appcmd.CreateDeckHandler{Repo: deckRepo})
+	r := Router(authSvc, testHandlers(repo, deckRepo), RateLimits{})
 	req := httptest.NewRequest("GET", "/cards", nil)
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, req)
@@ -113,9 +188,9 @@ func TestGetSuccess(t *testing.T) {
 
 func TestLoginAndCreateDeck(t *testing.T) {
 	repo := &mockRepo{}
-	authSvc := auth.NewService()
+	authSvc := testAuth(t)
 	deckRepo := deckstore.NewInMemoryStore()
-	r := Router(authSvc, &appcmd.CreateCardHandler{Repo: repo}, &appcmd.UpdateCardHandler{Repo: repo}, &appquery.SearchCardsHandler{Repo: repo}, &appcmd.CreateDeckHandler{Repo: deckRepo})
+	r := Router(authSvc, testHandlers(repo, deckRepo), RateLimits{})
 
 	loginReq := httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"username":"user","password":"password"}`))
 	w := httptest.NewRecorder()
@@ -123,7 +198,6 @@ func TestLoginAndCreateDeck(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected 200 got %d", w.Code)
 	}
-	token := w.Body.String()
 	// token value like {"token":"..."}
 	var resp struct {
 		Token string `json:"token"`
@@ -139,3 +213,89 @@ func TestLoginAndCreateDeck(t *testing.T) {
 		t.Fatalf("expected 200 got %d", w2.Code)
 	}
 }
+
+func TestCreateDeckViolations(t *testing.T) {
+	repo := &mockRepo{}
+	authSvc := testAuth(t)
+	rules, _ := deck.NewRules([]deck.Format{{ID: "std"}})
+	h := testHandlers(repo, deckstore.NewInMemoryStore())
+	h.CreateDeck.Validator = &appcmd.DeckValidator{Rules: rules, Cards: repo}
+	r := Router(authSvc, h, RateLimits{})
+	token := login(t, authSvc)
+
+	req := httptest.NewRequest("POST", "/decks", bytes.NewBufferString(`{"name":"d","format":"std","cardIDs":["`+uuid.NewString()+`"]}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 got %d", w.Code)
+	}
+	var resp struct {
+		Violations []deck.Violation `json:"violations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Violations) != 1 || resp.Violations[0].Rule != deck.RuleUnknownCard {
+		t.Fatalf("unexpected body %s", w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/decks", bytes.NewBufferString(`{"name":"d","format":"other"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+}
+
+func TestCardsImportExport(t *testing.T) {
+	cards := eventstore.NewInMemoryStore()
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(cards, deckstore.NewInMemoryStore()), RateLimits{})
+	designer := loginAs(t, authSvc, "designer")
+	csvBody := "name,cost,faction\nFireball,3,Red\n,1,Red\nBolt,x,Red\nShock,1,Red\n"
+
+	req := httptest.NewRequest("POST", "/cards/import?dry_run=true", bytes.NewBufferString(csvBody))
+	req.Header.Set("Authorization", "Bearer "+designer)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var resp struct {
+		DryRun bool                     `json:"dryRun"`
+		Cards  []map[string]interface{} `json:"cards"`
+		Errors []appcmd.RowError        `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || w.Code != http.StatusOK || !resp.DryRun || len(resp.Cards) != 2 {
+		t.Fatalf("unexpected dry run %d %s", w.Code, w.Body.String())
+	}
+	if len(resp.Errors) != 2 || resp.Errors[0].Row != 3 || resp.Errors[1].Row != 4 {
+		t.Fatalf("unexpected errors %+v", resp.Errors)
+	}
+	if stored, _ := cards.Search(context.Background(), "", 0, "", "", ""); len(stored) != 0 {
+		t.Fatalf("dry run stored %d cards", len(stored))
+	}
+
+	req = httptest.NewRequest("POST", "/cards/import", bytes.NewBufferString(csvBody))
+	req.Header.Set("Authorization", "Bearer "+designer)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d", w.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/cards/export", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv; charset=utf-8" {
+		t.Fatalf("unexpected export %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[1], "Fireball") || !strings.Contains(lines[2], "Shock") {
+		t.Fatalf("unexpected export %q", w.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/cards/import", bytes.NewBufferString("cost\n1\n"))
+	req.Header.Set("Authorization", "Bearer "+designer)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+}
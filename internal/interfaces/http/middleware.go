@@ -0,0 +1,146 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"demo/internal/domain/audit"
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/i18n"
+	"demo/internal/infrastructure/auth"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// bearerToken returns the token of the Authorization header, or "" when the
+// request has none.
+func bearerToken(c *gin.Context) string {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	return strings.TrimSpace(token)
+}
+
+// deniedKey is the gin context key under which the permission middlewares
+// leave the permission they denied.
+const deniedKey = "auth.denied"
+
+// authenticate returns the middleware authenticating every request. The
+// client and the principal of a valid bearer token are put into the request
+// context, where auth.ClientFromContext and auth.FromContext find them.
+// Requests without an Authorization header pass through anonymously while
+// invalid tokens are rejected with 401. Permissions denied further down the
+// chain are recorded in the audit log.
+func authenticate(authSvc *auth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		client := auth.Client{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+		c.Request = c.Request.WithContext(auth.WithClient(c.Request.Context(), client))
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		p, ok := authSvc.Authenticate(c.Request.Context(), bearerToken(c))
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), p))
+		c.Next()
+		if perm, ok := c.Get(deniedKey); ok {
+			_ = authSvc.Record(c.Request.Context(), audit.Entry{
+				Action:  audit.PermissionDenied,
+				Target:  c.Request.Method + " " + c.FullPath(),
+				Details: string(perm.(user.Permission)),
+			})
+		}
+	}
+}
+
+// TenantHeader is the request header naming the tenant of a request.
+const TenantHeader = "X-Tenant"
+
+// tenancy returns the middleware putting the tenant of every request into
+// its context, where tenant.FromContext finds it. The tenant is taken from
+// the X-Tenant header or else from the access token or API key; requests
+// with neither belong to the default tenant. Invalid tenants are rejected
+// with 400, tenants missing from tenants with 404 and headers naming another
+// tenant than the access token or API key with 403. It must run after
+// authenticate.
+func tenancy(tenants *tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		id := c.GetHeader(TenantHeader)
+		p, _ := auth.FromContext(c.Request.Context())
+		switch {
+		case id == "":
+			id = p.Tenant
+		case tenant.Check(id) != nil:
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_tenant")})
+			return
+		case p.Tenant != "" && p.Tenant != id:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": i18n.Translate(lang, "tenant_mismatch")})
+			return
+		}
+		if id != "" && !tenants.Known(id) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": i18n.Translate(lang, "unknown_tenant")})
+			return
+		}
+		c.Request = c.Request.WithContext(tenant.NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// deny rejects a request of a user lacking perm with 403.
+func deny(c *gin.Context, perm user.Permission) {
+	c.Set(deniedKey, perm)
+	lang := c.GetHeader("Accept-Language")
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": i18n.Translate(lang, "forbidden")})
+}
+
+// requirePermission rejects anonymous requests with 401 and requests of users
+// lacking perm with 403.
+func requirePermission(perm user.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		p, ok := auth.FromContext(c.Request.Context())
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		if !p.Can(perm) {
+			deny(c, perm)
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireAccess applies requirePermission with read for GET and HEAD
+// requests and with write for any other method.
+func requireAccess(read, write user.Permission) gin.HandlerFunc {
+	readers, writers := requirePermission(read), requirePermission(write)
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			readers(c)
+		} else {
+			writers(c)
+		}
+	}
+}
+
+// restrict guards endpoints open to anonymous requests: authenticated
+// callers lacking perm, such as API keys without the scope, get 403.
+func restrict(perm user.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if p, ok := auth.FromContext(c.Request.Context()); ok && !p.Can(perm) {
+			deny(c, perm)
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUser returns the id of the authenticated user, or uuid.Nil for
+// anonymous requests.
+func currentUser(c *gin.Context) uuid.UUID {
+	p, _ := auth.FromContext(c.Request.Context())
+	return p.UserID
+}
@@ -0,0 +1,206 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"demo/internal/domain/tenant"
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/auth"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+)
+
+func TestCardPermissions(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(eventstore.NewInMemoryStore(), deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	player, designer, admin := login(t, authSvc), loginAs(t, authSvc, "designer"), loginAs(t, authSvc, "admin")
+
+	for _, route := range []struct{ method, path, body string }{
+		{"POST", "/cards", `{"name":"n"}`},
+		{"PUT", "/cards/00000000-0000-0000-0000-000000000001", `{"name":"n"}`},
+		{"POST", "/cards/import", "name\nn\n"},
+	} {
+		for token, code := range map[string]int{
+			"":       http.StatusUnauthorized,
+			"bogus":  http.StatusUnauthorized,
+			player:   http.StatusForbidden,
+			designer: http.StatusOK,
+			admin:    http.StatusOK,
+		} {
+			if got := do(route.method, route.path, route.body, token); got != code {
+				t.Fatalf("%s %s: expected %d got %d", route.method, route.path, code, got)
+			}
+		}
+	}
+
+	if got := do("GET", "/cards", "", ""); got != http.StatusOK {
+		t.Fatalf("expected anonymous searches to work got %d", got)
+	}
+	if got := do("GET", "/cards", "", "bogus"); got != http.StatusUnauthorized {
+		t.Fatalf("expected invalid tokens to be rejected everywhere got %d", got)
+	}
+	if got := do("GET", "/decks", "", ""); got != http.StatusUnauthorized {
+		t.Fatalf("expected decks to need a user got %d", got)
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(eventstore.NewInMemoryStore(), deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+	ctx := context.Background()
+	key := func(username string, scopes ...user.Permission) string {
+		u, _ := authSvc.Users.ByUsername(ctx, username)
+		_, secret, err := authSvc.CreateAPIKey(ctx, u.ID, "test", scopes, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return secret
+	}
+	readCards := key("designer", user.PermReadCards)
+	writeCards := key("designer", user.PermReadCards, user.PermWriteCards)
+	playerWrite := key("user", user.PermWriteCards)
+	readDecks := key("user", user.PermReadDecks)
+
+	for _, c := range []struct {
+		method, path, body, token string
+		code                      int
+	}{
+		{"GET", "/cards", "", readCards, http.StatusOK},
+		{"GET", "/cards", "", readDecks, http.StatusForbidden},
+		{"POST", "/cards", `{"name":"n"}`, readCards, http.StatusForbidden},
+		{"POST", "/cards", `{"name":"n"}`, writeCards, http.StatusOK},
+		{"POST", "/cards", `{"name":"n"}`, playerWrite, http.StatusForbidden},
+		{"GET", "/decks", "", readDecks, http.StatusOK},
+		{"GET", "/decks", "", readCards, http.StatusForbidden},
+		{"POST", "/decks", `{"name":"d"}`, readDecks, http.StatusForbidden},
+		{"POST", "/decks/stats", `{"cardIDs":[]}`, readDecks, http.StatusOK},
+		{"POST", "/decks/stats", `{"cardIDs":[]}`, readCards, http.StatusForbidden},
+		{"GET", "/gallery", "", readDecks, http.StatusOK},
+		{"GET", "/gallery", "", readCards, http.StatusForbidden},
+		{"POST", "/logout", "", readDecks, http.StatusForbidden},
+		{"DELETE", "/users/me", "", readDecks, http.StatusForbidden},
+		{"GET", "/decks", "", auth.APIKeyPrefix + "bogus", http.StatusUnauthorized},
+	} {
+		if got := do(c.method, c.path, c.body, c.token); got != c.code {
+			t.Fatalf("%s %s: expected %d got %d", c.method, c.path, c.code, got)
+		}
+	}
+}
+
+func TestTenancy(t *testing.T) {
+	authSvc := testAuth(t)
+	tenants, err := tenant.NewRegistry("acme", "globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authSvc.Tenants = tenants
+	r := Router(authSvc, testHandlers(eventstore.NewInMemoryStore(), deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, tenantID, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if tenantID != "" {
+			req.Header.Set(TenantHeader, tenantID)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	loginTo := func(tenantID, username string) string {
+		t.Helper()
+		w := do("POST", "/login", `{"username":"`+username+`","password":"password"}`, tenantID, "")
+		var resp struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Token == "" {
+			t.Fatalf("login %s: %d %s", tenantID, w.Code, w.Body)
+		}
+		return resp.Token
+	}
+	designer := loginTo("acme", "designer")
+
+	if w := do("POST", "/cards", `{"name":"Acme Rocket"}`, "", designer); w.Code != http.StatusOK {
+		t.Fatalf("expected the tenant of the token to be used got %d", w.Code)
+	}
+	for tenantID, want := range map[string]int{"acme": 1, "globex": 0, "": 0} {
+		var cards []json.RawMessage
+		w := do("GET", "/cards?name=Acme%20Rocket", "", tenantID, "")
+		if err := json.Unmarshal(w.Body.Bytes(), &cards); err != nil || len(cards) != want {
+			t.Fatalf("tenant %q: expected %d cards got %s", tenantID, want, w.Body)
+		}
+	}
+
+	if w := do("GET", "/cards", "", "globex", designer); w.Code != http.StatusForbidden {
+		t.Fatalf("expected a token of acme to be refused in globex got %d", w.Code)
+	}
+	if w := do("GET", "/cards", "", "acme", designer); w.Code != http.StatusOK {
+		t.Fatalf("expected the tenant of the token to be accepted got %d", w.Code)
+	}
+	for _, tenantID := range []string{"Acme", "acme corp", "-acme"} {
+		if w := do("GET", "/cards", "", tenantID, ""); w.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400 got %d", tenantID, w.Code)
+		}
+	}
+	if w := do("POST", "/login", `{"username":"user","password":"password"}`, "initech", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown tenant to be refused got %d", w.Code)
+	}
+
+	// API keys only work in the tenant they were created in
+	u, _ := authSvc.Users.ByUsername(context.Background(), "designer")
+	_, key, err := authSvc.CreateAPIKey(tenant.NewContext(context.Background(), "acme"), u.ID, "ci", []user.Permission{user.PermReadCards}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cards []json.RawMessage
+	if w := do("GET", "/cards?name=Acme%20Rocket", "", "", key); json.Unmarshal(w.Body.Bytes(), &cards) != nil || len(cards) != 1 {
+		t.Fatalf("expected the tenant of the API key to be used got %d %s", w.Code, w.Body)
+	}
+	if w := do("GET", "/cards", "", "globex", key); w.Code != http.StatusForbidden {
+		t.Fatalf("expected an API key of acme to be refused in globex got %d", w.Code)
+	}
+
+	player := loginTo("acme", "user")
+	w := do("POST", "/decks", `{"name":"d","cardIDs":[]}`, "", player)
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || created.ID == "" {
+		t.Fatalf("create deck: %d %s", w.Code, w.Body)
+	}
+	if w := do("GET", "/decks/"+created.ID, "", "", player); w.Code != http.StatusOK {
+		t.Fatalf("expected the deck in acme got %d", w.Code)
+	}
+	other := loginTo("globex", "user")
+	if w := do("GET", "/decks/"+created.ID, "", "", other); w.Code != http.StatusNotFound {
+		t.Fatalf("expected the deck not to be found in globex got %d", w.Code)
+	}
+	if w := do("DELETE", "/decks/"+created.ID, "", "", other); w.Code == http.StatusOK || w.Code == http.StatusNoContent {
+		t.Fatalf("expected the deck not to be deleted from globex got %d", w.Code)
+	}
+	if w := do("GET", "/decks/"+created.ID, "", "", player); w.Code != http.StatusOK {
+		t.Fatalf("expected the deck to be kept in acme got %d", w.Code)
+	}
+}
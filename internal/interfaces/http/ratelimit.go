@@ -0,0 +1,99 @@
+package http
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"demo/internal/i18n"
+	"demo/internal/infrastructure/auth"
+	"demo/internal/infrastructure/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RateLimits configures the rate limiting of the router. Requests are
+// limited per API key, user or, when anonymous, client address. A nil
+// Limiter turns rate limiting off.
+type RateLimits struct {
+	Limiter ratelimit.Limiter
+	// Routes maps routes, a method and the path as registered such as
+	// "GET /decks/:id", to their policy. Each route has buckets of its own
+	// while the other routes share those of Default.
+	Routes  map[string]ratelimit.Policy
+	Default ratelimit.Policy
+}
+
+// DefaultRateLimits returns the policies of the service: card searches,
+// which replay every card, and the other expensive routes are limited
+// tighter than the rest.
+func DefaultRateLimits(l ratelimit.Limiter) RateLimits {
+	return RateLimits{
+		Limiter: l,
+		Routes: map[string]ratelimit.Policy{
+			"GET /cards":         {Limit: 30, Period: time.Minute, Burst: 10},
+			"GET /gallery":       {Limit: 60, Period: time.Minute, Burst: 20},
+			"POST /cards/import": {Limit: 10, Period: time.Minute, Burst: 2},
+			"POST /decks/stats":  {Limit: 60, Period: time.Minute, Burst: 10},
+		},
+		Default: ratelimit.Policy{Limit: 600, Period: time.Minute, Burst: 100},
+	}
+}
+
+// rateLimitKey returns the key of the caller of a request.
+func rateLimitKey(c *gin.Context) string {
+	p, _ := auth.FromContext(c.Request.Context())
+	switch {
+	case p.APIKeyID != uuid.Nil:
+		return "key:" + p.APIKeyID.String()
+	case p.UserID != uuid.Nil:
+		return "user:" + p.UserID.String()
+	}
+	return "ip:" + auth.ClientFromContext(c.Request.Context()).IP
+}
+
+// seconds rounds d up to whole seconds.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// rateLimit returns the middleware applying limits. Responses carry the
+// RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy
+// headers, and denied requests get 429 with Retry-After. Requests pass when
+// the limiter fails, so that an outage of its store does not take the
+// service down. It must run after authenticate.
+func rateLimit(limits RateLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limits.Limiter == nil {
+			c.Next()
+			return
+		}
+		route := c.Request.Method + " " + c.FullPath()
+		policy, ok := limits.Routes[route]
+		if !ok {
+			route, policy = "default", limits.Default
+		}
+		if policy.Unlimited() {
+			c.Next()
+			return
+		}
+		res, err := limits.Limiter.Allow(c.Request.Context(), route+"|"+rateLimitKey(c), policy)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Header("RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Header("RateLimit-Reset", seconds(res.ResetAfter))
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%s;burst=%d", policy.Limit, seconds(policy.Period), res.Limit))
+		if !res.Allowed {
+			c.Header("Retry-After", seconds(res.RetryAfter))
+			lang := c.GetHeader("Accept-Language")
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": i18n.Translate(lang, "rate_limited")})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"demo/internal/domain/user"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+	"demo/internal/infrastructure/ratelimit"
+)
+
+// failingLimiter fails every decision.
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, p ratelimit.Policy) (ratelimit.Result, error) {
+	return ratelimit.Result{}, context.DeadlineExceeded
+}
+
+func TestRateLimit(t *testing.T) {
+	authSvc := testAuth(t)
+	limits := RateLimits{
+		Limiter: ratelimit.NewInMemoryLimiter(),
+		Routes:  map[string]ratelimit.Policy{"GET /cards": {Limit: 2, Period: time.Minute}},
+		Default: ratelimit.Policy{Limit: 60, Period: time.Minute, Burst: 10},
+	}
+	r := Router(authSvc, testHandlers(eventstore.NewInMemoryStore(), deckstore.NewInMemoryStore()), limits)
+	do := func(path, ip, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = ip + ":1234"
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, remaining := range []string{"1", "0"} {
+		w := do("/cards", "10.0.0.1", "")
+		if w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != remaining {
+			t.Fatalf("expected 200 with %s remaining got %d %v", remaining, w.Code, w.Header())
+		}
+		if got := w.Header().Get("RateLimit-Policy"); got != "2;w=60;burst=2" {
+			t.Fatalf("unexpected policy %q", got)
+		}
+	}
+	w := do("/cards", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "30" || w.Header().Get("RateLimit-Reset") != "60" {
+		t.Fatalf("expected 429 with Retry-After: 30 got %d %v", w.Code, w.Header())
+	}
+	if w := do("/cards", "10.0.0.2", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected other addresses to be limited apart got %d", w.Code)
+	}
+	if w := do("/cards/export", "10.0.0.1", ""); w.Code == http.StatusTooManyRequests || w.Header().Get("RateLimit-Limit") != "10" {
+		t.Fatalf("expected other routes to use the default policy got %d %v", w.Code, w.Header())
+	}
+
+	// users are limited apart from their address and from each other
+	token := login(t, authSvc)
+	for i := 0; i < 2; i++ {
+		if w := do("/cards", "10.0.0.1", token); w.Code != http.StatusOK {
+			t.Fatalf("expected the user to have buckets of their own got %d", w.Code)
+		}
+	}
+	if w := do("/cards", "10.0.0.1", token); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the user to be limited got %d", w.Code)
+	}
+	if w := do("/cards", "10.0.0.1", loginAs(t, authSvc, "designer")); w.Code != http.StatusOK {
+		t.Fatalf("expected other users to be limited apart got %d", w.Code)
+	}
+	player, _ := authSvc.Users.ByUsername(context.Background(), "user")
+	_, key, err := authSvc.CreateAPIKey(context.Background(), player.ID, "ci", []user.Permission{user.PermReadCards}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := do("/cards", "10.0.0.1", key); w.Code != http.StatusOK {
+		t.Fatalf("expected API keys to be limited apart from their user got %d", w.Code)
+	}
+
+	limits.Limiter = failingLimiter{}
+	r = Router(authSvc, testHandlers(eventstore.NewInMemoryStore(), deckstore.NewInMemoryStore()), limits)
+	if w := do("/cards", "10.0.0.1", ""); w.Code != http.StatusOK || w.Header().Get("RateLimit-Limit") != "" {
+		t.Fatalf("expected requests to pass when the limiter fails got %d", w.Code)
+	}
+}
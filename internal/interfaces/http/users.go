@@ -0,0 +1,170 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"demo/internal/domain/user"
+	"demo/internal/i18n"
+	"demo/internal/infrastructure/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// userError writes the response for a failed account operation.
+func userError(c *gin.Context, lang string, err error) {
+	switch {
+	case errors.Is(err, user.ErrInvalidUsername):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_username")})
+	case errors.Is(err, user.ErrInvalidEmail):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_email")})
+	case errors.Is(err, user.ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "weak_password")})
+	case errors.Is(err, user.ErrInvalidRole):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_role")})
+	case errors.Is(err, auth.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_scope")})
+	case errors.Is(err, auth.ErrInvalidExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_expiry")})
+	case errors.Is(err, user.ErrUsernameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(lang, "username_taken")})
+	case errors.Is(err, user.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": i18n.Translate(lang, "email_taken")})
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+	case errors.Is(err, auth.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+	case errors.Is(err, auth.ErrOIDCLogin):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": i18n.Translate(lang, "oidc_login_failed")})
+	case errors.Is(err, auth.ErrLockedOut):
+		var lockout *auth.LockoutError
+		if errors.As(err, &lockout) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockout.RetryAfter.Seconds()))))
+		}
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": i18n.Translate(lang, "login_locked")})
+	case errors.Is(err, auth.ErrPasswordLogin):
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.Translate(lang, "password_login_disabled")})
+	case errors.Is(err, auth.ErrUserDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": i18n.Translate(lang, "user_disabled")})
+	case errors.Is(err, user.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(lang, "user_not_found")})
+	case errors.Is(err, auth.ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(lang, "api_key_not_found")})
+	case errors.Is(err, auth.ErrOIDCDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": i18n.Translate(lang, "oidc_disabled")})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Translate(lang, "internal_error")})
+	}
+}
+
+// tokensJSON is the response body of a login or token refresh.
+func tokensJSON(t *auth.Tokens) gin.H {
+	return gin.H{
+		"token":        t.AccessToken,
+		"refreshToken": t.RefreshToken,
+		"expiresIn":    int(time.Until(t.ExpiresAt).Round(time.Second).Seconds()),
+	}
+}
+
+// userRoutes registers the account endpoints.
+func userRoutes(r *gin.Engine, authSvc *auth.Service) {
+	r.POST("/register", func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		var body struct {
+			Username string `json:"username" binding:"required"`
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": i18n.Translate(lang, "invalid_body")})
+			return
+		}
+		u, err := authSvc.Register(c.Request.Context(), body.Username, body.Email, body.Password)
+		if err != nil {
+			userError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"id": u.ID.String(), "username": u.Username, "email": u.Email})
+	})
+
+	r.POST("/login", func(c *gin.Context) {
+		var body struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		tokens, err := authSvc.Login(c.Request.Context(), body.Username, body.Password)
+		if err != nil {
+			userError(c, c.GetHeader("Accept-Language"), err)
+			return
+		}
+		c.JSON(http.StatusOK, tokensJSON(tokens))
+	})
+
+	r.GET("/oidc/login", func(c *gin.Context) {
+		loginURL, err := authSvc.OIDCLoginURL(c.Request.Context())
+		if err != nil {
+			userError(c, c.GetHeader("Accept-Language"), err)
+			return
+		}
+		c.Redirect(http.StatusFound, loginURL)
+	})
+
+	r.GET("/oidc/callback", func(c *gin.Context) {
+		lang := c.GetHeader("Accept-Language")
+		// the provider reports refused logins in the error parameter
+		if c.Query("error") != "" {
+			userError(c, lang, auth.ErrOIDCLogin)
+			return
+		}
+		tokens, err := authSvc.OIDCCallback(c.Request.Context(), c.Query("state"), c.Query("code"))
+		if err != nil {
+			userError(c, lang, err)
+			return
+		}
+		c.JSON(http.StatusOK, tokensJSON(tokens))
+	})
+
+	r.POST("/token/refresh", func(c *gin.Context) {
+		var body struct {
+			RefreshToken string `json:"refreshToken" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body"})
+			return
+		}
+		tokens, err := authSvc.Refresh(c.Request.Context(), body.RefreshToken)
+		if err != nil {
+			userError(c, c.GetHeader("Accept-Language"), err)
+			return
+		}
+		c.JSON(http.StatusOK, tokensJSON(tokens))
+	})
+
+	r.POST("/logout", requirePermission(user.PermAccount), func(c *gin.Context) {
+		var body struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		// the body is optional
+		_ = c.ShouldBindJSON(&body)
+		if err := authSvc.Logout(c.Request.Context(), bearerToken(c), body.RefreshToken); err != nil {
+			userError(c, c.GetHeader("Accept-Language"), err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	r.DELETE("/users/me", requirePermission(user.PermAccount), func(c *gin.Context) {
+		userID := currentUser(c)
+		if err := authSvc.Delete(c.Request.Context(), userID); err != nil {
+			userError(c, c.GetHeader("Accept-Language"), err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
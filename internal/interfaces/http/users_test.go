@@ -0,0 +1,262 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	appcmd "demo/internal/application/command"
+	"demo/internal/domain/deck"
+	"demo/internal/infrastructure/auth"
+	"demo/internal/infrastructure/auth/authtest"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/eventstore"
+	"demo/internal/infrastructure/shredding"
+)
+
+func TestUserRoutes(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("POST", "/register", `{"username":"alice","email":"Alice@Example.com","password":"secret-pw"}`, "")
+	var created struct{ ID, Username, Email string }
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated || created.Email != "alice@example.com" {
+		t.Fatalf("unexpected register %d %s", w.Code, w.Body.String())
+	}
+	for body, code := range map[string]int{
+		`{"username":"Alice","email":"a2@example.com","password":"secret-pw"}`:     http.StatusConflict,
+		`{"username":"alice2","email":"alice@example.com","password":"secret-pw"}`: http.StatusConflict,
+		`{"username":"alice2","email":"alice","password":"secret-pw"}`:             http.StatusBadRequest,
+		`{"username":"a","email":"a2@example.com","password":"secret-pw"}`:         http.StatusBadRequest,
+		`{"username":"alice2","email":"a2@example.com","password":"short"}`:        http.StatusBadRequest,
+		`{"username":"alice2"}`: http.StatusBadRequest,
+	} {
+		if w := do("POST", "/register", body, ""); w.Code != code {
+			t.Fatalf("register %s: expected %d got %d", body, code, w.Code)
+		}
+	}
+
+	w = do("POST", "/login", `{"username":"alice","password":"secret-pw"}`, "")
+	var login struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil || w.Code != http.StatusOK || login.Token == "" {
+		t.Fatalf("unexpected login %d %s", w.Code, w.Body.String())
+	}
+	if w := do("POST", "/login", `{"username":"alice","password":"wrong-pw"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", w.Code)
+	}
+
+	if w := do("DELETE", "/users/me", "", login.Token); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", w.Code)
+	}
+	if w := do("GET", "/decks", "", login.Token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the session to end got %d", w.Code)
+	}
+	if w := do("POST", "/login", `{"username":"alice","password":"secret-pw"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a deleted user got %d", w.Code)
+	}
+}
+
+func TestTokenRoutes(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	type tokens struct {
+		Token        string
+		RefreshToken string
+		ExpiresIn    int
+	}
+	issue := func(path, body string) tokens {
+		t.Helper()
+		w := do("POST", path, body, "")
+		var got tokens
+		if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK || got.Token == "" || got.RefreshToken == "" {
+			t.Fatalf("POST %s: unexpected %d %s", path, w.Code, w.Body.String())
+		}
+		return got
+	}
+
+	login := issue("/login", `{"username":"user","password":"password"}`)
+	if login.ExpiresIn != 900 {
+		t.Fatalf("expected the access token to expire in 15 minutes got %d", login.ExpiresIn)
+	}
+	refreshed := issue("/token/refresh", `{"refreshToken":"`+login.RefreshToken+`"}`)
+	if w := do("GET", "/decks", "", refreshed.Token); w.Code != http.StatusOK {
+		t.Fatalf("expected the refreshed token to work got %d", w.Code)
+	}
+	if w := do("POST", "/token/refresh", `{"refreshToken":"`+login.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a used refresh token to be rejected got %d", w.Code)
+	}
+	if w := do("POST", "/token/refresh", `{}`, ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", w.Code)
+	}
+
+	if w := do("POST", "/logout", `{"refreshToken":"`+refreshed.RefreshToken+`"}`, refreshed.Token); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", w.Code)
+	}
+	if w := do("GET", "/decks", "", refreshed.Token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the access token to be revoked got %d", w.Code)
+	}
+	if w := do("POST", "/token/refresh", `{"refreshToken":"`+refreshed.RefreshToken+`"}`, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the refresh token to be deleted got %d", w.Code)
+	}
+	if w := do("POST", "/logout", "", refreshed.Token); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a revoked token got %d", w.Code)
+	}
+}
+
+func TestOIDCRoutes(t *testing.T) {
+	authSvc := testAuth(t)
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+	if w := get("/oidc/login"); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 without a provider got %d", w.Code)
+	}
+
+	p := authtest.NewOIDCProvider(t)
+	var err error
+	authSvc.OIDC, err = auth.NewOIDCProvider(context.Background(), auth.OIDCConfig{
+		IssuerURL:    p.Issuer(),
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  "http://cards.example.com/oidc/callback",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SignIn(map[string]interface{}{"sub": "1", "email": "sso@example.com", "email_verified": true})
+	w := get("/oidc/login")
+	if w.Code != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider got %d", w.Code)
+	}
+	callback := p.Authorize(t, w.Header().Get("Location"))
+	w = get(callback.RequestURI())
+	var tokens struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &tokens); err != nil || w.Code != http.StatusOK || tokens.Token == "" {
+		t.Fatalf("unexpected %d %s", w.Code, w.Body.String())
+	}
+	req := httptest.NewRequest("GET", "/decks", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.Token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the token to work got %d", w.Code)
+	}
+	if w := get(callback.RequestURI()); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a used callback to be rejected got %d", w.Code)
+	}
+
+	p.SignIn(nil)
+	callback = p.Authorize(t, get("/oidc/login").Header().Get("Location"))
+	if w := get(callback.RequestURI()); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a denied login to be rejected got %d", w.Code)
+	}
+
+	authSvc.DisablePasswords = true
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"username":"user","password":"password"}`)))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected password logins to be disabled got %d", w.Code)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	authSvc := testAuth(t)
+	authSvc.UserLockout = auth.LockoutPolicy{Attempts: 2, Delay: time.Minute, MaxDelay: time.Hour, Window: time.Hour}
+	r := Router(authSvc, testHandlers(&mockRepo{}, deckstore.NewInMemoryStore()), RateLimits{})
+	login := func(password string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("POST", "/login", bytes.NewBufferString(`{"username":"user","password":"`+password+`"}`)))
+		return w
+	}
+	for i := 0; i < 2; i++ {
+		if w := login("wrong"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401 got %d", w.Code)
+		}
+	}
+	w := login("password")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected 429 with Retry-After: 60 got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestDeleteUserForgetsData(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	keys, err := shredding.NewFileKeyStore(filepath.Join(dir, "keys.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := eventstore.NewFileStore(filepath.Join(dir, "events"), eventstore.FileOptions{Cipher: shredding.NewProtector(keys)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+	authSvc := testAuth(t)
+	h := testHandlers(&mockRepo{}, deckstore.NewEventStore(events))
+	authSvc.Forget = &appcmd.ForgetUserHandler{Keys: keys, Decks: h.DeleteDeck.Repo}
+	r := Router(authSvc, h, RateLimits{})
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	token, admin := login(t, authSvc), loginAs(t, authSvc, "admin")
+
+	w := do("POST", "/decks", `{"name":"Secret deck"}`, token)
+	var created struct{ ID string }
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("unexpected %d %s", w.Code, w.Body.String())
+	}
+	if w := do("PUT", "/decks/"+created.ID+"/visibility", `{"visibility":"public"}`, token); w.Code != http.StatusOK {
+		t.Fatalf("expected 200 got %d %s", w.Code, w.Body.String())
+	}
+	if w := do("GET", "/gallery/"+created.ID, "", admin); !strings.Contains(w.Body.String(), "Secret deck") {
+		t.Fatalf("expected the deck in the gallery got %d %s", w.Code, w.Body.String())
+	}
+
+	if w := do("DELETE", "/users/me", "", token); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d %s", w.Code, w.Body.String())
+	}
+	for _, path := range []string{"/gallery", "/gallery/" + created.ID, "/decks/" + created.ID, "/decks/" + created.ID + "/versions"} {
+		if w := do("GET", path, "", admin); strings.Contains(w.Body.String(), "Secret deck") {
+			t.Fatalf("GET %s: expected the deck gone got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+	// the events left in the log replay redacted
+	evts, err := events.Events(ctx, created.ID)
+	if err != nil || len(evts) == 0 {
+		t.Fatalf("unexpected events %v %v", evts, err)
+	}
+	if evt, ok := evts[0].(deck.DeckCreated); !ok || evt.Name != shredding.Redacted {
+		t.Fatalf("expected a redacted name got %+v", evts[0])
+	}
+}
@@ -0,0 +1,142 @@
+// Package transfer reads and writes the file formats used to import and
+// export cards and decks: CSV card catalogs and text or CSV deck lists.
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	appcmd "demo/internal/application/command"
+	"demo/internal/domain/card"
+	"github.com/google/uuid"
+)
+
+// CardColumns are the columns of card CSV files, in the order they are
+// exported. Imports match columns by header name; rows with an id update
+// that card.
+var CardColumns = []string{"id", "name", "cost", "faction", "category", "subcategory", "description"}
+
+// ErrMissingColumn is returned for CSV files lacking a required column.
+var ErrMissingColumn = errors.New("transfer: missing column")
+
+// ReadCardsCSV reads a card CSV file with a header row. Rows that cannot be
+// parsed are reported as row errors; an error is returned only when the file
+// as a whole is unusable.
+func ReadCardsCSV(r io.Reader) ([]appcmd.CardRow, []appcmd.RowError, error) {
+	cr := newCSVReader(r)
+	cols, err := readHeader(cr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, ok := cols["name"]; !ok {
+		return nil, nil, fmt.Errorf("%w: name", ErrMissingColumn)
+	}
+	var rows []appcmd.CardRow
+	var rowErrs []appcmd.RowError
+	err = eachRecord(cr, &rowErrs, func(line int, rec []string) {
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		row := appcmd.CardRow{Row: line, Card: appcmd.CreateCardCommand{
+			Name:        get("name"),
+			Faction:     get("faction"),
+			Category:    get("category"),
+			SubCategory: get("subcategory"),
+			Description: get("description"),
+		}}
+		if s := get("id"); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				rowErrs = append(rowErrs, appcmd.RowError{Row: line, Error: fmt.Sprintf("invalid id %q", s)})
+				return
+			}
+			row.ID = id
+		}
+		if s := get("cost"); s != "" {
+			cost, err := strconv.Atoi(s)
+			if err != nil {
+				rowErrs = append(rowErrs, appcmd.RowError{Row: line, Error: fmt.Sprintf("invalid cost %q", s)})
+				return
+			}
+			row.Card.Cost = cost
+		}
+		rows = append(rows, row)
+	})
+	return rows, rowErrs, err
+}
+
+// WriteCardsCSV writes cards sorted by name as a CSV file with a header row.
+func WriteCardsCSV(w io.Writer, cards []*card.Card) error {
+	sorted := append([]*card.Card(nil), cards...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Name != sorted[j].Name {
+			return sorted[i].Name < sorted[j].Name
+		}
+		return sorted[i].ID.String() < sorted[j].ID.String()
+	})
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CardColumns); err != nil {
+		return err
+	}
+	for _, c := range sorted {
+		rec := []string{c.ID.String(), c.Name, strconv.Itoa(c.Cost), c.Faction, c.Category, c.SubCategory, c.Description}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func newCSVReader(r io.Reader) *csv.Reader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	return cr
+}
+
+// readHeader reads the header row and maps lower-cased column names to
+// their index.
+func readHeader(cr *csv.Reader) (map[string]int, error) {
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: empty file", ErrMissingColumn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string]int)
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return cols, nil
+}
+
+// eachRecord calls fn with every remaining record and the line it starts on.
+// Malformed records are added to rowErrs.
+func eachRecord(cr *csv.Reader, rowErrs *[]appcmd.RowError, fn func(line int, rec []string)) error {
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			*rowErrs = append(*rowErrs, appcmd.RowError{Row: perr.StartLine, Error: perr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		line, _ := cr.FieldPos(0)
+		fn(line, rec)
+	}
+}
@@ -0,0 +1,65 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"demo/internal/domain/card"
+)
+
+func TestReadCardsCSV(t *testing.T) {
+	in := "\ufeffName,Cost,Faction,Description\n" +
+		"Fireball,3,Red,\"Deals 3 damage,\nthen burns\"\n" +
+		"Bad,x,Red,\n" +
+		"\n" +
+		"Bolt,,Red\n"
+	rows, rowErrs, err := ReadCardsCSV(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || len(rowErrs) != 1 {
+		t.Fatalf("unexpected rows %+v errors %+v", rows, rowErrs)
+	}
+	if rows[0].Row != 2 || rows[0].Card.Name != "Fireball" || rows[0].Card.Cost != 3 || rows[0].Card.Description != "Deals 3 damage,\nthen burns" {
+		t.Fatalf("unexpected row %+v", rows[0])
+	}
+	if rowErrs[0].Row != 4 {
+		t.Fatalf("expected error on line 4 got %+v", rowErrs[0])
+	}
+	if rows[1].Row != 6 || rows[1].Card.Name != "Bolt" || rows[1].Card.Cost != 0 {
+		t.Fatalf("unexpected row %+v", rows[1])
+	}
+
+	if _, rowErrs, _ := ReadCardsCSV(strings.NewReader("id,name\nx,A\n")); len(rowErrs) != 1 {
+		t.Fatalf("expected an invalid id to be rejected got %+v", rowErrs)
+	}
+	if _, _, err := ReadCardsCSV(strings.NewReader("cost\n1\n")); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn got %v", err)
+	}
+	if _, _, err := ReadCardsCSV(strings.NewReader("")); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn for empty file got %v", err)
+	}
+}
+
+func TestCardsCSVRoundTrip(t *testing.T) {
+	cards := []*card.Card{
+		card.NewCard("B", 2, "F", "C", "S", "with \"quotes\", commas"),
+		card.NewCard("A", 1, "F", "C", "S", "D"),
+	}
+	var buf bytes.Buffer
+	if err := WriteCardsCSV(&buf, cards); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), strings.Join(CardColumns, ",")+"\n") {
+		t.Fatalf("unexpected header in %q", buf.String())
+	}
+	rows, rowErrs, err := ReadCardsCSV(&buf)
+	if err != nil || len(rowErrs) != 0 || len(rows) != 2 {
+		t.Fatalf("unexpected %+v %+v %v", rows, rowErrs, err)
+	}
+	if rows[0].Card.Name != "A" || rows[0].ID != cards[1].ID || rows[1].Card.Description != cards[0].Description || rows[1].Card.Cost != 2 {
+		t.Fatalf("unexpected rows %+v", rows)
+	}
+}
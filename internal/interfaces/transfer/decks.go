@@ -0,0 +1,173 @@
+package transfer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appcmd "demo/internal/application/command"
+	appquery "demo/internal/application/query"
+	"demo/internal/domain/deck"
+)
+
+// Deck list formats.
+const (
+	// ListText is one card per line, e.g. "3x Fireball". The count may be
+	// written as "3 Fireball" or left out for a single copy; blank lines and
+	// lines starting with "#" or "//" are ignored. Cards are named or given
+	// by id. A line like "Sideboard:" puts the cards after it in that zone;
+	// cards before the first such line are in the main zone.
+	ListText = "text"
+	// ListCSV is a CSV file with a header row and the columns count, id,
+	// name and zone, of which id or name is required. An empty zone is the
+	// main zone.
+	ListCSV = "csv"
+)
+
+// ErrUnknownListFormat is returned for deck list formats other than ListText
+// and ListCSV.
+var ErrUnknownListFormat = errors.New("transfer: unknown deck list format")
+
+var (
+	textLine = regexp.MustCompile(`^(\d+)\s*[xX]?\s+(\S.*)$`)
+	zoneLine = regexp.MustCompile(`^([A-Za-z][\w -]*):$`)
+)
+
+// ReadDeckList reads a deck list in the given format. Lines that cannot be
+// parsed are reported as row errors.
+func ReadDeckList(r io.Reader, format string) ([]appcmd.DeckListLine, []appcmd.RowError, error) {
+	switch format {
+	case ListText:
+		return readDeckText(r)
+	case ListCSV:
+		return readDeckCSV(r)
+	default:
+		return nil, nil, ErrUnknownListFormat
+	}
+}
+
+func readDeckText(r io.Reader) ([]appcmd.DeckListLine, []appcmd.RowError, error) {
+	var lines []appcmd.DeckListLine
+	var rowErrs []appcmd.RowError
+	zone := deck.ZoneMain
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || strings.HasPrefix(s, "#") || strings.HasPrefix(s, "//") {
+			continue
+		}
+		if m := zoneLine.FindStringSubmatch(s); m != nil {
+			zone = strings.ToLower(strings.TrimSpace(m[1]))
+			continue
+		}
+		line := appcmd.DeckListLine{Row: n, Count: 1, Card: s, Zone: zone}
+		if m := textLine.FindStringSubmatch(s); m != nil {
+			count, err := strconv.Atoi(m[1])
+			if err != nil {
+				rowErrs = append(rowErrs, appcmd.RowError{Row: n, Error: fmt.Sprintf("invalid count %q", m[1])})
+				continue
+			}
+			line.Count, line.Card = count, strings.TrimSpace(m[2])
+		}
+		lines = append(lines, line)
+	}
+	return lines, rowErrs, sc.Err()
+}
+
+func readDeckCSV(r io.Reader) ([]appcmd.DeckListLine, []appcmd.RowError, error) {
+	cr := newCSVReader(r)
+	cols, err := readHeader(cr)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, hasID := cols["id"]
+	_, hasName := cols["name"]
+	if !hasID && !hasName {
+		return nil, nil, fmt.Errorf("%w: id or name", ErrMissingColumn)
+	}
+	var lines []appcmd.DeckListLine
+	var rowErrs []appcmd.RowError
+	err = eachRecord(cr, &rowErrs, func(n int, rec []string) {
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		line := appcmd.DeckListLine{Row: n, Count: 1, Card: get("id"), Zone: strings.ToLower(get("zone"))}
+		if line.Zone == "" {
+			line.Zone = deck.ZoneMain
+		}
+		if line.Card == "" {
+			line.Card = get("name")
+		}
+		if line.Card == "" {
+			rowErrs = append(rowErrs, appcmd.RowError{Row: n, Error: "id or name is required"})
+			return
+		}
+		if s := get("count"); s != "" {
+			count, err := strconv.Atoi(s)
+			if err != nil {
+				rowErrs = append(rowErrs, appcmd.RowError{Row: n, Error: fmt.Sprintf("invalid count %q", s)})
+				return
+			}
+			line.Count = count
+		}
+		lines = append(lines, line)
+	})
+	return lines, rowErrs, err
+}
+
+// WriteDeckList writes the cards of a deck in the given format, grouped by
+// zone as returned by GetDeckHandler. Cards missing from the catalog are
+// written by id.
+func WriteDeckList(w io.Writer, cards []appquery.DeckCard, format string) error {
+	switch format {
+	case ListText:
+		bw := bufio.NewWriter(w)
+		zone := deck.ZoneMain
+		for _, dc := range cards {
+			if dc.Zone != zone && dc.Zone != "" {
+				zone = dc.Zone
+				if _, err := fmt.Fprintf(bw, "\n%s%s:\n", strings.ToUpper(zone[:1]), zone[1:]); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(bw, "%dx %s\n", dc.Count, cardRef(dc)); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	case ListCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write([]string{"count", "id", "name", "zone"}); err != nil {
+			return err
+		}
+		for _, dc := range cards {
+			name := ""
+			if dc.Card != nil {
+				name = dc.Card.Name
+			}
+			if err := cw.Write([]string{strconv.Itoa(dc.Count), dc.ID.String(), name, dc.Zone}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return ErrUnknownListFormat
+	}
+}
+
+// cardRef is the text used for a card in a text deck list.
+func cardRef(dc appquery.DeckCard) string {
+	if dc.Card == nil || dc.Card.Name == "" {
+		return dc.ID.String()
+	}
+	return dc.Card.Name
+}
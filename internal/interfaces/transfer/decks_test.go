@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	appcmd "demo/internal/application/command"
+	appquery "demo/internal/application/query"
+	"demo/internal/domain/card"
+	"demo/internal/domain/deck"
+	"github.com/google/uuid"
+)
+
+func TestReadDeckText(t *testing.T) {
+	in := "# burn\n3x Fireball\n\n2 Bolt\nShock\n// sideboard\n1x 2 Headed Giant\nSideboard:\n2x Bolt\n"
+	lines, rowErrs, err := ReadDeckList(strings.NewReader(in), ListText)
+	if err != nil || len(rowErrs) != 0 {
+		t.Fatalf("unexpected %+v %v", rowErrs, err)
+	}
+	want := []appcmd.DeckListLine{
+		{Row: 2, Count: 3, Card: "Fireball", Zone: deck.ZoneMain},
+		{Row: 4, Count: 2, Card: "Bolt", Zone: deck.ZoneMain},
+		{Row: 5, Count: 1, Card: "Shock", Zone: deck.ZoneMain},
+		{Row: 7, Count: 1, Card: "2 Headed Giant", Zone: deck.ZoneMain},
+		{Row: 9, Count: 2, Card: "Bolt", Zone: "sideboard"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("line %d: expected %+v got %+v", i, want[i], lines[i])
+		}
+	}
+	if _, _, err := ReadDeckList(strings.NewReader(""), "xml"); !errors.Is(err, ErrUnknownListFormat) {
+		t.Fatalf("expected ErrUnknownListFormat got %v", err)
+	}
+}
+
+func TestReadDeckCSV(t *testing.T) {
+	id := uuid.NewString()
+	in := "count,id,name,zone\n3,,Fireball,\n1," + id + ",Bolt,Sideboard\nx,,Shock,\n2,,,\n"
+	lines, rowErrs, err := ReadDeckList(strings.NewReader(in), ListCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 || lines[0].Card != "Fireball" || lines[0].Count != 3 || lines[1].Card != id || lines[0].Zone != deck.ZoneMain || lines[1].Zone != "sideboard" {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+	if len(rowErrs) != 2 || rowErrs[0].Row != 4 || rowErrs[1].Row != 5 {
+		t.Fatalf("unexpected errors %+v", rowErrs)
+	}
+	if _, _, err := ReadDeckList(strings.NewReader("count\n1\n"), ListCSV); !errors.Is(err, ErrMissingColumn) {
+		t.Fatalf("expected ErrMissingColumn got %v", err)
+	}
+}
+
+func TestWriteDeckList(t *testing.T) {
+	fireball := card.NewCard("Fireball", 3, "F", "C", "S", "D")
+	missing := uuid.New()
+	cards := []appquery.DeckCard{
+		{ID: fireball.ID, Card: fireball, Count: 3, Zone: "main"},
+		{ID: missing, Count: 1, Zone: "main"},
+		{ID: fireball.ID, Card: fireball, Count: 1, Zone: "sideboard"},
+	}
+
+	var text bytes.Buffer
+	if err := WriteDeckList(&text, cards, ListText); err != nil {
+		t.Fatal(err)
+	}
+	if text.String() != "3x Fireball\n1x "+missing.String()+"\n\nSideboard:\n1x Fireball\n" {
+		t.Fatalf("unexpected text %q", text.String())
+	}
+	lines, _, _ := ReadDeckList(&text, ListText)
+	if len(lines) != 3 || lines[0].Count != 3 || lines[1].Card != missing.String() || lines[2].Zone != "sideboard" {
+		t.Fatalf("unexpected round trip %+v", lines)
+	}
+
+	var csvOut bytes.Buffer
+	if err := WriteDeckList(&csvOut, cards, ListCSV); err != nil {
+		t.Fatal(err)
+	}
+	lines, _, _ = ReadDeckList(&csvOut, ListCSV)
+	if len(lines) != 3 || lines[0].Card != fireball.ID.String() || lines[0].Count != 3 || lines[2].Zone != "sideboard" {
+		t.Fatalf("unexpected round trip %+v", lines)
+	}
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"demo/internal/domain/audit"
+	"demo/internal/domain/audit/audittest"
+	"demo/internal/domain/card"
+	"demo/internal/domain/card/cardtest"
+	"demo/internal/domain/deck"
+	"demo/internal/domain/deck/decktest"
+	"demo/internal/domain/user"
+	"demo/internal/domain/user/usertest"
+	"demo/internal/infrastructure/auditstore"
+	"demo/internal/infrastructure/auth"
+	"demo/internal/infrastructure/auth/authtest"
+	"demo/internal/infrastructure/deckstore"
+	"demo/internal/infrastructure/userstore"
+)
+
+func TestMySQLConformance(t *testing.T) {
+	repo := openMySQL(t)
+	cardtest.RepositorySuite.Run(t, func(t *testing.T) card.Repository {
+		truncate(repo.DB, "event_records")
+		return repo
+	})
+}
+
+func TestPostgresConformance(t *testing.T) {
+	repo := openPostgres(t)
+	cardtest.RepositorySuite.Run(t, func(t *testing.T) card.Repository {
+		truncate(repo.DB, "event_records")
+		return repo
+	})
+}
+
+func TestMySQLDeckConformance(t *testing.T) {
+	repo, err := deckstore.NewMySQLStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decktest.RepositorySuite.Run(t, func(t *testing.T) deck.Repository {
+		truncate(repo.DB, "decks", "deck_cards", "deck_revisions")
+		return repo
+	})
+}
+
+func TestMySQLDeckEventStoreConformance(t *testing.T) {
+	es := openMySQL(t)
+	index, err := deckstore.NewMySQLUserIndex(es.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decktest.RepositorySuite.Run(t, func(t *testing.T) deck.Repository {
+		truncate(es.DB, "event_records", "user_decks")
+		repo := deckstore.NewEventStore(es)
+		repo.Index = index
+		return repo
+	})
+}
+
+func TestMySQLDeckHistoryConformance(t *testing.T) {
+	repo, err := deckstore.NewMySQLStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decktest.HistorySuite.Run(t, func(t *testing.T) decktest.Store {
+		truncate(repo.DB, "decks", "deck_cards", "deck_revisions")
+		return repo
+	})
+}
+
+func TestMySQLDeckErasureConformance(t *testing.T) {
+	repo, err := deckstore.NewMySQLStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decktest.ErasureSuite.Run(t, func(t *testing.T) decktest.Erasable {
+		truncate(repo.DB, "decks", "deck_cards", "deck_revisions")
+		return decktest.Erasable{Store: repo, Dump: func(t *testing.T) string {
+			var decks []deckstore.DeckRecord
+			var cards []deckstore.DeckCardRecord
+			var revs []deckstore.DeckRevisionRecord
+			for _, rows := range []interface{}{&decks, &cards, &revs} {
+				if err := repo.DB.Find(rows).Error; err != nil {
+					t.Fatal(err)
+				}
+			}
+			return fmt.Sprintf("%+v %+v %+v", decks, cards, revs)
+		}}
+	})
+}
+
+func TestMySQLDeckEventStoreHistoryConformance(t *testing.T) {
+	es := openMySQL(t)
+	decktest.HistorySuite.Run(t, func(t *testing.T) decktest.Store {
+		truncate(es.DB, "event_records")
+		return deckstore.NewEventStore(es)
+	})
+}
+
+func TestMySQLDeckGalleryConformance(t *testing.T) {
+	gallery, err := deckstore.NewMySQLGallery(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decktest.GallerySuite.Run(t, func(t *testing.T) deck.GalleryRepository {
+		truncate(gallery.DB, "gallery_decks", "gallery_deck_cards", "gallery_deck_factions", "gallery_likes")
+		return gallery
+	})
+}
+
+func TestMySQLCardNumberConformance(t *testing.T) {
+	numbers, err := deckstore.NewMySQLCardNumbers(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decktest.CardNumberSuite.Run(t, func(t *testing.T) deck.CardNumberRepository {
+		truncate(numbers.DB, "card_numbers")
+		return numbers
+	})
+}
+
+func TestMySQLUserConformance(t *testing.T) {
+	store, err := userstore.NewMySQLStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usertest.RepositorySuite.Run(t, func(t *testing.T) user.Repository {
+		truncate(store.DB, "users")
+		return store
+	})
+}
+
+func TestMySQLAPIKeyConformance(t *testing.T) {
+	store, err := auth.NewMySQLAPIKeyStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authtest.APIKeySuite.Run(t, func(t *testing.T) auth.APIKeyRepository {
+		truncate(store.DB, "api_keys")
+		return store
+	})
+}
+
+func TestMySQLIdentityConformance(t *testing.T) {
+	store, err := auth.NewMySQLIdentityStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authtest.IdentitySuite.Run(t, func(t *testing.T) auth.IdentityRepository {
+		truncate(store.DB, "user_identities")
+		return store
+	})
+}
+
+func TestMySQLAuditConformance(t *testing.T) {
+	store, err := auditstore.NewMySQLStore(openMySQL(t).DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audittest.RepositorySuite.Run(t, func(t *testing.T) audit.Repository {
+		truncate(store.DB, "audit_log")
+		return store
+	})
+}
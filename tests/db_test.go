@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+
+	"demo/internal/infrastructure/eventstore"
+	"gorm.io/gorm"
+)
+
+// The databases the tests run against. Tests needing one are skipped when it
+// is not running.
+const (
+	mysqlDSN    = "root@tcp(127.0.0.1:3306)/card_test?parseTime=true"
+	postgresDSN = "postgres://postgres@127.0.0.1:5432/card_test?sslmode=disable"
+)
+
+// openMySQL opens the MySQL event store with empty event_records, or skips
+// t when MySQL is not available.
+func openMySQL(t *testing.T) *eventstore.MySQLStore {
+	t.Helper()
+	repo, err := eventstore.NewMySQLStore(mysqlDSN)
+	if err != nil {
+		t.Skipf("mysql not available: %v", err)
+	}
+	truncate(repo.DB, "event_records")
+	return repo
+}
+
+// openPostgres opens the Postgres event store with empty event_records, or
+// skips t when Postgres is not available.
+func openPostgres(t *testing.T) *eventstore.PostgresStore {
+	t.Helper()
+	repo, err := eventstore.NewPostgresStore(postgresDSN)
+	if err != nil {
+		t.Skipf("postgres not available: %v", err)
+	}
+	truncate(repo.DB, "event_records")
+	return repo
+}
+
+// truncate empties tables of db.
+func truncate(db *gorm.DB, tables ...string) {
+	for _, table := range tables {
+		_ = db.Exec("TRUNCATE TABLE " + table)
+	}
+}
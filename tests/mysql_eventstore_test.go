@@ -6,17 +6,10 @@ import (
 
 	"demo/internal/application/command"
 	"demo/internal/application/query"
-	"demo/internal/infrastructure/eventstore"
 )
 
 func TestMySQLCreateAndSearchCard(t *testing.T) {
-	repo, err := eventstore.NewMySQLStore("root@tcp(127.0.0.1:3306)/card_test?parseTime=true")
-	if err != nil {
-		t.Skipf("mysql not available: %v", err)
-	}
-	// clean table
-	_ = repo.DB.Exec("TRUNCATE TABLE event_records")
-
+	repo := openMySQL(t)
 	handler := &command.CreateCardHandler{Repo: repo}
 	card, err := handler.Handle(context.Background(), command.CreateCardCommand{
 		Name:        "Test",
@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"demo/internal/application/command"
+	"demo/internal/application/query"
+	"demo/internal/domain/card"
+	"demo/internal/domain/tenant"
+	"github.com/google/uuid"
+)
+
+func TestPostgresCreateAndSearchCard(t *testing.T) {
+	repo := openPostgres(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	notifications, err := repo.Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &command.CreateCardHandler{Repo: repo}
+	card, err := handler.Handle(ctx, command.CreateCardCommand{
+		Name:        "Test",
+		Cost:        1,
+		Faction:     "Human",
+		Category:    "Soldier",
+		SubCategory: "Infantry",
+		Description: "test card",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case n := <-notifications:
+		if n.Tenant != tenant.Default || n.CardID != card.ID.String() || n.Version != 1 {
+			t.Fatalf("unexpected notification %+v", n)
+		}
+	case <-ctx.Done():
+		t.Fatal("no notification received")
+	}
+
+	queryHandler := &query.SearchCardsHandler{Repo: repo}
+	cards, err := queryHandler.Handle(ctx, query.SearchCardsQuery{Name: "Test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 {
+		t.Fatalf("expected 1 card got %d", len(cards))
+	}
+}
+
+func TestPostgresTenantVersions(t *testing.T) {
+	repo := openPostgres(t)
+
+	// the same stream in two tenants is versioned apart
+	id := uuid.New()
+	for _, tenantID := range []string{"acme", "globex"} {
+		ctx := tenant.NewContext(context.Background(), tenantID)
+		if err := repo.Save(ctx, []interface{}{card.CardCreated{ID: id, Name: tenantID}}); err != nil {
+			t.Fatalf("%s: %v", tenantID, err)
+		}
+		c, err := repo.Load(ctx, id.String())
+		if err != nil || c == nil || c.Name != tenantID {
+			t.Fatalf("%s: unexpected card %+v %v", tenantID, c, err)
+		}
+	}
+}